@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/wedaly/local-news/internal/export"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/query"
+	"github.com/wedaly/local-news/internal/store"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// runExport implements the "export" subcommand, which writes items
+// from the database to a file or standard output.
+func runExport(localizer *i18n.Localizer, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	dbPath := flags.String("db", getDefaultDBPath(), "path to the database")
+	formatName := flags.String("format", "jsonl", "output format: jsonl, csv, markdown, or html")
+	outPath := flags.String("o", "", "output file (default standard output)")
+	title := flags.String("title", "", "title of the Markdown list or HTML page")
+	localizedDates := flags.Bool("localized-dates", false, "format dates for the current locale instead of RFC 3339")
+	feedName := flags.String("feed", "", "export items in the feed with this ID, URL, or name")
+	folder := flags.String("folder", "", "export items in feeds in this folder")
+	highlighted := flags.Bool("highlighted", false, "export items highlighted by filter rules")
+	searchName := flags.String("search", "", "export items matching the saved search with this name")
+	queryText := flags.String("query", "", "export items matching a search query, e.g. \"cve newer:7d\"")
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	format, err := export.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	feedStore := store.NewFeedStore(*dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		return err
+	}
+	defer feedStore.Close()
+
+	feeds, err := feedStore.RetrieveFeeds()
+	if err != nil {
+		return err
+	}
+
+	// Choose the items to export.  Without any options, every item is exported.
+	var records []store.FeedItemRecord
+	q := query.Query{}
+	switch {
+	case len(*feedName) > 0:
+		var feedId store.FeedId
+		if feedId, err = findFeed(feeds, *feedName); err == nil {
+			records, err = feedStore.RetrieveFeedItems(feedId)
+		}
+
+	case len(*searchName) > 0:
+		var searchId store.SavedSearchId
+		if searchId, err = findSavedSearch(feedStore, *searchName); err == nil {
+			records, err = feedStore.RetrieveSavedSearchItems(searchId)
+		}
+
+	default:
+		if len(*queryText) > 0 {
+			if q, err = query.Parse(*queryText); err != nil {
+				return err
+			}
+		}
+
+		if len(*folder) > 0 {
+			q.Terms = append(q.Terms, query.Term{Field: query.FieldFolder, Value: *folder})
+		}
+
+		if *highlighted {
+			q.Terms = append(q.Terms, query.Term{Field: query.FieldIs, Value: query.StateHighlighted})
+		}
+
+		records, err = feedStore.RetrieveQueryItems(q)
+	}
+	if err != nil {
+		return err
+	}
+
+	w := stdout
+	if len(*outPath) > 0 {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	opts := export.Options{Title: *title}
+	if *localizedDates {
+		opts.Localizer = localizer
+	}
+	return export.Write(w, format, export.NewItems(records, feeds), opts)
+}
+
+// findFeed looks up a feed by ID, URL, or name (ignoring case)
+func findFeed(feeds []store.FeedRecord, s string) (store.FeedId, error) {
+	id, _ := strconv.ParseInt(s, 10, 64)
+	for _, feed := range feeds {
+		if int64(feed.Id) == id || feed.Url == s || strings.EqualFold(feed.DisplayName(), s) {
+			return feed.Id, nil
+		}
+	}
+	return 0, fmt.Errorf("No feed found with ID, URL, or name %q", s)
+}
+
+// findSavedSearch looks up a saved search by name (ignoring case)
+func findSavedSearch(feedStore *store.FeedStore, name string) (store.SavedSearchId, error) {
+	searches, err := feedStore.RetrieveSavedSearches()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, search := range searches {
+		if strings.EqualFold(search.Name, name) {
+			return search.Id, nil
+		}
+	}
+	return 0, fmt.Errorf("No saved search found named %q", name)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/wedaly/local-news/internal/mailbox"
+	"github.com/wedaly/local-news/internal/settings"
+	"github.com/wedaly/local-news/internal/store"
+	"github.com/wedaly/local-news/internal/task"
+	"io"
+	"path/filepath"
+	"sync"
+)
+
+// runMail implements the "mail" subcommand, which refreshes every feed
+// and writes the items not yet delivered to a Maildir or mbox as emails.
+func runMail(args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("mail", flag.ContinueOnError)
+	dbPath := flags.String("db", getDefaultDBPath(), "path to the database")
+	maildirPath := flags.String("maildir", "", "deliver to this Maildir")
+	mboxPath := flags.String("mbox", "", "deliver to this mbox file")
+	from := flags.String("from", mailbox.DefaultFrom, "sender address of the emails")
+	refresh := flags.Bool("refresh", true, "refresh every feed before delivering")
+	catchUp := flags.Bool("catch-up", false, "mark the current items delivered without writing them")
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var format mailbox.Format
+	var path string
+	switch {
+	case len(*maildirPath) > 0 && len(*mboxPath) > 0:
+		return fmt.Errorf("Choose either -maildir or -mbox, not both")
+	case len(*maildirPath) > 0:
+		format, path = mailbox.FormatMaildir, *maildirPath
+	case len(*mboxPath) > 0:
+		format, path = mailbox.FormatMbox, *mboxPath
+	default:
+		return fmt.Errorf("A -maildir or -mbox is required")
+	}
+
+	// Deliveries are tracked separately for each mailbox
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	destination := format.String() + ":" + absPath
+
+	appSettings, err := settings.LoadSettings(getSettingsSearchPaths())
+	if err != nil {
+		return err
+	}
+
+	feedStore := store.NewFeedStore(*dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		return err
+	}
+	defer feedStore.Close()
+
+	if *refresh && !*catchUp {
+		if err := refreshAllFeeds(appSettings, feedStore, stderr); err != nil {
+			return err
+		}
+	}
+
+	if *catchUp {
+		records, err := feedStore.RetrieveUndeliveredItems(destination)
+		if err != nil {
+			return err
+		}
+
+		itemIds := make([]store.FeedItemId, len(records))
+		for i, record := range records {
+			itemIds[i] = record.Id
+		}
+		if err := feedStore.MarkItemsDelivered(destination, itemIds); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Marked %v items delivered to %v\n", len(records), path)
+		return nil
+	}
+
+	box, err := mailbox.Open(format, path)
+	if err != nil {
+		return err
+	}
+	defer box.Close()
+
+	n, err := mailbox.DeliverItems(feedStore, destination, box, *from)
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		fmt.Fprintf(stdout, "Delivered %v items to %v\n", n, path)
+	}
+	return nil
+}
+
+// refreshAllFeeds loads every feed and waits until all of them have
+// been loaded.  Feeds that fail to load are reported, but don't stop
+// the others from being delivered.
+func refreshAllFeeds(appSettings settings.Settings, feedStore *store.FeedStore, stderr io.Writer) error {
+	feeds, err := feedStore.RetrieveFeeds()
+	if err != nil {
+		return err
+	}
+
+	taskManager := task.NewTaskManager(feedStore, appSettings.LoaderConfig())
+	syncers, err := newSyncers(appSettings, feedStore)
+	if err != nil {
+		return err
+	}
+	registerRemotes(syncers, taskManager)
+	taskManager.SetArchivePolicy(appSettings.ArchivePolicy())
+
+	waiter := &taskWaiter{stderr: stderr}
+	waiter.Add(len(feeds))
+	taskManager.Subscribe(waiter)
+	for _, f := range feeds {
+		taskManager.ScheduleLoadFeedTask(f.Id)
+	}
+	waiter.Wait()
+
+	// Finish archiving articles before the database is closed
+	taskManager.WaitForArchiveTasks()
+	return nil
+}
+
+// taskWaiter counts completed tasks and reports failures
+type taskWaiter struct {
+	sync.WaitGroup
+	mutex  sync.Mutex
+	stderr io.Writer
+}
+
+// HandleTaskScheduled implements task.TaskSubscriber
+func (w *taskWaiter) HandleTaskScheduled() {
+	// ignore
+}
+
+// HandleTaskCompleted implements task.TaskSubscriber
+func (w *taskWaiter) HandleTaskCompleted(result task.TaskResult) {
+	defer w.Done()
+	if result.Err != nil {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		fmt.Fprintf(w.stderr, "Could not refresh feed %v: %v\n", result.FeedId, result.Err)
+	}
+}
@@ -3,42 +3,83 @@ package main
 import (
 	"fmt"
 	"github.com/wedaly/local-news/internal/controller"
+	"github.com/wedaly/local-news/internal/download"
+	"github.com/wedaly/local-news/internal/hook"
 	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/notify"
+	"github.com/wedaly/local-news/internal/readlater"
+	"github.com/wedaly/local-news/internal/settings"
 	"github.com/wedaly/local-news/internal/store"
 	"github.com/wedaly/local-news/internal/task"
 	"os"
 	"os/user"
 	"path"
+	"time"
 )
 
 func main() {
+	localizer := newLocalizer()
+
+	// Subcommands have their own arguments.  An existing database
+	// with the same name as a subcommand (e.g. "sync") is opened instead.
+	if len(os.Args) > 1 && !fileExists(os.Args[1]) {
+		switch os.Args[1] {
+		case "export":
+			if err := runExport(localizer, os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not export items: %v\n", err)
+				os.Exit(1)
+			}
+			return
+
+		case "mail":
+			if err := runMail(os.Args[2:], os.Stdout, os.Stderr); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not deliver items: %v\n", err)
+				os.Exit(1)
+			}
+			return
+
+		case "publish":
+			if err := runPublish(os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not publish items: %v\n", err)
+				os.Exit(1)
+			}
+			return
+
+		case "sync":
+			if err := runSync(os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not sync: %v\n", err)
+				os.Exit(1)
+			}
+			return
+
+		case "serve":
+			if err := runServe(os.Args[2:], os.Stderr); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not serve the API: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// Command line arg to set the DB path (optional)
 	dbPath := getDefaultDBPath()
 	if len(os.Args) > 1 {
 		dbPath = os.Args[1]
 	}
 
-	// Set locale from environment variables (e.g. LC_ALL)
-	if err := i18n.SetLocaleFromEnv(); err != nil {
-		// Fallback to "C", which should be available everywhere
-		if err := i18n.SetLocale("C"); err != nil {
-			panic(err)
-		}
-	}
-
-	// Initialize i18n modules based on current locale
-	i18n.InitTranslations("localnews", []string{
-		"./configs/locale",
-		"/usr/share/locale",
-	})
-	i18n.InitDateFormats()
-
 	// Load localized app configuration
-	config := i18n.LoadConfig([]string{
+	config := i18n.LoadConfig(localizer, []string{
 		"./configs/etc",
 		"/etc/localnews",
 	})
 
+	// Load settings that apply regardless of locale
+	appSettings, err := settings.LoadSettings(getSettingsSearchPaths())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not load settings: %v", err)
+		os.Exit(1)
+	}
+
 	// Open connection to the SQLite database
 	feedStore := store.NewFeedStore(dbPath)
 	if err := feedStore.Initialize(); err != nil {
@@ -48,19 +89,120 @@ func main() {
 	defer feedStore.Close()
 
 	// Set up task manager
-	taskManager := task.NewTaskManager(feedStore)
+	taskManager := task.NewTaskManager(feedStore, appSettings.LoaderConfig())
+
+	// Load feeds linked to remote accounts from their servers
+	syncers, err := newSyncers(appSettings, feedStore)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not load settings: %v", err)
+		os.Exit(1)
+	}
+	registerRemotes(syncers, taskManager)
+	taskManager.SetArchivePolicy(appSettings.ArchivePolicy())
+
+	// Finish archiving articles before the database is closed
+	defer taskManager.WaitForArchiveTasks()
+
+	// Send new items to the hooks in the settings, if any
+	if hooks := appSettings.HookList(); len(hooks) > 0 {
+		hookRunner := hook.NewRunner(hooks, feedStore)
+		taskManager.Subscribe(hookRunner)
+		defer hookRunner.Wait()
+	}
+
+	// Periodically refresh feeds in the background
+	scheduler := task.NewScheduler(feedStore, taskManager, appSettings.RefreshPolicy())
+	scheduler.Start(time.Minute)
+	defer scheduler.Stop()
+
+	// Set up manager for podcast episodes and other enclosures.
+	// Downloads use the loader's proxy and TLS settings, but have
+	// no overall timeout, since large files can take a long time.
+	downloadLoaderConfig := appSettings.LoaderConfig()
+	downloadLoaderConfig.Timeout = 0
+	downloadClient, err := downloadLoaderConfig.NewHttpClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not load settings: %v", err)
+		os.Exit(1)
+	}
+	downloadManager := download.NewManager(appSettings.DownloadConfig(), downloadClient)
+
+	// Send articles to the read-later service, if enabled in the settings
+	var outbox *readlater.Outbox
+	if readLaterConfig := appSettings.ReadLaterConfig(); readLaterConfig.Enabled() {
+		httpClient, err := appSettings.LoaderConfig().NewHttpClient()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not load settings: %v", err)
+			os.Exit(1)
+		}
+		outbox = readlater.NewWallabagOutbox(readLaterConfig, httpClient, feedStore)
+		outbox.Start()
+		defer outbox.Stop()
+	}
 
 	// Set up TUI and run event loop
 	ac := controller.NewAppController(
 		config,
+		localizer,
 		feedStore,
-		taskManager)
+		taskManager,
+		scheduler,
+		downloadManager,
+		appSettings.Downloads.Player,
+		outbox)
+
+	// Notify the user about new items, if enabled in the settings.
+	// The notifier is subscribed before feeds are refreshed, so it sees
+	// every refresh scheduled at startup and batches their new items.
+	if notifyConfig := appSettings.NotifyConfig(); notifyConfig.Enabled() {
+		notifier := notify.NewNotifier(notifyConfig, localizer, feedStore, ac.Beep)
+		taskManager.Subscribe(notifier)
+		defer notifier.Wait()
+	}
+
+	// Trigger an async refresh of all feeds
+	ac.RefreshAllFeeds()
+
 	if err := ac.App.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error running event loop: %v", err)
 		os.Exit(1)
 	}
 }
 
+// newLocalizer translates messages and formats dates and numbers
+// for the locale set by environment variables (e.g. LC_ALL)
+func newLocalizer() *i18n.Localizer {
+	searchPaths := []string{
+		"./configs/locale",
+		"/usr/share/locale",
+	}
+
+	localizer, err := i18n.NewLocalizer(i18n.LocaleFromEnv(), "localnews", searchPaths)
+	if err != nil {
+		// Fallback to "C", which should be available everywhere
+		localizer, err = i18n.NewLocalizer(i18n.NewLocale("C"), "localnews", searchPaths)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return localizer
+}
+
+func getSettingsSearchPaths() []string {
+	searchPaths := []string{"./configs/etc", "/etc/localnews"}
+	if usr, err := user.Current(); err == nil {
+		userDir := path.Join(usr.HomeDir, ".config", "localnews")
+		searchPaths = append([]string{userDir}, searchPaths...)
+	}
+	return searchPaths
+}
+
+// fileExists returns whether there's a file at the specified path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 func getDefaultDBPath() string {
 	const dbName string = ".localnews.db"
 	if usr, err := user.Current(); err != nil {
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"github.com/wedaly/local-news/internal/publish"
+	"github.com/wedaly/local-news/internal/query"
+	"github.com/wedaly/local-news/internal/store"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// runPublish implements the "publish" subcommand, which writes an
+// Atom or RSS feed combining the items from several feeds.
+func runPublish(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("publish", flag.ContinueOnError)
+	dbPath := flags.String("db", getDefaultDBPath(), "path to the database")
+	formatName := flags.String("format", "atom", "output format: atom or rss")
+	outPath := flags.String("o", "", "output file (default standard output)")
+	title := flags.String("title", "", "title of the published feed (default the folder or search name)")
+	feedUrl := flags.String("url", "", "URL where the published feed will be available, used as its ID")
+	siteUrl := flags.String("link", "", "URL of the website associated with the published feed")
+	description := flags.String("description", "", "description of the published feed")
+	folder := flags.String("folder", "", "publish items in feeds in this folder")
+	searchName := flags.String("search", "", "publish items matching the saved search with this name")
+	limit := flags.Int("limit", 50, "maximum number of items, most recent first (0 for no limit)")
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	format, err := publish.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	feedStore := store.NewFeedStore(*dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		return err
+	}
+	defer feedStore.Close()
+
+	feeds, err := feedStore.RetrieveFeeds()
+	if err != nil {
+		return err
+	}
+
+	// Choose the items to publish.  Without any options, items from every feed are published.
+	var records []store.FeedItemRecord
+	channel := publish.Channel{
+		Title:       "localnews",
+		Url:         *feedUrl,
+		Link:        *siteUrl,
+		Description: *description,
+	}
+	switch {
+	case len(*searchName) > 0:
+		var searchId store.SavedSearchId
+		if searchId, err = findSavedSearch(feedStore, *searchName); err == nil {
+			channel.Title = *searchName
+			records, err = feedStore.RetrieveSavedSearchItems(searchId)
+		}
+
+	case len(*folder) > 0:
+		channel.Title = *folder
+		q := query.Query{Terms: []query.Term{{Field: query.FieldFolder, Value: *folder}}}
+		records, err = feedStore.RetrieveQueryItems(q)
+
+	default:
+		records, err = feedStore.RetrieveQueryItems(query.Query{})
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(*title) > 0 {
+		channel.Title = *title
+	}
+
+	if *limit > 0 && len(records) > *limit {
+		records = records[:*limit]
+	}
+
+	items := publish.NewItems(records, feeds)
+	if len(*outPath) == 0 {
+		return publish.Write(stdout, format, channel, items)
+	}
+	return writePublishedFile(*outPath, format, channel, items)
+}
+
+// writePublishedFile replaces the file with the published feed.
+// The feed is written to a temporary file first, so a web server
+// serving the file never sees a partially written document.
+func writePublishedFile(path string, format publish.Format, channel publish.Channel, items []publish.Item) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".localnews-publish-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := publish.Write(f, format, channel, items); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Temporary files are only readable by the owner, but the
+	// published feed is meant to be read by others.
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/wedaly/local-news/internal/api"
+	"github.com/wedaly/local-news/internal/hook"
+	"github.com/wedaly/local-news/internal/remote"
+	"github.com/wedaly/local-news/internal/settings"
+	"github.com/wedaly/local-news/internal/store"
+	"github.com/wedaly/local-news/internal/task"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Environment variables can set secrets without showing them in the process list
+const (
+	tokenEnvVar    = "LOCALNEWS_API_TOKEN"
+	passwordEnvVar = "LOCALNEWS_API_PASSWORD"
+)
+
+// runServe implements the "serve" subcommand, which serves the JSON API
+// and refreshes feeds in the background until interrupted.
+func runServe(args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	dbPath := flags.String("db", getDefaultDBPath(), "path to the database")
+	listenAddr := flags.String("listen", "127.0.0.1:8080", "address to listen on")
+	token := flags.String("token", os.Getenv(tokenEnvVar), "token required to use the API (default $"+tokenEnvVar+")")
+	allowOrigin := flags.String("allow-origin", "", "allow web pages from this origin to use the API")
+	username := flags.String("user", "", "username for the Fever and Google Reader APIs")
+	password := flags.String("password", os.Getenv(passwordEnvVar), "password for the Fever and Google Reader APIs (default $"+passwordEnvVar+")")
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if len(*username) > 0 && len(*password) == 0 {
+		return fmt.Errorf("A password is required with -user")
+	}
+
+	appSettings, err := settings.LoadSettings(getSettingsSearchPaths())
+	if err != nil {
+		return err
+	}
+
+	feedStore := store.NewFeedStore(*dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		return err
+	}
+	defer feedStore.Close()
+
+	taskManager := task.NewTaskManager(feedStore, appSettings.LoaderConfig())
+
+	syncers, err := newSyncers(appSettings, feedStore)
+	if err != nil {
+		return err
+	}
+	registerRemotes(syncers, taskManager)
+	taskManager.SetArchivePolicy(appSettings.ArchivePolicy())
+
+	// Send new items to the hooks in the settings, if any
+	if hooks := appSettings.HookList(); len(hooks) > 0 {
+		hookRunner := hook.NewRunner(hooks, feedStore)
+		taskManager.Subscribe(hookRunner)
+		defer hookRunner.Wait()
+	}
+
+	// Periodically refresh feeds in the background
+	scheduler := task.NewScheduler(feedStore, taskManager, appSettings.RefreshPolicy())
+	scheduler.Start(time.Minute)
+	defer scheduler.Stop()
+
+	// Pick up subscriptions added or removed on the servers since the last run
+	for name, syncer := range syncers {
+		go func(name string, syncer *remote.Syncer) {
+			linkedIds, err := syncer.SyncSubscriptions()
+			if err != nil {
+				fmt.Fprintf(stderr, "Could not sync subscriptions for '%v': %v\n", name, err)
+				return
+			}
+			for _, feedId := range linkedIds {
+				taskManager.ScheduleLoadFeedTask(feedId)
+			}
+		}(name, syncer)
+	}
+
+	config := api.Config{
+		Token:       *token,
+		AllowOrigin: *allowOrigin,
+		Username:    *username,
+		Password:    *password,
+	}
+	apiServer := api.NewServer(config, feedStore, taskManager)
+	taskManager.Subscribe(apiServer)
+
+	listener, err := net.Listen("tcp", *listenAddr)
+	if err != nil {
+		return err
+	}
+
+	if len(*token) == 0 && !isLoopback(listener.Addr()) {
+		fmt.Fprintf(stderr, "Warning: the API is available to other machines without a token\n")
+	}
+	fmt.Fprintf(stderr, "Serving the API at http://%v/api/v1/\n", listener.Addr())
+	if len(*username) > 0 {
+		fmt.Fprintf(stderr, "Serving the Fever API at http://%v/fever/\n", listener.Addr())
+		fmt.Fprintf(stderr, "Serving the Google Reader API at http://%v/\n", listener.Addr())
+	}
+
+	// Shut down gracefully when interrupted
+	httpServer := &http.Server{Handler: apiServer}
+	go func() {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		<-interrupt
+
+		apiServer.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(ctx)
+	}()
+
+	if err := httpServer.Serve(listener); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func isLoopback(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	return ok && tcpAddr.IP.IsLoopback()
+}
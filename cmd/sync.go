@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/wedaly/local-news/internal/remote"
+	"github.com/wedaly/local-news/internal/settings"
+	"github.com/wedaly/local-news/internal/store"
+	"github.com/wedaly/local-news/internal/task"
+	"io"
+)
+
+// runSync implements the "sync" subcommand, which syncs the subscriptions
+// and items of the remote accounts in the settings with the database.
+func runSync(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	dbPath := flags.String("db", getDefaultDBPath(), "path to the database")
+	accountName := flags.String("account", "", "sync only the remote account with this name")
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	appSettings, err := settings.LoadSettings(getSettingsSearchPaths())
+	if err != nil {
+		return err
+	}
+
+	feedStore := store.NewFeedStore(*dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		return err
+	}
+	defer feedStore.Close()
+
+	syncers, err := newSyncers(appSettings, feedStore)
+	if err != nil {
+		return err
+	}
+
+	if len(*accountName) > 0 {
+		syncer, ok := syncers[*accountName]
+		if !ok {
+			return fmt.Errorf("No remote account named '%v' in the settings", *accountName)
+		}
+		syncers = map[string]*remote.Syncer{*accountName: syncer}
+	} else if len(syncers) == 0 {
+		return fmt.Errorf("No remote accounts in the settings")
+	}
+
+	for name, syncer := range syncers {
+		if _, err := syncer.SyncSubscriptions(); err != nil {
+			return fmt.Errorf("Could not sync subscriptions for '%v': %v", name, err)
+		}
+
+		remoteFeeds, err := feedStore.RetrieveRemoteFeeds(name)
+		if err != nil {
+			return err
+		}
+
+		numItems := 0
+		for _, remoteFeed := range remoteFeeds {
+			newItems, err := syncer.SyncFeed(remoteFeed)
+			if err != nil {
+				feedStore.SetFeedSyncStatusError(remoteFeed.FeedId, err)
+				return fmt.Errorf("Could not sync '%v': %v", remoteFeed.Title, err)
+			}
+			numItems += len(newItems)
+		}
+
+		fmt.Fprintf(stdout, "%v: synced %v feeds, %v new items\n", name, len(remoteFeeds), numItems)
+	}
+	return nil
+}
+
+// newSyncers creates a syncer for each remote account in the settings
+func newSyncers(appSettings settings.Settings, feedStore *store.FeedStore) (map[string]*remote.Syncer, error) {
+	accounts := appSettings.RemoteList()
+	syncers := make(map[string]*remote.Syncer, len(accounts))
+	if len(accounts) == 0 {
+		return syncers, nil
+	}
+
+	httpClient, err := appSettings.LoaderConfig().NewHttpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, account := range accounts {
+		client, err := remote.NewClient(account, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid remote account '%v': %v", account.Name, err)
+		}
+		syncers[account.Name] = remote.NewSyncer(account.Name, client, feedStore)
+	}
+	return syncers, nil
+}
+
+// registerRemotes loads remote feeds from their accounts' servers
+func registerRemotes(syncers map[string]*remote.Syncer, taskManager *task.TaskManager) {
+	for name, syncer := range syncers {
+		taskManager.RegisterRemote(name, syncer)
+	}
+}
@@ -3,7 +3,8 @@ module github.com/wedaly/local-news
 go 1.12
 
 require (
-	github.com/PuerkitoBio/goquery v1.5.0 // indirect
+	github.com/PuerkitoBio/goquery v1.5.0
+	github.com/andybalholm/cascadia v1.0.0
 	github.com/atotto/clipboard v0.1.2
 	github.com/gdamore/tcell v1.1.2
 	github.com/mattn/go-sqlite3 v1.10.0
@@ -11,5 +12,6 @@ require (
 	github.com/mmcdole/goxpp v0.0.0-20181012175147-0068e33feabf // indirect
 	github.com/rivo/tview v0.0.0-20190515161233-bd836ef13b4b
 	github.com/stretchr/testify v1.3.0 // indirect
-	golang.org/x/net v0.0.0-20190404232315-eb5bcb51f2a3 // indirect
+	golang.org/x/net v0.0.0-20190404232315-eb5bcb51f2a3
+	golang.org/x/text v0.3.0
 )
@@ -0,0 +1,610 @@
+package api
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/wedaly/local-news/internal/query"
+	"github.com/wedaly/local-news/internal/store"
+	"github.com/wedaly/local-news/internal/task"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pathPrefix is the prefix of every API path.  The version changes
+// only when a change would break existing clients.
+const pathPrefix = "/api/v1/"
+
+// keepAliveInterval is how often an idle event stream sends a comment,
+// so proxies and clients don't close the connection.
+const keepAliveInterval time.Duration = 30 * time.Second
+
+// Config controls access to the API
+type Config struct {
+	// If set, every request must include this token, either in an
+	// "Authorization: Bearer" header or an "access_token" query parameter.
+	Token string
+
+	// If set, browsers allow pages from this origin to call the API.
+	AllowOrigin string
+
+	// Credentials for the Fever and Google Reader APIs used by mobile apps.
+	// Those APIs are disabled unless both are set.
+	Username string
+	Password string
+}
+
+// Server handles API requests, reading and writing the feed store
+// and scheduling tasks to load feeds.  It also streams task events
+// to clients, so it must be subscribed to the task manager.
+type Server struct {
+	config       Config
+	feedStore    *store.FeedStore
+	taskManager  *task.TaskManager
+	streamsMutex sync.Mutex
+	streams      map[chan event]struct{}
+	done         chan struct{}
+}
+
+// NewServer creates an API server, which should be subscribed
+// to the task manager before handling requests.
+func NewServer(config Config, feedStore *store.FeedStore, taskManager *task.TaskManager) *Server {
+	return &Server{
+		config:      config,
+		feedStore:   feedStore,
+		taskManager: taskManager,
+		streams:     make(map[chan event]struct{}, 0),
+		done:        make(chan struct{}),
+	}
+}
+
+// Close ends every event stream, which would otherwise stay open
+// while the HTTP server shuts down.  It must be called only once.
+func (s *Server) Close() {
+	close(s.done)
+}
+
+// event is a task event sent to clients of the event stream
+type event struct {
+	name string
+	data interface{}
+}
+
+type completedEvent struct {
+	FeedId       store.FeedId `json:"feed_id"`
+	Error        string       `json:"error,omitempty"`
+	MergedFeedId store.FeedId `json:"merged_feed_id,omitempty"`
+	NewItems     int          `json:"new_items"`
+}
+
+// HandleTaskScheduled implements task.TaskSubscriber
+func (s *Server) HandleTaskScheduled() {
+	s.broadcast(event{"scheduled", struct{}{}})
+}
+
+// HandleTaskCompleted implements task.TaskSubscriber
+func (s *Server) HandleTaskCompleted(result task.TaskResult) {
+	data := completedEvent{
+		FeedId:       result.FeedId,
+		MergedFeedId: result.MergedFeedId,
+		NewItems:     len(result.NewItems),
+	}
+	if result.Err != nil {
+		data.Error = result.Err.Error()
+	}
+	s.broadcast(event{"completed", data})
+}
+
+// broadcast sends the event to every connected stream.  The task manager
+// waits for subscribers, so events are dropped for slow clients
+// rather than blocking.
+func (s *Server) broadcast(e event) {
+	s.streamsMutex.Lock()
+	defer s.streamsMutex.Unlock()
+	{
+		for stream := range s.streams {
+			select {
+			case stream <- e:
+			default:
+			}
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Mobile apps reach these on other host names (such as "nas.local"),
+	// and they always require a username and password.
+	if isFeverPath(r.URL.Path) || isGReaderPath(r.URL.Path) {
+		if len(s.config.Username) == 0 || len(s.config.Password) == 0 {
+			http.Error(w, "Not found", http.StatusNotFound)
+		} else if isFeverPath(r.URL.Path) {
+			s.handleFever(w, r)
+		} else {
+			s.handleGReader(w, r)
+		}
+		return
+	}
+
+	// Reject requests for other host names, so a web page can't
+	// reach the API by rebinding its own domain to a local address.
+	if !isLocalHost(r.Host) {
+		writeError(w, http.StatusForbidden, "Host not allowed")
+		return
+	}
+
+	if len(s.config.AllowOrigin) > 0 {
+		w.Header().Set("Access-Control-Allow-Origin", s.config.AllowOrigin)
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "Missing or invalid token")
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, pathPrefix) {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	// Route by the path segments after the prefix, e.g. "feeds/1/items"
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, pathPrefix), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "feeds":
+		s.handleMethods(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:  s.listFeeds,
+			http.MethodPost: s.addFeed,
+		})
+
+	case len(parts) >= 2 && parts[0] == "feeds":
+		feedId, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "Not found")
+			return
+		}
+		s.routeFeed(w, r, store.FeedId(feedId), parts[2:])
+
+	case len(parts) == 1 && parts[0] == "items":
+		s.handleMethods(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: s.listItems,
+		})
+
+	case len(parts) == 2 && parts[0] == "items":
+		itemId, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "Not found")
+			return
+		}
+		s.handleMethods(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:   func(w http.ResponseWriter, r *http.Request) { s.getItem(w, store.FeedItemId(itemId)) },
+			http.MethodPatch: func(w http.ResponseWriter, r *http.Request) { s.updateItem(w, r, store.FeedItemId(itemId)) },
+		})
+
+	case len(parts) == 1 && parts[0] == "refresh":
+		s.handleMethods(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: s.refreshAllFeeds,
+		})
+
+	case len(parts) == 1 && parts[0] == "events":
+		s.handleMethods(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: s.streamEvents,
+		})
+
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (s *Server) routeFeed(w http.ResponseWriter, r *http.Request, feedId store.FeedId, parts []string) {
+	// Every feed path refers to an existing feed
+	feedRecord, err := s.feedStore.RetrieveFeed(feedId)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "Feed not found")
+		return
+	} else if err != nil {
+		panic(err)
+	}
+
+	switch {
+	case len(parts) == 0:
+		s.handleMethods(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:    func(w http.ResponseWriter, r *http.Request) { s.getFeed(w, feedRecord) },
+			http.MethodDelete: func(w http.ResponseWriter, r *http.Request) { s.deleteFeed(w, feedId) },
+		})
+
+	case len(parts) == 1 && parts[0] == "items":
+		s.handleMethods(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) { s.listFeedItems(w, feedId) },
+		})
+
+	case len(parts) == 1 && parts[0] == "refresh":
+		s.handleMethods(w, r, map[string]http.HandlerFunc{
+			http.MethodPost: func(w http.ResponseWriter, r *http.Request) { s.refreshFeed(w, feedId) },
+		})
+
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (s *Server) handleMethods(w http.ResponseWriter, r *http.Request, handlers map[string]http.HandlerFunc) {
+	if handler, ok := handlers[r.Method]; ok {
+		handler(w, r)
+		return
+	}
+
+	allowed := make([]string, 0, len(handlers))
+	for method := range handlers {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if len(s.config.Token) == 0 {
+		return true
+	}
+
+	// Browsers can't set headers for event streams, so the
+	// token can also be sent as a query parameter.
+	token := r.URL.Query().Get("access_token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Token)) == 1
+}
+
+// isHttpUrl checks whether a feed URL can be added through the API.
+// Other sources (such as commands and local files) can only be added
+// in the app, so a client of the API can't run commands or read files.
+func isHttpUrl(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && len(u.Host) > 0
+}
+
+// isLocalHost checks whether the Host header names this machine
+// by address or as "localhost".
+func isLocalHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	return strings.EqualFold(host, "localhost") || net.ParseIP(host) != nil
+}
+
+type feedJson struct {
+	Id          store.FeedId `json:"id"`
+	Url         string       `json:"url"`
+	Name        string       `json:"name"`
+	CustomName  string       `json:"custom_name,omitempty"`
+	Folder      string       `json:"folder,omitempty"`
+	SiteUrl     string       `json:"site_url,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Language    string       `json:"language,omitempty"`
+	UnreadCount int          `json:"unread_count"`
+}
+
+func newFeedJson(record store.FeedRecord, unreadCount int) feedJson {
+	return feedJson{
+		Id:          record.Id,
+		Url:         record.Url,
+		Name:        record.DisplayName(),
+		CustomName:  record.CustomName,
+		Folder:      record.Folder,
+		SiteUrl:     record.SiteUrl,
+		Description: record.Description,
+		Language:    record.Language,
+		UnreadCount: unreadCount,
+	}
+}
+
+type itemJson struct {
+	Id          store.FeedItemId `json:"id"`
+	FeedId      store.FeedId     `json:"feed_id"`
+	Guid        string           `json:"guid"`
+	Url         string           `json:"url"`
+	Title       string           `json:"title"`
+	Date        time.Time        `json:"date"`
+	Updated     *time.Time       `json:"updated,omitempty"`
+	Author      string           `json:"author,omitempty"`
+	ContentHtml string           `json:"content_html,omitempty"`
+	ContentText string           `json:"content_text,omitempty"`
+	Read        bool             `json:"read"`
+	Hidden      bool             `json:"hidden"`
+	Highlighted bool             `json:"highlighted"`
+	Starred     bool             `json:"starred"`
+}
+
+func newItemJson(record store.FeedItemRecord) itemJson {
+	item := itemJson{
+		Id:          record.Id,
+		FeedId:      record.FeedId,
+		Guid:        record.Guid,
+		Url:         record.Url,
+		Title:       record.Title,
+		Date:        record.Date.UTC(),
+		Author:      record.Author,
+		ContentHtml: record.ContentHtml,
+		ContentText: record.ContentText,
+		Read:        record.Read,
+		Hidden:      record.Hidden,
+		Highlighted: record.Highlighted,
+		Starred:     record.Starred,
+	}
+	if !record.Updated.IsZero() {
+		updated := record.Updated.UTC()
+		item.Updated = &updated
+	}
+	return item
+}
+
+func newItemsJson(records []store.FeedItemRecord) []itemJson {
+	items := make([]itemJson, len(records))
+	for i, record := range records {
+		items[i] = newItemJson(record)
+	}
+	return items
+}
+
+func (s *Server) listFeeds(w http.ResponseWriter, r *http.Request) {
+	records, err := s.feedStore.RetrieveFeeds()
+	if err != nil {
+		panic(err)
+	}
+
+	unreadCounts, err := s.feedStore.RetrieveUnreadCounts()
+	if err != nil {
+		panic(err)
+	}
+
+	feeds := make([]feedJson, len(records))
+	for i, record := range records {
+		feeds[i] = newFeedJson(record, unreadCounts[record.Id])
+	}
+	writeJson(w, http.StatusOK, feeds)
+}
+
+func (s *Server) getFeed(w http.ResponseWriter, record store.FeedRecord) {
+	unreadCounts, err := s.feedStore.RetrieveUnreadCounts()
+	if err != nil {
+		panic(err)
+	}
+	writeJson(w, http.StatusOK, newFeedJson(record, unreadCounts[record.Id]))
+}
+
+func (s *Server) addFeed(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Url string `json:"url"`
+	}
+	if !readJson(w, r, &req) {
+		return
+	}
+
+	if !isHttpUrl(req.Url) {
+		writeError(w, http.StatusBadRequest, "The URL must be an HTTP or HTTPS URL")
+		return
+	}
+
+	feedId, err := s.feedStore.GetOrCreateFeedWithUrl(req.Url)
+	if err != nil {
+		panic(err)
+	}
+
+	record, err := s.feedStore.RetrieveFeed(feedId)
+	if err != nil {
+		panic(err)
+	}
+
+	s.taskManager.ScheduleLoadFeedTask(feedId)
+	w.Header().Set("Location", fmt.Sprintf("%vfeeds/%v", pathPrefix, feedId))
+	writeJson(w, http.StatusCreated, newFeedJson(record, 0))
+}
+
+func (s *Server) deleteFeed(w http.ResponseWriter, feedId store.FeedId) {
+	if err := s.feedStore.DeleteFeed(feedId); err != nil {
+		panic(err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listFeedItems(w http.ResponseWriter, feedId store.FeedId) {
+	records, err := s.feedStore.RetrieveFeedItems(feedId)
+	if err != nil {
+		panic(err)
+	}
+	writeJson(w, http.StatusOK, newItemsJson(records))
+}
+
+// listItems lists the items in every feed, optionally
+// filtered by a query in the "q" parameter.
+func (s *Server) listItems(w http.ResponseWriter, r *http.Request) {
+	var q query.Query
+	if text := r.URL.Query().Get("q"); len(strings.TrimSpace(text)) > 0 {
+		var err error
+		if q, err = query.Parse(text); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid query: %v", err))
+			return
+		}
+	}
+
+	records, err := s.feedStore.RetrieveQueryItems(q)
+	if err != nil {
+		panic(err)
+	}
+	writeJson(w, http.StatusOK, newItemsJson(records))
+}
+
+func (s *Server) getItem(w http.ResponseWriter, itemId store.FeedItemId) {
+	record, err := s.feedStore.RetrieveFeedItem(itemId)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "Item not found")
+		return
+	} else if err != nil {
+		panic(err)
+	}
+	writeJson(w, http.StatusOK, newItemJson(record))
+}
+
+// updateItem marks an item read or unread, starred or unstarred
+func (s *Server) updateItem(w http.ResponseWriter, r *http.Request, itemId store.FeedItemId) {
+	var req struct {
+		Read    *bool `json:"read"`
+		Starred *bool `json:"starred"`
+	}
+	if !readJson(w, r, &req) {
+		return
+	}
+
+	if req.Read == nil && req.Starred == nil {
+		writeError(w, http.StatusBadRequest, "Missing \"read\" or \"starred\" field")
+		return
+	}
+
+	if _, err := s.feedStore.RetrieveFeedItem(itemId); err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, "Item not found")
+		return
+	} else if err != nil {
+		panic(err)
+	}
+
+	if req.Read != nil {
+		if err := s.feedStore.SetItemRead(itemId, *req.Read); err != nil {
+			panic(err)
+		}
+	}
+
+	if req.Starred != nil {
+		if err := s.feedStore.SetItemStarred(itemId, *req.Starred); err != nil {
+			panic(err)
+		}
+		if *req.Starred {
+			s.archiveStarredItems()
+		}
+	}
+	s.getItem(w, itemId)
+}
+
+// archiveStarredItems archives the articles linked from newly starred
+// items, if the archive policy includes starred items.
+func (s *Server) archiveStarredItems() {
+	if err := s.taskManager.ScheduleArchiveTasks(); err != nil {
+		panic(err)
+	}
+}
+
+func (s *Server) refreshFeed(w http.ResponseWriter, feedId store.FeedId) {
+	s.taskManager.ScheduleLoadFeedTask(feedId)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) refreshAllFeeds(w http.ResponseWriter, r *http.Request) {
+	records, err := s.feedStore.RetrieveFeeds()
+	if err != nil {
+		panic(err)
+	}
+
+	for _, record := range records {
+		s.taskManager.ScheduleLoadFeedTask(record.Id)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// streamEvents sends task events as Server-Sent Events until the client disconnects
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	stream := make(chan event, 16)
+	s.streamsMutex.Lock()
+	s.streams[stream] = struct{}{}
+	s.streamsMutex.Unlock()
+
+	defer func() {
+		s.streamsMutex.Lock()
+		delete(s.streams, stream)
+		s.streamsMutex.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case e := <-stream:
+			data, err := json.Marshal(e.data)
+			if err != nil {
+				panic(err)
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, data); err != nil {
+				return
+			}
+
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+
+		case <-r.Context().Done():
+			return
+
+		case <-s.done:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// readJson decodes the request body, writing an error response if it's invalid.
+// Requiring the JSON content type also prevents other web pages from
+// submitting forms to the API.
+func readJson(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "The request body must be JSON")
+		return false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	// An error means the client disconnected, so there's no one to report it to
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJson(w, status, struct {
+		Error string `json:"error"`
+	}{msg})
+}
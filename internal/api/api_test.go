@@ -0,0 +1,279 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/store"
+	"github.com/wedaly/local-news/internal/task"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// completedSubscriber signals when tasks complete
+type completedSubscriber chan task.TaskResult
+
+func (s completedSubscriber) HandleTaskScheduled() {}
+
+func (s completedSubscriber) HandleTaskCompleted(r task.TaskResult) {
+	s <- r
+}
+
+func execWithServer(t *testing.T, config Config, f func(*Server, *store.FeedStore, completedSubscriber)) {
+	// Can't use an in-memory DB because tasks access the store concurrently
+	dbPath := path.Join(os.TempDir(), "test-api.db")
+	defer func() { os.Remove(dbPath) }()
+	feedStore := store.NewFeedStore(dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer feedStore.Close()
+
+	completed := make(completedSubscriber, 10)
+	taskManager := task.NewTaskManager(feedStore, feed.LoaderConfig{})
+	server := NewServer(config, feedStore, taskManager)
+	taskManager.Subscribe(server)
+	taskManager.Subscribe(completed)
+	f(server, feedStore, completed)
+}
+
+func createFeed(t *testing.T, feedStore *store.FeedStore) store.FeedId {
+	feedId, err := feedStore.GetOrCreateFeedWithUrl("http://foo.com/feed")
+	if err != nil {
+		t.Fatalf("Could not create feed: %v", err)
+	}
+
+	f := feed.Feed{
+		Name: "Foo",
+		Items: []feed.FeedItem{
+			feed.FeedItem{Title: "First", Date: time.Unix(1, 0), Url: "http://foo.com/1", Guid: "guid.1"},
+			feed.FeedItem{Title: "Second", Date: time.Unix(2, 0), Url: "http://foo.com/2", Guid: "guid.2"},
+		},
+	}
+	if _, err := feedStore.SyncFeed(feedId, f); err != nil {
+		t.Fatalf("Could not sync feed: %v", err)
+	}
+	return feedId
+}
+
+func doRequest(s *Server, method string, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Host = "localhost:8080"
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+		t.Fatalf("Could not decode response %q: %v", w.Body.String(), err)
+	}
+}
+
+func TestListFeedsAndItems(t *testing.T) {
+	execWithServer(t, Config{}, func(s *Server, feedStore *store.FeedStore, completed completedSubscriber) {
+		feedId := createFeed(t, feedStore)
+
+		w := doRequest(s, "GET", "/api/v1/feeds", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, but got %v", w.Code)
+		}
+
+		var feeds []feedJson
+		decodeResponse(t, w, &feeds)
+		if len(feeds) != 1 || feeds[0].Id != feedId || feeds[0].Name != "Foo" || feeds[0].UnreadCount != 2 {
+			t.Errorf("Unexpected feeds %v", feeds)
+		}
+
+		w = doRequest(s, "GET", "/api/v1/feeds/1/items", "")
+		var items []itemJson
+		decodeResponse(t, w, &items)
+		if len(items) != 2 || items[0].Title != "Second" || items[0].FeedId != feedId {
+			t.Errorf("Unexpected items %v", items)
+		}
+
+		w = doRequest(s, "GET", "/api/v1/items", "")
+		decodeResponse(t, w, &items)
+		if len(items) != 2 {
+			t.Errorf("Expected every item without a query, but got %v", items)
+		}
+
+		w = doRequest(s, "GET", "/api/v1/items?q=title:first", "")
+		decodeResponse(t, w, &items)
+		if len(items) != 1 || items[0].Title != "First" {
+			t.Errorf("Unexpected items %v", items)
+		}
+
+		w = doRequest(s, "GET", "/api/v1/items?q=color:red", "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for invalid query, but got %v", w.Code)
+		}
+
+		w = doRequest(s, "GET", "/api/v1/feeds/99/items", "")
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for missing feed, but got %v", w.Code)
+		}
+	})
+}
+
+func TestMarkItemRead(t *testing.T) {
+	execWithServer(t, Config{}, func(s *Server, feedStore *store.FeedStore, completed completedSubscriber) {
+		createFeed(t, feedStore)
+
+		w := doRequest(s, "PATCH", "/api/v1/items/1", `{"read": true}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, but got %v: %v", w.Code, w.Body.String())
+		}
+
+		var item itemJson
+		decodeResponse(t, w, &item)
+		if !item.Read {
+			t.Errorf("Expected item to be read")
+		}
+
+		if record, err := feedStore.RetrieveFeedItem(1); err != nil || !record.Read {
+			t.Errorf("Expected stored item to be read, but got %v, %v", record, err)
+		}
+
+		if w := doRequest(s, "PATCH", "/api/v1/items/1", `{}`); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 without read field, but got %v", w.Code)
+		}
+
+		if w := doRequest(s, "PATCH", "/api/v1/items/99", `{"read": true}`); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for missing item, but got %v", w.Code)
+		}
+
+		if w := doRequest(s, "DELETE", "/api/v1/items/1", ""); w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status 405, but got %v", w.Code)
+		} else if allow := w.Header().Get("Allow"); allow != "GET, PATCH" {
+			t.Errorf("Expected allowed methods GET, PATCH, but got %v", allow)
+		}
+	})
+}
+
+func TestAddAndDeleteFeed(t *testing.T) {
+	execWithServer(t, Config{}, func(s *Server, feedStore *store.FeedStore, completed completedSubscriber) {
+		// Commands and local files can't be added through the API
+		for _, url := range []string{"exec:rm -rf /", "file:///etc/passwd", "not a url"} {
+			w := doRequest(s, "POST", "/api/v1/feeds", `{"url": "`+url+`"}`)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for %q, but got %v", url, w.Code)
+			}
+		}
+
+		// Forms can't be submitted to the API from other web pages
+		req := httptest.NewRequest("POST", "/api/v1/feeds", strings.NewReader("url=http://foo.com"))
+		req.Host = "localhost"
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("Expected status 415 for form, but got %v", w.Code)
+		}
+
+		w = doRequest(s, "POST", "/api/v1/feeds", `{"url": "http://127.0.0.1:1/feed"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, but got %v: %v", w.Code, w.Body.String())
+		}
+
+		var f feedJson
+		decodeResponse(t, w, &f)
+		if f.Url != "http://127.0.0.1:1/feed" {
+			t.Errorf("Unexpected feed %v", f)
+		}
+
+		if location := w.Header().Get("Location"); location != "/api/v1/feeds/1" {
+			t.Errorf("Unexpected location %v", location)
+		}
+
+		// Nothing is listening at the URL, so loading the feed fails
+		if r := <-completed; r.FeedId != f.Id || r.Err == nil {
+			t.Errorf("Expected failed task for feed %v, but got %v", f.Id, r)
+		}
+
+		if w := doRequest(s, "DELETE", "/api/v1/feeds/1", ""); w.Code != http.StatusNoContent {
+			t.Errorf("Expected status 204, but got %v", w.Code)
+		}
+
+		if feeds, err := feedStore.RetrieveFeeds(); err != nil || len(feeds) != 0 {
+			t.Errorf("Expected feed to be deleted, but got %v, %v", feeds, err)
+		}
+	})
+}
+
+func TestAccessControl(t *testing.T) {
+	execWithServer(t, Config{Token: "secret"}, func(s *Server, feedStore *store.FeedStore, completed completedSubscriber) {
+		if w := doRequest(s, "GET", "/api/v1/feeds", ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 without token, but got %v", w.Code)
+		}
+
+		req := httptest.NewRequest("GET", "/api/v1/feeds", nil)
+		req.Host = "127.0.0.1:8080"
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 with token, but got %v", w.Code)
+		}
+
+		if w := doRequest(s, "GET", "/api/v1/feeds?access_token=secret", ""); w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 with token parameter, but got %v", w.Code)
+		}
+
+		// Other host names are rejected, even with a valid token
+		req = httptest.NewRequest("GET", "/api/v1/feeds?access_token=secret", nil)
+		req.Host = "attacker.example.com"
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 for other host, but got %v", w.Code)
+		}
+	})
+}
+
+func TestStreamEvents(t *testing.T) {
+	execWithServer(t, Config{}, func(s *Server, feedStore *store.FeedStore, completed completedSubscriber) {
+		httpServer := httptest.NewServer(s)
+		defer httpServer.Close()
+		defer s.Close()
+
+		resp, err := http.Get(httpServer.URL + "/api/v1/events")
+		if err != nil {
+			t.Fatalf("Could not connect to event stream: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+			t.Errorf("Unexpected content type %v", contentType)
+		}
+
+		s.HandleTaskScheduled()
+		s.HandleTaskCompleted(task.TaskResult{FeedId: 3, NewItems: make([]store.FeedItemRecord, 2)})
+
+		expected := []string{
+			"event: scheduled",
+			"data: {}",
+			"",
+			"event: completed",
+			`data: {"feed_id":3,"new_items":2}`,
+		}
+		scanner := bufio.NewScanner(resp.Body)
+		for _, line := range expected {
+			if !scanner.Scan() {
+				t.Fatalf("Expected line %q, but stream ended: %v", line, scanner.Err())
+			}
+			if scanner.Text() != line {
+				t.Errorf("Expected line %q, but got %q", line, scanner.Text())
+			}
+		}
+	})
+}
@@ -0,0 +1,361 @@
+package api
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"github.com/wedaly/local-news/internal/store"
+	"hash/crc32"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// feverItemsPerRequest is the maximum number of items returned at once,
+// as specified by the Fever API.
+const feverItemsPerRequest int = 50
+
+func isFeverPath(path string) bool {
+	return path == "/fever" || path == "/fever/"
+}
+
+// handleFever implements the Fever API used by mobile apps such as Reeder.
+// Clients send every request as a form to "/fever/?api", with the
+// name of each requested resource as a parameter.
+func (s *Server) handleFever(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"api_version": 3,
+		"auth":        0,
+	}
+
+	// Clients check "auth" in the response rather than the status code
+	expectedKey := feverApiKey(s.config.Username, s.config.Password)
+	apiKey := strings.ToLower(r.PostFormValue("api_key"))
+	if subtle.ConstantTimeCompare([]byte(apiKey), []byte(expectedKey)) != 1 {
+		writeJson(w, http.StatusOK, resp)
+		return
+	}
+	resp["auth"] = 1
+
+	feeds, err := s.feedStore.RetrieveFeeds()
+	if err != nil {
+		panic(err)
+	}
+
+	// Changes are made before retrieving anything, so the response includes them
+	if _, ok := r.Form["mark"]; ok {
+		s.handleFeverMark(r, feeds)
+	}
+
+	resp["last_refreshed_on_time"] = s.lastRefreshed(feeds).Unix()
+
+	if _, ok := r.Form["groups"]; ok {
+		resp["groups"] = feverGroups(feeds)
+		resp["feeds_groups"] = feverFeedsGroups(feeds)
+	}
+
+	if _, ok := r.Form["feeds"]; ok {
+		resp["feeds"] = s.feverFeeds(feeds)
+		resp["feeds_groups"] = feverFeedsGroups(feeds)
+	}
+
+	if _, ok := r.Form["favicons"]; ok {
+		resp["favicons"] = []struct{}{}
+	}
+
+	if _, ok := r.Form["links"]; ok {
+		resp["links"] = []struct{}{}
+	}
+
+	if _, ok := r.Form["items"]; ok {
+		items, err := s.feedStore.RetrieveFilteredItems(feverItemsFilter(r))
+		if err != nil {
+			panic(err)
+		}
+
+		total, err := s.feedStore.CountFilteredItems(store.ItemFilter{})
+		if err != nil {
+			panic(err)
+		}
+
+		resp["items"] = feverItems(items)
+		resp["total_items"] = total
+	}
+
+	if _, ok := r.Form["unread_item_ids"]; ok {
+		resp["unread_item_ids"] = s.feverItemIds(store.ItemFilter{Unread: true})
+	}
+
+	if _, ok := r.Form["saved_item_ids"]; ok {
+		resp["saved_item_ids"] = s.feverItemIds(store.ItemFilter{Starred: true})
+	}
+
+	writeJson(w, http.StatusOK, resp)
+}
+
+// feverApiKey returns the key clients send to authenticate,
+// which is the MD5 hash of the username and password.
+func feverApiKey(username string, password string) string {
+	sum := md5.Sum([]byte(username + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// handleFeverMark marks an item, feed, or group (folder)
+func (s *Server) handleFeverMark(r *http.Request, feeds []store.FeedRecord) {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	// Feeds and groups are marked read up to the time the client last refreshed,
+	// so items retrieved since then stay unread.
+	filter := store.ItemFilter{}
+	if before, err := strconv.ParseInt(r.FormValue("before"), 10, 64); err == nil && before > 0 {
+		filter.Until = time.Unix(before, 0)
+	}
+
+	switch r.FormValue("mark") {
+	case "item":
+		itemId := store.FeedItemId(id)
+		switch r.FormValue("as") {
+		case "read":
+			err = s.feedStore.SetItemRead(itemId, true)
+		case "unread":
+			err = s.feedStore.SetItemRead(itemId, false)
+		case "saved":
+			if err = s.feedStore.SetItemStarred(itemId, true); err == nil {
+				s.archiveStarredItems()
+			}
+		case "unsaved":
+			err = s.feedStore.SetItemStarred(itemId, false)
+		}
+
+	case "feed":
+		if r.FormValue("as") != "read" {
+			return
+		}
+		filter.FeedId = store.FeedId(id)
+		err = s.feedStore.MarkFilteredItemsRead(filter)
+
+	case "group":
+		if r.FormValue("as") != "read" {
+			return
+		}
+
+		// Group zero contains every feed, and negative groups
+		// (such as Fever's "Sparks") contain none.
+		if id < 0 {
+			return
+		} else if id > 0 {
+			folder, ok := feverGroupFolder(feeds, id)
+			if !ok {
+				return
+			}
+			filter.Folder = folder
+		}
+		err = s.feedStore.MarkFilteredItemsRead(filter)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// feverItemsFilter pages through items in the order they were inserted,
+// which is how Fever clients keep track of the items they've seen.
+func feverItemsFilter(r *http.Request) store.ItemFilter {
+	filter := store.ItemFilter{
+		Order: store.IdAscending,
+		Limit: feverItemsPerRequest,
+	}
+
+	if withIds := r.FormValue("with_ids"); len(withIds) > 0 {
+		filter.Ids = make([]store.FeedItemId, 0)
+		for _, s := range strings.Split(withIds, ",") {
+			if id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+				filter.Ids = append(filter.Ids, store.FeedItemId(id))
+			}
+		}
+	} else if maxId, err := strconv.ParseInt(r.FormValue("max_id"), 10, 64); err == nil {
+		// Zero means the most recent items
+		filter.BeforeId = store.FeedItemId(maxId)
+		filter.Order = store.IdDescending
+	} else if sinceId, err := strconv.ParseInt(r.FormValue("since_id"), 10, 64); err == nil {
+		filter.AfterId = store.FeedItemId(sinceId)
+	}
+
+	return filter
+}
+
+func (s *Server) feverItemIds(filter store.ItemFilter) string {
+	filter.Order = store.IdAscending
+	ids, err := s.feedStore.RetrieveFilteredItemIds(filter)
+	if err != nil {
+		panic(err)
+	}
+
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.FormatInt(int64(id), 10)
+	}
+	return strings.Join(strs, ",")
+}
+
+// lastRefreshed returns the date any feed was last synced
+func (s *Server) lastRefreshed(feeds []store.FeedRecord) time.Time {
+	var latest time.Time
+	for _, feed := range feeds {
+		if _, status, err := s.feedStore.RetrieveFeedSyncStatus(feed.Id); err != nil {
+			panic(err)
+		} else if status.Date.After(latest) {
+			latest = status.Date
+		}
+	}
+	return latest
+}
+
+// feverGroupId returns a stable ID for a folder.  Folders don't have
+// their own IDs, so the ID is derived from the folder's name.
+func feverGroupId(folder string) int64 {
+	return int64(crc32.ChecksumIEEE([]byte(folder)) & 0x7fffffff)
+}
+
+func feverGroupFolder(feeds []store.FeedRecord, groupId int64) (string, bool) {
+	for _, folder := range feedFolders(feeds) {
+		if feverGroupId(folder) == groupId {
+			return folder, true
+		}
+	}
+	return "", false
+}
+
+// feedFolders returns the distinct folders of the feeds, sorted by name
+func feedFolders(feeds []store.FeedRecord) []string {
+	seen := make(map[string]bool, 0)
+	folders := make([]string, 0)
+	for _, feed := range feeds {
+		if len(feed.Folder) > 0 && !seen[feed.Folder] {
+			seen[feed.Folder] = true
+			folders = append(folders, feed.Folder)
+		}
+	}
+	sort.Strings(folders)
+	return folders
+}
+
+type feverGroup struct {
+	Id    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+func feverGroups(feeds []store.FeedRecord) []feverGroup {
+	folders := feedFolders(feeds)
+	groups := make([]feverGroup, len(folders))
+	for i, folder := range folders {
+		groups[i] = feverGroup{feverGroupId(folder), folder}
+	}
+	return groups
+}
+
+type feverFeedsGroup struct {
+	GroupId int64  `json:"group_id"`
+	FeedIds string `json:"feed_ids"`
+}
+
+func feverFeedsGroups(feeds []store.FeedRecord) []feverFeedsGroup {
+	feedIds := make(map[string][]string, 0)
+	for _, feed := range feeds {
+		if len(feed.Folder) > 0 {
+			feedIds[feed.Folder] = append(feedIds[feed.Folder], strconv.FormatInt(int64(feed.Id), 10))
+		}
+	}
+
+	folders := feedFolders(feeds)
+	feedsGroups := make([]feverFeedsGroup, len(folders))
+	for i, folder := range folders {
+		feedsGroups[i] = feverFeedsGroup{feverGroupId(folder), strings.Join(feedIds[folder], ",")}
+	}
+	return feedsGroups
+}
+
+type feverFeed struct {
+	Id                store.FeedId `json:"id"`
+	FaviconId         int          `json:"favicon_id"`
+	Title             string       `json:"title"`
+	Url               string       `json:"url"`
+	SiteUrl           string       `json:"site_url"`
+	IsSpark           int          `json:"is_spark"`
+	LastUpdatedOnTime int64        `json:"last_updated_on_time"`
+}
+
+func (s *Server) feverFeeds(feeds []store.FeedRecord) []feverFeed {
+	result := make([]feverFeed, len(feeds))
+	for i, feed := range feeds {
+		_, status, err := s.feedStore.RetrieveFeedSyncStatus(feed.Id)
+		if err != nil {
+			panic(err)
+		}
+
+		result[i] = feverFeed{
+			Id:                feed.Id,
+			Title:             feed.DisplayName(),
+			Url:               feed.Url,
+			SiteUrl:           feed.SiteUrl,
+			LastUpdatedOnTime: status.Date.Unix(),
+		}
+	}
+	return result
+}
+
+type feverItem struct {
+	Id            store.FeedItemId `json:"id"`
+	FeedId        store.FeedId     `json:"feed_id"`
+	Title         string           `json:"title"`
+	Author        string           `json:"author"`
+	Html          string           `json:"html"`
+	Url           string           `json:"url"`
+	IsSaved       int              `json:"is_saved"`
+	IsRead        int              `json:"is_read"`
+	CreatedOnTime int64            `json:"created_on_time"`
+}
+
+func feverItems(records []store.FeedItemRecord) []feverItem {
+	items := make([]feverItem, len(records))
+	for i, record := range records {
+		items[i] = feverItem{
+			Id:            record.Id,
+			FeedId:        record.FeedId,
+			Title:         record.Title,
+			Author:        record.Author,
+			Html:          itemHtml(record),
+			Url:           record.Url,
+			IsSaved:       boolToInt(record.Starred),
+			IsRead:        boolToInt(record.Read),
+			CreatedOnTime: record.Date.Unix(),
+		}
+	}
+	return items
+}
+
+// itemHtml returns the item's content as HTML, converting plain text if necessary
+func itemHtml(record store.FeedItemRecord) string {
+	if len(record.ContentHtml) > 0 {
+		return record.ContentHtml
+	}
+	return strings.Replace(html.EscapeString(record.ContentText), "\n", "<br>", -1)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
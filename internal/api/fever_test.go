@@ -0,0 +1,110 @@
+package api
+
+import (
+	"github.com/wedaly/local-news/internal/store"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var feverConfig = Config{Username: "user", Password: "pass"}
+
+// doFeverRequest posts a form the way Fever clients do
+func doFeverRequest(s *Server, apiKey string, params string) *httptest.ResponseRecorder {
+	form := url.Values{"api_key": {apiKey}}
+	req := httptest.NewRequest("POST", "/fever/?api&"+params, strings.NewReader(form.Encode()))
+	req.Host = "nas.local"
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func TestFeverAuth(t *testing.T) {
+	execWithServer(t, feverConfig, func(s *Server, feedStore *store.FeedStore, completed completedSubscriber) {
+		var resp map[string]interface{}
+		decodeResponse(t, doFeverRequest(s, feverApiKey("user", "wrong"), "feeds"), &resp)
+		if resp["auth"] != 0.0 || resp["feeds"] != nil {
+			t.Errorf("Expected auth 0 with wrong password, but got %v", resp)
+		}
+
+		decodeResponse(t, doFeverRequest(s, feverApiKey("user", "pass"), ""), &resp)
+		if resp["auth"] != 1.0 || resp["api_version"] != 3.0 {
+			t.Errorf("Expected auth 1, but got %v", resp)
+		}
+	})
+
+	// Disabled without credentials
+	execWithServer(t, Config{}, func(s *Server, feedStore *store.FeedStore, completed completedSubscriber) {
+		if w := doFeverRequest(s, feverApiKey("", ""), ""); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 without credentials, but got %v", w.Code)
+		}
+	})
+}
+
+func TestFeverFeedsAndItems(t *testing.T) {
+	execWithServer(t, feverConfig, func(s *Server, feedStore *store.FeedStore, completed completedSubscriber) {
+		feedId := createFeed(t, feedStore)
+		err := feedStore.UpdateFeedSettings(feedId, store.FeedSettings{Url: "http://foo.com/feed", Folder: "News"})
+		if err != nil {
+			t.Fatalf("Could not update feed: %v", err)
+		}
+		apiKey := feverApiKey("user", "pass")
+
+		var resp struct {
+			Groups      []feverGroup      `json:"groups"`
+			FeedsGroups []feverFeedsGroup `json:"feeds_groups"`
+			Feeds       []feverFeed       `json:"feeds"`
+			Items       []feverItem       `json:"items"`
+			TotalItems  int               `json:"total_items"`
+			UnreadIds   string            `json:"unread_item_ids"`
+			SavedIds    string            `json:"saved_item_ids"`
+		}
+		decodeResponse(t, doFeverRequest(s, apiKey, "groups&feeds"), &resp)
+		if len(resp.Groups) != 1 || resp.Groups[0].Title != "News" {
+			t.Errorf("Unexpected groups %v", resp.Groups)
+		}
+		if len(resp.FeedsGroups) != 1 || resp.FeedsGroups[0].GroupId != resp.Groups[0].Id || resp.FeedsGroups[0].FeedIds != "1" {
+			t.Errorf("Unexpected feeds groups %v", resp.FeedsGroups)
+		}
+		if len(resp.Feeds) != 1 || resp.Feeds[0].Id != feedId || resp.Feeds[0].Title != "Foo" {
+			t.Errorf("Unexpected feeds %v", resp.Feeds)
+		}
+
+		decodeResponse(t, doFeverRequest(s, apiKey, "items&since_id=1"), &resp)
+		if resp.TotalItems != 2 || len(resp.Items) != 1 || resp.Items[0].Id != 2 || resp.Items[0].Title != "Second" {
+			t.Errorf("Unexpected items %v of %v", resp.Items, resp.TotalItems)
+		}
+
+		decodeResponse(t, doFeverRequest(s, apiKey, "items&max_id=0"), &resp)
+		if len(resp.Items) != 2 || resp.Items[0].Id != 2 {
+			t.Errorf("Expected newest items first, but got %v", resp.Items)
+		}
+
+		decodeResponse(t, doFeverRequest(s, apiKey, "items&with_ids=1"), &resp)
+		if len(resp.Items) != 1 || resp.Items[0].Id != 1 {
+			t.Errorf("Unexpected items %v", resp.Items)
+		}
+
+		decodeResponse(t, doFeverRequest(s, apiKey, "mark=item&as=saved&id=1&saved_item_ids&unread_item_ids"), &resp)
+		if resp.SavedIds != "1" || resp.UnreadIds != "1,2" {
+			t.Errorf("Unexpected saved %q and unread %q item IDs", resp.SavedIds, resp.UnreadIds)
+		}
+
+		decodeResponse(t, doFeverRequest(s, apiKey, "mark=item&as=read&id=2&unread_item_ids"), &resp)
+		if resp.UnreadIds != "1" {
+			t.Errorf("Unexpected unread item IDs %q", resp.UnreadIds)
+		}
+
+		groupId := feverGroupId("News")
+		params := "mark=group&as=read&before=1000&unread_item_ids&id=" + strconv.FormatInt(groupId, 10)
+		decodeResponse(t, doFeverRequest(s, apiKey, params), &resp)
+		if resp.UnreadIds != "" {
+			t.Errorf("Expected every item in the group to be read, but got %q", resp.UnreadIds)
+		}
+	})
+}
@@ -0,0 +1,726 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"github.com/wedaly/local-news/internal/greader"
+	"github.com/wedaly/local-news/internal/store"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Paths of the Google Reader API, which is
+// used by mobile apps such as NetNewsWire and Reeder.
+const (
+	greaderLoginPath  = "/accounts/ClientLogin"
+	greaderPathPrefix = "/reader/api/0/"
+)
+
+// Default and maximum number of items returned at once
+const (
+	greaderDefaultItems  int = 20
+	greaderMaxItemIds    int = 10000
+	greaderMaxItemBodies int = 1000
+)
+
+func isGReaderPath(path string) bool {
+	return path == greaderLoginPath || strings.HasPrefix(path, greaderPathPrefix)
+}
+
+// handleGReader implements the subset of the Google Reader API that
+// sync clients use: subscriptions, labels (folders), item streams,
+// and the read and starred states.
+func (s *Server) handleGReader(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Path == greaderLoginPath {
+		s.greaderLogin(w, r)
+		return
+	}
+
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+	if subtle.ConstantTimeCompare([]byte(auth), []byte(s.greaderToken())) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, greaderPathPrefix)
+	switch {
+	case path == "token":
+		// Clients send this back with changes to prevent forgery,
+		// which the Authorization header already prevents.
+		fmt.Fprint(w, s.greaderToken())
+
+	case path == "user-info":
+		writeJson(w, http.StatusOK, map[string]string{
+			"userId":        "1",
+			"userName":      s.config.Username,
+			"userProfileId": "1",
+			"userEmail":     s.config.Username,
+		})
+
+	case path == "subscription/list":
+		s.greaderSubscriptionList(w)
+
+	case path == "subscription/quickadd" && r.Method == http.MethodPost:
+		s.greaderQuickAdd(w, r)
+
+	case path == "subscription/edit" && r.Method == http.MethodPost:
+		s.greaderEditSubscription(w, r)
+
+	case path == "tag/list":
+		s.greaderTagList(w)
+
+	case path == "rename-tag" && r.Method == http.MethodPost:
+		s.greaderRenameTag(w, r, labelName(r.FormValue("dest")))
+
+	case path == "disable-tag" && r.Method == http.MethodPost:
+		s.greaderRenameTag(w, r, "")
+
+	case path == "unread-count":
+		s.greaderUnreadCount(w)
+
+	case path == "stream/items/ids":
+		s.greaderItemIds(w, r)
+
+	case path == "stream/items/contents":
+		s.greaderItemContents(w, r)
+
+	case strings.HasPrefix(path, "stream/contents"):
+		streamId := strings.TrimPrefix(strings.TrimPrefix(path, "stream/contents"), "/")
+		if len(streamId) == 0 {
+			streamId = r.FormValue("s")
+		}
+		s.greaderStreamContents(w, r, streamId)
+
+	case path == "edit-tag" && r.Method == http.MethodPost:
+		s.greaderEditTag(w, r)
+
+	case path == "mark-all-as-read" && r.Method == http.MethodPost:
+		s.greaderMarkAllAsRead(w, r)
+
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// greaderToken returns the token clients receive when they log in.
+// It's derived from the credentials, so clients stay logged in when
+// the server restarts, and are logged out when the password changes.
+func (s *Server) greaderToken() string {
+	sum := sha256.Sum256([]byte("greader:" + s.config.Username + ":" + s.config.Password))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Server) greaderLogin(w http.ResponseWriter, r *http.Request) {
+	username := []byte(r.FormValue("Email"))
+	password := []byte(r.FormValue("Passwd"))
+	usernameOk := subtle.ConstantTimeCompare(username, []byte(s.config.Username)) == 1
+	passwordOk := subtle.ConstantTimeCompare(password, []byte(s.config.Password)) == 1
+	if !usernameOk || !passwordOk {
+		http.Error(w, "Error=BadAuthentication", http.StatusUnauthorized)
+		return
+	}
+
+	token := s.greaderToken()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "SID=%s\nLSID=%s\nAuth=%s\n", token, token, token)
+}
+
+func (s *Server) greaderSubscriptionList(w http.ResponseWriter) {
+	feeds, err := s.feedStore.RetrieveFeeds()
+	if err != nil {
+		panic(err)
+	}
+
+	type category struct {
+		Id    string `json:"id"`
+		Label string `json:"label"`
+	}
+
+	type subscription struct {
+		Id         string     `json:"id"`
+		Title      string     `json:"title"`
+		Categories []category `json:"categories"`
+		Url        string     `json:"url"`
+		HtmlUrl    string     `json:"htmlUrl"`
+		IconUrl    string     `json:"iconUrl"`
+	}
+
+	subscriptions := make([]subscription, len(feeds))
+	for i, feed := range feeds {
+		subscriptions[i] = subscription{
+			Id:         feedStreamId(feed.Id),
+			Title:      feed.DisplayName(),
+			Categories: make([]category, 0, 1),
+			Url:        feed.Url,
+			HtmlUrl:    feed.SiteUrl,
+			IconUrl:    feed.ImageUrl,
+		}
+		if len(feed.Folder) > 0 {
+			subscriptions[i].Categories = append(subscriptions[i].Categories, category{
+				Id:    greader.LabelPrefix + feed.Folder,
+				Label: feed.Folder,
+			})
+		}
+	}
+
+	writeJson(w, http.StatusOK, map[string]interface{}{"subscriptions": subscriptions})
+}
+
+// addFeedWithUrl adds a feed and schedules a task to load it,
+// unless the URL isn't an HTTP or HTTPS URL.
+func (s *Server) addFeedWithUrl(feedUrl string) (store.FeedId, bool) {
+	if !isHttpUrl(feedUrl) {
+		return 0, false
+	}
+
+	feedId, err := s.feedStore.GetOrCreateFeedWithUrl(feedUrl)
+	if err != nil {
+		panic(err)
+	}
+
+	s.taskManager.ScheduleLoadFeedTask(feedId)
+	return feedId, true
+}
+
+func (s *Server) greaderQuickAdd(w http.ResponseWriter, r *http.Request) {
+	feedUrl := strings.TrimPrefix(r.FormValue("quickadd"), greader.FeedPrefix)
+	feedId, ok := s.addFeedWithUrl(feedUrl)
+	if !ok {
+		writeJson(w, http.StatusOK, map[string]interface{}{"numResults": 0, "query": feedUrl})
+		return
+	}
+
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"numResults": 1,
+		"query":      feedUrl,
+		"streamId":   feedStreamId(feedId),
+		"streamName": feedUrl,
+	})
+}
+
+// greaderEditSubscription subscribes to, unsubscribes from, or renames
+// feeds, and adds or removes a feed's label (folder).
+func (s *Server) greaderEditSubscription(w http.ResponseWriter, r *http.Request) {
+	action := r.FormValue("ac")
+	for _, streamId := range r.Form["s"] {
+		var feedId store.FeedId
+		if action == "subscribe" {
+			var ok bool
+			if feedId, ok = s.addFeedWithUrl(strings.TrimPrefix(streamId, greader.FeedPrefix)); !ok {
+				http.Error(w, "The URL must be an HTTP or HTTPS URL", http.StatusBadRequest)
+				return
+			}
+		} else if feedId = s.findStreamFeed(streamId); feedId == 0 {
+			http.Error(w, "Feed not found", http.StatusNotFound)
+			return
+		}
+
+		if action == "unsubscribe" {
+			if err := s.feedStore.DeleteFeed(feedId); err != nil {
+				panic(err)
+			}
+			continue
+		}
+
+		record, err := s.feedStore.RetrieveFeed(feedId)
+		if err != nil {
+			panic(err)
+		}
+
+		settings := feedSettings(record)
+		if title := r.FormValue("t"); len(title) > 0 {
+			settings.CustomName = title
+		}
+		if label := labelName(r.FormValue("r")); len(label) > 0 && label == settings.Folder {
+			settings.Folder = ""
+		}
+		if label := labelName(r.FormValue("a")); len(label) > 0 {
+			settings.Folder = label
+		}
+
+		if err := s.feedStore.UpdateFeedSettings(feedId, settings); err != nil {
+			panic(err)
+		}
+	}
+
+	fmt.Fprint(w, "OK")
+}
+
+func (s *Server) greaderTagList(w http.ResponseWriter) {
+	feeds, err := s.feedStore.RetrieveFeeds()
+	if err != nil {
+		panic(err)
+	}
+
+	type tag struct {
+		Id   string `json:"id"`
+		Type string `json:"type,omitempty"`
+	}
+
+	tags := []tag{{Id: greader.Starred}}
+	for _, folder := range feedFolders(feeds) {
+		tags = append(tags, tag{greader.LabelPrefix + folder, "folder"})
+	}
+
+	writeJson(w, http.StatusOK, map[string]interface{}{"tags": tags})
+}
+
+// greaderRenameTag moves every feed in a folder to another folder,
+// or out of any folder if the new name is empty.
+func (s *Server) greaderRenameTag(w http.ResponseWriter, r *http.Request, newFolder string) {
+	oldFolder := labelName(r.FormValue("s"))
+	if len(oldFolder) == 0 {
+		oldFolder = r.FormValue("t")
+	}
+
+	feeds, err := s.feedStore.RetrieveFeeds()
+	if err != nil {
+		panic(err)
+	}
+
+	for _, feed := range feeds {
+		if len(oldFolder) > 0 && feed.Folder == oldFolder {
+			settings := feedSettings(feed)
+			settings.Folder = newFolder
+			if err := s.feedStore.UpdateFeedSettings(feed.Id, settings); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	fmt.Fprint(w, "OK")
+}
+
+func (s *Server) greaderUnreadCount(w http.ResponseWriter) {
+	feeds, err := s.feedStore.RetrieveFeeds()
+	if err != nil {
+		panic(err)
+	}
+
+	unreadCounts, err := s.feedStore.RetrieveUnreadCounts()
+	if err != nil {
+		panic(err)
+	}
+
+	type unreadCount struct {
+		Id                      string `json:"id"`
+		Count                   int    `json:"count"`
+		NewestItemTimestampUsec string `json:"newestItemTimestampUsec"`
+	}
+
+	counts := make([]unreadCount, 0, len(feeds))
+	folderCounts := make(map[string]int, 0)
+	total := 0
+	for _, feed := range feeds {
+		newest, err := s.feedStore.RetrieveFilteredItems(store.ItemFilter{FeedId: feed.Id, Limit: 1})
+		if err != nil {
+			panic(err)
+		}
+
+		var newestUsec string
+		if len(newest) > 0 {
+			newestUsec = usecString(newest[0].Date)
+		}
+
+		count := unreadCounts[feed.Id]
+		counts = append(counts, unreadCount{feedStreamId(feed.Id), count, newestUsec})
+		folderCounts[feed.Folder] += count
+		total += count
+	}
+
+	for _, folder := range feedFolders(feeds) {
+		counts = append(counts, unreadCount{Id: greader.LabelPrefix + folder, Count: folderCounts[folder]})
+	}
+	counts = append(counts, unreadCount{Id: greader.ReadingList, Count: total})
+
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"max":          total,
+		"unreadcounts": counts,
+	})
+}
+
+// greaderItemIds lists the IDs of the items in a stream, in decimal
+func (s *Server) greaderItemIds(w http.ResponseWriter, r *http.Request) {
+	filter, ok := s.greaderStreamFilter(w, r, r.FormValue("s"), greaderMaxItemIds)
+	if !ok {
+		return
+	}
+
+	items, err := s.feedStore.RetrieveFilteredItems(filter)
+	if err != nil {
+		panic(err)
+	}
+
+	type itemRef struct {
+		Id              string   `json:"id"`
+		DirectStreamIds []string `json:"directStreamIds"`
+		TimestampUsec   string   `json:"timestampUsec"`
+	}
+
+	refs := make([]itemRef, len(items))
+	for i, item := range items {
+		refs[i] = itemRef{
+			Id:              strconv.FormatInt(int64(item.Id), 10),
+			DirectStreamIds: []string{},
+			TimestampUsec:   usecString(item.Date),
+		}
+	}
+
+	resp := map[string]interface{}{"itemRefs": refs}
+	if c := continuation(filter, len(items)); len(c) > 0 {
+		resp["continuation"] = c
+	}
+	writeJson(w, http.StatusOK, resp)
+}
+
+// greaderItemContents returns the items with the IDs in the "i" parameters
+func (s *Server) greaderItemContents(w http.ResponseWriter, r *http.Request) {
+	filter := store.ItemFilter{Ids: make([]store.FeedItemId, 0, len(r.Form["i"]))}
+	for _, s := range r.Form["i"] {
+		id, err := parseGReaderItemId(s)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid item ID %q", s), http.StatusBadRequest)
+			return
+		}
+		filter.Ids = append(filter.Ids, id)
+	}
+
+	if len(filter.Ids) > greaderMaxItemBodies {
+		http.Error(w, "Too many items", http.StatusBadRequest)
+		return
+	}
+
+	items, err := s.feedStore.RetrieveFilteredItems(filter)
+	if err != nil {
+		panic(err)
+	}
+	s.writeGReaderItems(w, greader.ReadingList, items, "")
+}
+
+// greaderStreamContents returns the items in a stream with their content
+func (s *Server) greaderStreamContents(w http.ResponseWriter, r *http.Request, streamId string) {
+	filter, ok := s.greaderStreamFilter(w, r, streamId, greaderMaxItemBodies)
+	if !ok {
+		return
+	}
+
+	items, err := s.feedStore.RetrieveFilteredItems(filter)
+	if err != nil {
+		panic(err)
+	}
+	s.writeGReaderItems(w, streamId, items, continuation(filter, len(items)))
+}
+
+type greaderLink struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type greaderContent struct {
+	Direction string `json:"direction"`
+	Content   string `json:"content"`
+}
+
+type greaderOrigin struct {
+	StreamId string `json:"streamId"`
+	Title    string `json:"title"`
+	HtmlUrl  string `json:"htmlUrl"`
+}
+
+type greaderItem struct {
+	Id            string         `json:"id"`
+	CrawlTimeMsec string         `json:"crawlTimeMsec"`
+	TimestampUsec string         `json:"timestampUsec"`
+	Published     int64          `json:"published"`
+	Updated       int64          `json:"updated"`
+	Title         string         `json:"title"`
+	Canonical     []greaderLink  `json:"canonical"`
+	Alternate     []greaderLink  `json:"alternate"`
+	Categories    []string       `json:"categories"`
+	Origin        greaderOrigin  `json:"origin"`
+	Summary       greaderContent `json:"summary"`
+	Author        string         `json:"author"`
+}
+
+func (s *Server) writeGReaderItems(w http.ResponseWriter, streamId string, records []store.FeedItemRecord, continuation string) {
+	feeds, err := s.feedStore.RetrieveFeeds()
+	if err != nil {
+		panic(err)
+	}
+
+	feedsById := make(map[store.FeedId]store.FeedRecord, len(feeds))
+	for _, feed := range feeds {
+		feedsById[feed.Id] = feed
+	}
+
+	items := make([]greaderItem, len(records))
+	for i, record := range records {
+		feed := feedsById[record.FeedId]
+		updated := record.Updated
+		if updated.IsZero() {
+			updated = record.Date
+		}
+
+		categories := []string{greader.ReadingList}
+		if record.Read {
+			categories = append(categories, greader.Read)
+		}
+		if record.Starred {
+			categories = append(categories, greader.Starred)
+		}
+		if len(feed.Folder) > 0 {
+			categories = append(categories, greader.LabelPrefix+feed.Folder)
+		}
+
+		items[i] = greaderItem{
+			Id:            fmt.Sprintf("%s%016x", greader.ItemIdPrefix, int64(record.Id)),
+			CrawlTimeMsec: strconv.FormatInt(record.Date.UnixNano()/int64(time.Millisecond), 10),
+			TimestampUsec: usecString(record.Date),
+			Published:     record.Date.Unix(),
+			Updated:       updated.Unix(),
+			Title:         record.Title,
+			Canonical:     []greaderLink{{Href: record.Url}},
+			Alternate:     []greaderLink{{Href: record.Url, Type: "text/html"}},
+			Categories:    categories,
+			Origin: greaderOrigin{
+				StreamId: feedStreamId(feed.Id),
+				Title:    feed.DisplayName(),
+				HtmlUrl:  feed.SiteUrl,
+			},
+			Summary: greaderContent{"ltr", itemHtml(record)},
+			Author:  record.Author,
+		}
+	}
+
+	resp := map[string]interface{}{
+		"direction": "ltr",
+		"id":        streamId,
+		"updated":   time.Now().Unix(),
+		"items":     items,
+	}
+	if len(continuation) > 0 {
+		resp["continuation"] = continuation
+	}
+	writeJson(w, http.StatusOK, resp)
+}
+
+// greaderEditTag adds or removes the read and starred states of items.
+// Clients can't add labels to items, since folders contain feeds.
+func (s *Server) greaderEditTag(w http.ResponseWriter, r *http.Request) {
+	itemIds := make([]store.FeedItemId, 0, len(r.Form["i"]))
+	for _, s := range r.Form["i"] {
+		id, err := parseGReaderItemId(s)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid item ID %q", s), http.StatusBadRequest)
+			return
+		}
+		itemIds = append(itemIds, id)
+	}
+
+	type change struct {
+		tag   string
+		added bool
+	}
+	changes := make([]change, 0)
+	for _, tag := range r.Form["a"] {
+		changes = append(changes, change{greader.NormalizeStreamId(tag), true})
+	}
+	for _, tag := range r.Form["r"] {
+		changes = append(changes, change{greader.NormalizeStreamId(tag), false})
+	}
+
+	starred := false
+	for _, id := range itemIds {
+		for _, c := range changes {
+			var err error
+			switch c.tag {
+			case greader.Read:
+				err = s.feedStore.SetItemRead(id, c.added)
+			case greader.KeptUnread:
+				if c.added {
+					err = s.feedStore.SetItemRead(id, false)
+				}
+			case greader.Starred:
+				err = s.feedStore.SetItemStarred(id, c.added)
+				starred = starred || c.added
+			}
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	if starred {
+		s.archiveStarredItems()
+	}
+
+	fmt.Fprint(w, "OK")
+}
+
+// greaderMarkAllAsRead marks the items in a stream as read,
+// up to the time in microseconds in the "ts" parameter, if any.
+func (s *Server) greaderMarkAllAsRead(w http.ResponseWriter, r *http.Request) {
+	filter, ok := s.streamFilter(r.FormValue("s"))
+	if !ok {
+		http.Error(w, "Unknown stream", http.StatusBadRequest)
+		return
+	}
+
+	if ts, err := strconv.ParseInt(r.FormValue("ts"), 10, 64); err == nil && ts > 0 {
+		filter.Until = time.Unix(0, ts*int64(time.Microsecond))
+	}
+
+	if err := s.feedStore.MarkFilteredItemsRead(filter); err != nil {
+		panic(err)
+	}
+	fmt.Fprint(w, "OK")
+}
+
+// greaderStreamFilter selects the items in a stream, using the
+// parameters clients send to exclude read items and page through them.
+func (s *Server) greaderStreamFilter(w http.ResponseWriter, r *http.Request, streamId string, maxItems int) (store.ItemFilter, bool) {
+	filter, ok := s.streamFilter(streamId)
+	if !ok {
+		http.Error(w, "Unknown stream", http.StatusBadRequest)
+		return filter, false
+	}
+
+	// Only "read" and "starred" can be included or excluded
+	for _, target := range r.Form["xt"] {
+		if greader.NormalizeStreamId(target) == greader.Read {
+			filter.Unread = true
+		}
+	}
+	for _, target := range r.Form["it"] {
+		switch greader.NormalizeStreamId(target) {
+		case greader.Read:
+			filter.Read = true
+		case greader.Starred:
+			filter.Starred = true
+		}
+	}
+
+	if ot, err := strconv.ParseInt(r.FormValue("ot"), 10, 64); err == nil && ot > 0 {
+		filter.Since = time.Unix(ot, 0)
+	}
+	if nt, err := strconv.ParseInt(r.FormValue("nt"), 10, 64); err == nil && nt > 0 {
+		filter.Until = time.Unix(nt, 0)
+	}
+
+	if r.FormValue("r") == "o" {
+		filter.Order = store.OldestFirst
+	}
+
+	filter.Limit = greaderDefaultItems
+	if n, err := strconv.Atoi(r.FormValue("n")); err == nil && n > 0 {
+		filter.Limit = n
+	}
+	if filter.Limit > maxItems {
+		filter.Limit = maxItems
+	}
+
+	// The continuation is the number of items already returned
+	if offset, err := strconv.Atoi(r.FormValue("c")); err == nil && offset > 0 {
+		filter.Offset = offset
+	}
+
+	return filter, true
+}
+
+// streamFilter selects the items in a feed, label (folder), or state stream
+func (s *Server) streamFilter(streamId string) (store.ItemFilter, bool) {
+	streamId = greader.NormalizeStreamId(streamId)
+	switch {
+	case streamId == greader.ReadingList:
+		return store.ItemFilter{}, true
+	case streamId == greader.Starred:
+		return store.ItemFilter{Starred: true}, true
+	case streamId == greader.Read:
+		return store.ItemFilter{Read: true}, true
+	case strings.HasPrefix(streamId, greader.LabelPrefix):
+		return store.ItemFilter{Folder: labelName(streamId)}, true
+	case strings.HasPrefix(streamId, greader.FeedPrefix):
+		if feedId := s.findStreamFeed(streamId); feedId > 0 {
+			return store.ItemFilter{FeedId: feedId}, true
+		}
+	}
+	return store.ItemFilter{}, false
+}
+
+// findStreamFeed returns the ID of the feed in a stream ID, which
+// contains either the feed's ID or its URL.  Returns zero if not found.
+func (s *Server) findStreamFeed(streamId string) store.FeedId {
+	value := strings.TrimPrefix(streamId, greader.FeedPrefix)
+	feeds, err := s.feedStore.RetrieveFeeds()
+	if err != nil {
+		panic(err)
+	}
+
+	id, _ := strconv.ParseInt(value, 10, 64)
+	for _, feed := range feeds {
+		if int64(feed.Id) == id || feed.Url == value {
+			return feed.Id
+		}
+	}
+	return 0
+}
+
+// continuation returns the continuation for the next page of items,
+// or the empty string if there are no more.
+func continuation(filter store.ItemFilter, numItems int) string {
+	if numItems < filter.Limit {
+		return ""
+	}
+	return strconv.Itoa(filter.Offset + numItems)
+}
+
+// labelName returns the folder named by a label stream ID,
+// or the empty string if the stream isn't a label.
+func labelName(streamId string) string {
+	streamId = greader.NormalizeStreamId(streamId)
+	if !strings.HasPrefix(streamId, greader.LabelPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(streamId, greader.LabelPrefix)
+}
+
+func feedStreamId(feedId store.FeedId) string {
+	return greader.FeedPrefix + strconv.FormatInt(int64(feedId), 10)
+}
+
+// parseGReaderItemId parses an item ID in the long form, which is
+// hexadecimal, or the short form, which is decimal.
+func parseGReaderItemId(s string) (store.FeedItemId, error) {
+	if strings.HasPrefix(s, greader.ItemIdPrefix) {
+		id, err := strconv.ParseUint(strings.TrimPrefix(s, greader.ItemIdPrefix), 16, 63)
+		return store.FeedItemId(id), err
+	}
+
+	id, err := strconv.ParseInt(s, 10, 64)
+	return store.FeedItemId(id), err
+}
+
+func usecString(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano()/int64(time.Microsecond), 10)
+}
+
+// feedSettings returns the current settings of a feed, so some can be changed
+func feedSettings(record store.FeedRecord) store.FeedSettings {
+	return store.FeedSettings{
+		Url:             record.Url,
+		CustomName:      record.CustomName,
+		Folder:          record.Folder,
+		RefreshInterval: record.RefreshInterval,
+		Muted:           record.Muted,
+		ArchiveArticles: record.ArchiveArticles,
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"github.com/wedaly/local-news/internal/store"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// doGReaderRequest sends a request the way Google Reader clients do,
+// with form parameters and the token from logging in.
+func doGReaderRequest(s *Server, method string, path string, form url.Values, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	req.Host = "nas.local"
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "GoogleLogin auth="+token)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func greaderLogin(t *testing.T, s *Server) string {
+	form := url.Values{"Email": {"user"}, "Passwd": {"pass"}}
+	w := doGReaderRequest(s, "POST", "/accounts/ClientLogin", form, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %v: %v", w.Code, w.Body.String())
+	}
+
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if strings.HasPrefix(line, "Auth=") {
+			return strings.TrimPrefix(line, "Auth=")
+		}
+	}
+	t.Fatalf("No token in response %q", w.Body.String())
+	return ""
+}
+
+func TestGReaderLogin(t *testing.T) {
+	execWithServer(t, feverConfig, func(s *Server, feedStore *store.FeedStore, completed completedSubscriber) {
+		form := url.Values{"Email": {"user"}, "Passwd": {"wrong"}}
+		if w := doGReaderRequest(s, "POST", "/accounts/ClientLogin", form, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 with wrong password, but got %v", w.Code)
+		}
+
+		if w := doGReaderRequest(s, "GET", "/reader/api/0/user-info", nil, "wrong"); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 with wrong token, but got %v", w.Code)
+		}
+
+		token := greaderLogin(t, s)
+		if w := doGReaderRequest(s, "GET", "/reader/api/0/user-info", nil, token); w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 with token, but got %v", w.Code)
+		}
+	})
+}
+
+func TestGReaderSubscriptions(t *testing.T) {
+	execWithServer(t, feverConfig, func(s *Server, feedStore *store.FeedStore, completed completedSubscriber) {
+		feedId := createFeed(t, feedStore)
+		token := greaderLogin(t, s)
+
+		form := url.Values{"ac": {"edit"}, "s": {"feed/1"}, "a": {"user/-/label/News"}, "t": {"Bar"}}
+		if w := doGReaderRequest(s, "POST", "/reader/api/0/subscription/edit", form, token); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, but got %v: %v", w.Code, w.Body.String())
+		}
+
+		var resp struct {
+			Subscriptions []struct {
+				Id         string `json:"id"`
+				Title      string `json:"title"`
+				Categories []struct {
+					Id string `json:"id"`
+				} `json:"categories"`
+			} `json:"subscriptions"`
+		}
+		decodeResponse(t, doGReaderRequest(s, "GET", "/reader/api/0/subscription/list?output=json", nil, token), &resp)
+		if len(resp.Subscriptions) != 1 {
+			t.Fatalf("Expected one subscription, but got %v", resp.Subscriptions)
+		}
+
+		sub := resp.Subscriptions[0]
+		if sub.Id != "feed/1" || sub.Title != "Bar" || len(sub.Categories) != 1 || sub.Categories[0].Id != "user/-/label/News" {
+			t.Errorf("Unexpected subscription %v", sub)
+		}
+
+		form = url.Values{"s": {"user/-/label/News"}, "dest": {"user/-/label/Tech"}}
+		doGReaderRequest(s, "POST", "/reader/api/0/rename-tag", form, token)
+		if record, err := feedStore.RetrieveFeed(feedId); err != nil || record.Folder != "Tech" || record.CustomName != "Bar" {
+			t.Errorf("Expected feed in renamed folder, but got %v, %v", record, err)
+		}
+
+		form = url.Values{"quickadd": {"file:///etc/passwd"}}
+		var quickAdd struct {
+			NumResults int `json:"numResults"`
+		}
+		decodeResponse(t, doGReaderRequest(s, "POST", "/reader/api/0/subscription/quickadd", form, token), &quickAdd)
+		if quickAdd.NumResults != 0 {
+			t.Errorf("Expected local file not to be added")
+		}
+
+		form = url.Values{"ac": {"unsubscribe"}, "s": {"feed/1"}}
+		doGReaderRequest(s, "POST", "/reader/api/0/subscription/edit", form, token)
+		if feeds, err := feedStore.RetrieveFeeds(); err != nil || len(feeds) != 0 {
+			t.Errorf("Expected feed to be deleted, but got %v, %v", feeds, err)
+		}
+	})
+}
+
+func TestGReaderStreams(t *testing.T) {
+	execWithServer(t, feverConfig, func(s *Server, feedStore *store.FeedStore, completed completedSubscriber) {
+		createFeed(t, feedStore)
+		token := greaderLogin(t, s)
+
+		var ids struct {
+			ItemRefs []struct {
+				Id string `json:"id"`
+			} `json:"itemRefs"`
+			Continuation string `json:"continuation"`
+		}
+		path := "/reader/api/0/stream/items/ids?s=user/-/state/com.google/reading-list&n=1&r=o"
+		decodeResponse(t, doGReaderRequest(s, "GET", path, nil, token), &ids)
+		if len(ids.ItemRefs) != 1 || ids.ItemRefs[0].Id != "1" || ids.Continuation != "1" {
+			t.Errorf("Unexpected item IDs %v", ids)
+		}
+
+		decodeResponse(t, doGReaderRequest(s, "GET", path+"&c=1", nil, token), &ids)
+		if len(ids.ItemRefs) != 1 || ids.ItemRefs[0].Id != "2" {
+			t.Errorf("Unexpected item IDs %v", ids)
+		}
+
+		form := url.Values{
+			"i": {"tag:google.com,2005:reader/item/0000000000000001"},
+			"a": {"user/-/state/com.google/read", "user/1001/state/com.google/starred"},
+		}
+		if w := doGReaderRequest(s, "POST", "/reader/api/0/edit-tag", form, token); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, but got %v: %v", w.Code, w.Body.String())
+		}
+
+		if record, err := feedStore.RetrieveFeedItem(1); err != nil || !record.Read || !record.Starred {
+			t.Errorf("Expected item to be read and starred, but got %v, %v", record, err)
+		}
+
+		path = "/reader/api/0/stream/items/ids?s=feed/1&xt=user/-/state/com.google/read"
+		decodeResponse(t, doGReaderRequest(s, "GET", path, nil, token), &ids)
+		if len(ids.ItemRefs) != 1 || ids.ItemRefs[0].Id != "2" {
+			t.Errorf("Expected only unread items, but got %v", ids)
+		}
+
+		var contents struct {
+			Items []greaderItem `json:"items"`
+		}
+		form = url.Values{"i": {"1"}}
+		decodeResponse(t, doGReaderRequest(s, "POST", "/reader/api/0/stream/items/contents", form, token), &contents)
+		if len(contents.Items) != 1 {
+			t.Fatalf("Expected one item, but got %v", contents.Items)
+		}
+
+		item := contents.Items[0]
+		if item.Id != "tag:google.com,2005:reader/item/0000000000000001" || item.Title != "First" || item.Origin.StreamId != "feed/1" {
+			t.Errorf("Unexpected item %v", item)
+		}
+		if strings.Join(item.Categories, " ") != "user/-/state/com.google/reading-list user/-/state/com.google/read user/-/state/com.google/starred" {
+			t.Errorf("Unexpected categories %v", item.Categories)
+		}
+
+		path = "/reader/api/0/stream/contents/user/-/state/com.google/starred"
+		decodeResponse(t, doGReaderRequest(s, "GET", path, nil, token), &contents)
+		if len(contents.Items) != 1 || contents.Items[0].Title != "First" {
+			t.Errorf("Expected starred item, but got %v", contents.Items)
+		}
+
+		form = url.Values{"s": {"user/-/state/com.google/reading-list"}}
+		doGReaderRequest(s, "POST", "/reader/api/0/mark-all-as-read", form, token)
+		var counts struct {
+			Max int `json:"max"`
+		}
+		decodeResponse(t, doGReaderRequest(s, "GET", "/reader/api/0/unread-count", nil, token), &counts)
+		if counts.Max != 0 {
+			t.Errorf("Expected every item to be read, but got %v unread", counts.Max)
+		}
+	})
+}
@@ -0,0 +1,308 @@
+package archive
+
+import (
+	"errors"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"io"
+	"math"
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+// Article is the main content of a web page, such as a blog post
+// or news story, without the page's navigation, ads, comments, etc.
+type Article struct {
+	// Title of the page, which may be empty
+	Title string
+
+	// The content as HTML, with links resolved against the page's URL
+	ContentHtml string
+}
+
+// ErrNoContent means that the page doesn't seem to contain an article
+var ErrNoContent = errors.New("Could not find the article's content")
+
+// minArticleLength is the minimum length of an article's text
+const minArticleLength = 100
+
+// Elements that never contain the article's content
+const removedSelector = "script, style, noscript, template, link, meta, nav, header, footer, aside, " +
+	"form, button, input, select, textarea, iframe, object, embed, canvas, svg, dialog"
+
+// Classes and IDs of elements that are unlikely or likely to contain the content.
+// These are similar to the ones used by Mozilla's Readability.
+var (
+	unlikelyRegexp = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cover-wrap|disqus|` +
+		`extra|foot|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|` +
+		`sponsor|ad-break|agegate|pagination|pager|popup|share|subscribe|newsletter|cookie`)
+	maybeRegexp    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveRegexp = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeRegexp = regexp.MustCompile(`(?i)hidden|banner|combx|comment|com-|contact|foot|footer|footnote|` +
+		`masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|` +
+		`shopping|tags|tool|widget`)
+)
+
+// Attributes kept in the extracted content.  Others (such as classes
+// and event handlers) are removed.
+var keptAttributes = map[string]bool{
+	"href":    true,
+	"src":     true,
+	"alt":     true,
+	"title":   true,
+	"colspan": true,
+	"rowspan": true,
+}
+
+// Extract finds the main content of an HTML page, readability-style:
+// paragraphs are scored by their length and punctuation, and the
+// element with the highest total score of the paragraphs it contains
+// (penalized for links and unlikely classes) is chosen as the article.
+// Returns `ErrNoContent` if the page has too little text.
+func Extract(r io.Reader, pageUrl string) (Article, error) {
+	baseUrl, err := neturl.Parse(pageUrl)
+	if err != nil {
+		return Article{}, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return Article{}, err
+	}
+
+	// The <base> element, if present, overrides the page URL for relative links
+	if baseHref, ok := doc.Find("head base[href]").First().Attr("href"); ok {
+		if u, err := baseUrl.Parse(baseHref); err == nil {
+			baseUrl = u
+		}
+	}
+
+	article := Article{Title: extractTitle(doc)}
+
+	doc.Find(removedSelector).Remove()
+	removeUnlikelyCandidates(doc)
+
+	top := findTopCandidate(doc)
+	if top == nil {
+		return Article{}, ErrNoContent
+	}
+
+	content := collectContent(top)
+	cleanContent(content, baseUrl)
+
+	if len(collapseSpace(content.Text())) < minArticleLength {
+		return Article{}, ErrNoContent
+	}
+
+	article.ContentHtml, err = content.Html()
+	if err != nil {
+		return Article{}, err
+	}
+
+	return article, nil
+}
+
+// extractTitle prefers the Open Graph title, since the <title> element
+// often includes the site's name.
+func extractTitle(doc *goquery.Document) string {
+	if title, ok := doc.Find(`head meta[property="og:title"]`).First().Attr("content"); ok {
+		if title = collapseSpace(title); len(title) > 0 {
+			return title
+		}
+	}
+
+	return collapseSpace(doc.Find("head title").First().Text())
+}
+
+func removeUnlikelyCandidates(doc *goquery.Document) {
+	doc.Find("body *").Each(func(_ int, s *goquery.Selection) {
+		if s.Is("article, main, body, a, table, tbody, tr, td") {
+			return
+		}
+
+		match := classAndId(s)
+		if unlikelyRegexp.MatchString(match) && !maybeRegexp.MatchString(match) {
+			s.Remove()
+		}
+	})
+}
+
+// findTopCandidate returns the element most likely to be the
+// article's container, or nil if the page has no paragraphs.
+func findTopCandidate(doc *goquery.Document) *goquery.Selection {
+	scores := make(map[*html.Node]float64, 0)
+	candidates := make([]*goquery.Selection, 0)
+
+	addScore := func(s *goquery.Selection, score float64) {
+		if len(s.Nodes) == 0 || s.Is("html") {
+			return
+		}
+
+		node := s.Nodes[0]
+		if _, ok := scores[node]; !ok {
+			scores[node] = initialScore(s)
+			candidates = append(candidates, s)
+		}
+		scores[node] += score
+	}
+
+	doc.Find("p, pre, td, blockquote").Each(func(_ int, s *goquery.Selection) {
+		text := collapseSpace(s.Text())
+		if len(text) < 25 {
+			return
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text)/100), 3)
+		parent := s.Parent()
+		addScore(parent, score)
+		addScore(parent.Parent(), score/2)
+	})
+
+	var top *goquery.Selection
+	var topScore float64
+	for _, s := range candidates {
+		score := scores[s.Nodes[0]] * (1 - linkDensity(s))
+		scores[s.Nodes[0]] = score
+		if top == nil || score > topScore {
+			top, topScore = s, score
+		}
+	}
+
+	if top == nil {
+		return nil
+	}
+
+	// Siblings of the top candidate often contain parts of the article
+	// (such as when each paragraph is wrapped in its own <div>)
+	threshold := math.Max(10, topScore*0.2)
+	content := top
+	top.Siblings().Each(func(_ int, s *goquery.Selection) {
+		if score, ok := scores[s.Nodes[0]]; ok && score >= threshold {
+			content = content.AddSelection(s)
+		} else if s.Is("p") {
+			text := collapseSpace(s.Text())
+			if len(text) > 80 && linkDensity(s) < 0.25 {
+				content = content.AddSelection(s)
+			}
+		}
+	})
+
+	return content
+}
+
+// initialScore weights elements by their tag, class, and ID
+func initialScore(s *goquery.Selection) float64 {
+	var score float64
+	switch goquery.NodeName(s) {
+	case "article":
+		score = 10
+	case "div", "main", "section":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+
+	match := classAndId(s)
+	if negativeRegexp.MatchString(match) {
+		score -= 25
+	}
+	if positiveRegexp.MatchString(match) {
+		score += 25
+	}
+
+	return score
+}
+
+// collectContent copies the selected elements into a new <div>,
+// so the original document isn't modified.
+func collectContent(s *goquery.Selection) *goquery.Selection {
+	div := &html.Node{Type: html.ElementNode, Data: "div"}
+	s.Each(func(_ int, s *goquery.Selection) {
+		div.AppendChild(cloneNode(s.Nodes[0]))
+	})
+	return goquery.NewDocumentFromNode(div).Selection
+}
+
+func cloneNode(n *html.Node) *html.Node {
+	clone := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		clone.AppendChild(cloneNode(c))
+	}
+
+	return clone
+}
+
+// cleanContent removes presentational attributes and link-heavy
+// lists or tables, and resolves relative links.
+func cleanContent(content *goquery.Selection, baseUrl *neturl.URL) {
+	content.Find("ul, ol, table, div").Each(func(_ int, s *goquery.Selection) {
+		if s.Find("img, pre").Length() == 0 && linkDensity(s) > 0.5 {
+			s.Remove()
+		}
+	})
+
+	content.Find("*").Each(func(_ int, s *goquery.Selection) {
+		node := s.Nodes[0]
+
+		// Lazy-loaded images keep their URL in another attribute
+		if node.Data == "img" {
+			if src, ok := s.Attr("data-src"); ok {
+				s.SetAttr("src", src)
+			}
+		}
+
+		attrs := make([]html.Attribute, 0, len(node.Attr))
+		for _, attr := range node.Attr {
+			if !keptAttributes[attr.Key] {
+				continue
+			}
+
+			if attr.Key == "href" || attr.Key == "src" {
+				u, err := baseUrl.Parse(strings.TrimSpace(attr.Val))
+				if err != nil || u.Scheme == "javascript" {
+					continue
+				}
+				attr.Val = u.String()
+			}
+			attrs = append(attrs, attr)
+		}
+		node.Attr = attrs
+	})
+}
+
+// linkDensity is the fraction of an element's text inside links
+func linkDensity(s *goquery.Selection) float64 {
+	textLength := len(collapseSpace(s.Text()))
+	if textLength == 0 {
+		return 0
+	}
+
+	var linkLength int
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLength += len(collapseSpace(a.Text()))
+	})
+
+	return float64(linkLength) / float64(textLength)
+}
+
+func classAndId(s *goquery.Selection) string {
+	class, _ := s.Attr("class")
+	id, _ := s.Attr("id")
+	return class + " " + id
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
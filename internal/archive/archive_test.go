@@ -0,0 +1,94 @@
+package archive
+
+import (
+	"strings"
+	"testing"
+)
+
+const articleHtml = `
+	<!DOCTYPE html>
+	<html>
+		<head>
+			<title>A story | Example News</title>
+			<meta property="og:title" content="A   story">
+			<script>trackVisitor();</script>
+		</head>
+		<body>
+			<header class="masthead"><a href="/">Example News</a></header>
+			<nav><a href="/world">World</a> <a href="/sports">Sports</a></nav>
+			<div class="sidebar">
+				<p>Subscribe to our newsletter, which is the best newsletter, for news, sports, and weather.</p>
+			</div>
+			<div id="main-content" class="post">
+				<h1>A story</h1>
+				<p class="lead" onclick="track()">The first paragraph of the story, which is long enough
+					to be scored, has commas, and continues for a while.</p>
+				<p>The second paragraph links to <a href="/sources/1">a source</a>, and includes
+					an image, <img data-src="images/photo.jpg" alt="A photo">, inline.</p>
+				<ul class="share"><li><a href="/share">Share</a></li></ul>
+			</div>
+			<div class="comments">
+				<p>A comment about the story, which is long and has commas, but isn't part of it.</p>
+			</div>
+			<footer><p>Copyright Example News, all rights reserved, forever and ever.</p></footer>
+		</body>
+	</html>`
+
+func TestExtract(t *testing.T) {
+	article, err := Extract(strings.NewReader(articleHtml), "https://example.com/news/story")
+	if err != nil {
+		t.Fatalf("Could not extract article: %v", err)
+	}
+
+	if article.Title != "A story" {
+		t.Errorf("Expected title 'A story', but got '%v'", article.Title)
+	}
+
+	included := []string{
+		"The first paragraph of the story",
+		"The second paragraph",
+		`<a href="https://example.com/sources/1">a source</a>`,
+		`<img alt="A photo" src="https://example.com/news/images/photo.jpg"/>`,
+	}
+	for _, s := range included {
+		if !strings.Contains(article.ContentHtml, s) {
+			t.Errorf("Expected content to include '%v', but got %v", s, article.ContentHtml)
+		}
+	}
+
+	excluded := []string{"trackVisitor", "Sports", "newsletter", "A comment", "Copyright", "Share", "onclick", "class="}
+	for _, s := range excluded {
+		if strings.Contains(article.ContentHtml, s) {
+			t.Errorf("Expected content to exclude '%v', but got %v", s, article.ContentHtml)
+		}
+	}
+}
+
+func TestExtractSiblingParagraphs(t *testing.T) {
+	paragraph := "<p>This paragraph is part of the article, and it is long enough to be included as a sibling.</p>"
+	page := "<html><body><div><p>Intro, which has commas, and is long enough, to be scored.</p></div>" +
+		paragraph + paragraph + "</body></html>"
+
+	article, err := Extract(strings.NewReader(page), "https://example.com/")
+	if err != nil {
+		t.Fatalf("Could not extract article: %v", err)
+	}
+
+	if !strings.Contains(article.ContentHtml, "Intro") || strings.Count(article.ContentHtml, "This paragraph") != 2 {
+		t.Errorf("Expected the intro and both paragraphs, but got %v", article.ContentHtml)
+	}
+}
+
+func TestExtractNoContent(t *testing.T) {
+	pages := []string{
+		"",
+		"<html><body><nav><a href='/'>Home</a></nav></body></html>",
+		"<html><body><p>Too short.</p></body></html>",
+	}
+
+	for _, page := range pages {
+		if _, err := Extract(strings.NewReader(page), "https://example.com/"); err != ErrNoContent {
+			t.Errorf("Expected ErrNoContent for %q, but got %v", page, err)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package choice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Parse returns the index of the name that matches `name`, ignoring case.
+// Packages use this to choose a value of an enum from its name,
+// e.g. on the command line, where the names are listed in enum order.
+func Parse(name string, names []string) (int, error) {
+	for i, n := range names {
+		if strings.EqualFold(name, n) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown format %q, expected one of: %v", name, strings.Join(names, ", "))
+}
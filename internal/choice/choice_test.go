@@ -0,0 +1,14 @@
+package choice
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	names := []string{"atom", "rss"}
+	if i, err := Parse("RSS", names); err != nil || i != 1 {
+		t.Errorf("Expected index 1, got %v (err %v)", i, err)
+	}
+
+	if _, err := Parse("json", names); err == nil {
+		t.Errorf("Expected error for unknown name")
+	}
+}
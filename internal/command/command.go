@@ -0,0 +1,86 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrTimeout is returned when a command doesn't exit before its timeout
+var ErrTimeout = errors.New("Command timed out")
+
+// maxStderrLength is the maximum length of a command's
+// error output included in an error message.
+const maxStderrLength int = 200
+
+// Run executes a shell command and returns its standard output.
+// If `stdin` is not nil, it is sent to the command's standard input.
+// If the command doesn't exit within the timeout (if positive), the command
+// and any processes it started are killed and ErrTimeout is returned.
+// If the command fails, the error includes the beginning of its error output.
+func Run(command string, stdin io.Reader, timeout time.Duration) ([]byte, error) {
+	return RunWithArgs(command, nil, stdin, timeout)
+}
+
+// RunWithArgs is like Run, but appends arguments to the command.
+// The arguments are passed as positional parameters, so they aren't
+// interpreted by the shell even if they contain untrusted text.
+func RunWithArgs(command string, args []string, stdin io.Reader, timeout time.Duration) ([]byte, error) {
+	if len(args) > 0 {
+		command += ` "$@"`
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sh", append([]string{"-c", command, "sh"}, args...)...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Start the command in its own process group, so we can kill
+	// any child processes along with the shell.  Otherwise, a child
+	// could keep the output pipes open after the shell is killed.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var timeoutChan <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutChan = timer.C
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, commandError(err, stderr.String())
+		}
+		return stdout.Bytes(), nil
+
+	case <-timeoutChan:
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return nil, ErrTimeout
+	}
+}
+
+func commandError(err error, stderr string) error {
+	stderr = strings.TrimSpace(stderr)
+	if len(stderr) == 0 {
+		return err
+	}
+
+	if len(stderr) > maxStderrLength {
+		stderr = stderr[:maxStderrLength] + "..."
+	}
+	return errors.New(err.Error() + ": " + stderr)
+}
@@ -0,0 +1,53 @@
+package command
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	out, err := Run("cat; echo done", strings.NewReader("input\n"), time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error running command: %v", err)
+	}
+
+	if string(out) != "input\ndone\n" {
+		t.Errorf("Incorrect output: %q", out)
+	}
+}
+
+func TestRunWithArgs(t *testing.T) {
+	out, err := RunWithArgs("printf '%s|'", []string{"a b", "$(echo injected)"}, nil, time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error running command: %v", err)
+	}
+
+	if string(out) != "a b|$(echo injected)|" {
+		t.Errorf("Incorrect output: %q", out)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	_, err := Run("echo oops >&2; exit 3", nil, time.Second)
+	if err == nil {
+		t.Fatalf("Expected error running command")
+	}
+
+	if !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("Error should contain exit status and output, got %v", err)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	start := time.Now()
+	_, err := Run("sleep 10; echo done", nil, 50*time.Millisecond)
+	if err != ErrTimeout {
+		t.Errorf("Expected timeout error, but got %v", err)
+	}
+
+	// Child processes are killed along with the shell
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Command ran for %v after timeout", elapsed)
+	}
+}
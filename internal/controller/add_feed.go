@@ -4,34 +4,47 @@ import (
 	"github.com/atotto/clipboard"
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/feed"
 	"github.com/wedaly/local-news/internal/i18n"
 	"github.com/wedaly/local-news/internal/store"
 	"github.com/wedaly/local-news/internal/task"
-	"net/url"
 )
 
 // AddFeedController handles the form for creating a new feed from a URL
 type AddFeedController struct {
 	appController *AppController
 	config        i18n.Config
+	localizer     *i18n.Localizer
 	feedStore     *store.FeedStore
 	taskManager   *task.TaskManager
+	flex          *tview.Flex
 	form          *tview.Form
 	urlField      *tview.InputField
+	authFields    *authFields
+	scrapeFields  *scrapeFields
+	preview       *feedPreview
+	statusFooter  *tview.TextView
 }
 
 func NewAddFeedController(
 	appController *AppController,
 	config i18n.Config,
+	localizer *i18n.Localizer,
 	feedStore *store.FeedStore,
 	taskManager *task.TaskManager) *AddFeedController {
 
 	// Set up the form
 	form := tview.NewForm().
-		AddInputField(i18n.Gettext("URL"), "", 0, nil, nil).
-		AddButton(i18n.Gettext("OK"), nil)
+		AddInputField(localizer.Gettext("URL"), "", 0, nil, nil).
+		AddButton(localizer.Gettext("OK"), nil).
+		AddButton(localizer.Gettext("Preview"), nil)
 	form.SetBorder(true).SetTitle(
-		i18n.Gettext("Add feed"))
+		localizer.Gettext("Add feed"))
+	authFields := addAuthFields(localizer, form)
+	scrapeFields := addScrapeFields(localizer, form)
+
+	// Remove padding so all the fields fit on smaller screens
+	form.SetItemPadding(0)
 
 	// Set initial colors based on localized config
 	form.SetLabelColor(tcell.GetColor(config.FormLabelColor))
@@ -46,28 +59,46 @@ func NewAddFeedController(
 		panic("Could not retrieve input field from form")
 	}
 	urlField.SetPlaceholder(
-		i18n.Gettext("Press Ctrl-V to paste feed URL"))
+		localizer.Gettext("Press Ctrl-V to paste feed URL"))
 	urlField.SetPlaceholderTextColor(tcell.ColorBlack)
 
+	// Set up a preview of the feed's items and a footer for validation errors
+	preview := newFeedPreview(appController, localizer, taskManager)
+	statusFooter := tview.NewTextView()
+
+	flex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(form, 0, 1, true).
+		AddItem(preview.textView, preview.height(), 0, false).
+		AddItem(statusFooter, 1, 0, false)
+
 	c := &AddFeedController{
 		appController,
 		config,
+		localizer,
 		feedStore,
 		taskManager,
+		flex,
 		form,
 		urlField,
+		authFields,
+		scrapeFields,
+		preview,
+		statusFooter,
 	}
 
-	// Install event handlers for text changed and OK pressed
+	// Install event handlers for text changed and buttons pressed
 	urlField.SetChangedFunc(c.handleUrlFieldChange)
 	okButton := form.GetButton(0)
 	okButton.SetSelectedFunc(c.handleOkButton)
+	previewButton := form.GetButton(1)
+	previewButton.SetSelectedFunc(c.handlePreviewButton)
 
 	return c
 }
 
 func (c *AddFeedController) GetPage() tview.Primitive {
-	return c.form
+	return c.flex
 }
 
 func (c *AddFeedController) HandleInput(event *tcell.EventKey) *tcell.EventKey {
@@ -112,28 +143,86 @@ func (c *AddFeedController) handleUrlFieldChange(text string) {
 }
 
 func (c *AddFeedController) handleOkButton() {
-	urlText := c.urlField.GetText()
-	if !validateUrl(urlText) {
-		c.appController.App.SetFocus(c.urlField)
+	req, ok := c.validateForm()
+	if !ok {
 		return
 	}
 
 	// Create a placeholder database record for the feed
-	feedId, err := c.feedStore.GetOrCreateFeedWithUrl(urlText)
+	feedId, err := c.feedStore.GetOrCreateFeedWithUrl(req.Url)
 	if err != nil {
 		panic(err)
 	}
 
+	// Store credentials and headers for private feeds
+	if err := c.feedStore.SetFeedCredentials(feedId, req.Credentials); err != nil {
+		panic(err)
+	}
+
+	if err := c.feedStore.SetFeedHeaders(feedId, req.Headers); err != nil {
+		panic(err)
+	}
+
+	// Store selectors for pages that are scraped instead of parsed as feeds
+	if err := c.feedStore.SetFeedScrapeConfig(feedId, req.Scrape); err != nil {
+		panic(err)
+	}
+
 	// Schedule background task to load the feed data
 	c.taskManager.ScheduleLoadFeedTask(feedId)
 
 	// Reset the UI
 	c.urlField.SetText("")
+	c.authFields.setValues(feed.Credentials{}, nil)
+	c.scrapeFields.setValues(feed.ScrapeConfig{})
+	c.preview.clear()
+	c.statusFooter.SetText("")
 
 	// Switch back to the feed list page
 	c.appController.SwitchToPage(pageFeedList)
 }
 
+func (c *AddFeedController) handlePreviewButton() {
+	if req, ok := c.validateForm(); ok {
+		c.preview.load(req)
+	}
+}
+
+// validateForm returns a request to load the feed entered in the form.
+// If the form is invalid, this displays an error, focuses the invalid field,
+// and returns false.
+func (c *AddFeedController) validateForm() (feed.LoadRequest, bool) {
+	urlText := c.urlField.GetText()
+	if !validateUrl(urlText) {
+		c.appController.App.SetFocus(c.urlField)
+		return feed.LoadRequest{}, false
+	}
+
+	headers, ok := c.authFields.headers()
+	if !ok {
+		c.statusFooter.SetText(
+			c.localizer.Gettext("Headers must have the format 'Name: value; Name: value'."))
+		c.appController.App.SetFocus(c.authFields.headersField)
+		return feed.LoadRequest{}, false
+	}
+
+	scrapeConfig := c.scrapeFields.config()
+	if errMsg := validateScrapeConfig(c.localizer, urlText, scrapeConfig); len(errMsg) > 0 {
+		c.statusFooter.SetText(errMsg)
+		c.appController.App.SetFocus(c.scrapeFields.itemSelectorField)
+		return feed.LoadRequest{}, false
+	}
+
+	c.statusFooter.SetText("")
+	req := feed.LoadRequest{
+		Url:         urlText,
+		Credentials: c.authFields.credentials(),
+		Headers:     headers,
+		Scrape:      scrapeConfig,
+	}
+	return req, true
+}
+
 func (c *AddFeedController) showError() {
 	c.appController.App.QueueUpdateDraw(func() {
 		bg := tcell.GetColor(c.config.FormErrorBackgroundColor)
@@ -152,7 +241,9 @@ func (c *AddFeedController) hideError() {
 	})
 }
 
+// validateUrl checks whether a feed can be loaded from the URL.
+// Besides HTTP(S) URLs, this accepts local files ("file:///path/feed.xml")
+// and commands that output a feed ("exec:command args").
 func validateUrl(s string) bool {
-	url, err := url.ParseRequestURI(s)
-	return err == nil && len(url.Host) > 0
+	return feed.ValidateUrl(s)
 }
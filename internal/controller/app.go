@@ -3,16 +3,30 @@ package controller
 import (
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/download"
 	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/readlater"
 	"github.com/wedaly/local-news/internal/store"
 	"github.com/wedaly/local-news/internal/task"
+	"io"
+	"os"
 )
 
 const (
-	pageFeedList      = "feedList"
-	pageAddFeed       = "addFeed"
-	pageFeedDetail    = "feedDetail"
-	pageDeleteConfirm = "deleteConfirm"
+	pageFeedList        = "feedList"
+	pageAddFeed         = "addFeed"
+	pageFeedDetail      = "feedDetail"
+	pageDeleteConfirm   = "deleteConfirm"
+	pageEditFeed        = "editFeed"
+	pageSyncHistory     = "syncHistory"
+	pageDownloads       = "downloads"
+	pageItemView        = "itemView"
+	pageCategoryFilter  = "categoryFilter"
+	pageFilterRules     = "filterRules"
+	pageFilterRuleForm  = "filterRuleForm"
+	pageFilterMatches   = "filterMatches"
+	pageSavedSearchForm = "savedSearchForm"
+	pageExport          = "export"
 )
 
 // AppController controls the UI for the application,
@@ -26,8 +40,13 @@ type AppController struct {
 
 func NewAppController(
 	config i18n.Config,
+	localizer *i18n.Localizer,
 	feedStore *store.FeedStore,
-	taskManager *task.TaskManager) *AppController {
+	taskManager *task.TaskManager,
+	scheduler *task.Scheduler,
+	downloadManager *download.Manager,
+	playerCommand string,
+	outbox *readlater.Outbox) *AppController {
 
 	app := tview.NewApplication()
 	pages := tview.NewPages()
@@ -39,22 +58,119 @@ func NewAppController(
 	deleteConfirmController := NewDeleteConfirmController(
 		ac,
 		config,
+		localizer,
 		feedStore)
 	pageControllers[pageDeleteConfirm] = deleteConfirmController
 
+	// Set up the "edit feed" page controller
+	editFeedController := NewEditFeedController(
+		ac,
+		config,
+		localizer,
+		feedStore,
+		taskManager)
+	pageControllers[pageEditFeed] = editFeedController
+
+	// Set up the "sync history" page controller
+	syncHistoryController := NewSyncHistoryController(
+		ac,
+		localizer,
+		feedStore)
+	pageControllers[pageSyncHistory] = syncHistoryController
+
+	// Set up the "item view" page controller
+	itemViewController := NewItemViewController(
+		ac,
+		localizer,
+		feedStore,
+		taskManager)
+	pageControllers[pageItemView] = itemViewController
+
+	// Set up the "category filter" page controller
+	categoryController := NewCategoryFilterController(
+		ac,
+		localizer,
+		feedStore)
+	pageControllers[pageCategoryFilter] = categoryController
+
+	// Set up the "downloads" page controller
+	player := &mediaPlayer{app, playerCommand}
+	downloadsController := NewDownloadsController(
+		ac,
+		localizer,
+		downloadManager,
+		player)
+	pageControllers[pageDownloads] = downloadsController
+
+	// Set up the "filter rules" page controllers
+	filterRuleFormController := NewFilterRuleFormController(
+		ac,
+		config,
+		localizer,
+		feedStore)
+	pageControllers[pageFilterRuleForm] = filterRuleFormController
+
+	filterMatchesController := NewFilterMatchesController(
+		ac,
+		localizer,
+		feedStore)
+	pageControllers[pageFilterMatches] = filterMatchesController
+
+	filterRulesController := NewFilterRulesController(
+		ac,
+		localizer,
+		filterRuleFormController,
+		filterMatchesController,
+		feedStore)
+	pageControllers[pageFilterRules] = filterRulesController
+
+	// Set up the "saved search" page controller
+	savedSearchFormController := NewSavedSearchFormController(
+		ac,
+		config,
+		localizer,
+		feedStore)
+	pageControllers[pageSavedSearchForm] = savedSearchFormController
+
+	// Set up the "export" page controller
+	exportController := NewExportController(
+		ac,
+		config,
+		localizer,
+		feedStore,
+		downloadManager)
+	pageControllers[pageExport] = exportController
+
 	// Set up the "feed details" page controller
 	feedDetailController := NewFeedDetailController(
 		ac,
+		config,
+		localizer,
 		deleteConfirmController,
+		editFeedController,
+		syncHistoryController,
+		itemViewController,
+		categoryController,
+		savedSearchFormController,
+		exportController,
 		feedStore,
-		taskManager)
+		taskManager,
+		scheduler,
+		downloadManager,
+		player,
+		outbox)
 	pageControllers[pageFeedDetail] = feedDetailController
 
 	// Set up the "feed list" page controller
 	feedListController := NewFeedListController(
 		ac,
+		localizer,
 		feedDetailController,
+		downloadsController,
+		filterRulesController,
+		savedSearchFormController,
 		deleteConfirmController,
+		editFeedController,
 		feedStore,
 		taskManager)
 	pageControllers[pageFeedList] = feedListController
@@ -63,6 +179,7 @@ func NewAppController(
 	addFeedController := NewAddFeedController(
 		ac,
 		config,
+		localizer,
 		feedStore,
 		taskManager)
 	pageControllers[pageAddFeed] = addFeedController
@@ -70,9 +187,6 @@ func NewAppController(
 	// Load initial data from database
 	feedListController.LoadFeedsFromStore()
 
-	// Trigger an async refresh of all feeds
-	feedListController.RefreshAllFeeds()
-
 	// Add all pages to the tview `Pages` instance
 	// This has to happen last so that the UI setup from the child controllers
 	// is visible to tview.
@@ -80,6 +194,16 @@ func NewAppController(
 	pages.AddPage(pageAddFeed, addFeedController.GetPage(), true, false)
 	pages.AddPage(pageFeedDetail, feedDetailController.GetPage(), true, false)
 	pages.AddPage(pageDeleteConfirm, deleteConfirmController.GetPage(), true, false)
+	pages.AddPage(pageEditFeed, editFeedController.GetPage(), true, false)
+	pages.AddPage(pageSyncHistory, syncHistoryController.GetPage(), true, false)
+	pages.AddPage(pageDownloads, downloadsController.GetPage(), true, false)
+	pages.AddPage(pageItemView, itemViewController.GetPage(), true, false)
+	pages.AddPage(pageCategoryFilter, categoryController.GetPage(), true, false)
+	pages.AddPage(pageFilterRules, filterRulesController.GetPage(), true, false)
+	pages.AddPage(pageFilterRuleForm, filterRuleFormController.GetPage(), true, false)
+	pages.AddPage(pageFilterMatches, filterMatchesController.GetPage(), true, false)
+	pages.AddPage(pageSavedSearchForm, savedSearchFormController.GetPage(), true, false)
+	pages.AddPage(pageExport, exportController.GetPage(), true, false)
 	app.SetRoot(pages, true)
 
 	return ac
@@ -107,3 +231,20 @@ func (c *AppController) SwitchToPage(page string) {
 		c.currentPage = page
 	})
 }
+
+// RefreshAllFeeds triggers an async refresh of every feed in the feed list.
+// This is called once at startup, after every task subscriber is subscribed,
+// so subscribers see the tasks scheduled for the refresh.
+func (c *AppController) RefreshAllFeeds() {
+	c.pageControllers[pageFeedList].(*FeedListController).RefreshAllFeeds()
+}
+
+// Beep rings the terminal bell.  This is thread-safe.
+// The pinned tcell version has no Screen.Beep, so the bell is written
+// from the event loop, where it can't be interleaved with the escape
+// sequences tcell writes while drawing the screen.
+func (c *AppController) Beep() {
+	c.App.QueueUpdate(func() {
+		io.WriteString(os.Stdout, "\a")
+	})
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/i18n"
+	"sort"
+	"strings"
+)
+
+// authFields are the form fields for a feed's credentials and custom headers.
+// These are shared by the forms for adding and editing feeds.
+type authFields struct {
+	schemeDropDown     *tview.DropDown
+	usernameField      *tview.InputField
+	secretField        *tview.InputField
+	secretCommandField *tview.InputField
+	headersField       *tview.InputField
+}
+
+// addAuthFields appends the credential and header fields to a form
+func addAuthFields(localizer *i18n.Localizer, form *tview.Form) *authFields {
+	// The order of the options must match the values of `feed.AuthScheme`
+	schemeDropDown := tview.NewDropDown().
+		SetLabel(localizer.Gettext("Authentication")).
+		SetOptions([]string{
+			// translators: this is an authentication option
+			localizer.Gettext("None"),
+			// translators: this is an authentication option
+			localizer.Gettext("Basic (username and password)"),
+			// translators: this is an authentication option
+			localizer.Gettext("Bearer token"),
+		}, nil).
+		SetCurrentOption(int(feed.AuthNone))
+
+	usernameField := tview.NewInputField().
+		SetLabel(localizer.Gettext("Username"))
+
+	secretField := tview.NewInputField().
+		SetLabel(localizer.Gettext("Password or token")).
+		SetMaskCharacter('*')
+
+	secretCommandField := tview.NewInputField().
+		SetLabel(localizer.Gettext("Password command")).
+		SetPlaceholder(localizer.Gettext("Optional, e.g. pass show feeds/wiki"))
+
+	headersField := tview.NewInputField().
+		SetLabel(localizer.Gettext("HTTP headers")).
+		SetPlaceholder(localizer.Gettext("Optional, e.g. X-Api-Key: abc; Accept: text/xml"))
+
+	form.AddFormItem(schemeDropDown).
+		AddFormItem(usernameField).
+		AddFormItem(secretField).
+		AddFormItem(secretCommandField).
+		AddFormItem(headersField)
+
+	return &authFields{
+		schemeDropDown,
+		usernameField,
+		secretField,
+		secretCommandField,
+		headersField,
+	}
+}
+
+// setValues displays the specified credentials and headers in the form
+func (f *authFields) setValues(creds feed.Credentials, headers map[string]string) {
+	f.schemeDropDown.SetCurrentOption(int(creds.Scheme))
+	f.usernameField.SetText(creds.Username)
+	f.secretField.SetText(creds.Secret)
+	f.secretCommandField.SetText(creds.SecretCommand)
+	f.headersField.SetText(formatHeaders(headers))
+}
+
+// credentials returns the credentials entered in the form
+func (f *authFields) credentials() feed.Credentials {
+	schemeIdx, _ := f.schemeDropDown.GetCurrentOption()
+	scheme := feed.AuthScheme(schemeIdx)
+	if scheme == feed.AuthNone {
+		return feed.Credentials{}
+	}
+
+	return feed.Credentials{
+		Scheme:        scheme,
+		Username:      strings.TrimSpace(f.usernameField.GetText()),
+		Secret:        f.secretField.GetText(),
+		SecretCommand: strings.TrimSpace(f.secretCommandField.GetText()),
+	}
+}
+
+// headers returns the headers entered in the form.
+// The second return value is false if the headers are invalid.
+func (f *authFields) headers() (map[string]string, bool) {
+	return parseHeaders(f.headersField.GetText())
+}
+
+// parseHeaders parses headers in the format "Name: value; Name: value"
+func parseHeaders(s string) (map[string]string, bool) {
+	headers := make(map[string]string, 0)
+	for _, part := range strings.Split(s, ";") {
+		if len(strings.TrimSpace(part)) == 0 {
+			continue
+		}
+
+		nameAndValue := strings.SplitN(part, ":", 2)
+		if len(nameAndValue) != 2 {
+			return nil, false
+		}
+
+		name := strings.TrimSpace(nameAndValue[0])
+		if len(name) == 0 || strings.ContainsAny(name, " \t") {
+			return nil, false
+		}
+
+		headers[name] = strings.TrimSpace(nameAndValue[1])
+	}
+	return headers, true
+}
+
+func formatHeaders(headers map[string]string) string {
+	parts := make([]string, 0, len(headers))
+	for name, value := range headers {
+		parts = append(parts, name+": "+value)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/store"
+)
+
+// CategorySubscriber is notified when the user chooses a category
+type CategorySubscriber interface {
+	// HandleCategorySelected is invoked with the chosen category,
+	// or the empty string to show items in every category.
+	HandleCategorySelected(feedId store.FeedId, category string)
+}
+
+// CategoryFilterController lets the user choose which category
+// of a feed's items to display.
+type CategoryFilterController struct {
+	appController *AppController
+	localizer     *i18n.Localizer
+	feedStore     *store.FeedStore
+	grid          *tview.Grid
+	list          *tview.List
+	feedId        store.FeedId
+	categories    []string
+	subscribers   []CategorySubscriber
+}
+
+func NewCategoryFilterController(
+	appController *AppController,
+	localizer *i18n.Localizer,
+	feedStore *store.FeedStore) *CategoryFilterController {
+
+	// Set up the list of categories
+	list := tview.NewList().
+		ShowSecondaryText(false)
+	list.Box.SetBorder(true)
+
+	// Set up a footer to display help text
+	// translators: the characters in parentheses are keyboard commands
+	helpText := localizer.Gettext("(Enter) Show items   (ESC) Back")
+	helpFooter := tview.NewTextView().
+		SetText(helpText)
+
+	grid := tview.NewGrid().
+		SetRows(0, 2).
+		AddItem(list, 0, 0, 1, 1, 0, 0, true).
+		AddItem(helpFooter, 1, 0, 1, 1, 0, 0, false)
+
+	c := &CategoryFilterController{
+		appController,
+		localizer,
+		feedStore,
+		grid,
+		list,
+		store.FeedId(0),
+		nil,
+		make([]CategorySubscriber, 0),
+	}
+
+	list.SetSelectedFunc(c.handleSelected)
+
+	return c
+}
+
+func (c *CategoryFilterController) GetPage() tview.Primitive {
+	return c.grid
+}
+
+func (c *CategoryFilterController) HandleInput(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape {
+		c.appController.SwitchToPage(pageFeedDetail)
+		return nil
+	}
+
+	return event
+}
+
+// Subscribe registers a subscriber to receive category notifications
+// This is NOT thread-safe, so it should be called from the main UI thread only.
+func (c *CategoryFilterController) Subscribe(s CategorySubscriber) {
+	c.subscribers = append(c.subscribers, s)
+}
+
+// SetFeed loads the categories of the specified feed,
+// selecting the category currently displayed.
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (c *CategoryFilterController) SetFeed(feedId store.FeedId, currentCategory string) {
+	feed, err := c.feedStore.RetrieveFeed(feedId)
+	if err != nil {
+		panic(err)
+	}
+
+	categories, err := c.feedStore.RetrieveFeedCategories(feedId)
+	if err != nil {
+		panic(err)
+	}
+
+	c.feedId = feedId
+	c.categories = categories
+
+	// translators: the argument is the feed title
+	boxTitle := fmt.Sprintf(c.localizer.Gettext("Categories in %v"), feed.DisplayName())
+	c.list.Box.SetTitle(boxTitle)
+
+	// The first entry shows items in every category
+	c.list.Clear()
+	c.list.AddItem(c.localizer.Gettext("All items"), "", 0, nil)
+	for i, category := range categories {
+		c.list.AddItem(category, "", 0, nil)
+		if category == currentCategory {
+			c.list.SetCurrentItem(i + 1)
+		}
+	}
+}
+
+func (c *CategoryFilterController) handleSelected(idx int, mainText string, secondaryText string, shortcut rune) {
+	category := ""
+	if idx > 0 && idx <= len(c.categories) {
+		category = c.categories[idx-1]
+	}
+
+	for _, s := range c.subscribers {
+		s.HandleCategorySelected(c.feedId, category)
+	}
+
+	c.appController.SwitchToPage(pageFeedDetail)
+}
@@ -16,6 +16,7 @@ type DeleteSubscriber interface {
 // DeleteConfirmController is a modal dialog for confirming deletion of a feed
 type DeleteConfirmController struct {
 	appController *AppController
+	localizer     *i18n.Localizer
 	feedStore     *store.FeedStore
 	modal         *tview.Modal
 	feedId        store.FeedId
@@ -25,14 +26,15 @@ type DeleteConfirmController struct {
 func NewDeleteConfirmController(
 	appController *AppController,
 	config i18n.Config,
+	localizer *i18n.Localizer,
 	feedStore *store.FeedStore) *DeleteConfirmController {
 
 	modal := tview.NewModal().
 		AddButtons([]string{
 			// translators: this is text for a button
-			i18n.Gettext("Yes"),
+			localizer.Gettext("Yes"),
 			// translators: this is text for a button
-			i18n.Gettext("No")})
+			localizer.Gettext("No")})
 
 	// Set localized button colors
 	textColor := tcell.GetColor(config.ModalTextColor)
@@ -49,6 +51,7 @@ func NewDeleteConfirmController(
 
 	c := &DeleteConfirmController{
 		appController,
+		localizer,
 		feedStore,
 		modal,
 		store.FeedId(0),
@@ -100,8 +103,8 @@ func (c *DeleteConfirmController) updateModalText() {
 
 	confirmText := fmt.Sprintf(
 		// translators: the argument is the feed title
-		i18n.Gettext("Delete feed '%v'?"),
-		feed.Name)
+		c.localizer.Gettext("Delete feed '%v'?"),
+		feed.DisplayName())
 	c.modal.SetText(confirmText)
 }
 
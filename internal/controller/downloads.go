@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/download"
+	"github.com/wedaly/local-news/internal/i18n"
+	"path/filepath"
+)
+
+// DownloadsController displays the progress of enclosure downloads
+type DownloadsController struct {
+	appController   *AppController
+	localizer       *i18n.Localizer
+	downloadManager *download.Manager
+	player          *mediaPlayer
+	grid            *tview.Grid
+	list            *tview.List
+	statusHeader    *tview.TextView
+	listIdxToId     []int
+	listIdxToPath   []string
+}
+
+func NewDownloadsController(
+	appController *AppController,
+	localizer *i18n.Localizer,
+	downloadManager *download.Manager,
+	player *mediaPlayer) *DownloadsController {
+
+	// Set up the list of downloads
+	list := tview.NewList().
+		ShowSecondaryText(false)
+	list.Box.SetBorder(true).
+		SetTitle(localizer.Gettext("Downloads"))
+
+	// Set up a header to display errors
+	statusHeader := tview.NewTextView()
+
+	// Set up a footer to display help text
+	// translators: the characters in parentheses are keyboard commands
+	helpText := localizer.Gettext("(p) Play   (x) Cancel   (ESC) Back")
+	helpFooter := tview.NewTextView().
+		SetText(helpText)
+
+	grid := tview.NewGrid().
+		SetRows(1, 0, 2).
+		AddItem(statusHeader, 0, 0, 1, 1, 0, 0, false).
+		AddItem(list, 1, 0, 1, 1, 0, 0, true).
+		AddItem(helpFooter, 2, 0, 1, 1, 0, 0, false)
+
+	c := &DownloadsController{
+		appController,
+		localizer,
+		downloadManager,
+		player,
+		grid,
+		list,
+		statusHeader,
+		nil,
+		nil,
+	}
+
+	// Subscribe for download progress
+	downloadManager.Subscribe(c)
+
+	return c
+}
+
+func (c *DownloadsController) GetPage() tview.Primitive {
+	return c.grid
+}
+
+func (c *DownloadsController) HandleInput(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape {
+		c.appController.SwitchToPage(pageFeedList)
+		return nil
+	}
+
+	if event.Rune() == 'x' {
+		if idx := c.list.GetCurrentItem(); idx < len(c.listIdxToId) {
+			c.downloadManager.Cancel(c.listIdxToId[idx])
+		}
+		return nil
+	}
+
+	if event.Rune() == 'p' {
+		c.playSelected()
+		return nil
+	}
+
+	return event
+}
+
+func (c *DownloadsController) HandleDownloadUpdated(download.Download) {
+	c.appController.App.QueueUpdateDraw(func() {
+		c.LoadDownloads()
+	})
+}
+
+// LoadDownloads displays the current status of every download
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (c *DownloadsController) LoadDownloads() {
+	downloads := c.downloadManager.Downloads()
+
+	// Show the most recent downloads first, keeping the selection
+	currentIdx := c.list.GetCurrentItem()
+	c.list.Clear()
+	c.listIdxToId = make([]int, 0, len(downloads))
+	c.listIdxToPath = make([]string, 0, len(downloads))
+	for i := len(downloads) - 1; i >= 0; i-- {
+		d := downloads[i]
+		itemText := fmt.Sprintf(
+			// translators: [1] is the download's status and [2] is the file name
+			c.localizer.Gettext("%[1]v  %[2]v"),
+			downloadStatusText(c.localizer, d),
+			filepath.Base(d.Path))
+		c.list.AddItem(itemText, "", 0, nil)
+		c.listIdxToId = append(c.listIdxToId, d.Id)
+		c.listIdxToPath = append(c.listIdxToPath, d.Path)
+	}
+
+	if currentIdx < c.list.GetItemCount() {
+		c.list.SetCurrentItem(currentIdx)
+	}
+}
+
+func (c *DownloadsController) playSelected() {
+	idx := c.list.GetCurrentItem()
+	if idx >= len(c.listIdxToPath) {
+		return
+	}
+
+	for _, d := range c.downloadManager.Downloads() {
+		if d.Id == c.listIdxToId[idx] && d.Status != download.StatusCompleted {
+			c.statusHeader.SetText(c.localizer.Gettext("The download has not completed yet."))
+			return
+		}
+	}
+
+	if err := c.player.play(c.listIdxToPath[idx]); err != nil {
+		c.statusHeader.SetText(c.localizer.Gettext(
+			"Could not play the file.  Please check the player command in your settings."))
+	} else {
+		c.statusHeader.SetText("")
+	}
+}
+
+func downloadStatusText(localizer *i18n.Localizer, d download.Download) string {
+	switch d.Status {
+	case download.StatusQueued:
+		return localizer.Gettext("Queued")
+
+	case download.StatusDownloading:
+		if d.TotalBytes > 0 {
+			percent := int(d.BytesDownloaded * 100 / d.TotalBytes)
+			// translators: the argument is the percent downloaded
+			return fmt.Sprintf(localizer.Gettext("Downloading %v%%"), localizer.FormatNumber(percent))
+		}
+		kilobytes := int(d.BytesDownloaded / 1024)
+		// translators: the argument is the number of kilobytes downloaded
+		return fmt.Sprintf(localizer.Gettext("Downloading %v KB"), localizer.FormatNumber(kilobytes))
+
+	case download.StatusCompleted:
+		return localizer.Gettext("Completed")
+
+	case download.StatusFailed:
+		if d.Err == download.ErrTooLarge {
+			return localizer.Gettext("Failed (file too large)")
+		}
+		// translators: the argument is an error message
+		return fmt.Sprintf(localizer.Gettext("Failed (%v)"), d.Err)
+
+	case download.StatusCanceled:
+		return localizer.Gettext("Canceled")
+
+	default:
+		return ""
+	}
+}
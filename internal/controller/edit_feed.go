@@ -0,0 +1,363 @@
+package controller
+
+import (
+	"github.com/atotto/clipboard"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/store"
+	"github.com/wedaly/local-news/internal/task"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EditSubscriber is notified when a feed's settings are changed
+type EditSubscriber interface {
+	HandleFeedEdited(store.FeedId)
+}
+
+// EditFeedController handles the form for changing a feed's settings
+type EditFeedController struct {
+	appController        *AppController
+	config               i18n.Config
+	localizer            *i18n.Localizer
+	feedStore            *store.FeedStore
+	taskManager          *task.TaskManager
+	flex                 *tview.Flex
+	form                 *tview.Form
+	nameField            *tview.InputField
+	urlField             *tview.InputField
+	folderField          *tview.InputField
+	refreshIntervalField *tview.InputField
+	mutedCheckbox        *tview.Checkbox
+	archiveCheckbox      *tview.Checkbox
+	authFields           *authFields
+	loaderFields         *loaderFields
+	scrapeFields         *scrapeFields
+	preview              *feedPreview
+	statusFooter         *tview.TextView
+	feedId               store.FeedId
+	subscribers          []EditSubscriber
+}
+
+func NewEditFeedController(
+	appController *AppController,
+	config i18n.Config,
+	localizer *i18n.Localizer,
+	feedStore *store.FeedStore,
+	taskManager *task.TaskManager) *EditFeedController {
+
+	// Set up the form
+	form := tview.NewForm().
+		AddInputField(localizer.Gettext("Title"), "", 0, nil, nil).
+		AddInputField(localizer.Gettext("URL"), "", 0, nil, nil).
+		AddInputField(localizer.Gettext("Folder"), "", 0, nil, nil).
+		// translators: the refresh interval is a number of minutes
+		AddInputField(localizer.Gettext("Refresh every (minutes)"), "", 0, tview.InputFieldInteger, nil).
+		AddCheckbox(localizer.Gettext("Mute notifications"), false, nil).
+		// translators: the articles linked from the feed's items are saved for reading offline
+		AddCheckbox(localizer.Gettext("Archive linked articles"), false, nil).
+		AddButton(localizer.Gettext("OK"), nil).
+		AddButton(localizer.Gettext("Preview"), nil)
+	form.SetBorder(true).SetTitle(
+		localizer.Gettext("Edit feed"))
+
+	// Set initial colors based on localized config
+	form.SetLabelColor(tcell.GetColor(config.FormLabelColor))
+	form.SetButtonBackgroundColor(tcell.GetColor(config.FormButtonBackgroundColor))
+	form.SetButtonTextColor(tcell.GetColor(config.FormButtonTextColor))
+	form.SetFieldBackgroundColor(tcell.GetColor(config.FormFieldBackgroundColor))
+	form.SetFieldTextColor(tcell.GetColor(config.FormFieldTextColor))
+
+	// Retrieve the input fields so we can read their values later
+	fields := make([]*tview.InputField, 4)
+	for i := range fields {
+		field, ok := form.GetFormItem(i).(*tview.InputField)
+		if !ok {
+			panic("Could not retrieve input field from form")
+		}
+		field.SetPlaceholderTextColor(tcell.ColorBlack)
+		fields[i] = field
+	}
+	fields[3].SetPlaceholder(localizer.Gettext("Default"))
+	mutedCheckbox, ok := form.GetFormItem(4).(*tview.Checkbox)
+	if !ok {
+		panic("Could not retrieve checkbox from form")
+	}
+	archiveCheckbox, ok := form.GetFormItem(5).(*tview.Checkbox)
+	if !ok {
+		panic("Could not retrieve checkbox from form")
+	}
+	authFields := addAuthFields(localizer, form)
+	loaderFields := addLoaderFields(localizer, form)
+	scrapeFields := addScrapeFields(localizer, form)
+
+	// Remove padding so all the fields fit on smaller screens
+	form.SetItemPadding(0)
+
+	// Set up a preview of the feed's items and a footer for validation errors
+	preview := newFeedPreview(appController, localizer, taskManager)
+	statusFooter := tview.NewTextView()
+
+	flex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(form, 0, 1, true).
+		AddItem(preview.textView, preview.height(), 0, false).
+		AddItem(statusFooter, 1, 0, false)
+
+	c := &EditFeedController{
+		appController,
+		config,
+		localizer,
+		feedStore,
+		taskManager,
+		flex,
+		form,
+		fields[0],
+		fields[1],
+		fields[2],
+		fields[3],
+		mutedCheckbox,
+		archiveCheckbox,
+		authFields,
+		loaderFields,
+		scrapeFields,
+		preview,
+		statusFooter,
+		store.FeedId(0),
+		make([]EditSubscriber, 0),
+	}
+
+	// Install event handlers for text changed and buttons pressed
+	c.urlField.SetChangedFunc(c.handleUrlFieldChange)
+	okButton := form.GetButton(0)
+	okButton.SetSelectedFunc(c.handleOkButton)
+	previewButton := form.GetButton(1)
+	previewButton.SetSelectedFunc(c.handlePreviewButton)
+
+	return c
+}
+
+func (c *EditFeedController) GetPage() tview.Primitive {
+	return c.flex
+}
+
+func (c *EditFeedController) HandleInput(event *tcell.EventKey) *tcell.EventKey {
+	// Workaround for https://github.com/gdamore/tcell/issues/200
+	// See AddFeedController for details.
+	if event.Key() == tcell.KeyCtrlV {
+		c.pasteClipboard()
+		return nil
+	}
+
+	if event.Key() == tcell.KeyEscape {
+		c.appController.SwitchToPage(pageFeedDetail)
+		return nil
+	}
+
+	return event
+}
+
+// Subscribe registers a subscriber to receive edit notifications
+// This is NOT thread-safe, so it should be called from the main UI thread only.
+func (c *EditFeedController) Subscribe(s EditSubscriber) {
+	c.subscribers = append(c.subscribers, s)
+}
+
+// SetFeed populates the form with the current settings of the specified feed
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (c *EditFeedController) SetFeed(feedId store.FeedId) {
+	feedRecord, err := c.feedStore.RetrieveFeed(feedId)
+	if err != nil {
+		panic(err)
+	}
+
+	c.feedId = feedId
+	c.nameField.SetText(feedRecord.CustomName)
+	c.nameField.SetPlaceholder(feedRecord.Name)
+	c.urlField.SetText(feedRecord.Url)
+	c.folderField.SetText(feedRecord.Folder)
+
+	refreshIntervalText := ""
+	if feedRecord.RefreshInterval > 0 {
+		refreshIntervalText = strconv.Itoa(int(feedRecord.RefreshInterval / time.Minute))
+	}
+	c.refreshIntervalField.SetText(refreshIntervalText)
+	c.mutedCheckbox.SetChecked(feedRecord.Muted)
+	c.archiveCheckbox.SetChecked(feedRecord.ArchiveArticles)
+
+	creds, err := c.feedStore.RetrieveFeedCredentials(feedId)
+	if err != nil {
+		panic(err)
+	}
+
+	headers, err := c.feedStore.RetrieveFeedHeaders(feedId)
+	if err != nil {
+		panic(err)
+	}
+
+	c.authFields.setValues(creds, headers)
+
+	loaderConfig, err := c.feedStore.RetrieveFeedLoaderConfig(feedId)
+	if err != nil {
+		panic(err)
+	}
+
+	c.loaderFields.setValues(loaderConfig)
+
+	scrapeConfig, err := c.feedStore.RetrieveFeedScrapeConfig(feedId)
+	if err != nil {
+		panic(err)
+	}
+
+	c.scrapeFields.setValues(scrapeConfig)
+
+	c.preview.clear()
+	c.statusFooter.SetText("")
+}
+
+func (c *EditFeedController) pasteClipboard() {
+	clipboardText, err := clipboard.ReadAll()
+	if err != nil {
+		return
+	}
+
+	// Only the URL field is likely to be pasted, so replace it
+	// regardless of which field has focus.
+	c.urlField.SetText(clipboardText)
+}
+
+func (c *EditFeedController) handleUrlFieldChange(text string) {
+	if len(text) == 0 || validateUrl(text) {
+		c.hideError()
+	} else {
+		c.showError()
+	}
+}
+
+func (c *EditFeedController) handleOkButton() {
+	req, ok := c.validateForm()
+	if !ok {
+		return
+	}
+
+	// An empty or zero interval means "use the default"
+	var refreshInterval time.Duration
+	if minutes, err := strconv.Atoi(c.refreshIntervalField.GetText()); err == nil && minutes > 0 {
+		refreshInterval = time.Duration(minutes) * time.Minute
+	}
+
+	settings := store.FeedSettings{
+		Url:             req.Url,
+		CustomName:      strings.TrimSpace(c.nameField.GetText()),
+		Folder:          strings.TrimSpace(c.folderField.GetText()),
+		RefreshInterval: refreshInterval,
+		Muted:           c.mutedCheckbox.IsChecked(),
+		ArchiveArticles: c.archiveCheckbox.IsChecked(),
+	}
+
+	err := c.feedStore.UpdateFeedSettings(c.feedId, settings)
+	if err == store.ErrDuplicateFeedUrl {
+		c.statusFooter.SetText(
+			c.localizer.Gettext("Another feed already has this URL."))
+		c.appController.App.SetFocus(c.urlField)
+		return
+	} else if err != nil {
+		panic(err)
+	}
+
+	if err := c.feedStore.SetFeedCredentials(c.feedId, req.Credentials); err != nil {
+		panic(err)
+	}
+
+	if err := c.feedStore.SetFeedHeaders(c.feedId, req.Headers); err != nil {
+		panic(err)
+	}
+
+	if err := c.feedStore.SetFeedLoaderConfig(c.feedId, req.Config); err != nil {
+		panic(err)
+	}
+
+	if err := c.feedStore.SetFeedScrapeConfig(c.feedId, req.Scrape); err != nil {
+		panic(err)
+	}
+
+	// Reload the feed, since the new URL or credentials may
+	// change the items.  Existing items are kept.
+	c.taskManager.ScheduleLoadFeedTask(c.feedId)
+
+	// Archive the feed's existing items, if archiving was enabled
+	if settings.ArchiveArticles {
+		if err := c.taskManager.ScheduleArchiveTasks(); err != nil {
+			panic(err)
+		}
+	}
+
+	for _, s := range c.subscribers {
+		s.HandleFeedEdited(c.feedId)
+	}
+
+	c.appController.SwitchToPage(pageFeedDetail)
+}
+
+func (c *EditFeedController) handlePreviewButton() {
+	if req, ok := c.validateForm(); ok {
+		c.preview.load(req)
+	}
+}
+
+// validateForm returns a request to load the feed with the settings
+// entered in the form.  If the form is invalid, this displays an error,
+// focuses the invalid field, and returns false.
+func (c *EditFeedController) validateForm() (feed.LoadRequest, bool) {
+	urlText := c.urlField.GetText()
+	if !validateUrl(urlText) {
+		c.appController.App.SetFocus(c.urlField)
+		return feed.LoadRequest{}, false
+	}
+
+	headers, ok := c.authFields.headers()
+	if !ok {
+		c.statusFooter.SetText(
+			c.localizer.Gettext("Headers must have the format 'Name: value; Name: value'."))
+		c.appController.App.SetFocus(c.authFields.headersField)
+		return feed.LoadRequest{}, false
+	}
+
+	scrapeConfig := c.scrapeFields.config()
+	if errMsg := validateScrapeConfig(c.localizer, urlText, scrapeConfig); len(errMsg) > 0 {
+		c.statusFooter.SetText(errMsg)
+		c.appController.App.SetFocus(c.scrapeFields.itemSelectorField)
+		return feed.LoadRequest{}, false
+	}
+
+	c.statusFooter.SetText("")
+	req := feed.LoadRequest{
+		Url:         urlText,
+		Credentials: c.authFields.credentials(),
+		Headers:     headers,
+		Config:      c.loaderFields.config(),
+		Scrape:      scrapeConfig,
+	}
+	return req, true
+}
+
+func (c *EditFeedController) showError() {
+	c.appController.App.QueueUpdateDraw(func() {
+		bg := tcell.GetColor(c.config.FormErrorBackgroundColor)
+		txt := tcell.GetColor(c.config.FormErrorTextColor)
+		c.form.SetFieldBackgroundColor(bg)
+		c.form.SetFieldTextColor(txt)
+	})
+}
+
+func (c *EditFeedController) hideError() {
+	c.appController.App.QueueUpdateDraw(func() {
+		bg := tcell.GetColor(c.config.FormFieldBackgroundColor)
+		txt := tcell.GetColor(c.config.FormFieldTextColor)
+		c.form.SetFieldBackgroundColor(bg)
+		c.form.SetFieldTextColor(txt)
+	})
+}
@@ -0,0 +1,262 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/download"
+	"github.com/wedaly/local-news/internal/export"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/query"
+	"github.com/wedaly/local-news/internal/store"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// exportScope identifies which items are written to the file
+type exportScope struct {
+	// Label shown in the drop-down
+	label string
+
+	// Name used for the default file name and the document title
+	name string
+
+	// Retrieves the items to export
+	items func() ([]store.FeedItemRecord, error)
+}
+
+// ExportController handles the form for exporting items to a file
+type ExportController struct {
+	appController   *AppController
+	localizer       *i18n.Localizer
+	feedStore       *store.FeedStore
+	downloadManager *download.Manager
+	flex            *tview.Flex
+	form            *tview.Form
+	scopeDropDown   *tview.DropDown
+	formatDropDown  *tview.DropDown
+	datesDropDown   *tview.DropDown
+	pathField       *tview.InputField
+	statusFooter    *tview.TextView
+	scopes          []exportScope
+}
+
+func NewExportController(
+	appController *AppController,
+	config i18n.Config,
+	localizer *i18n.Localizer,
+	feedStore *store.FeedStore,
+	downloadManager *download.Manager) *ExportController {
+
+	// The scopes are set when the form is shown
+	scopeDropDown := tview.NewDropDown().
+		SetLabel(localizer.Gettext("Items"))
+
+	// The order of the options must match the `export.Format` values
+	formatDropDown := tview.NewDropDown().
+		SetLabel(localizer.Gettext("Format")).
+		SetOptions([]string{
+			localizer.Gettext("JSON Lines"),
+			localizer.Gettext("CSV"),
+			localizer.Gettext("Markdown"),
+			localizer.Gettext("HTML"),
+		}, nil).
+		SetCurrentOption(int(export.FormatJsonLines))
+
+	// The first option writes RFC 3339 dates, the second localized dates
+	datesDropDown := tview.NewDropDown().
+		SetLabel(localizer.Gettext("Dates")).
+		SetOptions([]string{
+			// translators: this is a standard date format, e.g. 2019-10-12T07:20:50Z
+			localizer.Gettext("RFC 3339"),
+			// translators: dates are written in the format for the user's language
+			localizer.Gettext("Localized"),
+		}, nil).
+		SetCurrentOption(0)
+
+	pathField := tview.NewInputField().
+		SetLabel(localizer.Gettext("File"))
+
+	form := tview.NewForm().
+		AddFormItem(scopeDropDown).
+		AddFormItem(formatDropDown).
+		AddFormItem(datesDropDown).
+		AddFormItem(pathField)
+	form.SetBorder(true)
+	form.SetTitle(localizer.Gettext("Export items"))
+
+	// Set initial colors based on localized config
+	form.SetLabelColor(tcell.GetColor(config.FormLabelColor))
+	form.SetButtonBackgroundColor(tcell.GetColor(config.FormButtonBackgroundColor))
+	form.SetButtonTextColor(tcell.GetColor(config.FormButtonTextColor))
+	form.SetFieldBackgroundColor(tcell.GetColor(config.FormFieldBackgroundColor))
+	form.SetFieldTextColor(tcell.GetColor(config.FormFieldTextColor))
+
+	// Set up a footer for errors and the result of the export
+	statusFooter := tview.NewTextView()
+
+	flex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(form, 0, 1, true).
+		AddItem(statusFooter, 1, 0, false)
+
+	c := &ExportController{
+		appController,
+		localizer,
+		feedStore,
+		downloadManager,
+		flex,
+		form,
+		scopeDropDown,
+		formatDropDown,
+		datesDropDown,
+		pathField,
+		statusFooter,
+		nil,
+	}
+
+	form.AddButton(localizer.Gettext("OK"), c.handleOkButton)
+
+	// Keep the default file name in sync with the format
+	formatDropDown.SetSelectedFunc(func(string, int) { c.updateDefaultPath() })
+
+	return c
+}
+
+func (c *ExportController) GetPage() tview.Primitive {
+	return c.flex
+}
+
+func (c *ExportController) HandleInput(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape {
+		c.appController.SwitchToPage(pageFeedDetail)
+		return nil
+	}
+
+	return event
+}
+
+// SetItems prepares the form to export the displayed items, which are
+// titled with the specified name.  If the folder isn't empty, the user
+// can also choose to export every item in the folder.
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (c *ExportController) SetItems(name string, items []store.FeedItemRecord, folder string) {
+	c.scopes = []exportScope{
+		{
+			// translators: the argument is the name of a feed or saved search
+			label: fmt.Sprintf(c.localizer.Gettext("Displayed items in %v"), name),
+			name:  name,
+			items: func() ([]store.FeedItemRecord, error) { return items, nil },
+		},
+	}
+
+	if len(folder) > 0 {
+		c.scopes = append(c.scopes, exportScope{
+			// translators: the argument is the name of a folder
+			label: fmt.Sprintf(c.localizer.Gettext("All items in folder %v"), folder),
+			name:  folder,
+			items: c.queryItems(query.Term{Field: query.FieldFolder, Value: folder}),
+		})
+	}
+
+	c.scopes = append(c.scopes, exportScope{
+		label: c.localizer.Gettext("Highlighted items in all feeds"),
+		name:  c.localizer.Gettext("Highlighted"),
+		items: c.queryItems(query.Term{Field: query.FieldIs, Value: query.StateHighlighted}),
+	})
+
+	labels := make([]string, len(c.scopes))
+	for i, scope := range c.scopes {
+		labels[i] = scope.label
+	}
+	// Selecting a scope also sets the default file name
+	c.scopeDropDown.SetOptions(labels, func(string, int) { c.updateDefaultPath() })
+	c.scopeDropDown.SetCurrentOption(0)
+	c.statusFooter.SetText("")
+
+	c.appController.App.SetFocus(c.form)
+}
+
+// queryItems returns a function that retrieves the items matching the term
+func (c *ExportController) queryItems(term query.Term) func() ([]store.FeedItemRecord, error) {
+	return func() ([]store.FeedItemRecord, error) {
+		return c.feedStore.RetrieveQueryItems(query.Query{Terms: []query.Term{term}})
+	}
+}
+
+// updateDefaultPath suggests a file in the download directory
+// named after the selected scope and format
+func (c *ExportController) updateDefaultPath() {
+	scopeIdx, _ := c.scopeDropDown.GetCurrentOption()
+	formatIdx, _ := c.formatDropDown.GetCurrentOption()
+	if scopeIdx < 0 || scopeIdx >= len(c.scopes) || formatIdx < 0 {
+		return
+	}
+
+	name := sanitizeFileName(c.scopes[scopeIdx].name) + export.Format(formatIdx).Extension()
+	if path, err := c.downloadManager.PathForName(name); err == nil {
+		c.pathField.SetText(path)
+	}
+}
+
+func (c *ExportController) handleOkButton() {
+	scopeIdx, _ := c.scopeDropDown.GetCurrentOption()
+	formatIdx, _ := c.formatDropDown.GetCurrentOption()
+	datesIdx, _ := c.datesDropDown.GetCurrentOption()
+	path := strings.TrimSpace(c.pathField.GetText())
+
+	if len(path) == 0 {
+		c.statusFooter.SetText(c.localizer.Gettext("Please enter a file path."))
+		c.appController.App.SetFocus(c.pathField)
+		return
+	}
+
+	scope := c.scopes[scopeIdx]
+	records, err := scope.items()
+	if err != nil {
+		panic(err)
+	}
+
+	feeds, err := c.feedStore.RetrieveFeeds()
+	if err != nil {
+		panic(err)
+	}
+
+	opts := export.Options{Title: scope.name}
+	if datesIdx == 1 {
+		opts.Localizer = c.localizer
+	}
+	err = writeExportFile(path, export.Format(formatIdx), export.NewItems(records, feeds), opts)
+	if err != nil {
+		// translators: the argument is an error message
+		c.statusFooter.SetText(fmt.Sprintf(c.localizer.Gettext("Could not export items: %v"), err))
+		return
+	}
+
+	msg := fmt.Sprintf(
+		// translators: [1] is a number of items and [2] is a file path
+		c.localizer.NGettext("Exported %[1]v item to %[2]v", "Exported %[1]v items to %[2]v", len(records)),
+		c.localizer.FormatNumber(len(records)),
+		path)
+	c.statusFooter.SetText(msg)
+}
+
+// writeExportFile creates the file, and its directory if necessary,
+// then writes the items in the specified format.
+func writeExportFile(path string, format export.Format, items []export.Item, opts export.Options) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := export.Write(f, format, items, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
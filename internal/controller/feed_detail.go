@@ -4,32 +4,70 @@ import (
 	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/download"
+	"github.com/wedaly/local-news/internal/feed"
 	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/readlater"
 	"github.com/wedaly/local-news/internal/store"
 	"github.com/wedaly/local-news/internal/task"
-	"os/exec"
+	"strings"
 )
 
+// ItemReadSubscriber is notified when the user reads an item
+type ItemReadSubscriber interface {
+	HandleItemRead()
+}
+
 // FeedDetailController handles the UI for details about a particular feed,
-// mainly the list of items in the feed.
+// mainly the list of items in the feed.  It also displays the items
+// matching a saved search, as if the search were a feed.
 type FeedDetailController struct {
 	appController           *AppController
+	config                  i18n.Config
+	localizer               *i18n.Localizer
 	deleteConfirmController *DeleteConfirmController
+	editFeedController      *EditFeedController
+	syncHistoryController   *SyncHistoryController
+	itemViewController      *ItemViewController
+	categoryController      *CategoryFilterController
+	savedSearchController   *SavedSearchFormController
+	exportController        *ExportController
 	feedStore               *store.FeedStore
 	taskManager             *task.TaskManager
+	scheduler               *task.Scheduler
+	downloadManager         *download.Manager
+	player                  *mediaPlayer
+	outbox                  *readlater.Outbox
 	grid                    *tview.Grid
 	list                    *tview.List
 	statusHeader            *tview.TextView
+	infoHeader              *tview.TextView
 	helpFooter              *tview.TextView
 	feedId                  store.FeedId
-	listIdxToItemUrl        []string
+	searchId                store.SavedSearchId
+	feedName                string
+	category                string
+	listIdxToItem           []store.FeedItemRecord
+	subscribers             []ItemReadSubscriber
 }
 
 func NewFeedDetailController(
 	appController *AppController,
+	config i18n.Config,
+	localizer *i18n.Localizer,
 	deleteConfirmController *DeleteConfirmController,
+	editFeedController *EditFeedController,
+	syncHistoryController *SyncHistoryController,
+	itemViewController *ItemViewController,
+	categoryController *CategoryFilterController,
+	savedSearchController *SavedSearchFormController,
+	exportController *ExportController,
 	feedStore *store.FeedStore,
-	taskManager *task.TaskManager) *FeedDetailController {
+	taskManager *task.TaskManager,
+	scheduler *task.Scheduler,
+	downloadManager *download.Manager,
+	player *mediaPlayer,
+	outbox *readlater.Outbox) *FeedDetailController {
 
 	// Set up the list of feed items
 	list := tview.NewList().
@@ -39,38 +77,74 @@ func NewFeedDetailController(
 	// Set up a header to display the feed's status (last synced, error, etc)
 	statusHeader := tview.NewTextView()
 
-	// Set up a footer to display help text
-	// translators: the characters in brackets are keyboard commands
-	helpText := i18n.Gettext("(o) Open in browser   (d) Delete Feed   (ESC) Back")
-	helpFooter := tview.NewTextView().
-		SetText(helpText)
+	// Set up a header to display where the feed points (site, description, language)
+	infoHeader := tview.NewTextView()
+
+	// Set up a footer to display help text, which depends on
+	// whether a feed or a saved search is displayed
+	helpFooter := tview.NewTextView()
 
-	// Set up a grid to hold the list, header, and footer
+	// Set up a grid to hold the list, headers, and footer
 	grid := tview.NewGrid().
-		SetRows(1, 0, 2).
+		SetRows(1, 1, 0, 2).
 		AddItem(statusHeader, 0, 0, 1, 1, 0, 0, false).
-		AddItem(list, 1, 0, 1, 1, 0, 0, true).
-		AddItem(helpFooter, 2, 0, 1, 1, 0, 0, false)
+		AddItem(infoHeader, 1, 0, 1, 1, 0, 0, false).
+		AddItem(list, 2, 0, 1, 1, 0, 0, true).
+		AddItem(helpFooter, 3, 0, 1, 1, 0, 0, false)
 
 	c := &FeedDetailController{
 		appController,
+		config,
+		localizer,
 		deleteConfirmController,
+		editFeedController,
+		syncHistoryController,
+		itemViewController,
+		categoryController,
+		savedSearchController,
+		exportController,
 		feedStore,
 		taskManager,
+		scheduler,
+		downloadManager,
+		player,
+		outbox,
 		grid,
 		list,
 		statusHeader,
+		infoHeader,
 		helpFooter,
 		store.FeedId(0),
+		store.SavedSearchId(0),
+		"",
+		"",
 		nil,
+		make([]ItemReadSubscriber, 0),
 	}
 
+	// Display the item's content when selected
+	list.SetSelectedFunc(c.handleItemSelected)
+
 	// Subscribe for task updates
 	taskManager.Subscribe(c)
 
 	// Subscribe for delete notifications
 	deleteConfirmController.Subscribe(c)
 
+	// Subscribe for edit notifications
+	editFeedController.Subscribe(c)
+
+	// Subscribe for category filter changes
+	categoryController.Subscribe(c)
+
+	// Subscribe for saved search changes
+	savedSearchController.Subscribe(c)
+
+	// Subscribe for articles sent to the read-later service, if any
+	if outbox != nil {
+		outbox.Subscribe(c)
+	}
+
 	return c
 }
 
@@ -84,40 +158,155 @@ func (c *FeedDetailController) HandleInput(event *tcell.EventKey) *tcell.EventKe
 		return nil
 	}
 
+	if c.searchId > 0 {
+		return c.handleSearchInput(event)
+	}
+
 	if event.Rune() == 'd' {
 		c.deleteConfirmController.SetFeed(c.feedId)
 		c.appController.SwitchToPage(pageDeleteConfirm)
 		return nil
 	}
 
+	if event.Rune() == 'e' {
+		c.editFeedController.SetFeed(c.feedId)
+		c.appController.SwitchToPage(pageEditFeed)
+		return nil
+	}
+
+	if event.Rune() == 'h' {
+		c.syncHistoryController.SetFeed(c.feedId)
+		c.appController.SwitchToPage(pageSyncHistory)
+		return nil
+	}
+
+	if event.Rune() == 't' {
+		c.categoryController.SetFeed(c.feedId, c.category)
+		c.appController.SwitchToPage(pageCategoryFilter)
+		return nil
+	}
+
+	return c.handleItemInput(event)
+}
+
+// handleSearchInput handles keys while a saved search is displayed.
+// Commands that apply to a single feed aren't available.
+func (c *FeedDetailController) handleSearchInput(event *tcell.EventKey) *tcell.EventKey {
+	if event.Rune() == 'e' {
+		search, err := c.feedStore.RetrieveSavedSearch(c.searchId)
+		if err != nil {
+			panic(err)
+		}
+		c.savedSearchController.SetSearch(search, pageFeedDetail)
+		c.appController.SwitchToPage(pageSavedSearchForm)
+		return nil
+	}
+
+	return c.handleItemInput(event)
+}
+
+// handleItemInput handles keys that operate on the selected item
+func (c *FeedDetailController) handleItemInput(event *tcell.EventKey) *tcell.EventKey {
 	if event.Rune() == 'o' {
 		c.openItemInBrowser()
 		return nil
 	}
 
+	if event.Rune() == 's' {
+		c.downloadEnclosure()
+		return nil
+	}
+
+	if event.Rune() == 'p' {
+		c.playEnclosure()
+		return nil
+	}
+
+	if event.Rune() == 'x' {
+		c.exportItems()
+		return nil
+	}
+
+	if event.Rune() == 'l' {
+		c.saveItemForLater()
+		return nil
+	}
+
 	return event
 }
 
+// Subscribe registers a subscriber to receive notifications when items are read
+// This is NOT thread-safe, so it should be called from the main UI thread only.
+func (c *FeedDetailController) Subscribe(s ItemReadSubscriber) {
+	c.subscribers = append(c.subscribers, s)
+}
+
 func (c *FeedDetailController) HandleFeedDeleted(feedId store.FeedId) {
 	if c.feedId > 0 && c.feedId == feedId {
 		c.feedId = 0
 	}
 }
 
+func (c *FeedDetailController) HandleFeedEdited(feedId store.FeedId) {
+	if c.feedId > 0 && c.feedId == feedId {
+		c.LoadFeedDetailsFromStore()
+	}
+}
+
+func (c *FeedDetailController) HandleSavedSearchChanged(searchId store.SavedSearchId, deleted bool) {
+	if c.searchId > 0 && c.searchId == searchId {
+		if deleted {
+			c.searchId = 0
+		} else {
+			c.LoadFeedDetailsFromStore()
+		}
+	}
+}
+
+func (c *FeedDetailController) HandleCategorySelected(feedId store.FeedId, category string) {
+	if c.feedId > 0 && c.feedId == feedId {
+		c.category = category
+		c.LoadFeedDetailsFromStore()
+		c.list.SetCurrentItem(0)
+	}
+}
+
 // SetDisplayedFeed loads and displayes the latest version of the specified feed
 // Assumes that this is called from within the TUI event loop
 func (c *FeedDetailController) SetDisplayedFeed(feedId store.FeedId) {
 	c.feedId = feedId
+	c.searchId = 0
+	c.category = ""
+	c.LoadFeedDetailsFromStore()
+}
+
+// SetDisplayedSearch loads and displays the items matching the specified saved search
+// Assumes that this is called from within the TUI event loop
+func (c *FeedDetailController) SetDisplayedSearch(searchId store.SavedSearchId) {
+	c.feedId = 0
+	c.searchId = searchId
+	c.category = ""
 	c.LoadFeedDetailsFromStore()
+	c.list.SetCurrentItem(0)
 }
 
 func (c *FeedDetailController) LoadFeedDetailsFromStore() {
+	if c.searchId > 0 {
+		c.loadSearchFromStore()
+		return
+	}
+
 	feed, err := c.feedStore.RetrieveFeed(c.feedId)
 	if err != nil {
 		panic(err)
 	}
 
-	feedItems, err := c.feedStore.RetrieveFeedItems(c.feedId)
+	var feedItems []store.FeedItemRecord
+	if len(c.category) > 0 {
+		feedItems, err = c.feedStore.RetrieveFeedItemsInCategory(c.feedId, c.category)
+	} else {
+		feedItems, err = c.feedStore.RetrieveFeedItems(c.feedId)
+	}
 	if err != nil {
 		panic(err)
 	}
@@ -128,39 +317,82 @@ func (c *FeedDetailController) LoadFeedDetailsFromStore() {
 	}
 
 	// Display the name of the feed
-	boxTitle := fmt.Sprintf(i18n.Gettext("Feed: %v"), feed.Name)
-	c.list.Box.SetTitle(boxTitle)
-
-	// Replace existing items with items from the database
-	// Keep track of the URL for each feed item so we can open it later.
-	c.list.Clear()
-	c.listIdxToItemUrl = make([]string, len(feedItems))
-	for i, item := range feedItems {
-		itemText := fmt.Sprintf(
-			// translators: [1] is the item's date and [2] is the item's title
-			i18n.Gettext("%[1]v  %[2]v"),
-			i18n.FormatDate(item.Date),
-			item.Title)
-		c.list.AddItem(itemText, "", 0, nil)
-		c.listIdxToItemUrl[i] = item.Url
+	c.feedName = feed.DisplayName()
+	boxTitle := fmt.Sprintf(c.localizer.Gettext("Feed: %v"), c.feedName)
+	if len(c.category) > 0 {
+		boxTitle = fmt.Sprintf(
+			// translators: [1] is the feed title and [2] is a category (tag)
+			c.localizer.Gettext("Feed: %[1]v (%[2]v)"),
+			c.feedName,
+			c.category)
 	}
+	c.list.Box.SetTitle(boxTitle)
+	c.infoHeader.SetText(feedInfoText(c.localizer, feed))
+	c.helpFooter.SetText(
+		// translators: the characters in brackets are keyboard commands
+		c.localizer.Gettext("(Enter) View   (o) Open in browser   (t) Categories   (s) Download   (p) Play   (l) Read later   (x) Export   (e) Edit Feed   (h) History   (d) Delete Feed   (ESC) Back"))
+	c.displayItems(feedItems)
 
 	// Display the feed's last sync status (if any)
 	if hasSynced {
 		if syncStatus.Success {
-			formattedDate := i18n.FormatDatetime(syncStatus.Date)
+			nextRefresh, err := c.scheduler.NextRefresh(c.feedId)
+			if err != nil {
+				panic(err)
+			}
+
 			lastSyncedText := fmt.Sprintf(
-				// translators: the value is a date
-				i18n.Gettext("Last synced %v"),
-				formattedDate)
+				// translators: [1] is the date the feed was loaded and [2] is the date it will be loaded again
+				c.localizer.Gettext("Last synced %[1]v, next refresh %[2]v"),
+				c.localizer.FormatDatetime(syncStatus.Date),
+				c.localizer.FormatDatetime(nextRefresh))
 			c.statusHeader.SetText(lastSyncedText)
 		} else {
-			loadErrText := i18n.Gettext(
+			loadErrText := c.localizer.Gettext(
 				"An error occurred while loading the feed.  Please try reloading the feed later.")
 			c.statusHeader.SetText(loadErrText)
 		}
 	} else {
-		c.statusHeader.SetText(i18n.Gettext("Loading feed..."))
+		c.statusHeader.SetText(c.localizer.Gettext("Loading feed..."))
+	}
+}
+
+// loadSearchFromStore displays the latest items matching the saved search
+func (c *FeedDetailController) loadSearchFromStore() {
+	search, err := c.feedStore.RetrieveSavedSearch(c.searchId)
+	if err != nil {
+		panic(err)
+	}
+
+	items, err := c.feedStore.RetrieveSavedSearchItems(c.searchId)
+	if err != nil {
+		panic(err)
+	}
+
+	// Downloads from a search are saved under the search's name
+	c.feedName = search.Name
+	c.list.Box.SetTitle(fmt.Sprintf(c.localizer.Gettext("Search: %v"), search.Name))
+	c.infoHeader.SetText(search.Query)
+	c.helpFooter.SetText(
+		// translators: the characters in brackets are keyboard commands
+		c.localizer.Gettext("(Enter) View   (o) Open in browser   (s) Download   (p) Play   (l) Read later   (x) Export   (e) Edit Search   (ESC) Back"))
+	c.displayItems(items)
+
+	if len(items) == 0 {
+		c.statusHeader.SetText(c.localizer.Gettext("No items match this search."))
+	} else {
+		// translators: the argument is a number of items
+		c.statusHeader.SetText(fmt.Sprintf(c.localizer.Gettext("%v matching items"), c.localizer.FormatNumber(len(items))))
+	}
+}
+
+// displayItems replaces the existing items in the list.
+// Keep track of each feed item so we can open it later.
+func (c *FeedDetailController) displayItems(items []store.FeedItemRecord) {
+	c.list.Clear()
+	c.listIdxToItem = items
+	for _, item := range items {
+		c.list.AddItem(c.itemText(item), "", 0, nil)
 	}
 }
 
@@ -170,26 +402,241 @@ func (c *FeedDetailController) HandleTaskScheduled() {
 
 func (c *FeedDetailController) HandleTaskCompleted(r task.TaskResult) {
 	c.appController.App.QueueUpdateDraw(func() {
-		if c.feedId > 0 && c.feedId == r.FeedId {
+		// Follow the feed if it was merged into another feed
+		// after its URL moved.
+		if c.feedId > 0 && c.feedId == r.MergedFeedId {
+			c.feedId = r.FeedId
+		}
+
+		// A search may match new items from any feed
+		if (c.feedId > 0 && c.feedId == r.FeedId) || c.searchId > 0 {
 			c.LoadFeedDetailsFromStore()
 		}
 	})
 }
 
+// itemText formats an item for the list.  Items highlighted by
+// a filter rule and items already read are shown in different colors.
+func (c *FeedDetailController) itemText(item store.FeedItemRecord) string {
+	text := tview.Escape(fmt.Sprintf(
+		// translators: [1] is the item's date and [2] is the item's title
+		c.localizer.Gettext("%[1]v  %[2]v"),
+		c.localizer.FormatDate(item.Date),
+		item.Title))
+
+	if item.Highlighted && len(c.config.HighlightTextColor) > 0 {
+		return fmt.Sprintf("[%s]%s", c.config.HighlightTextColor, text)
+	} else if item.Read && len(c.config.ReadTextColor) > 0 {
+		return fmt.Sprintf("[%s]%s", c.config.ReadTextColor, text)
+	}
+	return text
+}
+
+func (c *FeedDetailController) handleItemSelected(idx int, mainText string, secondaryText string, shortcut rune) {
+	if item, ok := c.currentItem(); ok {
+		c.markItemRead(idx, item)
+		c.itemViewController.SetItem(item)
+		c.appController.SwitchToPage(pageItemView)
+	}
+}
+
+// markItemRead marks an item read after the user views or opens it
+func (c *FeedDetailController) markItemRead(idx int, item store.FeedItemRecord) {
+	if item.Read {
+		return
+	}
+
+	if err := c.feedStore.SetItemRead(item.Id, true); err != nil {
+		panic(err)
+	}
+
+	item.Read = true
+	c.listIdxToItem[idx] = item
+	c.list.SetItemText(idx, c.itemText(item), "")
+
+	for _, s := range c.subscribers {
+		s.HandleItemRead()
+	}
+}
+
 func (c *FeedDetailController) openItemInBrowser() {
-	idx := c.list.GetCurrentItem()
-	url := c.listIdxToItemUrl[idx]
-
-	// Open the url using xdg-open
-	// This assumes that xdg-open is installed, so any distribution
-	// of this program should specify xdg-utils as a dependency.
-	cmd := exec.Command("xdg-open", url)
-	if err := cmd.Start(); err != nil {
-		errMsg := i18n.Gettext("Could not open browser.  Please check that the xdg-open command is installed.")
+	item, ok := c.currentItem()
+	if !ok {
+		return
+	}
+	url := item.Url
+	c.markItemRead(c.list.GetCurrentItem(), item)
+
+	if err := openInBrowser(url); err != nil {
+		errMsg := c.localizer.Gettext("Could not open browser.  Please check that the xdg-open command is installed.")
 		c.statusHeader.SetText(errMsg)
 	} else {
 		// translators: the argument is a URL
-		msg := fmt.Sprintf(i18n.Gettext("Opened %v"), url)
+		msg := fmt.Sprintf(c.localizer.Gettext("Opened %v"), url)
+		c.statusHeader.SetText(msg)
+	}
+}
+
+func (c *FeedDetailController) downloadEnclosure() {
+	enclosure, ok := c.currentEnclosure()
+	if !ok {
+		return
+	}
+
+	name := enclosureFileName(c.feedName, enclosure)
+	if path, ok := c.downloadManager.CompletedPath(name); ok {
+		// translators: the argument is a file path
+		msg := fmt.Sprintf(c.localizer.Gettext("Already downloaded to %v"), path)
+		c.statusHeader.SetText(msg)
+		return
+	}
+
+	d, err := c.downloadManager.Enqueue(enclosure.Url, name)
+	if err != nil {
+		// translators: the argument is an error message
+		msg := fmt.Sprintf(c.localizer.Gettext("Could not download: %v"), err)
+		c.statusHeader.SetText(msg)
+		return
+	}
+
+	// translators: the argument is a file path
+	msg := fmt.Sprintf(c.localizer.Gettext("Downloading to %v"), d.Path)
+	c.statusHeader.SetText(msg)
+}
+
+func (c *FeedDetailController) playEnclosure() {
+	enclosure, ok := c.currentEnclosure()
+	if !ok {
+		return
+	}
+
+	// Play the downloaded file if available, otherwise stream it
+	target := enclosure.Url
+	name := enclosureFileName(c.feedName, enclosure)
+	if path, ok := c.downloadManager.CompletedPath(name); ok {
+		target = path
+	} else if !feed.IsEnclosureUrl(target) {
+		c.statusHeader.SetText(c.localizer.Gettext("The file can't be played from this URL."))
+		return
+	}
+
+	if err := c.player.play(target); err != nil {
+		c.statusHeader.SetText(c.localizer.Gettext(
+			"Could not play the file.  Please check the player command in your settings."))
+	} else {
+		// translators: the argument is a file path or URL
+		msg := fmt.Sprintf(c.localizer.Gettext("Played %v"), target)
 		c.statusHeader.SetText(msg)
 	}
 }
+
+// saveItemForLater queues the selected item to be sent to the
+// read-later service.  The result is displayed once it's sent.
+func (c *FeedDetailController) saveItemForLater() {
+	item, ok := c.currentItem()
+	if !ok {
+		return
+	}
+
+	if c.outbox == nil {
+		c.statusHeader.SetText(c.localizer.Gettext(
+			"No read-later service is configured.  Please add one to your settings."))
+		return
+	}
+
+	if err := c.outbox.Save(item.Url, item.Title); err != nil {
+		panic(err)
+	}
+
+	// translators: the argument is the title of an article
+	c.statusHeader.SetText(fmt.Sprintf(c.localizer.Gettext("Saving \"%v\" for later..."), item.Title))
+}
+
+func (c *FeedDetailController) HandleReadLaterResult(r readlater.Result) {
+	c.appController.App.QueueUpdateDraw(func() {
+		var msg string
+		switch {
+		case r.Err == nil:
+			// translators: the argument is the title of an article
+			msg = fmt.Sprintf(c.localizer.Gettext("Saved \"%v\" for later"), r.Entry.Title)
+		case r.Dropped:
+			// translators: [1] is the title of an article and [2] is an error message
+			msg = fmt.Sprintf(c.localizer.Gettext("Could not save \"%[1]v\" for later: %[2]v"), r.Entry.Title, r.Err)
+		default:
+			msg = fmt.Sprintf(
+				// translators: [1] is a number of articles and [2] is an error message
+				c.localizer.NGettext(
+					"Could not reach the read-later service, %[1]v article will be sent later: %[2]v",
+					"Could not reach the read-later service, %[1]v articles will be sent later: %[2]v",
+					r.Pending),
+				c.localizer.FormatNumber(r.Pending),
+				r.Err)
+		}
+		c.statusHeader.SetText(tview.Escape(msg))
+	})
+}
+
+// exportItems opens the form to export the displayed items.
+// For a feed in a folder, the form also offers the whole folder.
+func (c *FeedDetailController) exportItems() {
+	var folder string
+	if c.feedId > 0 {
+		feed, err := c.feedStore.RetrieveFeed(c.feedId)
+		if err != nil {
+			panic(err)
+		}
+		folder = feed.Folder
+	}
+
+	c.exportController.SetItems(c.feedName, c.listIdxToItem, folder)
+	c.appController.SwitchToPage(pageExport)
+}
+
+func (c *FeedDetailController) currentItem() (store.FeedItemRecord, bool) {
+	idx := c.list.GetCurrentItem()
+	if idx < 0 || idx >= len(c.listIdxToItem) {
+		return store.FeedItemRecord{}, false
+	}
+	return c.listIdxToItem[idx], true
+}
+
+// currentEnclosure returns the first file attached to the selected item.
+// If the item has no attachments, this displays a message and returns false.
+func (c *FeedDetailController) currentEnclosure() (store.EnclosureRecord, bool) {
+	item, ok := c.currentItem()
+	if !ok {
+		return store.EnclosureRecord{}, false
+	}
+
+	enclosures, err := c.feedStore.RetrieveItemEnclosures(item.Id)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(enclosures) == 0 {
+		c.statusHeader.SetText(c.localizer.Gettext("This item has no attachments."))
+		return store.EnclosureRecord{}, false
+	}
+
+	return enclosures[0], true
+}
+
+// feedInfoText describes the website a feed belongs to,
+// using whichever metadata the feed provided.
+func feedInfoText(localizer *i18n.Localizer, feed store.FeedRecord) string {
+	parts := make([]string, 0, 3)
+	if len(feed.SiteUrl) > 0 {
+		parts = append(parts, feed.SiteUrl)
+	}
+
+	if len(feed.Description) > 0 {
+		parts = append(parts, feed.Description)
+	}
+
+	if len(feed.Language) > 0 {
+		// translators: the argument is a language code, e.g. "en-us"
+		parts = append(parts, fmt.Sprintf(localizer.Gettext("Language: %v"), feed.Language))
+	}
+
+	return strings.Join(parts, "  |  ")
+}
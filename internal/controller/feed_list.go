@@ -11,24 +11,40 @@ import (
 	"strings"
 )
 
+// feedListEntry is a feed or a saved search displayed in the feed list.
+// Exactly one of the IDs is nonzero.
+type feedListEntry struct {
+	feedId   store.FeedId
+	searchId store.SavedSearchId
+}
+
 // FeedListController handles the "feed list" page in the UI
 type FeedListController struct {
-	appController        *AppController
-	feedDetailController *FeedDetailController
-	feedStore            *store.FeedStore
-	taskManager          *task.TaskManager
-	grid                 *tview.Grid
-	list                 *tview.List
-	statusHeader         *tview.TextView
-	helpFooter           *tview.TextView
-	listIdxToFeedId      []store.FeedId
-	numUncompletedTasks  int
+	appController             *AppController
+	localizer                 *i18n.Localizer
+	feedDetailController      *FeedDetailController
+	downloadsController       *DownloadsController
+	filterRulesController     *FilterRulesController
+	savedSearchFormController *SavedSearchFormController
+	feedStore                 *store.FeedStore
+	taskManager               *task.TaskManager
+	grid                      *tview.Grid
+	list                      *tview.List
+	statusHeader              *tview.TextView
+	helpFooter                *tview.TextView
+	listIdxToEntry            []feedListEntry
+	numUncompletedTasks       int
 }
 
 func NewFeedListController(
 	appController *AppController,
+	localizer *i18n.Localizer,
 	feedDetailController *FeedDetailController,
+	downloadsController *DownloadsController,
+	filterRulesController *FilterRulesController,
+	savedSearchFormController *SavedSearchFormController,
 	deleteConfirmController *DeleteConfirmController,
+	editFeedController *EditFeedController,
 	feedStore *store.FeedStore,
 	taskManager *task.TaskManager) *FeedListController {
 
@@ -36,14 +52,14 @@ func NewFeedListController(
 	list := tview.NewList().
 		ShowSecondaryText(false)
 	list.Box.SetBorder(true).
-		SetTitle(i18n.Gettext("All Feeds"))
+		SetTitle(localizer.Gettext("All Feeds"))
 
 	// Set up the header to display feed loading status
 	statusHeader := tview.NewTextView()
 
 	// Set up the footer to show help text
 	// translators: the characters in parentheses are keyboard commands
-	helpText := i18n.Gettext("(a) Add Feed   (r) Refresh All   (ESC) Quit")
+	helpText := localizer.Gettext("(a) Add Feed   (s) Add Search   (r) Refresh All   (w) Downloads   (f) Filters   (ESC) Quit")
 	helpFooter := tview.NewTextView().
 		SetText(helpText)
 
@@ -57,7 +73,11 @@ func NewFeedListController(
 	// Create the controller and install the handler for list selection events
 	c := &FeedListController{
 		appController,
+		localizer,
 		feedDetailController,
+		downloadsController,
+		filterRulesController,
+		savedSearchFormController,
 		feedStore,
 		taskManager,
 		grid,
@@ -75,6 +95,15 @@ func NewFeedListController(
 	// Subscribe for delete notifications
 	deleteConfirmController.Subscribe(c)
 
+	// Subscribe for edit notifications
+	editFeedController.Subscribe(c)
+
+	// Subscribe for saved search notifications
+	savedSearchFormController.Subscribe(c)
+
+	// Subscribe for items being read, which changes the unread counts
+	feedDetailController.Subscribe(c)
+
 	return c
 }
 
@@ -88,11 +117,29 @@ func (c *FeedListController) HandleInput(event *tcell.EventKey) *tcell.EventKey
 		return nil
 	}
 
+	if event.Rune() == 's' {
+		c.savedSearchFormController.SetSearch(store.SavedSearchRecord{}, pageFeedList)
+		c.appController.SwitchToPage(pageSavedSearchForm)
+		return nil
+	}
+
 	if event.Rune() == 'r' {
 		c.RefreshAllFeeds()
 		return nil
 	}
 
+	if event.Rune() == 'w' {
+		c.downloadsController.LoadDownloads()
+		c.appController.SwitchToPage(pageDownloads)
+		return nil
+	}
+
+	if event.Rune() == 'f' {
+		c.filterRulesController.LoadRulesFromStore()
+		c.appController.SwitchToPage(pageFilterRules)
+		return nil
+	}
+
 	if event.Key() == tcell.KeyEscape {
 		c.appController.App.Stop()
 		return nil
@@ -105,41 +152,89 @@ func (c *FeedListController) HandleFeedDeleted(store.FeedId) {
 	c.LoadFeedsFromStore()
 }
 
+func (c *FeedListController) HandleFeedEdited(store.FeedId) {
+	c.LoadFeedsFromStore()
+}
+
+func (c *FeedListController) HandleSavedSearchChanged(store.SavedSearchId, bool) {
+	c.LoadFeedsFromStore()
+}
+
+func (c *FeedListController) HandleItemRead() {
+	c.LoadFeedsFromStore()
+}
+
 func (c *FeedListController) LoadFeedsFromStore() {
 	feedRecords, err := c.feedStore.RetrieveFeeds()
 	if err != nil {
 		panic(err)
 	}
 
-	// Sort the feeds ascending by name
+	// Sort the feeds ascending by folder, then by name
 	// (case-insensitive, locale-aware)
+	// Feeds without a folder sort first.
 	sort.SliceStable(feedRecords, func(i, j int) bool {
-		s1 := strings.ToLower(feedRecords[i].Name)
-		s2 := strings.ToLower(feedRecords[j].Name)
-		return i18n.CompareStrings(s1, s2)
+		f1 := strings.ToLower(feedRecords[i].Folder)
+		f2 := strings.ToLower(feedRecords[j].Folder)
+		if f1 != f2 {
+			return c.localizer.CompareStrings(f1, f2)
+		}
+
+		s1 := strings.ToLower(feedRecords[i].DisplayName())
+		s2 := strings.ToLower(feedRecords[j].DisplayName())
+		return c.localizer.CompareStrings(s1, s2)
+	})
+
+	searchRecords, err := c.feedStore.RetrieveSavedSearches()
+	if err != nil {
+		panic(err)
+	}
+
+	// Sort the saved searches by name (case-insensitive, locale-aware)
+	sort.SliceStable(searchRecords, func(i, j int) bool {
+		s1 := strings.ToLower(searchRecords[i].Name)
+		s2 := strings.ToLower(searchRecords[j].Name)
+		return c.localizer.CompareStrings(s1, s2)
 	})
 
-	// Look up the currently selected feed ID
+	unreadCounts, err := c.feedStore.RetrieveUnreadCounts()
+	if err != nil {
+		panic(err)
+	}
+
+	// Look up the currently selected feed or search
 	// so we can preserve the selection after reloading
 	selectedIdx := c.list.GetCurrentItem()
-	selectedFeedId := store.FeedId(-1)
+	selectedEntry := feedListEntry{}
 	newSelectedIdx := -1
 
-	if selectedIdx < len(c.listIdxToFeedId) {
-		selectedFeedId = c.listIdxToFeedId[selectedIdx]
+	if selectedIdx < len(c.listIdxToEntry) {
+		selectedEntry = c.listIdxToEntry[selectedIdx]
 	}
 
-	// Replace existing items with feeds from the database.
-	// Keep track of the feed ID for each item in the list
-	// so we can operate on them later.
+	// Replace existing items with saved searches and feeds from the database.
+	// Keep track of the feed or search for each item in the list
+	// so we can operate on them later.  Saved searches are listed first.
 	c.list.Clear()
-	c.listIdxToFeedId = make([]store.FeedId, len(feedRecords))
-	for i, feed := range feedRecords {
-		c.list.AddItem(feed.Name, "", 0, nil)
-		c.listIdxToFeedId[i] = feed.Id
+	c.listIdxToEntry = make([]feedListEntry, 0, len(searchRecords)+len(feedRecords))
+	for _, search := range searchRecords {
+		unreadCount, err := c.feedStore.CountUnreadSavedSearchItems(search.Id)
+		if err != nil {
+			panic(err)
+		}
+
+		c.list.AddItem(withUnreadCount(c.localizer, savedSearchItemText(c.localizer, search), unreadCount), "", 0, nil)
+		c.listIdxToEntry = append(c.listIdxToEntry, feedListEntry{searchId: search.Id})
+	}
+
+	for _, feed := range feedRecords {
+		c.list.AddItem(withUnreadCount(c.localizer, feedListItemText(c.localizer, feed), unreadCounts[feed.Id]), "", 0, nil)
+		c.listIdxToEntry = append(c.listIdxToEntry, feedListEntry{feedId: feed.Id})
+	}
 
-		// Found the new idx for the previously selected feed
-		if feed.Id == selectedFeedId {
+	// Find the new idx for the previously selected feed or search
+	for i, entry := range c.listIdxToEntry {
+		if entry == selectedEntry {
 			newSelectedIdx = i
 		}
 	}
@@ -156,8 +251,10 @@ func (c *FeedListController) RefreshAllFeeds() {
 		return
 	}
 
-	for _, feedId := range c.listIdxToFeedId {
-		c.taskManager.ScheduleLoadFeedTask(feedId)
+	for _, entry := range c.listIdxToEntry {
+		if entry.feedId > 0 {
+			c.taskManager.ScheduleLoadFeedTask(entry.feedId)
+		}
 	}
 }
 
@@ -177,21 +274,58 @@ func (c *FeedListController) HandleTaskCompleted(r task.TaskResult) {
 }
 
 func (c *FeedListController) handleFeedSelected(idx int, text string, secondaryText string, shortcut rune) {
-	feedId := c.listIdxToFeedId[idx]
-	c.feedDetailController.SetDisplayedFeed(feedId)
+	entry := c.listIdxToEntry[idx]
+	if entry.searchId > 0 {
+		c.feedDetailController.SetDisplayedSearch(entry.searchId)
+	} else {
+		c.feedDetailController.SetDisplayedFeed(entry.feedId)
+	}
 	c.appController.SwitchToPage(pageFeedDetail)
 }
 
 func (c *FeedListController) updateTaskStatusText() {
-	status := i18n.Gettext("All feeds updated")
+	status := c.localizer.Gettext("All feeds updated")
 	if c.numUncompletedTasks > 0 {
 		// translators: the argument is the number of feeds being refreshed
-		refreshMsg := i18n.NGettext(
+		refreshMsg := c.localizer.NGettext(
 			"Refreshing %v feed...",
 			"Refreshing %v feeds...",
 			c.numUncompletedTasks)
-		formattedCount := i18n.FormatNumber(c.numUncompletedTasks)
+		formattedCount := c.localizer.FormatNumber(c.numUncompletedTasks)
 		status = fmt.Sprintf(refreshMsg, formattedCount)
 	}
 	c.statusHeader.SetText(status)
 }
+
+func feedListItemText(localizer *i18n.Localizer, feed store.FeedRecord) string {
+	if len(feed.Folder) == 0 {
+		return feed.DisplayName()
+	}
+
+	return fmt.Sprintf(
+		// translators: [1] is the folder name and [2] is the feed name
+		localizer.Gettext("%[1]v / %[2]v"),
+		feed.Folder,
+		feed.DisplayName())
+}
+
+func savedSearchItemText(localizer *i18n.Localizer, search store.SavedSearchRecord) string {
+	return fmt.Sprintf(
+		// translators: [1] is a label for saved searches, shown like a folder, and [2] is the search name
+		localizer.Gettext("%[1]v / %[2]v"),
+		localizer.Gettext("Saved searches"),
+		search.Name)
+}
+
+// withUnreadCount appends the number of unread items, if any, to the text of a feed or search
+func withUnreadCount(localizer *i18n.Localizer, text string, unreadCount int) string {
+	if unreadCount == 0 {
+		return text
+	}
+
+	return fmt.Sprintf(
+		// translators: [1] is the name of a feed and [2] is the number of unread items
+		localizer.Gettext("%[1]v (%[2]v)"),
+		text,
+		localizer.FormatNumber(unreadCount))
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/task"
+	"strings"
+)
+
+// maxPreviewItems is the number of items shown in a feed preview
+const maxPreviewItems int = 5
+
+// feedPreview loads a feed without saving it and displays its first few items,
+// so the user can check a feed's settings before saving them.
+type feedPreview struct {
+	appController *AppController
+	localizer     *i18n.Localizer
+	taskManager   *task.TaskManager
+	textView      *tview.TextView
+
+	// Incremented for each preview, so results from earlier previews
+	// that complete late are ignored.  Accessed only from the UI event loop.
+	seq int
+}
+
+func newFeedPreview(appController *AppController, localizer *i18n.Localizer, taskManager *task.TaskManager) *feedPreview {
+	textView := tview.NewTextView().
+		SetWrap(false)
+	return &feedPreview{
+		appController: appController,
+		localizer:     localizer,
+		taskManager:   taskManager,
+		textView:      textView,
+	}
+}
+
+// height is the number of lines needed to display a preview
+func (p *feedPreview) height() int {
+	return maxPreviewItems + 1
+}
+
+// load starts loading the feed and displays the result when it completes.
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (p *feedPreview) load(req feed.LoadRequest) {
+	p.seq++
+	seq := p.seq
+	p.textView.SetText(p.localizer.Gettext("Loading preview..."))
+	p.taskManager.PreviewFeed(req, func(f feed.Feed, err error) {
+		p.appController.App.QueueUpdateDraw(func() {
+			if seq == p.seq {
+				p.textView.SetText(formatPreview(p.localizer, f, err))
+			}
+		})
+	})
+}
+
+// clear removes the preview, ignoring any preview still loading.
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (p *feedPreview) clear() {
+	p.seq++
+	p.textView.SetText("")
+}
+
+func formatPreview(localizer *i18n.Localizer, f feed.Feed, err error) string {
+	if err != nil {
+		// translators: the value is an error message
+		return fmt.Sprintf(localizer.Gettext("Could not load preview: %v"), err)
+	}
+
+	lines := make([]string, 0, maxPreviewItems+1)
+	lines = append(lines, fmt.Sprintf(
+		// translators: [1] is the feed's name and [2] is the number of items
+		localizer.NGettext("%[1]v (%[2]d item)", "%[1]v (%[2]d items)", len(f.Items)),
+		f.Name,
+		len(f.Items)))
+
+	for i, item := range f.Items {
+		if i >= maxPreviewItems {
+			break
+		}
+
+		lines = append(lines, fmt.Sprintf(
+			// translators: [1] is the item's title and [2] is the item's URL
+			localizer.Gettext("  %[1]v  <%[2]v>"),
+			item.Title,
+			item.Url))
+	}
+
+	return strings.Join(lines, "\n")
+}
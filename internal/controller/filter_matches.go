@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/store"
+	"strings"
+)
+
+// FilterMatchesController displays the items a filter rule has matched
+type FilterMatchesController struct {
+	appController *AppController
+	localizer     *i18n.Localizer
+	feedStore     *store.FeedStore
+	grid          *tview.Grid
+	textView      *tview.TextView
+}
+
+func NewFilterMatchesController(
+	appController *AppController,
+	localizer *i18n.Localizer,
+	feedStore *store.FeedStore) *FilterMatchesController {
+
+	// Set up a scrollable view for the matched items
+	textView := tview.NewTextView().
+		SetScrollable(true).
+		SetWordWrap(true)
+	textView.Box.SetBorder(true)
+
+	// Set up a footer to display help text
+	// translators: the characters in parentheses are keyboard commands
+	helpText := localizer.Gettext("(ESC) Back")
+	helpFooter := tview.NewTextView().
+		SetText(helpText)
+
+	grid := tview.NewGrid().
+		SetRows(0, 2).
+		AddItem(textView, 0, 0, 1, 1, 0, 0, true).
+		AddItem(helpFooter, 1, 0, 1, 1, 0, 0, false)
+
+	return &FilterMatchesController{
+		appController,
+		localizer,
+		feedStore,
+		grid,
+		textView,
+	}
+}
+
+func (c *FilterMatchesController) GetPage() tview.Primitive {
+	return c.grid
+}
+
+func (c *FilterMatchesController) HandleInput(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape {
+		c.appController.SwitchToPage(pageFilterRules)
+		return nil
+	}
+
+	return event
+}
+
+// SetRule loads and displays the items matched by the specified rule
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (c *FilterMatchesController) SetRule(rule store.FilterRuleRecord) {
+	items, err := c.feedStore.RetrieveFilterRuleMatches(rule.Id)
+	if err != nil {
+		panic(err)
+	}
+
+	// translators: the argument is a filter rule's keyword or regex
+	boxTitle := fmt.Sprintf(c.localizer.Gettext("Matches for \"%v\""), rule.Rule.Pattern)
+	c.textView.Box.SetTitle(boxTitle)
+
+	if len(items) == 0 {
+		c.textView.SetText(c.localizer.Gettext(
+			"This rule hasn't matched any items yet.  Rules are applied when feeds are refreshed."))
+		return
+	}
+
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = fmt.Sprintf(
+			// translators: [1] is the item's date, [2] is the item's title, and [3] is its URL
+			c.localizer.Gettext("%[1]v  %[2]v  (%[3]v)"),
+			c.localizer.FormatDate(item.Date),
+			item.Title,
+			item.Url)
+	}
+	c.textView.SetText(strings.Join(lines, "\n"))
+	c.textView.ScrollToBeginning()
+}
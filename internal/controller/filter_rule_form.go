@@ -0,0 +1,242 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/filter"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/store"
+	"sort"
+	"strings"
+)
+
+// FilterRuleSubscriber is notified when a filter rule is added or changed
+type FilterRuleSubscriber interface {
+	HandleFilterRulesChanged()
+}
+
+// FilterRuleFormController handles the form for adding or editing a filter rule
+type FilterRuleFormController struct {
+	appController  *AppController
+	localizer      *i18n.Localizer
+	feedStore      *store.FeedStore
+	flex           *tview.Flex
+	form           *tview.Form
+	scopeDropDown  *tview.DropDown
+	fieldDropDown  *tview.DropDown
+	matchDropDown  *tview.DropDown
+	patternField   *tview.InputField
+	actionDropDown *tview.DropDown
+	statusFooter   *tview.TextView
+	ruleId         store.FilterRuleId
+	scopeFeedIds   []store.FeedId
+	subscribers    []FilterRuleSubscriber
+}
+
+func NewFilterRuleFormController(
+	appController *AppController,
+	config i18n.Config,
+	localizer *i18n.Localizer,
+	feedStore *store.FeedStore) *FilterRuleFormController {
+
+	// The feeds in the scope drop-down are loaded when the form is shown
+	scopeDropDown := tview.NewDropDown().
+		SetLabel(localizer.Gettext("Apply to"))
+
+	fieldDropDown := tview.NewDropDown().
+		SetLabel(localizer.Gettext("Match in")).
+		SetOptions(filterFieldOptions(localizer), nil)
+
+	// The order of the options must match `matchOptionKeyword` and `matchOptionRegex`
+	matchDropDown := tview.NewDropDown().
+		SetLabel(localizer.Gettext("Match type")).
+		SetOptions([]string{
+			// translators: this is how a filter rule's pattern is matched
+			localizer.Gettext("Keyword (ignoring case)"),
+			// translators: this is how a filter rule's pattern is matched
+			localizer.Gettext("Regular expression"),
+		}, nil)
+
+	patternField := tview.NewInputField().
+		SetLabel(localizer.Gettext("Pattern")).
+		SetPlaceholder(localizer.Gettext("e.g. sponsored"))
+	patternField.SetPlaceholderTextColor(tcell.ColorBlack)
+
+	actionDropDown := tview.NewDropDown().
+		SetLabel(localizer.Gettext("Action")).
+		SetOptions(filterActionOptions(localizer), nil)
+
+	// Set up the form
+	form := tview.NewForm().
+		AddFormItem(scopeDropDown).
+		AddFormItem(fieldDropDown).
+		AddFormItem(matchDropDown).
+		AddFormItem(patternField).
+		AddFormItem(actionDropDown).
+		AddButton(localizer.Gettext("OK"), nil)
+	form.SetBorder(true)
+
+	// Set initial colors based on localized config
+	form.SetLabelColor(tcell.GetColor(config.FormLabelColor))
+	form.SetButtonBackgroundColor(tcell.GetColor(config.FormButtonBackgroundColor))
+	form.SetButtonTextColor(tcell.GetColor(config.FormButtonTextColor))
+	form.SetFieldBackgroundColor(tcell.GetColor(config.FormFieldBackgroundColor))
+	form.SetFieldTextColor(tcell.GetColor(config.FormFieldTextColor))
+
+	// Set up a footer for validation errors
+	statusFooter := tview.NewTextView()
+
+	flex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(form, 0, 1, true).
+		AddItem(statusFooter, 1, 0, false)
+
+	c := &FilterRuleFormController{
+		appController,
+		localizer,
+		feedStore,
+		flex,
+		form,
+		scopeDropDown,
+		fieldDropDown,
+		matchDropDown,
+		patternField,
+		actionDropDown,
+		statusFooter,
+		store.FilterRuleId(0),
+		nil,
+		make([]FilterRuleSubscriber, 0),
+	}
+
+	okButton := form.GetButton(0)
+	okButton.SetSelectedFunc(c.handleOkButton)
+
+	return c
+}
+
+const (
+	matchOptionKeyword = iota
+	matchOptionRegex
+)
+
+func (c *FilterRuleFormController) GetPage() tview.Primitive {
+	return c.flex
+}
+
+func (c *FilterRuleFormController) HandleInput(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape {
+		c.appController.SwitchToPage(pageFilterRules)
+		return nil
+	}
+
+	return event
+}
+
+// Subscribe registers a subscriber to receive notifications when rules change
+// This is NOT thread-safe, so it should be called from the main UI thread only.
+func (c *FilterRuleFormController) Subscribe(s FilterRuleSubscriber) {
+	c.subscribers = append(c.subscribers, s)
+}
+
+// SetRule fills the form with an existing rule, or with defaults
+// for a new rule if the rule's ID is zero.
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (c *FilterRuleFormController) SetRule(rule store.FilterRuleRecord) {
+	c.loadScopeOptions(rule.FeedId)
+	c.ruleId = rule.Id
+
+	if rule.Id == 0 {
+		c.form.SetTitle(c.localizer.Gettext("Add filter rule"))
+	} else {
+		c.form.SetTitle(c.localizer.Gettext("Edit filter rule"))
+	}
+
+	matchOption := matchOptionKeyword
+	if rule.Rule.Regex {
+		matchOption = matchOptionRegex
+	}
+
+	c.fieldDropDown.SetCurrentOption(int(rule.Rule.Field))
+	c.matchDropDown.SetCurrentOption(matchOption)
+	c.patternField.SetText(rule.Rule.Pattern)
+	c.actionDropDown.SetCurrentOption(int(rule.Rule.Action))
+	c.statusFooter.SetText("")
+	c.appController.App.SetFocus(c.form)
+}
+
+// loadScopeOptions lists "all feeds" followed by each feed sorted by name,
+// selecting the specified feed (or "all feeds" if the ID is zero).
+func (c *FilterRuleFormController) loadScopeOptions(selectedFeedId store.FeedId) {
+	feeds, err := c.feedStore.RetrieveFeeds()
+	if err != nil {
+		panic(err)
+	}
+
+	sort.SliceStable(feeds, func(i, j int) bool {
+		n1 := strings.ToLower(feeds[i].DisplayName())
+		n2 := strings.ToLower(feeds[j].DisplayName())
+		return c.localizer.CompareStrings(n1, n2)
+	})
+
+	options := []string{c.localizer.Gettext("All feeds")}
+	c.scopeFeedIds = []store.FeedId{0}
+	selectedIdx := 0
+	for _, feed := range feeds {
+		if feed.Id == selectedFeedId {
+			selectedIdx = len(options)
+		}
+		options = append(options, feed.DisplayName())
+		c.scopeFeedIds = append(c.scopeFeedIds, feed.Id)
+	}
+
+	c.scopeDropDown.SetOptions(options, nil)
+	c.scopeDropDown.SetCurrentOption(selectedIdx)
+}
+
+func (c *FilterRuleFormController) handleOkButton() {
+	scopeIdx, _ := c.scopeDropDown.GetCurrentOption()
+	fieldIdx, _ := c.fieldDropDown.GetCurrentOption()
+	matchIdx, _ := c.matchDropDown.GetCurrentOption()
+	actionIdx, _ := c.actionDropDown.GetCurrentOption()
+
+	var feedId store.FeedId
+	if scopeIdx >= 0 && scopeIdx < len(c.scopeFeedIds) {
+		feedId = c.scopeFeedIds[scopeIdx]
+	}
+
+	record := store.FilterRuleRecord{
+		Id:     c.ruleId,
+		FeedId: feedId,
+		Rule: filter.Rule{
+			Field:   filter.Field(fieldIdx),
+			Pattern: c.patternField.GetText(),
+			Regex:   matchIdx == matchOptionRegex,
+			Action:  filter.Action(actionIdx),
+		},
+	}
+
+	// Check the pattern before saving, so we can show the error
+	if _, err := filter.Compile(record.Rule); err != nil {
+		// translators: the argument is an error message
+		c.statusFooter.SetText(fmt.Sprintf(c.localizer.Gettext("Invalid rule: %v"), err))
+		c.appController.App.SetFocus(c.patternField)
+		return
+	}
+
+	if record.Id == 0 {
+		if _, err := c.feedStore.CreateFilterRule(record); err != nil {
+			panic(err)
+		}
+	} else {
+		if err := c.feedStore.UpdateFilterRule(record); err != nil {
+			panic(err)
+		}
+	}
+
+	for _, s := range c.subscribers {
+		s.HandleFilterRulesChanged()
+	}
+
+	c.appController.SwitchToPage(pageFilterRules)
+}
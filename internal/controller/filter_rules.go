@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/filter"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/store"
+)
+
+// FilterRulesController displays the rules for hiding, marking,
+// and highlighting feed items
+type FilterRulesController struct {
+	appController      *AppController
+	localizer          *i18n.Localizer
+	ruleFormController *FilterRuleFormController
+	matchesController  *FilterMatchesController
+	feedStore          *store.FeedStore
+	grid               *tview.Grid
+	list               *tview.List
+	statusHeader       *tview.TextView
+	listIdxToRule      []store.FilterRuleRecord
+}
+
+func NewFilterRulesController(
+	appController *AppController,
+	localizer *i18n.Localizer,
+	ruleFormController *FilterRuleFormController,
+	matchesController *FilterMatchesController,
+	feedStore *store.FeedStore) *FilterRulesController {
+
+	// Set up the list of rules
+	list := tview.NewList().
+		ShowSecondaryText(false)
+	list.Box.SetBorder(true).
+		SetTitle(localizer.Gettext("Filter rules"))
+
+	// Set up a header to display errors
+	statusHeader := tview.NewTextView()
+
+	// Set up a footer to display help text
+	// translators: the characters in parentheses are keyboard commands
+	helpText := localizer.Gettext("(a) Add Rule   (e) Edit Rule   (m) Matches   (d) Delete Rule   (ESC) Back")
+	helpFooter := tview.NewTextView().
+		SetText(helpText)
+
+	grid := tview.NewGrid().
+		SetRows(1, 0, 2).
+		AddItem(statusHeader, 0, 0, 1, 1, 0, 0, false).
+		AddItem(list, 1, 0, 1, 1, 0, 0, true).
+		AddItem(helpFooter, 2, 0, 1, 1, 0, 0, false)
+
+	c := &FilterRulesController{
+		appController,
+		localizer,
+		ruleFormController,
+		matchesController,
+		feedStore,
+		grid,
+		list,
+		statusHeader,
+		nil,
+	}
+
+	// Subscribe for changes to rules
+	ruleFormController.Subscribe(c)
+
+	return c
+}
+
+func (c *FilterRulesController) GetPage() tview.Primitive {
+	return c.grid
+}
+
+func (c *FilterRulesController) HandleInput(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape {
+		c.appController.SwitchToPage(pageFeedList)
+		return nil
+	}
+
+	if event.Rune() == 'a' {
+		c.ruleFormController.SetRule(store.FilterRuleRecord{})
+		c.appController.SwitchToPage(pageFilterRuleForm)
+		return nil
+	}
+
+	if event.Rune() == 'e' {
+		if rule, ok := c.currentRule(); ok {
+			c.ruleFormController.SetRule(rule)
+			c.appController.SwitchToPage(pageFilterRuleForm)
+		}
+		return nil
+	}
+
+	if event.Rune() == 'm' {
+		if rule, ok := c.currentRule(); ok {
+			c.matchesController.SetRule(rule)
+			c.appController.SwitchToPage(pageFilterMatches)
+		}
+		return nil
+	}
+
+	if event.Rune() == 'd' {
+		if rule, ok := c.currentRule(); ok {
+			if err := c.feedStore.DeleteFilterRule(rule.Id); err != nil {
+				panic(err)
+			}
+			c.LoadRulesFromStore()
+		}
+		return nil
+	}
+
+	return event
+}
+
+func (c *FilterRulesController) HandleFilterRulesChanged() {
+	c.LoadRulesFromStore()
+}
+
+// LoadRulesFromStore displays the latest version of every rule
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (c *FilterRulesController) LoadRulesFromStore() {
+	rules, err := c.feedStore.RetrieveFilterRules()
+	if err != nil {
+		panic(err)
+	}
+
+	feeds, err := c.feedStore.RetrieveFeeds()
+	if err != nil {
+		panic(err)
+	}
+
+	feedNames := make(map[store.FeedId]string, len(feeds))
+	for _, feed := range feeds {
+		feedNames[feed.Id] = feed.DisplayName()
+	}
+
+	c.list.Clear()
+	c.listIdxToRule = rules
+	for _, rule := range rules {
+		scope := c.localizer.Gettext("All feeds")
+		if rule.FeedId > 0 {
+			scope = feedNames[rule.FeedId]
+		}
+
+		ruleText := fmt.Sprintf(
+			// translators: [1] is the feed the rule applies to, [2] is a field
+			// (e.g. "Title"), [3] is a keyword or regex, and [4] is an action
+			c.localizer.Gettext("%[1]v: %[2]v matches \"%[3]v\" → %[4]v"),
+			scope,
+			filterFieldText(c.localizer, rule.Rule.Field),
+			rule.Rule.Pattern,
+			filterActionText(c.localizer, rule.Rule.Action))
+		c.list.AddItem(tview.Escape(ruleText), "", 0, nil)
+	}
+
+	if len(rules) == 0 {
+		c.statusHeader.SetText(c.localizer.Gettext("No filter rules yet.  Press (a) to add one."))
+	} else {
+		c.statusHeader.SetText("")
+	}
+}
+
+func (c *FilterRulesController) currentRule() (store.FilterRuleRecord, bool) {
+	idx := c.list.GetCurrentItem()
+	if idx < 0 || idx >= len(c.listIdxToRule) {
+		return store.FilterRuleRecord{}, false
+	}
+	return c.listIdxToRule[idx], true
+}
+
+// filterFieldOptions are the names of the fields a rule can match.
+// The order must match the values of `filter.Field`
+func filterFieldOptions(localizer *i18n.Localizer) []string {
+	return []string{
+		// translators: this is the part of a feed item a filter rule matches
+		localizer.Gettext("Title"),
+		// translators: this is the part of a feed item a filter rule matches
+		localizer.Gettext("Content"),
+		// translators: this is the part of a feed item a filter rule matches
+		localizer.Gettext("Author"),
+		// translators: this is the part of a feed item a filter rule matches
+		localizer.Gettext("Category"),
+		// translators: this is the part of a feed item a filter rule matches
+		localizer.Gettext("URL"),
+	}
+}
+
+// filterActionOptions are the names of a rule's actions.
+// The order must match the values of `filter.Action`
+func filterActionOptions(localizer *i18n.Localizer) []string {
+	return []string{
+		// translators: this is what a filter rule does to matching items
+		localizer.Gettext("Hide"),
+		// translators: this is what a filter rule does to matching items
+		localizer.Gettext("Mark read"),
+		// translators: this is what a filter rule does to matching items
+		localizer.Gettext("Highlight"),
+	}
+}
+
+func filterFieldText(localizer *i18n.Localizer, field filter.Field) string {
+	options := filterFieldOptions(localizer)
+	if int(field) < 0 || int(field) >= len(options) {
+		return ""
+	}
+	return options[field]
+}
+
+func filterActionText(localizer *i18n.Localizer, action filter.Action) string {
+	options := filterActionOptions(localizer)
+	if int(action) < 0 || int(action) >= len(options) {
+		return ""
+	}
+	return options[action]
+}
@@ -0,0 +1,224 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/store"
+	"github.com/wedaly/local-news/internal/task"
+	"strings"
+)
+
+// ItemViewController displays the content of a feed item
+type ItemViewController struct {
+	appController *AppController
+	localizer     *i18n.Localizer
+	feedStore     *store.FeedStore
+	taskManager   *task.TaskManager
+	grid          *tview.Grid
+	textView      *tview.TextView
+	statusHeader  *tview.TextView
+	item          store.FeedItemRecord
+
+	// The article linked from the item, if it has been archived
+	article     store.ArchivedArticleRecord
+	showArticle bool
+}
+
+func NewItemViewController(
+	appController *AppController,
+	localizer *i18n.Localizer,
+	feedStore *store.FeedStore,
+	taskManager *task.TaskManager) *ItemViewController {
+
+	// Set up a scrollable view for the item's content
+	textView := tview.NewTextView().
+		SetScrollable(true).
+		SetWordWrap(true)
+	textView.Box.SetBorder(true)
+
+	// Set up a header to display errors
+	statusHeader := tview.NewTextView()
+
+	// Set up a footer to display help text
+	// translators: the characters in parentheses are keyboard commands
+	helpText := localizer.Gettext("(o) Open in browser   (a) Archived article   (ESC) Back")
+	helpFooter := tview.NewTextView().
+		SetText(helpText)
+
+	grid := tview.NewGrid().
+		SetRows(1, 0, 2).
+		AddItem(statusHeader, 0, 0, 1, 1, 0, 0, false).
+		AddItem(textView, 1, 0, 1, 1, 0, 0, true).
+		AddItem(helpFooter, 2, 0, 1, 1, 0, 0, false)
+
+	return &ItemViewController{
+		appController,
+		localizer,
+		feedStore,
+		taskManager,
+		grid,
+		textView,
+		statusHeader,
+		store.FeedItemRecord{},
+		store.ArchivedArticleRecord{},
+		false,
+	}
+}
+
+func (c *ItemViewController) GetPage() tview.Primitive {
+	return c.grid
+}
+
+func (c *ItemViewController) HandleInput(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape {
+		c.appController.SwitchToPage(pageFeedDetail)
+		return nil
+	}
+
+	if event.Rune() == 'o' {
+		if err := openInBrowser(c.item.Url); err != nil {
+			errMsg := c.localizer.Gettext("Could not open browser.  Please check that the xdg-open command is installed.")
+			c.statusHeader.SetText(errMsg)
+		} else {
+			// translators: the argument is a URL
+			msg := fmt.Sprintf(c.localizer.Gettext("Opened %v"), c.item.Url)
+			c.statusHeader.SetText(msg)
+		}
+		return nil
+	}
+
+	if event.Rune() == 'a' {
+		c.toggleArchivedArticle()
+		return nil
+	}
+
+	return event
+}
+
+// SetItem displays the specified item.
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (c *ItemViewController) SetItem(item store.FeedItemRecord) {
+	isArchived, article, err := c.feedStore.RetrieveArchivedArticle(item.Id)
+	if err != nil {
+		panic(err)
+	}
+
+	c.item = item
+	c.article = article
+	c.showArticle = false
+	c.statusHeader.SetText("")
+	if isArchived && len(article.ContentHtml) > 0 {
+		c.statusHeader.SetText(
+			c.localizer.Gettext("An archived copy of the article is available."))
+	}
+	c.showItem()
+}
+
+func (c *ItemViewController) showItem() {
+	item := c.item
+	categories, err := c.feedStore.RetrieveItemCategories(item.Id)
+	if err != nil {
+		panic(err)
+	}
+
+	c.textView.Box.SetTitle(item.Title)
+
+	lines := make([]string, 0)
+	if !item.Date.IsZero() {
+		// translators: the argument is the date the item was published
+		lines = append(lines, fmt.Sprintf(c.localizer.Gettext("Date: %v"), c.localizer.FormatDatetime(item.Date)))
+	}
+
+	if len(item.Author) > 0 {
+		// translators: the argument is a list of author names
+		lines = append(lines, fmt.Sprintf(c.localizer.Gettext("Author: %v"), item.Author))
+	}
+
+	if len(categories) > 0 {
+		// translators: the argument is a list of categories (tags)
+		lines = append(lines, fmt.Sprintf(c.localizer.Gettext("Categories: %v"), strings.Join(categories, ", ")))
+	}
+
+	// translators: the argument is a URL
+	lines = append(lines, fmt.Sprintf(c.localizer.Gettext("Link: %v"), item.Url))
+
+	// Prefer the HTML content, since feeds often put only
+	// a summary in the plain text.
+	content := item.ContentText
+	if len(item.ContentHtml) > 0 {
+		content = feed.HtmlToText(item.ContentHtml)
+	}
+
+	if len(content) > 0 {
+		lines = append(lines, "", content)
+	}
+
+	c.textView.SetText(strings.Join(lines, "\n"))
+	c.textView.ScrollToBeginning()
+}
+
+// showArchivedArticle displays the archived copy of the linked article
+func (c *ItemViewController) showArchivedArticle() {
+	title := c.article.Title
+	if len(title) == 0 {
+		title = c.item.Title
+	}
+	c.textView.Box.SetTitle(title)
+
+	lines := []string{
+		// translators: the argument is the date an article was downloaded
+		fmt.Sprintf(c.localizer.Gettext("Archived: %v"), c.localizer.FormatDatetime(c.article.Archived)),
+		// translators: the argument is a URL
+		fmt.Sprintf(c.localizer.Gettext("Link: %v"), c.article.Url),
+		"",
+		feed.HtmlToText(c.article.ContentHtml),
+	}
+
+	c.textView.SetText(strings.Join(lines, "\n"))
+	c.textView.ScrollToBeginning()
+}
+
+// toggleArchivedArticle switches between the item's content and the
+// archived article.  If the article hasn't been archived, it's archived now.
+func (c *ItemViewController) toggleArchivedArticle() {
+	if len(c.article.ContentHtml) > 0 {
+		c.showArticle = !c.showArticle
+		if c.showArticle {
+			c.showArchivedArticle()
+		} else {
+			c.showItem()
+		}
+		return
+	}
+
+	if len(c.item.Url) == 0 {
+		c.statusHeader.SetText(c.localizer.Gettext("The item doesn't link to an article."))
+		return
+	}
+
+	c.statusHeader.SetText(c.localizer.Gettext("Archiving article..."))
+	item := c.item
+	c.taskManager.ArchiveItem(item, func(article store.ArchivedArticleRecord, err error) {
+		c.appController.App.QueueUpdateDraw(func() {
+			// Ignore the result if the user moved on to another item
+			if c.item.Id != item.Id {
+				return
+			}
+
+			if err != nil {
+				// translators: the argument is an error message
+				msg := fmt.Sprintf(c.localizer.Gettext("Could not archive the article: %v"), err)
+				c.statusHeader.SetText(msg)
+				return
+			}
+
+			c.article = article
+			c.showArticle = true
+			c.statusHeader.SetText("")
+			c.showArchivedArticle()
+		})
+	})
+}
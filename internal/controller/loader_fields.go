@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/i18n"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loaderFields are the form fields for a feed's HTTP client settings,
+// which override the global settings.
+type loaderFields struct {
+	timeoutField               *tview.InputField
+	responseHeaderTimeoutField *tview.InputField
+	userAgentField             *tview.InputField
+	proxyField                 *tview.InputField
+	caCertFileField            *tview.InputField
+	insecureDropDown           *tview.DropDown
+}
+
+// addLoaderFields appends the HTTP client fields to a form
+func addLoaderFields(localizer *i18n.Localizer, form *tview.Form) *loaderFields {
+	// Empty fields use the global settings
+	defaultText := localizer.Gettext("Default")
+
+	timeoutField := tview.NewInputField().
+		// translators: the timeout is a number of seconds
+		SetLabel(localizer.Gettext("Timeout (seconds)")).
+		SetAcceptanceFunc(tview.InputFieldInteger).
+		SetPlaceholder(defaultText)
+
+	responseHeaderTimeoutField := tview.NewInputField().
+		// translators: the timeout is a number of seconds
+		SetLabel(localizer.Gettext("Response timeout (seconds)")).
+		SetAcceptanceFunc(tview.InputFieldInteger).
+		SetPlaceholder(defaultText)
+
+	userAgentField := tview.NewInputField().
+		SetLabel(localizer.Gettext("User-Agent")).
+		SetPlaceholder(defaultText)
+
+	proxyField := tview.NewInputField().
+		SetLabel(localizer.Gettext("Proxy")).
+		// translators: "none" must not be translated, since it's typed into the field
+		SetPlaceholder(localizer.Gettext("Optional, e.g. socks5://localhost:1080, or none"))
+
+	caCertFileField := tview.NewInputField().
+		SetLabel(localizer.Gettext("CA certificates file")).
+		SetPlaceholder(localizer.Gettext("Optional, PEM format"))
+
+	// The order of the options must match the values of `feed.Toggle`
+	insecureDropDown := tview.NewDropDown().
+		SetLabel(localizer.Gettext("TLS verification")).
+		SetOptions([]string{
+			defaultText,
+			// translators: this is a TLS verification option
+			localizer.Gettext("Skip (insecure)"),
+			// translators: this is a TLS verification option
+			localizer.Gettext("Verify"),
+		}, nil).
+		SetCurrentOption(int(feed.ToggleDefault))
+
+	form.AddFormItem(timeoutField).
+		AddFormItem(responseHeaderTimeoutField).
+		AddFormItem(userAgentField).
+		AddFormItem(proxyField).
+		AddFormItem(caCertFileField).
+		AddFormItem(insecureDropDown)
+
+	return &loaderFields{
+		timeoutField,
+		responseHeaderTimeoutField,
+		userAgentField,
+		proxyField,
+		caCertFileField,
+		insecureDropDown,
+	}
+}
+
+// setValues displays the specified config in the form
+func (f *loaderFields) setValues(config feed.LoaderConfig) {
+	f.timeoutField.SetText(formatSeconds(config.Timeout))
+	f.responseHeaderTimeoutField.SetText(formatSeconds(config.ResponseHeaderTimeout))
+	f.userAgentField.SetText(config.UserAgent)
+	f.proxyField.SetText(config.ProxyUrl)
+	f.caCertFileField.SetText(config.CACertFile)
+	f.insecureDropDown.SetCurrentOption(int(config.InsecureSkipVerify))
+}
+
+// config returns the config entered in the form
+func (f *loaderFields) config() feed.LoaderConfig {
+	insecureIdx, _ := f.insecureDropDown.GetCurrentOption()
+	return feed.LoaderConfig{
+		Timeout:               parseSeconds(f.timeoutField.GetText()),
+		ResponseHeaderTimeout: parseSeconds(f.responseHeaderTimeoutField.GetText()),
+		UserAgent:             strings.TrimSpace(f.userAgentField.GetText()),
+		ProxyUrl:              strings.TrimSpace(f.proxyField.GetText()),
+		CACertFile:            strings.TrimSpace(f.caCertFileField.GetText()),
+		InsecureSkipVerify:    feed.Toggle(insecureIdx),
+	}
+}
+
+func formatSeconds(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return strconv.Itoa(int(d / time.Second))
+}
+
+func parseSeconds(s string) time.Duration {
+	seconds, err := strconv.Atoi(s)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
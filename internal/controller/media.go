@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/store"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// mediaPlayer plays enclosures (e.g. podcast episodes) using an external command
+type mediaPlayer struct {
+	app     *tview.Application
+	command string
+}
+
+// play runs the player command with a file path or URL as its last argument.
+// The target must be an absolute path or an HTTP(S) URL, so an enclosure URL
+// from a feed can't be interpreted as one of the player's options.
+// The UI is suspended until the command exits, so players that run
+// in the terminal (like mpv) can use it.
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (p *mediaPlayer) play(target string) error {
+	if !filepath.IsAbs(target) && !feed.IsEnclosureUrl(target) {
+		return fmt.Errorf("Cannot play %v", target)
+	}
+
+	var err error
+	p.app.Suspend(func() {
+		// Pass the target as a positional parameter so it isn't
+		// interpreted by the shell.
+		cmd := exec.Command("sh", "-c", p.command+` "$1"`, "sh", target)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+	})
+	return err
+}
+
+// enclosureFileName returns the name of the file an enclosure is
+// downloaded to, relative to the download directory.
+// Files are grouped in a directory for each feed.
+func enclosureFileName(feedName string, enclosure store.EnclosureRecord) string {
+	fileName := ""
+	if u, err := url.Parse(enclosure.Url); err == nil {
+		fileName = sanitizeFileName(path.Base(u.Path))
+	}
+
+	if len(fileName) == 0 {
+		fileName = fmt.Sprintf("enclosure-%d", enclosure.Id)
+	}
+
+	dirName := sanitizeFileName(feedName)
+	if len(dirName) == 0 {
+		return fileName
+	}
+	return path.Join(dirName, fileName)
+}
+
+// sanitizeFileName removes characters that aren't safe in file names
+func sanitizeFileName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, s)
+
+	// Leading dots would create hidden files (or refer to parent directories)
+	return strings.TrimSpace(strings.TrimLeft(s, "."))
+}
+
+// openInBrowser opens a URL using xdg-open.
+// This assumes that xdg-open is installed, so any distribution
+// of this program should specify xdg-utils as a dependency.
+func openInBrowser(url string) error {
+	cmd := exec.Command("xdg-open", url)
+	return cmd.Start()
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/query"
+	"github.com/wedaly/local-news/internal/store"
+	"strings"
+)
+
+// SavedSearchSubscriber is notified when a saved search is added, changed, or deleted
+type SavedSearchSubscriber interface {
+	HandleSavedSearchChanged(searchId store.SavedSearchId, deleted bool)
+}
+
+// SavedSearchFormController handles the form for adding or editing a saved search
+type SavedSearchFormController struct {
+	appController *AppController
+	localizer     *i18n.Localizer
+	feedStore     *store.FeedStore
+	flex          *tview.Flex
+	form          *tview.Form
+	nameField     *tview.InputField
+	queryField    *tview.InputField
+	statusFooter  *tview.TextView
+	searchId      store.SavedSearchId
+	returnPage    string
+	subscribers   []SavedSearchSubscriber
+}
+
+func NewSavedSearchFormController(
+	appController *AppController,
+	config i18n.Config,
+	localizer *i18n.Localizer,
+	feedStore *store.FeedStore) *SavedSearchFormController {
+
+	nameField := tview.NewInputField().
+		SetLabel(localizer.Gettext("Name"))
+
+	queryField := tview.NewInputField().
+		SetLabel(localizer.Gettext("Query")).
+		SetPlaceholder(localizer.Gettext("e.g. title:CVE folder:Security newer:7d is:unread"))
+	queryField.SetPlaceholderTextColor(tcell.ColorBlack)
+
+	// Buttons are added when the form is shown
+	form := tview.NewForm().
+		AddFormItem(nameField).
+		AddFormItem(queryField)
+	form.SetBorder(true)
+
+	// Set initial colors based on localized config
+	form.SetLabelColor(tcell.GetColor(config.FormLabelColor))
+	form.SetButtonBackgroundColor(tcell.GetColor(config.FormButtonBackgroundColor))
+	form.SetButtonTextColor(tcell.GetColor(config.FormButtonTextColor))
+	form.SetFieldBackgroundColor(tcell.GetColor(config.FormFieldBackgroundColor))
+	form.SetFieldTextColor(tcell.GetColor(config.FormFieldTextColor))
+
+	// Explain the query syntax below the form
+	helpText := tview.NewTextView().
+		SetWordWrap(true).
+		SetText(localizer.Gettext(
+			"Every term must match.  Words match the title or content.  " +
+				"Fields: title: content: author: tag: url: feed: folder: is:read is:unread is:highlighted newer:7d.  " +
+				"Use quotes for phrases and '-' to exclude a term."))
+
+	// Set up a footer for validation errors
+	statusFooter := tview.NewTextView()
+
+	flex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(form, 0, 1, true).
+		AddItem(helpText, 3, 0, false).
+		AddItem(statusFooter, 1, 0, false)
+
+	return &SavedSearchFormController{
+		appController,
+		localizer,
+		feedStore,
+		flex,
+		form,
+		nameField,
+		queryField,
+		statusFooter,
+		store.SavedSearchId(0),
+		pageFeedList,
+		make([]SavedSearchSubscriber, 0),
+	}
+}
+
+func (c *SavedSearchFormController) GetPage() tview.Primitive {
+	return c.flex
+}
+
+func (c *SavedSearchFormController) HandleInput(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape {
+		c.appController.SwitchToPage(c.returnPage)
+		return nil
+	}
+
+	return event
+}
+
+// Subscribe registers a subscriber to receive notifications when searches change
+// This is NOT thread-safe, so it should be called from the main UI thread only.
+func (c *SavedSearchFormController) Subscribe(s SavedSearchSubscriber) {
+	c.subscribers = append(c.subscribers, s)
+}
+
+// SetSearch fills the form with an existing saved search, or an empty
+// form for a new search if the ID is zero.  Pressing escape switches
+// back to the return page.
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (c *SavedSearchFormController) SetSearch(record store.SavedSearchRecord, returnPage string) {
+	c.searchId = record.Id
+	c.returnPage = returnPage
+	c.nameField.SetText(record.Name)
+	c.queryField.SetText(record.Query)
+	c.statusFooter.SetText("")
+
+	// Only existing searches can be deleted
+	c.form.ClearButtons()
+	c.form.AddButton(c.localizer.Gettext("OK"), c.handleOkButton)
+	if record.Id == 0 {
+		c.form.SetTitle(c.localizer.Gettext("Add saved search"))
+	} else {
+		c.form.SetTitle(c.localizer.Gettext("Edit saved search"))
+		c.form.AddButton(c.localizer.Gettext("Delete"), c.handleDeleteButton)
+	}
+
+	c.appController.App.SetFocus(c.form)
+}
+
+func (c *SavedSearchFormController) handleOkButton() {
+	record := store.SavedSearchRecord{
+		Id:    c.searchId,
+		Name:  strings.TrimSpace(c.nameField.GetText()),
+		Query: strings.TrimSpace(c.queryField.GetText()),
+	}
+
+	if len(record.Name) == 0 {
+		c.statusFooter.SetText(c.localizer.Gettext("Please enter a name for the search."))
+		c.appController.App.SetFocus(c.nameField)
+		return
+	}
+
+	// Check the query before saving, so we can show the error
+	if _, err := query.Parse(record.Query); err != nil {
+		// translators: the argument is an error message
+		c.statusFooter.SetText(fmt.Sprintf(c.localizer.Gettext("Invalid query: %v"), err))
+		c.appController.App.SetFocus(c.queryField)
+		return
+	}
+
+	if record.Id == 0 {
+		searchId, err := c.feedStore.CreateSavedSearch(record)
+		if err != nil {
+			panic(err)
+		}
+		record.Id = searchId
+	} else {
+		if err := c.feedStore.UpdateSavedSearch(record); err != nil {
+			panic(err)
+		}
+	}
+
+	c.notifyChanged(record.Id, false)
+	c.appController.SwitchToPage(c.returnPage)
+}
+
+func (c *SavedSearchFormController) handleDeleteButton() {
+	if err := c.feedStore.DeleteSavedSearch(c.searchId); err != nil {
+		panic(err)
+	}
+
+	c.notifyChanged(c.searchId, true)
+	c.appController.SwitchToPage(pageFeedList)
+}
+
+func (c *SavedSearchFormController) notifyChanged(searchId store.SavedSearchId, deleted bool) {
+	for _, s := range c.subscribers {
+		s.HandleSavedSearchChanged(searchId, deleted)
+	}
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/i18n"
+	"strings"
+)
+
+// scrapeFields are the form fields for the CSS selectors used
+// to scrape items from a web page that doesn't have a feed.
+// These are shared by the forms for adding and editing feeds.
+type scrapeFields struct {
+	itemSelectorField  *tview.InputField
+	titleSelectorField *tview.InputField
+	linkSelectorField  *tview.InputField
+	dateSelectorField  *tview.InputField
+}
+
+// addScrapeFields appends the scrape selector fields to a form
+func addScrapeFields(localizer *i18n.Localizer, form *tview.Form) *scrapeFields {
+	itemSelectorField := tview.NewInputField().
+		SetLabel(localizer.Gettext("Scrape items")).
+		SetPlaceholder(localizer.Gettext("Optional CSS selector, e.g. article"))
+
+	titleSelectorField := tview.NewInputField().
+		SetLabel(localizer.Gettext("Scrape titles")).
+		SetPlaceholder(localizer.Gettext("Optional, e.g. h2"))
+
+	linkSelectorField := tview.NewInputField().
+		SetLabel(localizer.Gettext("Scrape links")).
+		SetPlaceholder(localizer.Gettext("Optional, e.g. a.permalink"))
+
+	dateSelectorField := tview.NewInputField().
+		SetLabel(localizer.Gettext("Scrape dates")).
+		SetPlaceholder(localizer.Gettext("Optional, e.g. time"))
+
+	form.AddFormItem(itemSelectorField).
+		AddFormItem(titleSelectorField).
+		AddFormItem(linkSelectorField).
+		AddFormItem(dateSelectorField)
+
+	return &scrapeFields{
+		itemSelectorField,
+		titleSelectorField,
+		linkSelectorField,
+		dateSelectorField,
+	}
+}
+
+// setValues displays the specified config in the form
+func (f *scrapeFields) setValues(config feed.ScrapeConfig) {
+	f.itemSelectorField.SetText(config.ItemSelector)
+	f.titleSelectorField.SetText(config.TitleSelector)
+	f.linkSelectorField.SetText(config.LinkSelector)
+	f.dateSelectorField.SetText(config.DateSelector)
+}
+
+// config returns the config entered in the form.
+// If no item selector is entered, the config isn't set,
+// and the other selectors are ignored.
+func (f *scrapeFields) config() feed.ScrapeConfig {
+	config := feed.ScrapeConfig{
+		ItemSelector:  strings.TrimSpace(f.itemSelectorField.GetText()),
+		TitleSelector: strings.TrimSpace(f.titleSelectorField.GetText()),
+		LinkSelector:  strings.TrimSpace(f.linkSelectorField.GetText()),
+		DateSelector:  strings.TrimSpace(f.dateSelectorField.GetText()),
+	}
+	if !config.IsSet() {
+		return feed.ScrapeConfig{}
+	}
+	return config
+}
+
+// validateScrapeConfig returns a localized error message if the
+// scrape config can't be used with the URL, or an empty string if it can.
+func validateScrapeConfig(localizer *i18n.Localizer, url string, config feed.ScrapeConfig) string {
+	if !config.IsSet() {
+		return ""
+	}
+
+	if !feed.IsScrapeableUrl(url) {
+		return localizer.Gettext("Only web pages (http or https URLs) can be scraped.")
+	}
+
+	if err := config.Validate(); err != nil {
+		return localizer.Gettext("One of the CSS selectors is invalid.")
+	}
+
+	return ""
+}
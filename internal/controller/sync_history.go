@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/store"
+	"strconv"
+	"strings"
+)
+
+// SyncHistoryController displays the sync history of a feed
+type SyncHistoryController struct {
+	appController *AppController
+	localizer     *i18n.Localizer
+	feedStore     *store.FeedStore
+	grid          *tview.Grid
+	textView      *tview.TextView
+	feedId        store.FeedId
+}
+
+func NewSyncHistoryController(
+	appController *AppController,
+	localizer *i18n.Localizer,
+	feedStore *store.FeedStore) *SyncHistoryController {
+
+	// Set up a scrollable view for the history entries
+	textView := tview.NewTextView().
+		SetScrollable(true).
+		SetWordWrap(true)
+	textView.Box.SetBorder(true)
+
+	// Set up a footer to display help text
+	// translators: the characters in parentheses are keyboard commands
+	helpText := localizer.Gettext("(ESC) Back")
+	helpFooter := tview.NewTextView().
+		SetText(helpText)
+
+	grid := tview.NewGrid().
+		SetRows(0, 2).
+		AddItem(textView, 0, 0, 1, 1, 0, 0, true).
+		AddItem(helpFooter, 1, 0, 1, 1, 0, 0, false)
+
+	return &SyncHistoryController{
+		appController,
+		localizer,
+		feedStore,
+		grid,
+		textView,
+		store.FeedId(0),
+	}
+}
+
+func (c *SyncHistoryController) GetPage() tview.Primitive {
+	return c.grid
+}
+
+func (c *SyncHistoryController) HandleInput(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape {
+		c.appController.SwitchToPage(pageFeedDetail)
+		return nil
+	}
+
+	return event
+}
+
+// SetFeed loads and displays the sync history for the specified feed
+// This is NOT thread-safe, so it must be called within the UI event loop.
+func (c *SyncHistoryController) SetFeed(feedId store.FeedId) {
+	feed, err := c.feedStore.RetrieveFeed(feedId)
+	if err != nil {
+		panic(err)
+	}
+
+	entries, err := c.feedStore.RetrieveFeedSyncLog(feedId)
+	if err != nil {
+		panic(err)
+	}
+
+	c.feedId = feedId
+
+	// translators: the argument is the feed title
+	boxTitle := fmt.Sprintf(c.localizer.Gettext("Sync history: %v"), feed.DisplayName())
+	c.textView.Box.SetTitle(boxTitle)
+
+	if len(entries) == 0 {
+		c.textView.SetText(c.localizer.Gettext("This feed has not been synced yet."))
+		return
+	}
+
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = fmt.Sprintf(
+			// translators: [1] is the date of the event and [2] describes the event
+			c.localizer.Gettext("%[1]v  %[2]v"),
+			c.localizer.FormatDatetime(entry.Date),
+			syncLogEntryText(c.localizer, entry))
+	}
+	c.textView.SetText(strings.Join(lines, "\n"))
+	c.textView.ScrollToBeginning()
+}
+
+func syncLogEntryText(localizer *i18n.Localizer, entry store.FeedSyncLogEntry) string {
+	switch entry.Kind {
+	case store.SyncLogSuccess:
+		numItems, _ := strconv.Atoi(entry.Detail)
+		// translators: the argument is the number of items in the feed
+		msg := localizer.NGettext(
+			"Synced %v item",
+			"Synced %v items",
+			numItems)
+		return fmt.Sprintf(msg, localizer.FormatNumber(numItems))
+
+	case store.SyncLogError:
+		// translators: the argument is an error message
+		return fmt.Sprintf(localizer.Gettext("Error: %v"), entry.Detail)
+
+	case store.SyncLogUrlMoved:
+		return fmt.Sprintf(
+			// translators: [1] is the old URL and [2] is the new URL
+			localizer.Gettext("Feed moved permanently from %[1]v to %[2]v"),
+			entry.Subject,
+			entry.Detail)
+
+	case store.SyncLogHookFailed:
+		return fmt.Sprintf(
+			// translators: [1] is a command and [2] is an error message
+			localizer.Gettext("Hook %[1]v failed: %[2]v"),
+			entry.Subject,
+			entry.Detail)
+
+	default:
+		return entry.Detail
+	}
+}
@@ -0,0 +1,457 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is the state of a download
+type Status int
+
+const (
+	StatusQueued Status = iota
+	StatusDownloading
+	StatusCompleted
+	StatusFailed
+	StatusCanceled
+)
+
+// partialSuffix is appended to the names of files still being downloaded
+const partialSuffix string = ".part"
+
+// validatorSuffix is appended to the name of a partial file to store the
+// ETag or Last-Modified date of the file on the server.  It's sent in the
+// If-Range header when resuming, so the server sends the whole file
+// instead of a range if the file has changed.
+const validatorSuffix string = ".validator"
+
+// progressInterval is the minimum time between progress notifications
+const progressInterval time.Duration = 250 * time.Millisecond
+
+// ErrTooLarge means the file exceeded the configured size limit
+var ErrTooLarge = errors.New("File exceeds the maximum download size")
+
+// Config controls where and how files are downloaded
+type Config struct {
+	// Directory where files are saved
+	Directory string
+
+	// Maximum size of a file in bytes, or zero for no limit
+	MaxBytes int64
+
+	// Number of files downloaded concurrently
+	NumWorkers int
+
+	// User-Agent header sent with every request
+	UserAgent string
+}
+
+// Download is a snapshot of a file being downloaded
+type Download struct {
+	Id  int
+	Url string
+
+	// Where the file is saved once completed
+	Path string
+
+	Status Status
+
+	// Number of bytes written so far, including bytes from
+	// an earlier attempt that was resumed.
+	BytesDownloaded int64
+
+	// Size of the file in bytes, or zero if unknown
+	TotalBytes int64
+
+	// Reason the download failed, if the status is `StatusFailed`
+	Err error
+}
+
+// Subscriber receives notifications about downloads
+type Subscriber interface {
+	// HandleDownloadUpdated is invoked when a download is queued,
+	// makes progress, or finishes.  This must be thread-safe.
+	HandleDownloadUpdated(Download)
+}
+
+// Manager downloads files in the background using a pool of workers.
+// Interrupted downloads are resumed from the partial file on disk
+// if the server supports HTTP range requests and the file hasn't changed.
+type Manager struct {
+	config           Config
+	client           *http.Client
+	mutex            sync.Mutex
+	downloads        []*Download
+	cancelFuncs      map[int]context.CancelFunc
+	subscribersMutex sync.Mutex
+	subscribers      []Subscriber
+	workerChan       chan struct{}
+}
+
+// NewManager creates a download manager with the specified config.
+// The client shouldn't have an overall timeout, since large files
+// can take a long time, but the server must start responding promptly.
+func NewManager(config Config, client *http.Client) *Manager {
+	numWorkers := config.NumWorkers
+	if numWorkers <= 0 {
+		numWorkers = 1
+	}
+
+	// Paths are absolute, so they can be passed to the media player
+	// without being interpreted as one of its options.
+	if dir, err := filepath.Abs(config.Directory); err == nil {
+		config.Directory = dir
+	}
+
+	workerChan := make(chan struct{}, numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		workerChan <- struct{}{}
+	}
+
+	return &Manager{
+		config:      config,
+		client:      client,
+		downloads:   make([]*Download, 0),
+		cancelFuncs: make(map[int]context.CancelFunc, 0),
+		subscribers: make([]Subscriber, 0),
+		workerChan:  workerChan,
+	}
+}
+
+func (m *Manager) Subscribe(s Subscriber) {
+	m.subscribersMutex.Lock()
+	defer m.subscribersMutex.Unlock()
+	{
+		m.subscribers = append(m.subscribers, s)
+	}
+}
+
+// PathForName returns where a file with the specified name is saved.
+// The name may include subdirectories, but it can't escape the
+// download directory.
+func (m *Manager) PathForName(name string) (string, error) {
+	cleanName := filepath.Clean("/" + name)
+	if cleanName == "/" {
+		return "", errors.New("Missing file name")
+	}
+	return filepath.Join(m.config.Directory, cleanName), nil
+}
+
+// CompletedPath returns the path of the file with the specified name
+// if it has been completely downloaded.
+func (m *Manager) CompletedPath(name string) (string, bool) {
+	path, err := m.PathForName(name)
+	if err != nil {
+		return "", false
+	}
+
+	fileInfo, err := os.Stat(path)
+	return path, err == nil && !fileInfo.IsDir()
+}
+
+// Enqueue schedules a file to be downloaded and saved with the specified name.
+// If the same file is already queued or downloading, this returns
+// the existing download instead of starting another.
+func (m *Manager) Enqueue(url string, name string) (Download, error) {
+	path, err := m.PathForName(name)
+	if err != nil {
+		return Download{}, err
+	}
+
+	m.mutex.Lock()
+	for _, d := range m.downloads {
+		if d.Path == path && (d.Status == StatusQueued || d.Status == StatusDownloading) {
+			snapshot := *d
+			m.mutex.Unlock()
+			return snapshot, nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Download{
+		Id:     len(m.downloads) + 1,
+		Url:    url,
+		Path:   path,
+		Status: StatusQueued,
+	}
+	m.downloads = append(m.downloads, d)
+	m.cancelFuncs[d.Id] = cancel
+	snapshot := *d
+	m.mutex.Unlock()
+
+	m.notifyUpdated(snapshot)
+
+	go func() {
+		// Block until a worker is available
+		<-m.workerChan
+		defer func() { m.workerChan <- struct{}{} }()
+
+		m.run(ctx, d.Id)
+	}()
+
+	return snapshot, nil
+}
+
+// Cancel stops a queued or active download.
+// The partial file is kept, so the download can be resumed later.
+func (m *Manager) Cancel(id int) {
+	m.mutex.Lock()
+	cancel, ok := m.cancelFuncs[id]
+	m.mutex.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+// Downloads returns a snapshot of every download, in the order queued
+func (m *Manager) Downloads() []Download {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	{
+		snapshots := make([]Download, 0, len(m.downloads))
+		for _, d := range m.downloads {
+			snapshots = append(snapshots, *d)
+		}
+		return snapshots
+	}
+}
+
+func (m *Manager) run(ctx context.Context, id int) {
+	if ctx.Err() != nil {
+		m.finish(id, context.Canceled)
+		return
+	}
+
+	snapshot := m.update(id, func(d *Download) {
+		d.Status = StatusDownloading
+	})
+	m.notifyUpdated(snapshot)
+
+	err := m.fetch(ctx, id, snapshot.Url, snapshot.Path)
+
+	// Errors caused by cancellation are reported as a canceled download
+	if err != nil && ctx.Err() != nil {
+		err = context.Canceled
+	}
+	m.finish(id, err)
+}
+
+// fetch downloads the file to a partial file, resuming if possible,
+// then moves the partial file to its final path.
+func (m *Manager) fetch(ctx context.Context, id int, url string, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// A partial file without a validator can't be resumed safely,
+	// since the file on the server may have changed, so start over.
+	partialPath := path + partialSuffix
+	validatorPath := partialPath + validatorSuffix
+	var offset int64
+	var validator string
+	if fileInfo, err := os.Stat(partialPath); err == nil {
+		if data, err := ioutil.ReadFile(validatorPath); err == nil && len(data) > 0 {
+			offset = fileInfo.Size()
+			validator = string(data)
+		}
+	}
+
+	resp, err := m.request(ctx, url, offset, validator)
+	if err != nil {
+		return err
+	}
+
+	// The partial file may be larger than the current version of the
+	// file on the server, so start over.
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 {
+		resp.Body.Close()
+		offset = 0
+		resp, err = m.request(ctx, url, offset, "")
+		if err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			return errors.New("Server returned an unexpected range")
+		}
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// The server ignored the range request, or the file changed, so start over
+		offset = 0
+		flags |= os.O_TRUNC
+		if err := saveValidator(validatorPath, resp.Header); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Received HTTP status %v from url %v", resp.StatusCode, url)
+	}
+
+	var totalBytes int64
+	if resp.ContentLength >= 0 {
+		totalBytes = offset + resp.ContentLength
+	}
+
+	if m.config.MaxBytes > 0 && totalBytes > m.config.MaxBytes {
+		return ErrTooLarge
+	}
+
+	f, err := os.OpenFile(partialPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = m.copyWithProgress(id, f, resp.Body, offset, totalBytes)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == ErrTooLarge {
+		os.Remove(partialPath)
+		os.Remove(validatorPath)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if err := os.Rename(partialPath, path); err != nil {
+		return err
+	}
+
+	os.Remove(validatorPath)
+	return nil
+}
+
+// saveValidator stores the response's strong ETag, or else its Last-Modified
+// date, for resuming the download.  Weak ETags can't be used with If-Range.
+// If the response has neither, any stored validator is removed.
+func saveValidator(validatorPath string, header http.Header) error {
+	validator := header.Get("ETag")
+	if len(validator) == 0 || strings.HasPrefix(validator, "W/") {
+		validator = header.Get("Last-Modified")
+	}
+
+	if len(validator) == 0 {
+		if err := os.Remove(validatorPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	return ioutil.WriteFile(validatorPath, []byte(validator), 0644)
+}
+
+func (m *Manager) request(ctx context.Context, url string, offset int64, validator string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	if len(m.config.UserAgent) > 0 {
+		req.Header.Set("User-Agent", m.config.UserAgent)
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+	}
+
+	return m.client.Do(req)
+}
+
+func (m *Manager) copyWithProgress(id int, w io.Writer, r io.Reader, offset int64, totalBytes int64) error {
+	buf := make([]byte, 32*1024)
+	written := offset
+	lastNotified := time.Now()
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			written += int64(n)
+			if m.config.MaxBytes > 0 && written > m.config.MaxBytes {
+				return ErrTooLarge
+			}
+
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+
+			if time.Since(lastNotified) >= progressInterval {
+				lastNotified = time.Now()
+				m.notifyUpdated(m.update(id, func(d *Download) {
+					d.BytesDownloaded = written
+					d.TotalBytes = totalBytes
+				}))
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		} else if readErr != nil {
+			return readErr
+		}
+	}
+
+	m.update(id, func(d *Download) {
+		d.BytesDownloaded = written
+		d.TotalBytes = totalBytes
+	})
+	return nil
+}
+
+func (m *Manager) finish(id int, err error) {
+	m.mutex.Lock()
+	delete(m.cancelFuncs, id)
+	m.mutex.Unlock()
+
+	snapshot := m.update(id, func(d *Download) {
+		if err == nil {
+			d.Status = StatusCompleted
+			d.TotalBytes = d.BytesDownloaded
+		} else if err == context.Canceled {
+			d.Status = StatusCanceled
+		} else {
+			d.Status = StatusFailed
+			d.Err = err
+		}
+	})
+	m.notifyUpdated(snapshot)
+}
+
+// update modifies a download while holding the lock,
+// and returns a snapshot of the result.
+func (m *Manager) update(id int, f func(*Download)) Download {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	{
+		d := m.downloads[id-1]
+		f(d)
+		return *d
+	}
+}
+
+func (m *Manager) notifyUpdated(d Download) {
+	m.subscribersMutex.Lock()
+	defer m.subscribersMutex.Unlock()
+	{
+		for _, s := range m.subscribers {
+			// The subscriber is responsible for ensuring that
+			// this method is thread-safe
+			s.HandleDownloadUpdated(d)
+		}
+	}
+}
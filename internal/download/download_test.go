@@ -0,0 +1,273 @@
+package download
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type StubSubscriber struct {
+	doneChan chan Download
+}
+
+func (s *StubSubscriber) HandleDownloadUpdated(d Download) {
+	switch d.Status {
+	case StatusCompleted, StatusFailed, StatusCanceled:
+		s.doneChan <- d
+	}
+}
+
+func withTempDir(t *testing.T, f func(dir string)) {
+	dir, err := ioutil.TempDir("", "localnews-download")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	f(dir)
+}
+
+func newTestManager(config Config) (*Manager, *StubSubscriber) {
+	m := NewManager(config, &http.Client{})
+	s := &StubSubscriber{doneChan: make(chan Download, 10)}
+	m.Subscribe(s)
+	return m, s
+}
+
+// serveFile serves content that supports range requests,
+// if the request's If-Range header matches the ETag
+func serveFile(content []byte, etag string, rangeHeaders *[]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rangeHeaders != nil {
+			*rangeHeaders = append(*rangeHeaders, r.Header.Get("Range"))
+		}
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "episode.mp3", time.Unix(0, 0), bytes.NewReader(content))
+	})
+}
+
+func TestDownloadFile(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		content := bytes.Repeat([]byte("abcdefgh"), 10000)
+		server := httptest.NewServer(serveFile(content, `"v1"`, nil))
+		defer server.Close()
+
+		m, s := newTestManager(Config{Directory: dir, NumWorkers: 2})
+		if _, err := m.Enqueue(server.URL+"/episode.mp3", "podcast/episode.mp3"); err != nil {
+			t.Fatalf("Could not enqueue download: %v", err)
+		}
+
+		d := <-s.doneChan
+		if d.Status != StatusCompleted {
+			t.Fatalf("Expected download to complete, got status %v and error %v", d.Status, d.Err)
+		}
+
+		expectedPath := filepath.Join(dir, "podcast", "episode.mp3")
+		if d.Path != expectedPath {
+			t.Errorf("Expected path %v, got %v", expectedPath, d.Path)
+		}
+
+		if d.BytesDownloaded != int64(len(content)) || d.TotalBytes != int64(len(content)) {
+			t.Errorf("Incorrect progress: %v of %v bytes", d.BytesDownloaded, d.TotalBytes)
+		}
+
+		data, err := ioutil.ReadFile(expectedPath)
+		if err != nil || !bytes.Equal(data, content) {
+			t.Errorf("Downloaded file has incorrect content (err %v)", err)
+		}
+
+		if path, ok := m.CompletedPath("podcast/episode.mp3"); !ok || path != expectedPath {
+			t.Errorf("Expected completed path %v, got %v", expectedPath, path)
+		}
+	})
+}
+
+func TestDownloadResumesPartialFile(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		content := []byte("0123456789abcdefghij")
+		rangeHeaders := make([]string, 0)
+		server := httptest.NewServer(serveFile(content, `"v1"`, &rangeHeaders))
+		defer server.Close()
+
+		// Simulate an interrupted download
+		partialPath := filepath.Join(dir, "episode.mp3"+partialSuffix)
+		if err := ioutil.WriteFile(partialPath, content[:8], 0644); err != nil {
+			t.Fatalf("Could not write partial file: %v", err)
+		}
+		validatorPath := partialPath + validatorSuffix
+		if err := ioutil.WriteFile(validatorPath, []byte(`"v1"`), 0644); err != nil {
+			t.Fatalf("Could not write validator: %v", err)
+		}
+
+		m, s := newTestManager(Config{Directory: dir})
+		if _, err := m.Enqueue(server.URL, "episode.mp3"); err != nil {
+			t.Fatalf("Could not enqueue download: %v", err)
+		}
+
+		d := <-s.doneChan
+		if d.Status != StatusCompleted {
+			t.Fatalf("Expected download to complete, got status %v and error %v", d.Status, d.Err)
+		}
+
+		if len(rangeHeaders) != 1 || rangeHeaders[0] != "bytes=8-" {
+			t.Errorf("Expected a range request, got %v", rangeHeaders)
+		}
+
+		data, err := ioutil.ReadFile(d.Path)
+		if err != nil || !bytes.Equal(data, content) {
+			t.Errorf("Resumed file has incorrect content %q (err %v)", data, err)
+		}
+
+		if _, err := os.Stat(partialPath); !os.IsNotExist(err) {
+			t.Errorf("Expected partial file to be removed")
+		}
+
+		if _, err := os.Stat(validatorPath); !os.IsNotExist(err) {
+			t.Errorf("Expected validator to be removed")
+		}
+	})
+}
+
+func TestDownloadRestartsWhenFileChanged(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		content := []byte("0123456789abcdefghij")
+		rangeHeaders := make([]string, 0)
+		server := httptest.NewServer(serveFile(content, `"v2"`, &rangeHeaders))
+		defer server.Close()
+
+		// The partial file is from an older version of the file
+		partialPath := filepath.Join(dir, "episode.mp3"+partialSuffix)
+		if err := ioutil.WriteFile(partialPath, []byte("stale"), 0644); err != nil {
+			t.Fatalf("Could not write partial file: %v", err)
+		}
+		if err := ioutil.WriteFile(partialPath+validatorSuffix, []byte(`"v1"`), 0644); err != nil {
+			t.Fatalf("Could not write validator: %v", err)
+		}
+
+		m, s := newTestManager(Config{Directory: dir})
+		if _, err := m.Enqueue(server.URL, "episode.mp3"); err != nil {
+			t.Fatalf("Could not enqueue download: %v", err)
+		}
+
+		d := <-s.doneChan
+		if len(rangeHeaders) != 1 || rangeHeaders[0] != "bytes=5-" {
+			t.Errorf("Expected a range request, got %v", rangeHeaders)
+		}
+
+		data, err := ioutil.ReadFile(d.Path)
+		if d.Status != StatusCompleted || err != nil || !bytes.Equal(data, content) {
+			t.Errorf("Expected download to restart, got status %v and content %q", d.Status, data)
+		}
+	})
+}
+
+func TestDownloadRestartsWhenRangeNotSatisfiable(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		content := []byte("short")
+		server := httptest.NewServer(serveFile(content, `"v1"`, nil))
+		defer server.Close()
+
+		// The partial file is longer than the file on the server
+		partialPath := filepath.Join(dir, "episode.mp3"+partialSuffix)
+		if err := ioutil.WriteFile(partialPath, []byte("stale partial file"), 0644); err != nil {
+			t.Fatalf("Could not write partial file: %v", err)
+		}
+
+		m, s := newTestManager(Config{Directory: dir})
+		if _, err := m.Enqueue(server.URL, "episode.mp3"); err != nil {
+			t.Fatalf("Could not enqueue download: %v", err)
+		}
+
+		d := <-s.doneChan
+		data, err := ioutil.ReadFile(d.Path)
+		if d.Status != StatusCompleted || err != nil || !bytes.Equal(data, content) {
+			t.Errorf("Expected download to restart, got status %v and content %q", d.Status, data)
+		}
+	})
+}
+
+func TestDownloadSizeLimit(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		content := bytes.Repeat([]byte("x"), 1024)
+
+		mux := http.NewServeMux()
+		mux.Handle("/known", serveFile(content, `"v1"`, nil))
+		mux.HandleFunc("/unknown", func(w http.ResponseWriter, r *http.Request) {
+			// Flushing prevents the server from setting Content-Length
+			w.Write(content[:512])
+			w.(http.Flusher).Flush()
+			w.Write(content[512:])
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		m, s := newTestManager(Config{Directory: dir, MaxBytes: 1000})
+		for _, name := range []string{"known", "unknown"} {
+			if _, err := m.Enqueue(server.URL+"/"+name, name); err != nil {
+				t.Fatalf("Could not enqueue download: %v", err)
+			}
+
+			d := <-s.doneChan
+			if d.Status != StatusFailed || d.Err != ErrTooLarge {
+				t.Errorf("Expected %v download to fail, got status %v and error %v", name, d.Status, d.Err)
+			}
+
+			if _, err := os.Stat(d.Path + partialSuffix); !os.IsNotExist(err) {
+				t.Errorf("Expected partial file for %v to be removed", name)
+			}
+		}
+	})
+}
+
+func TestDownloadCancel(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		blockChan := make(chan struct{})
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("partial"))
+			w.(http.Flusher).Flush()
+			<-blockChan
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+		defer server.Close()
+		defer close(blockChan)
+
+		m, s := newTestManager(Config{Directory: dir})
+		d, err := m.Enqueue(server.URL, "episode.mp3")
+		if err != nil {
+			t.Fatalf("Could not enqueue download: %v", err)
+		}
+
+		// Enqueuing the same file again returns the existing download
+		duplicate, err := m.Enqueue(server.URL, "episode.mp3")
+		if err != nil || duplicate.Id != d.Id {
+			t.Errorf("Expected existing download %v, got %v (err %v)", d.Id, duplicate.Id, err)
+		}
+
+		m.Cancel(d.Id)
+		d = <-s.doneChan
+		if d.Status != StatusCanceled {
+			t.Errorf("Expected download to be canceled, got status %v and error %v", d.Status, d.Err)
+		}
+	})
+}
+
+func TestPathForNameStaysInDirectory(t *testing.T) {
+	m := NewManager(Config{Directory: "/downloads"}, &http.Client{})
+	path, err := m.PathForName("../../etc/passwd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(path, "/downloads/") {
+		t.Errorf("Expected path within download directory, got %v", path)
+	}
+
+	if _, err := m.PathForName("/"); err == nil {
+		t.Errorf("Expected error for empty name")
+	}
+}
@@ -0,0 +1,251 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/wedaly/local-news/internal/choice"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/store"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// Format is a file format for exported items
+type Format int
+
+const (
+	// FormatJsonLines writes one JSON object per item, one per line
+	FormatJsonLines Format = iota
+
+	// FormatCsv writes a header row followed by one row per item
+	FormatCsv
+
+	// FormatMarkdown writes a list of links
+	FormatMarkdown
+
+	// FormatHtml writes a standalone HTML page with a list of links
+	FormatHtml
+)
+
+// formatNames are the names used to choose a format, e.g. on the command line
+var formatNames = []string{"jsonl", "csv", "markdown", "html"}
+
+// formatExtensions are the file extensions for each format
+var formatExtensions = []string{".jsonl", ".csv", ".md", ".html"}
+
+// ParseFormat returns the format with the specified name,
+// either "jsonl", "csv", "markdown", or "html".
+func ParseFormat(name string) (Format, error) {
+	i, err := choice.Parse(name, formatNames)
+	return Format(i), err
+}
+
+func (f Format) String() string {
+	return formatNames[f]
+}
+
+// Extension returns the file extension for the format, including the dot
+func (f Format) Extension() string {
+	return formatExtensions[f]
+}
+
+// Item is an exported feed item and the name of its feed
+type Item struct {
+	FeedName string
+	Record   store.FeedItemRecord
+}
+
+// NewItems looks up the name of each item's feed
+func NewItems(records []store.FeedItemRecord, feeds []store.FeedRecord) []Item {
+	feedNames := make(map[store.FeedId]string, len(feeds))
+	for _, feed := range feeds {
+		feedNames[feed.Id] = feed.DisplayName()
+	}
+
+	items := make([]Item, len(records))
+	for i, record := range records {
+		items[i] = Item{feedNames[record.FeedId], record}
+	}
+	return items
+}
+
+// Options control the content of exported files
+type Options struct {
+	// Title of the Markdown list or HTML page
+	Title string
+
+	// Localizer to format dates for its locale, or nil to format them as RFC 3339
+	Localizer *i18n.Localizer
+}
+
+// Write exports the items in the specified format
+func Write(w io.Writer, format Format, items []Item, opts Options) error {
+	switch format {
+	case FormatJsonLines:
+		return writeJsonLines(w, items, opts)
+	case FormatCsv:
+		return writeCsv(w, items, opts)
+	case FormatMarkdown:
+		return writeMarkdown(w, items, opts)
+	case FormatHtml:
+		return writeHtml(w, items, opts)
+	default:
+		return fmt.Errorf("Unknown format %d", format)
+	}
+}
+
+func formatDate(t time.Time, opts Options) string {
+	if opts.Localizer != nil {
+		return opts.Localizer.FormatDatetime(t)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+type jsonItem struct {
+	Feed        string `json:"feed"`
+	Title       string `json:"title"`
+	Url         string `json:"url"`
+	Guid        string `json:"guid"`
+	Date        string `json:"date"`
+	Author      string `json:"author,omitempty"`
+	ContentHtml string `json:"content_html,omitempty"`
+	ContentText string `json:"content_text,omitempty"`
+	Read        bool   `json:"read"`
+	Highlighted bool   `json:"highlighted"`
+}
+
+func writeJsonLines(w io.Writer, items []Item, opts Options) error {
+	// The encoder writes a newline after each value.
+	// The output isn't embedded in HTML, so keep characters like "<" readable.
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, item := range items {
+		err := encoder.Encode(jsonItem{
+			Feed:        item.FeedName,
+			Title:       item.Record.Title,
+			Url:         item.Record.Url,
+			Guid:        item.Record.Guid,
+			Date:        formatDate(item.Record.Date, opts),
+			Author:      item.Record.Author,
+			ContentHtml: item.Record.ContentHtml,
+			ContentText: item.Record.ContentText,
+			Read:        item.Record.Read,
+			Highlighted: item.Record.Highlighted,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCsv(w io.Writer, items []Item, opts Options) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"feed", "title", "url", "date", "author"}); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		row := []string{
+			item.FeedName,
+			item.Record.Title,
+			item.Record.Url,
+			formatDate(item.Record.Date, opts),
+			item.Record.Author,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// markdownEscaper escapes characters that would end a link's text or URL early
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`[`, `\[`,
+	`]`, `\]`,
+	"\n", " ")
+
+var markdownUrlEscaper = strings.NewReplacer(
+	"(", "%28",
+	")", "%29",
+	" ", "%20")
+
+func writeMarkdown(w io.Writer, items []Item, opts Options) error {
+	if len(opts.Title) > 0 {
+		if _, err := fmt.Fprintf(w, "# %s\n\n", markdownEscaper.Replace(opts.Title)); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range items {
+		_, err := fmt.Fprintf(w, "- [%s](%s) (%s, %s)\n",
+			markdownEscaper.Replace(item.Record.Title),
+			markdownUrlEscaper.Replace(item.Record.Url),
+			markdownEscaper.Replace(item.FeedName),
+			formatDate(item.Record.Date, opts))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// htmlTemplate is a standalone page, so the export can be opened
+// in a browser or attached to an email.  The template package
+// escapes every value and rejects unsafe URLs.
+var htmlTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; line-height: 1.5; }
+li { margin-bottom: 0.5em; }
+.meta { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<ul>
+{{- range .Items}}
+<li><a href="{{.Url}}">{{.Title}}</a> <span class="meta">{{.Feed}} &middot; {{.Date}}</span></li>
+{{- end}}
+</ul>
+</body>
+</html>
+`))
+
+type htmlItem struct {
+	Feed  string
+	Title string
+	Url   string
+	Date  string
+}
+
+func writeHtml(w io.Writer, items []Item, opts Options) error {
+	data := struct {
+		Title string
+		Items []htmlItem
+	}{
+		Title: opts.Title,
+		Items: make([]htmlItem, len(items)),
+	}
+
+	for i, item := range items {
+		data.Items[i] = htmlItem{
+			Feed:  item.FeedName,
+			Title: item.Record.Title,
+			Url:   item.Record.Url,
+			Date:  formatDate(item.Record.Date, opts),
+		}
+	}
+
+	return htmlTemplate.Execute(w, data)
+}
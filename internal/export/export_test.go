@@ -0,0 +1,106 @@
+package export
+
+import (
+	"bytes"
+	"github.com/wedaly/local-news/internal/store"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testItems() []Item {
+	return []Item{
+		Item{
+			FeedName: "Blog",
+			Record: store.FeedItemRecord{
+				Title:       "Hello [world]",
+				Url:         "https://example.com/a_(b)",
+				Guid:        "guid.1",
+				Date:        time.Date(2020, time.April, 6, 2, 0, 22, 0, time.UTC),
+				Author:      "Alice",
+				Highlighted: true,
+			},
+		},
+		Item{
+			FeedName: "News, etc",
+			Record: store.FeedItemRecord{
+				Title: "<script>alert(1)</script>",
+				Url:   "javascript:alert(1)",
+				Guid:  "guid.2",
+				Date:  time.Date(2020, time.April, 5, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	testCases := []struct {
+		format   Format
+		expected string
+	}{
+		{
+			format: FormatJsonLines,
+			expected: `{"feed":"Blog","title":"Hello [world]","url":"https://example.com/a_(b)","guid":"guid.1","date":"2020-04-06T02:00:22Z","author":"Alice","read":false,"highlighted":true}
+{"feed":"News, etc","title":"<script>alert(1)</script>","url":"javascript:alert(1)","guid":"guid.2","date":"2020-04-05T00:00:00Z","read":false,"highlighted":false}
+`,
+		},
+		{
+			format: FormatCsv,
+			expected: `feed,title,url,date,author
+Blog,Hello [world],https://example.com/a_(b),2020-04-06T02:00:22Z,Alice
+"News, etc",<script>alert(1)</script>,javascript:alert(1),2020-04-05T00:00:00Z,
+`,
+		},
+		{
+			format: FormatMarkdown,
+			expected: `# Digest
+
+- [Hello \[world\]](https://example.com/a_%28b%29) (Blog, 2020-04-06T02:00:22Z)
+- [<script>alert(1)</script>](javascript:alert%281%29) (News, etc, 2020-04-05T00:00:00Z)
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tc.format, testItems(), Options{Title: "Digest"}); err != nil {
+				t.Fatalf("Could not write items: %v", err)
+			}
+
+			if buf.String() != tc.expected {
+				t.Errorf("Expected %q, but got %q", tc.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteHtmlEscapesValues(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatHtml, testItems(), Options{Title: "Digest"}); err != nil {
+		t.Fatalf("Could not write items: %v", err)
+	}
+
+	page := buf.String()
+	expectedParts := []string{
+		"<title>Digest</title>",
+		`<a href="https://example.com/a_%28b%29">Hello [world]</a>`,
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		`href="#ZgotmplZ"`,
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(page, part) {
+			t.Errorf("Expected page to contain %q, but got %v", part, page)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, err := ParseFormat("Markdown"); err != nil || format != FormatMarkdown {
+		t.Errorf("Expected markdown format, but got %v, %v", format, err)
+	}
+
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Errorf("Expected error for unknown format")
+	}
+}
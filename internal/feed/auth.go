@@ -0,0 +1,114 @@
+package feed
+
+import (
+	"errors"
+	"github.com/wedaly/local-news/internal/command"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AuthScheme identifies how requests for a feed are authenticated
+type AuthScheme int
+
+const (
+	AuthNone AuthScheme = iota
+	AuthBasic
+	AuthBearer
+)
+
+// Credentials authenticate requests for a private feed
+type Credentials struct {
+	Scheme AuthScheme
+
+	// Username for basic auth (ignored for other schemes)
+	Username string
+
+	// The password for basic auth, or the token for bearer auth
+	Secret string
+
+	// If set, this shell command is run to retrieve the secret
+	// (e.g. "pass show feeds/wiki"), and `Secret` is ignored.
+	// The first line of the command's output is used as the secret.
+	SecretCommand string
+}
+
+// redactedText replaces secrets in error messages
+const redactedText = "xxxxx"
+
+// authorize adds credentials to an HTTP request.
+// It returns the secrets added to the request, so they can be
+// removed from any error messages.  A secret command is killed
+// if it doesn't exit within the timeout (if positive).
+func (c Credentials) authorize(req *http.Request, timeout time.Duration) ([]string, error) {
+	if c.Scheme == AuthNone {
+		return nil, nil
+	}
+
+	secret, err := c.resolveSecret(timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.Scheme {
+	case AuthBasic:
+		req.SetBasicAuth(c.Username, secret)
+		return []string{secret, req.Header.Get("Authorization")}, nil
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+secret)
+		return []string{secret}, nil
+	default:
+		return nil, errors.New("Unknown authentication scheme")
+	}
+}
+
+func (c Credentials) resolveSecret(timeout time.Duration) (string, error) {
+	if len(c.SecretCommand) == 0 {
+		return c.Secret, nil
+	}
+
+	// The command's output and stderr are deliberately excluded
+	// from the error, since they may contain the secret.
+	// Discarding stderr leaves only the exit status or timeout.
+	out, err := command.Run("exec 2>/dev/null; "+c.SecretCommand, nil, timeout)
+	if err != nil {
+		return "", errors.New("Could not retrieve secret from command: " + err.Error())
+	}
+
+	secret := strings.SplitN(string(out), "\n", 2)[0]
+	return strings.TrimRight(secret, "\r"), nil
+}
+
+// redactError removes secrets from an error's message,
+// including any password embedded in a URL.
+func redactError(err error, secrets []string) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	for _, secret := range secrets {
+		if len(secret) > 0 {
+			msg = strings.Replace(msg, secret, redactedText, -1)
+		}
+	}
+
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
+}
+
+// RedactUrl removes the password, if any, from a URL
+func RedactUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.User == nil {
+		return rawUrl
+	}
+
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), redactedText)
+	}
+	return u.String()
+}
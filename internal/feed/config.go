@@ -0,0 +1,195 @@
+package feed
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultUserAgent identifies this program to the servers hosting feeds.
+// Some servers block requests using the Go HTTP client's default User-Agent.
+const DefaultUserAgent string = "localnews/1.0 (+https://github.com/wedaly/local-news)"
+
+// LoaderConfig controls how feeds are retrieved over HTTP.
+// Zero values mean "not set", so that a per-feed config can
+// override only some of the global settings (see `Merge`).
+type LoaderConfig struct {
+	// Maximum time for the entire request, including reading the body
+	Timeout time.Duration
+
+	// Maximum time to wait for the server's response headers
+	ResponseHeaderTimeout time.Duration
+
+	// User-Agent header sent with every request
+	UserAgent string
+
+	// Proxy for requests, with scheme "http", "https", or "socks5".
+	// If empty, the proxy is read from environment variables (e.g. HTTPS_PROXY).
+	// `NoProxy` connects directly, even if a proxy is set elsewhere.
+	ProxyUrl string
+
+	// Path to a file of PEM-encoded CA certificates trusted
+	// in addition to the system's certificates.
+	CACertFile string
+
+	// Skip TLS certificate verification.
+	// This is insecure, so it should be used only for trusted internal servers.
+	InsecureSkipVerify Toggle
+}
+
+// NoProxy is the proxy URL for connecting directly to servers
+const NoProxy string = "none"
+
+// Toggle is a setting that is either on or off, or not set,
+// so that a per-feed config can turn off a global setting.
+type Toggle int
+
+const (
+	// ToggleDefault means "not set", which is off unless overridden
+	ToggleDefault Toggle = iota
+	ToggleOn
+	ToggleOff
+)
+
+// IsOn returns whether the toggle is explicitly turned on
+func (t Toggle) IsOn() bool {
+	return t == ToggleOn
+}
+
+// DefaultLoaderConfig returns the config used when no settings are provided
+func DefaultLoaderConfig() LoaderConfig {
+	return LoaderConfig{
+		Timeout:               60 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		UserAgent:             DefaultUserAgent,
+	}
+}
+
+// Merge returns a copy of the config, replacing each field
+// with the corresponding field of `override` if that field is set.
+func (c LoaderConfig) Merge(override LoaderConfig) LoaderConfig {
+	if override.Timeout > 0 {
+		c.Timeout = override.Timeout
+	}
+
+	if override.ResponseHeaderTimeout > 0 {
+		c.ResponseHeaderTimeout = override.ResponseHeaderTimeout
+	}
+
+	if len(override.UserAgent) > 0 {
+		c.UserAgent = override.UserAgent
+	}
+
+	if len(override.ProxyUrl) > 0 {
+		c.ProxyUrl = override.ProxyUrl
+	}
+
+	if len(override.CACertFile) > 0 {
+		c.CACertFile = override.CACertFile
+	}
+
+	if override.InsecureSkipVerify != ToggleDefault {
+		c.InsecureSkipVerify = override.InsecureSkipVerify
+	}
+
+	return c
+}
+
+// transportKey identifies the settings that require a separate
+// HTTP transport, so transports can be reused across requests.
+type transportKey struct {
+	responseHeaderTimeout time.Duration
+	proxyUrl              string
+	caCertFile            string
+	insecureSkipVerify    bool
+}
+
+func (c LoaderConfig) transportKey() transportKey {
+	return transportKey{
+		c.ResponseHeaderTimeout,
+		c.ProxyUrl,
+		c.CACertFile,
+		c.InsecureSkipVerify.IsOn(),
+	}
+}
+
+// NewHttpClient creates an HTTP client with the config's timeouts,
+// proxy, and TLS settings, for requests made outside the feed loader.
+func (c LoaderConfig) NewHttpClient() (*http.Client, error) {
+	transport, err := c.newTransport()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport, Timeout: c.Timeout}, nil
+}
+
+func (c LoaderConfig) newTransport() (*http.Transport, error) {
+	// Without a dial timeout, an unreachable server could block
+	// the connection until the OS gives up, which can take minutes.
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		IdleConnTimeout:       30 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: c.ResponseHeaderTimeout,
+	}
+
+	if c.ProxyUrl == NoProxy {
+		transport.Proxy = nil
+	} else if len(c.ProxyUrl) > 0 {
+		proxyUrl, err := url.Parse(c.ProxyUrl)
+		if err != nil {
+			return nil, errors.New("Invalid proxy URL")
+		}
+
+		switch proxyUrl.Scheme {
+		case "http", "https", "socks5":
+			transport.Proxy = http.ProxyURL(proxyUrl)
+		default:
+			return nil, fmt.Errorf("Unsupported proxy scheme '%v'", proxyUrl.Scheme)
+		}
+	}
+
+	if len(c.CACertFile) > 0 || c.InsecureSkipVerify.IsOn() {
+		tlsConfig := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify.IsOn()}
+		if len(c.CACertFile) > 0 {
+			pool, err := loadCertPool(c.CACertFile)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return transport, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("No certificates found in %v", path)
+	}
+
+	return pool, nil
+}
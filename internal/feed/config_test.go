@@ -0,0 +1,192 @@
+package feed
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+const minimalRssXml = `<rss><channel><title>Feed</title></channel></rss>`
+
+func TestMergeLoaderConfig(t *testing.T) {
+	global := LoaderConfig{
+		Timeout:   10 * time.Second,
+		UserAgent: "global",
+		ProxyUrl:  "http://proxy:8080",
+	}
+	override := LoaderConfig{
+		UserAgent:          "override",
+		InsecureSkipVerify: ToggleOn,
+	}
+
+	expected := LoaderConfig{
+		Timeout:            10 * time.Second,
+		UserAgent:          "override",
+		ProxyUrl:           "http://proxy:8080",
+		InsecureSkipVerify: ToggleOn,
+	}
+
+	merged := global.Merge(override)
+	if merged != expected {
+		t.Errorf("Incorrect merged config, expected %v but got %v", expected, merged)
+	}
+
+	// A feed can turn off settings enabled globally
+	override = LoaderConfig{
+		ProxyUrl:           NoProxy,
+		InsecureSkipVerify: ToggleOff,
+	}
+
+	expected = LoaderConfig{
+		Timeout:            10 * time.Second,
+		UserAgent:          "override",
+		ProxyUrl:           NoProxy,
+		InsecureSkipVerify: ToggleOff,
+	}
+
+	if merged := merged.Merge(override); merged != expected {
+		t.Errorf("Incorrect merged config, expected %v but got %v", expected, merged)
+	}
+}
+
+func TestLoaderUserAgent(t *testing.T) {
+	userAgentChan := make(chan string, 1)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		userAgentChan <- r.UserAgent()
+		fmt.Fprintln(w, minimalRssXml)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	loader := NewFeedLoader(DefaultLoaderConfig())
+	testCases := []struct {
+		req               LoadRequest
+		expectedUserAgent string
+	}{
+		{LoadRequest{Url: server.URL}, DefaultUserAgent},
+		{LoadRequest{Url: server.URL, Config: LoaderConfig{UserAgent: "custom"}}, "custom"},
+		{LoadRequest{Url: server.URL, Headers: map[string]string{"User-Agent": "header"}}, "header"},
+	}
+
+	for _, tc := range testCases {
+		if _, err := loader.LoadFeed(tc.req); err != nil {
+			t.Fatalf("Error loading feed from test server: %v", err)
+		}
+
+		if userAgent := <-userAgentChan; userAgent != tc.expectedUserAgent {
+			t.Errorf("Expected User-Agent %v but got %v", tc.expectedUserAgent, userAgent)
+		}
+	}
+}
+
+func TestLoaderTimeout(t *testing.T) {
+	done := make(chan struct{})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+	defer close(done)
+
+	loader := NewFeedLoader(DefaultLoaderConfig())
+	req := LoadRequest{
+		Url:    server.URL,
+		Config: LoaderConfig{Timeout: 50 * time.Millisecond},
+	}
+	if _, err := loader.LoadFeed(req); err == nil {
+		t.Errorf("Expected timeout error")
+	}
+}
+
+func TestLoaderProxy(t *testing.T) {
+	requestedHostChan := make(chan string, 1)
+	proxyHandler := func(w http.ResponseWriter, r *http.Request) {
+		requestedHostChan <- r.URL.Host
+		fmt.Fprintln(w, minimalRssXml)
+	}
+
+	proxy := httptest.NewServer(http.HandlerFunc(proxyHandler))
+	defer proxy.Close()
+
+	loader := NewFeedLoader(LoaderConfig{ProxyUrl: proxy.URL})
+	if _, err := loader.LoadFeedFromUrl("http://example.test/feed"); err != nil {
+		t.Fatalf("Error loading feed through proxy: %v", err)
+	}
+
+	if host := <-requestedHostChan; host != "example.test" {
+		t.Errorf("Proxy received request for wrong host %v", host)
+	}
+}
+
+func TestLoaderUnsupportedProxy(t *testing.T) {
+	loader := NewFeedLoader(LoaderConfig{ProxyUrl: "ftp://proxy"})
+	if _, err := loader.LoadFeedFromUrl("http://example.test/feed"); err == nil {
+		t.Errorf("Expected error for unsupported proxy scheme")
+	}
+}
+
+func TestLoaderNoProxyOverride(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, minimalRssXml)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	// The global proxy is unusable, so this succeeds only if the feed connects directly
+	loader := NewFeedLoader(LoaderConfig{ProxyUrl: "ftp://proxy"})
+	req := LoadRequest{Url: server.URL, Config: LoaderConfig{ProxyUrl: NoProxy}}
+	if _, err := loader.LoadFeed(req); err != nil {
+		t.Errorf("Error loading feed without proxy: %v", err)
+	}
+}
+
+func TestLoaderTLS(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, minimalRssXml)
+	}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	// Write the server's self-signed certificate to a CA bundle
+	caCertFile := path.Join(os.TempDir(), "test-loader-ca.pem")
+	defer os.Remove(caCertFile)
+	certPem := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	})
+	if err := ioutil.WriteFile(caCertFile, certPem, 0600); err != nil {
+		t.Fatalf("Could not write CA bundle: %v", err)
+	}
+
+	testCases := []struct {
+		global      LoaderConfig
+		config      LoaderConfig
+		expectError bool
+	}{
+		{DefaultLoaderConfig(), LoaderConfig{}, true},
+		{DefaultLoaderConfig(), LoaderConfig{CACertFile: caCertFile}, false},
+		{DefaultLoaderConfig(), LoaderConfig{InsecureSkipVerify: ToggleOn}, false},
+		{LoaderConfig{InsecureSkipVerify: ToggleOn}, LoaderConfig{}, false},
+		{LoaderConfig{InsecureSkipVerify: ToggleOn}, LoaderConfig{InsecureSkipVerify: ToggleOff}, true},
+	}
+
+	for _, tc := range testCases {
+		loader := NewFeedLoader(tc.global)
+		_, err := loader.LoadFeed(LoadRequest{Url: server.URL, Config: tc.config})
+		if tc.expectError && err == nil {
+			t.Errorf("Expected TLS error with config %v", tc.config)
+		} else if !tc.expectError && err != nil {
+			t.Errorf("Unexpected error with config %v: %v", tc.config, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package feed
+
+import (
+	"bytes"
+	"errors"
+	"github.com/wedaly/local-news/internal/command"
+	"strings"
+)
+
+// execPrefix starts a URL for a feed generated by a command
+const execPrefix string = "exec:"
+
+// execSource retrieves feeds by running a local shell command
+// and parsing its output, using URLs like "exec:~/bin/report --rss"
+type execSource struct{}
+
+func (s *execSource) ValidateUrl(url string) bool {
+	return len(execCommandFromUrl(url)) > 0
+}
+
+func (s *execSource) Load(r LoadRequest, config LoaderConfig) (Feed, error) {
+	cmd := execCommandFromUrl(r.Url)
+	if len(cmd) == 0 {
+		return Feed{}, errors.New("Missing command")
+	}
+
+	out, err := command.Run(cmd, nil, config.Timeout)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	return ParseExternalFeed(bytes.NewReader(out))
+}
+
+func execCommandFromUrl(url string) string {
+	if len(url) < len(execPrefix) || !strings.EqualFold(url[:len(execPrefix)], execPrefix) {
+		return ""
+	}
+	return strings.TrimSpace(url[len(execPrefix):])
+}
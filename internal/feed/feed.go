@@ -1,17 +1,105 @@
 package feed
 
-import "time"
+import (
+	neturl "net/url"
+	"time"
+)
 
 // Feed represents a content syndication feed (e.g. RSS or Atom)
 type Feed struct {
 	Name  string
 	Items []FeedItem
+
+	// URL of the website the feed belongs to, if provided
+	SiteUrl string
+
+	// Description of the feed, if provided
+	Description string
+
+	// Language code of the feed (e.g. "en-us"), if provided
+	Language string
+
+	// URL of the feed's logo or icon, if provided
+	ImageUrl string
+
+	// The feed's suggestions for how often to refresh it, if provided
+	RefreshHints RefreshHints
+
+	// If the source permanently redirected to another URL,
+	// this is the URL the feed moved to.  Otherwise it is empty.
+	MovedTo string
+}
+
+// RefreshHints are a feed's suggestions for how often to refresh it
+type RefreshHints struct {
+	// How long the feed may be cached before refreshing (RSS <ttl>), or zero
+	TTL time.Duration
+
+	// How often the feed is updated (sy:updatePeriod and sy:updateFrequency), or zero
+	UpdateInterval time.Duration
+
+	// Hours (0-23, in UTC) during which the feed shouldn't be refreshed
+	SkipHours []int
+
+	// Days (in UTC) on which the feed shouldn't be refreshed
+	SkipDays []time.Weekday
 }
 
 // FeedItem represents an item in a feed (e.g. a blog post)
 type FeedItem struct {
 	Title string
-	Date  time.Time
+
+	// Date the item was published.  This is zero if unknown,
+	// which happens only for items scraped from HTML pages.
+	Date time.Time
+
+	Url  string
+	Guid string
+
+	// Date the item was last modified, or zero if unknown
+	Updated time.Time
+
+	// Content of the item as HTML, if provided by the feed
+	ContentHtml string
+
+	// Content of the item as plain text, if provided by the feed
+	ContentText string
+
+	// People who wrote the item, if provided by the feed
+	Authors []Author
+
+	// Categories or tags of the item, if provided by the feed
+	Categories []string
+
+	// Files attached to the item (e.g. podcast episodes).
+	// RSS calls these enclosures, and JSON Feed calls them attachments.
+	Enclosures []Enclosure
+}
+
+// Author is a person who wrote an item
+type Author struct {
+	Name  string
 	Url   string
-	Guid  string
+	Email string
+}
+
+// Enclosure is a file attached to an item
+type Enclosure struct {
+	Url      string
+	MimeType string
+	Title    string
+
+	// Size of the file in bytes, or zero if unknown
+	Length int64
+
+	// Duration of audio or video, or zero if unknown
+	Duration time.Duration
+}
+
+// IsEnclosureUrl returns whether an enclosure can be downloaded or streamed
+// from the URL.  Only absolute HTTP(S) URLs are allowed, since enclosure URLs
+// come from the feed and are passed to the media player.
+func IsEnclosureUrl(url string) bool {
+	u, err := neturl.Parse(url)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && len(u.Host) > 0
 }
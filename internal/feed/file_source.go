@@ -0,0 +1,48 @@
+package feed
+
+import (
+	neturl "net/url"
+	"os"
+)
+
+// fileSource retrieves feeds from local files, using URLs
+// like "file:///path/to/feed.xml"
+type fileSource struct{}
+
+func (s *fileSource) ValidateUrl(url string) bool {
+	_, err := filePathFromUrl(url)
+	return err == nil
+}
+
+func (s *fileSource) Load(r LoadRequest, config LoaderConfig) (Feed, error) {
+	path, err := filePathFromUrl(r.Url)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Feed{}, err
+	}
+	defer f.Close()
+
+	return ParseExternalFeed(f)
+}
+
+func filePathFromUrl(url string) (string, error) {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return "", err
+	}
+
+	// Only local files are supported
+	if len(u.Host) > 0 && u.Host != "localhost" {
+		return "", &os.PathError{Op: "open", Path: url, Err: os.ErrNotExist}
+	}
+
+	if len(u.Path) == 0 || u.Path[0] != '/' {
+		return "", &os.PathError{Op: "open", Path: url, Err: os.ErrInvalid}
+	}
+
+	return u.Path, nil
+}
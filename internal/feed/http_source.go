@@ -0,0 +1,202 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+)
+
+// maxRedirects is the maximum number of redirects followed for one request
+const maxRedirects int = 10
+
+// httpSource retrieves feeds over HTTP or HTTPS
+type httpSource struct {
+	mutex      sync.Mutex
+	transports map[transportKey]*http.Transport
+}
+
+func newHttpSource() *httpSource {
+	return &httpSource{
+		transports: make(map[transportKey]*http.Transport, 0),
+	}
+}
+
+func (s *httpSource) ValidateUrl(url string) bool {
+	u, err := neturl.ParseRequestURI(url)
+	return err == nil && len(u.Host) > 0
+}
+
+func (s *httpSource) Load(r LoadRequest, config LoaderConfig) (Feed, error) {
+	resp, movedTo, err := s.fetch(r, config)
+	if err != nil {
+		return Feed{}, err
+	}
+	defer resp.Body.Close()
+
+	var feed Feed
+	if r.Scrape.IsSet() {
+		// Resolve links against the final URL after any redirects
+		feed, err = ScrapeFeed(resp.Body, resp.Request.URL.String(), r.Scrape)
+	} else {
+		feed, err = ParseExternalFeed(resp.Body)
+	}
+	if err != nil {
+		return Feed{}, err
+	}
+
+	feed.MovedTo = movedTo
+	return feed, nil
+}
+
+// loadPage retrieves an HTML page, reading at most `maxBytes` (if not zero)
+func (s *httpSource) loadPage(url string, config LoaderConfig, maxBytes int64) (Page, error) {
+	resp, _, err := s.fetch(LoadRequest{Url: url}, config)
+	if err != nil {
+		return Page{}, err
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); len(contentType) > 0 {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+			return Page{}, fmt.Errorf("Expected an HTML page, but received %v", contentType)
+		}
+	}
+
+	var body io.Reader = resp.Body
+	if maxBytes > 0 {
+		if resp.ContentLength > maxBytes {
+			return Page{}, ErrPageTooLarge
+		}
+		body = io.LimitReader(resp.Body, maxBytes+1)
+	}
+
+	html, err := ioutil.ReadAll(body)
+	if err != nil {
+		return Page{}, err
+	} else if maxBytes > 0 && int64(len(html)) > maxBytes {
+		return Page{}, ErrPageTooLarge
+	}
+
+	return Page{Url: resp.Request.URL.String(), Html: html}, nil
+}
+
+// fetch sends the request and returns a successful response.
+// The caller must close the response body.
+// If the URL permanently redirected to another URL, the second
+// return value is the new URL.
+func (s *httpSource) fetch(r LoadRequest, config LoaderConfig) (*http.Response, string, error) {
+	req, err := http.NewRequest("GET", r.Url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(config.UserAgent) > 0 {
+		req.Header.Set("User-Agent", config.UserAgent)
+	}
+
+	// Custom headers may override the User-Agent
+	for name, value := range r.Headers {
+		req.Header.Set(name, value)
+	}
+
+	secrets, err := r.Credentials.authorize(req, config.Timeout)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, movedTo, err := s.do(req, r.Headers, config)
+	return resp, movedTo, redactError(err, secrets)
+}
+
+// do sends the request, following redirects.  The custom headers
+// (which often contain API keys) are only sent to the original host.
+func (s *httpSource) do(req *http.Request, headers map[string]string, config LoaderConfig) (*http.Response, string, error) {
+	transport, err := s.transportForConfig(config)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Track redirects separately for each request, since
+	// the underlying transport is shared.
+	client := http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+	}
+	isPermanent := true
+	movedTo := ""
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("Stopped after %v redirects", maxRedirects)
+		}
+
+		// net/http only removes the Authorization and Cookie headers
+		// when redirecting to another host, so remove the custom headers too.
+		if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			for name := range headers {
+				req.Header.Del(name)
+			}
+		}
+
+		// Once a temporary redirect is encountered, later URLs
+		// in the chain can't be used as the feed's new URL.
+		if isPermanent && isPermanentRedirect(req.Response.StatusCode) {
+			movedTo = req.URL.String()
+		} else {
+			isPermanent = false
+		}
+		return nil
+	}
+
+	url := req.URL.String()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		errMsg := fmt.Sprintf(
+			"Received HTTP status %v from url %v",
+			resp.StatusCode, RedactUrl(url))
+		return nil, "", errors.New(errMsg)
+	}
+
+	if movedTo == url {
+		movedTo = ""
+	}
+
+	return resp, movedTo, nil
+}
+
+// transportForConfig returns a transport for the config,
+// reusing an existing transport if possible so that connections
+// are pooled across requests.
+func (s *httpSource) transportForConfig(config LoaderConfig) (*http.Transport, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := config.transportKey()
+	if transport, ok := s.transports[key]; ok {
+		return transport, nil
+	}
+
+	transport, err := config.newTransport()
+	if err != nil {
+		return nil, err
+	}
+
+	s.transports[key] = transport
+	return transport, nil
+}
+
+func isPermanentRedirect(statusCode int) bool {
+	return statusCode == http.StatusMovedPermanently ||
+		statusCode == http.StatusPermanentRedirect
+}
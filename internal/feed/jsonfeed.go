@@ -0,0 +1,241 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxDerivedTitleLength is the maximum number of characters in a title
+// derived from an item's content (JSON Feed items need not have titles)
+const maxDerivedTitleLength int = 80
+
+// jsonFeed is the top-level object of a JSON Feed (version 1.0 or 1.1)
+// See https://jsonfeed.org/version/1.1
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageUrl string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Language    string         `json:"language"`
+	Icon        string         `json:"icon"`
+	Favicon     string         `json:"favicon"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	// The spec requires a string, but some feeds use numbers
+	Id            json.RawMessage      `json:"id"`
+	Url           string               `json:"url"`
+	ExternalUrl   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHtml   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Author        *jsonFeedAuthor      `json:"author"`
+	Authors       []jsonFeedAuthor     `json:"authors"`
+	Attachments   []jsonFeedAttachment `json:"attachments"`
+	Tags          []string             `json:"tags"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+type jsonFeedAttachment struct {
+	Url               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	Title             string  `json:"title"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+// parseJsonFeed parses a JSON Feed into the standardized format.
+func parseJsonFeed(r io.Reader) (Feed, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	// The JSON decoder rejects a byte order mark, but some servers send one anyway
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var rawFeed jsonFeed
+	if err := json.Unmarshal(data, &rawFeed); err != nil {
+		return Feed{}, err
+	}
+
+	if !strings.HasPrefix(rawFeed.Version, "https://jsonfeed.org/version/") {
+		return Feed{}, errors.New("Unsupported JSON Feed version")
+	}
+
+	if len(rawFeed.Title) == 0 {
+		return Feed{}, errors.New("Missing feed name")
+	}
+
+	feed := Feed{
+		Name:        rawFeed.Title,
+		Items:       make([]FeedItem, 0, len(rawFeed.Items)),
+		SiteUrl:     rawFeed.HomePageUrl,
+		Description: rawFeed.Description,
+		Language:    rawFeed.Language,
+		ImageUrl:    rawFeed.Icon,
+	}
+	if len(feed.ImageUrl) == 0 {
+		feed.ImageUrl = rawFeed.Favicon
+	}
+
+	for _, rawItem := range rawFeed.Items {
+		item, err := convertJsonFeedItem(rawItem)
+		if err != nil {
+			return Feed{}, err
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	return feed, nil
+}
+
+func convertJsonFeedItem(rawItem jsonFeedItem) (FeedItem, error) {
+	guid, err := parseJsonFeedId(rawItem.Id)
+	if err != nil {
+		return FeedItem{}, err
+	}
+
+	url := rawItem.Url
+	if len(url) == 0 {
+		url = rawItem.ExternalUrl
+	}
+	if len(url) == 0 {
+		return FeedItem{}, errors.New("Missing item link")
+	}
+
+	published, err := parseJsonFeedDate(rawItem.DatePublished)
+	if err != nil {
+		return FeedItem{}, err
+	}
+
+	updated, err := parseJsonFeedDate(rawItem.DateModified)
+	if err != nil {
+		return FeedItem{}, err
+	}
+
+	// The publication date is optional in JSON Feed,
+	// so fallback to the modification date.
+	if published.IsZero() {
+		published = updated
+	}
+	if published.IsZero() {
+		return FeedItem{}, errors.New("Missing published date")
+	}
+
+	title := rawItem.Title
+	if len(title) == 0 {
+		title = deriveTitle(rawItem.Summary, rawItem.ContentText)
+	}
+	if len(title) == 0 {
+		return FeedItem{}, errors.New("Missing item title")
+	}
+
+	item := FeedItem{
+		Title:       title,
+		Date:        published,
+		Url:         url,
+		Guid:        guid,
+		Updated:     updated,
+		ContentHtml: rawItem.ContentHtml,
+		ContentText: rawItem.ContentText,
+	}
+
+	// Version 1.1 replaced "author" with "authors"
+	rawAuthors := rawItem.Authors
+	if len(rawAuthors) == 0 && rawItem.Author != nil {
+		rawAuthors = []jsonFeedAuthor{*rawItem.Author}
+	}
+	for _, rawAuthor := range rawAuthors {
+		author := Author{Name: rawAuthor.Name, Url: rawAuthor.Url}
+		item.Authors = append(item.Authors, author)
+	}
+
+	for _, tag := range rawItem.Tags {
+		if tag = strings.TrimSpace(tag); len(tag) > 0 {
+			item.Categories = append(item.Categories, tag)
+		}
+	}
+
+	for _, rawAttachment := range rawItem.Attachments {
+		if !IsEnclosureUrl(rawAttachment.Url) {
+			continue
+		}
+
+		enclosure := Enclosure{
+			Url:      rawAttachment.Url,
+			MimeType: rawAttachment.MimeType,
+			Title:    rawAttachment.Title,
+			Length:   rawAttachment.SizeInBytes,
+			Duration: time.Duration(rawAttachment.DurationInSeconds * float64(time.Second)),
+		}
+		item.Enclosures = append(item.Enclosures, enclosure)
+	}
+
+	return item, nil
+}
+
+func parseJsonFeedId(rawId json.RawMessage) (string, error) {
+	if len(rawId) == 0 {
+		return "", errors.New("Missing item ID")
+	}
+
+	var id string
+	if err := json.Unmarshal(rawId, &id); err == nil {
+		return id, nil
+	}
+
+	var numericId json.Number
+	if err := json.Unmarshal(rawId, &numericId); err == nil {
+		return numericId.String(), nil
+	}
+
+	return "", fmt.Errorf("Invalid item ID %s", rawId)
+}
+
+// parseJsonFeedDate parses an RFC 3339 date.
+// An empty string is parsed as the zero time.
+func parseJsonFeedDate(s string) (time.Time, error) {
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// deriveTitle uses the beginning of the first non-empty text as a title
+func deriveTitle(texts ...string) string {
+	for _, text := range texts {
+		text = collapseSpace(text)
+		if len(text) == 0 {
+			continue
+		}
+
+		if utf8.RuneCountInString(text) <= maxDerivedTitleLength {
+			return text
+		}
+
+		runes := []rune(text)
+		return string(runes[:maxDerivedTitleLength]) + "…"
+	}
+	return ""
+}
@@ -0,0 +1,221 @@
+package feed
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseJsonFeedVersion1_1(t *testing.T) {
+	feedJson := `
+		{
+			"version": "https://jsonfeed.org/version/1.1",
+			"title": "My JSON Feed",
+			"home_page_url": "https://example.com",
+			"description": "Posts about things",
+			"language": "en-US",
+			"favicon": "https://example.com/favicon.ico",
+			"items": [
+				{
+					"id": "abcd1234",
+					"url": "https://example.com/first",
+					"title": "First post!",
+					"content_html": "<p>Hello, world!</p>",
+					"content_text": "Hello, world!",
+					"date_published": "2019-04-06T02:00:22+00:00",
+					"date_modified": "2019-04-07T10:30:00-05:00",
+					"tags": ["go", " json "],
+					"authors": [
+						{"name": "Alice", "url": "https://example.com/alice"},
+						{"name": "Bob"}
+					],
+					"attachments": [
+						{
+							"url": "https://example.com/first.mp3",
+							"mime_type": "audio/mpeg",
+							"title": "Episode 1",
+							"size_in_bytes": 1024,
+							"duration_in_seconds": 90
+						},
+						{"url": "--script=/tmp/x.lua", "mime_type": "audio/mpeg"},
+						{"url": "file:///etc/passwd", "mime_type": "audio/mpeg"}
+					]
+				}
+			]
+		}`
+
+	r := bytes.NewReader([]byte(feedJson))
+	feed, err := ParseExternalFeed(r)
+	if err != nil {
+		t.Fatalf("Could not parse feed json: %v", err)
+	}
+
+	expectedFeed := Feed{
+		Name:        "My JSON Feed",
+		SiteUrl:     "https://example.com",
+		Description: "Posts about things",
+		Language:    "en-US",
+		ImageUrl:    "https://example.com/favicon.ico",
+		Items: []FeedItem{
+			FeedItem{
+				Title:       "First post!",
+				Date:        time.Unix(1554516022, 0).UTC(),
+				Url:         "https://example.com/first",
+				Guid:        "abcd1234",
+				Updated:     time.Unix(1554651000, 0).UTC(),
+				ContentHtml: "<p>Hello, world!</p>",
+				ContentText: "Hello, world!",
+				Authors: []Author{
+					Author{Name: "Alice", Url: "https://example.com/alice"},
+					Author{Name: "Bob"},
+				},
+				Categories: []string{"go", "json"},
+				Enclosures: []Enclosure{
+					Enclosure{
+						Url:      "https://example.com/first.mp3",
+						MimeType: "audio/mpeg",
+						Title:    "Episode 1",
+						Length:   1024,
+						Duration: 90 * time.Second,
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(feed, expectedFeed) {
+		t.Errorf(
+			"Incorrect values for feed, expected %v but got %v",
+			expectedFeed, feed)
+	}
+}
+
+func TestParseJsonFeedVersion1_0(t *testing.T) {
+	// Version 1.0 has a single author and allows numeric IDs in practice
+	feedJson := "\xef\xbb\xbf" + `
+		{
+			"version": "https://jsonfeed.org/version/1",
+			"title": "Old JSON Feed",
+			"items": [
+				{
+					"id": 42,
+					"external_url": "https://example.com/linked",
+					"content_text": "A short note without a title",
+					"date_modified": "2019-04-06T02:00:22Z",
+					"author": {"name": "Alice"}
+				}
+			]
+		}`
+
+	r := bytes.NewReader([]byte(feedJson))
+	feed, err := ParseExternalFeed(r)
+	if err != nil {
+		t.Fatalf("Could not parse feed json: %v", err)
+	}
+
+	expectedFeed := Feed{
+		Name: "Old JSON Feed",
+		Items: []FeedItem{
+			FeedItem{
+				Title:       "A short note without a title",
+				Date:        time.Unix(1554516022, 0).UTC(),
+				Url:         "https://example.com/linked",
+				Guid:        "42",
+				Updated:     time.Unix(1554516022, 0).UTC(),
+				ContentText: "A short note without a title",
+				Authors:     []Author{Author{Name: "Alice"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(feed, expectedFeed) {
+		t.Errorf(
+			"Incorrect values for feed, expected %v but got %v",
+			expectedFeed, feed)
+	}
+}
+
+func TestParseJsonFeedDerivedTitleIsTruncated(t *testing.T) {
+	feedJson := `
+		{
+			"version": "https://jsonfeed.org/version/1.1",
+			"title": "Microblog",
+			"items": [
+				{
+					"id": "1",
+					"url": "https://example.com/1",
+					"summary": "Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore",
+					"date_published": "2019-04-06T02:00:22Z"
+				}
+			]
+		}`
+
+	r := bytes.NewReader([]byte(feedJson))
+	feed, err := ParseExternalFeed(r)
+	if err != nil {
+		t.Fatalf("Could not parse feed json: %v", err)
+	}
+
+	expectedTitle := "Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor i…"
+	if feed.Items[0].Title != expectedTitle {
+		t.Errorf("Expected title %q but got %q", expectedTitle, feed.Items[0].Title)
+	}
+}
+
+func TestParseJsonFeedInvalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		feedJson string
+	}{
+		{
+			name:     "malformed json",
+			feedJson: `{"version": "https://jsonfeed.org/version/1.1", "title": `,
+		},
+		{
+			name:     "unsupported version",
+			feedJson: `{"version": "2", "title": "Feed", "items": []}`,
+		},
+		{
+			name:     "missing feed title",
+			feedJson: `{"version": "https://jsonfeed.org/version/1.1", "items": []}`,
+		},
+		{
+			name: "missing item id",
+			feedJson: `{"version": "https://jsonfeed.org/version/1.1", "title": "Feed", "items": [
+				{"url": "https://example.com/1", "title": "Post", "date_published": "2019-04-06T02:00:22Z"}
+			]}`,
+		},
+		{
+			name: "missing item url",
+			feedJson: `{"version": "https://jsonfeed.org/version/1.1", "title": "Feed", "items": [
+				{"id": "1", "title": "Post", "date_published": "2019-04-06T02:00:22Z"}
+			]}`,
+		},
+		{
+			name: "missing item date",
+			feedJson: `{"version": "https://jsonfeed.org/version/1.1", "title": "Feed", "items": [
+				{"id": "1", "url": "https://example.com/1", "title": "Post"}
+			]}`,
+		},
+		{
+			name: "invalid item date",
+			feedJson: `{"version": "https://jsonfeed.org/version/1.1", "title": "Feed", "items": [
+				{"id": "1", "url": "https://example.com/1", "title": "Post", "date_published": "yesterday"}
+			]}`,
+		},
+		{
+			name: "missing item title and content",
+			feedJson: `{"version": "https://jsonfeed.org/version/1.1", "title": "Feed", "items": [
+				{"id": "1", "url": "https://example.com/1", "date_published": "2019-04-06T02:00:22Z"}
+			]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := bytes.NewReader([]byte(tc.feedJson))
+			if _, err := ParseExternalFeed(r); err == nil {
+				t.Errorf("Expected error parsing invalid feed")
+			}
+		})
+	}
+}
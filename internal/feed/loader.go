@@ -3,38 +3,151 @@ package feed
 import (
 	"errors"
 	"fmt"
-	"net/http"
-	"time"
+	neturl "net/url"
+	"strings"
 )
 
 // FeedLoader retrieves a feed from a URL and parses it into
-// a standardized format.
+// a standardized format.  The URL's scheme determines which
+// source is used to retrieve the feed (see `Source`).
 type FeedLoader struct {
-	client *http.Client
+	config  LoaderConfig
+	sources map[string]Source
+	http    *httpSource
 }
 
-func NewFeedLoader() *FeedLoader {
-	transport := http.Transport{
-		IdleConnTimeout: 30 * time.Second,
+// NewFeedLoader creates a loader with the specified config,
+// which individual requests may override.
+// The loader supports "http", "https", "file", and "exec" URLs.
+func NewFeedLoader(config LoaderConfig) *FeedLoader {
+	httpSource := newHttpSource()
+	sources := map[string]Source{
+		"http":  httpSource,
+		"https": httpSource,
+		"file":  &fileSource{},
+		"exec":  &execSource{},
 	}
-	client := http.Client{Transport: &transport}
-	return &FeedLoader{&client}
+	return &FeedLoader{config, sources, httpSource}
 }
 
-// LoadFeedFromUrl retrieves a feed and parses it into the standardized format.
+// ValidateUrl returns whether a URL can be loaded by one of the built-in sources
+func ValidateUrl(url string) bool {
+	return NewFeedLoader(LoaderConfig{}).ValidateUrl(url)
+}
+
+// LoadRequest describes how to retrieve a feed
+type LoadRequest struct {
+	Url string
+
+	// Credentials for private feeds
+	Credentials Credentials
+
+	// Additional HTTP headers sent with the request
+	Headers map[string]string
+
+	// Overrides the loader's config for this request.
+	// Fields that aren't set use the loader's config.
+	Config LoaderConfig
+
+	// If set, items are scraped from the HTML page at the URL
+	// instead of parsing it as a feed.  Only HTTP(S) URLs can be scraped.
+	Scrape ScrapeConfig
+}
+
+// minRedactedHeaderLength is the length of the shortest header value
+// removed from error messages.
+const minRedactedHeaderLength int = 4
+
+// RegisterSource adds or replaces the source for a URL scheme.
+// This is NOT thread-safe, so it must be called before loading any feeds.
+func (f *FeedLoader) RegisterSource(scheme string, source Source) {
+	f.sources[strings.ToLower(scheme)] = source
+}
+
+// ValidateUrl returns whether the URL can be loaded by a registered source
+func (f *FeedLoader) ValidateUrl(url string) bool {
+	source, ok := f.sources[urlScheme(url)]
+	return ok && source.ValidateUrl(url)
+}
+
+// LoadFeedFromUrl retrieves a public feed and parses it into the standardized format.
 func (f *FeedLoader) LoadFeedFromUrl(url string) (Feed, error) {
-	resp, err := f.client.Get(url)
-	if err != nil {
-		return Feed{}, err
+	return f.LoadFeed(LoadRequest{Url: url})
+}
+
+// LoadFeed retrieves a feed and parses it into the standardized format.
+// If the URL permanently redirects (HTTP 301 or 308) to another URL,
+// the new URL is returned in the feed's `MovedTo` field.
+// Credentials and header values never appear in the returned error.
+func (f *FeedLoader) LoadFeed(r LoadRequest) (Feed, error) {
+	// Header values often contain API keys, so treat them as secrets.
+	// Very short values (like "1") are skipped, since redacting them
+	// would make error messages unreadable.
+	secrets := make([]string, 0, len(r.Headers))
+	for _, value := range r.Headers {
+		if len(value) >= minRedactedHeaderLength {
+			secrets = append(secrets, value)
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		errMsg := fmt.Sprintf(
-			"Received HTTP status %v from url %v",
-			resp.StatusCode, url)
-		return Feed{}, errors.New(errMsg)
+	// The proxy URL may contain a password
+	config := f.config.Merge(r.Config)
+	if proxyUrl, err := neturl.Parse(config.ProxyUrl); err == nil && proxyUrl.User != nil {
+		if password, ok := proxyUrl.User.Password(); ok {
+			secrets = append(secrets, password)
+		}
 	}
 
-	return ParseExternalFeed(resp.Body)
+	scheme := urlScheme(r.Url)
+	source, ok := f.sources[scheme]
+	if !ok {
+		return Feed{}, fmt.Errorf("Unsupported URL scheme '%v'", scheme)
+	}
+
+	if r.Scrape.IsSet() && !IsScrapeableUrl(r.Url) {
+		return Feed{}, errors.New("Only HTTP(S) pages can be scraped")
+	}
+
+	feed, err := source.Load(r, config)
+	return feed, redactError(err, secrets)
+}
+
+// Page is an HTML page retrieved by `LoadPage`
+type Page struct {
+	// URL of the page after any redirects
+	Url string
+
+	Html []byte
+}
+
+// ErrPageTooLarge means a page exceeded the size limit passed to `LoadPage`
+var ErrPageTooLarge = errors.New("Page exceeds the maximum size")
+
+// LoadPage retrieves the HTML page at an HTTP(S) URL, such as the
+// article linked from a feed item.  Fields set in `config` override
+// the loader's config, as for `LoadRequest.Config`.  Pages larger
+// than `maxBytes` are rejected, unless `maxBytes` is zero.
+func (f *FeedLoader) LoadPage(url string, config LoaderConfig, maxBytes int64) (Page, error) {
+	if !IsScrapeableUrl(url) {
+		return Page{}, errors.New("Only HTTP(S) pages can be retrieved")
+	}
+	return f.http.loadPage(url, f.config.Merge(config), maxBytes)
+}
+
+// IsScrapeableUrl returns whether items can be scraped from the URL
+// (see `LoadRequest.Scrape`).
+func IsScrapeableUrl(url string) bool {
+	scheme := urlScheme(url)
+	return (scheme == "http" || scheme == "https") && ValidateUrl(url)
+}
+
+// urlScheme returns the lowercase scheme of a URL, or an empty string.
+// This doesn't fully parse the URL, since some sources
+// (like "exec") accept URLs that aren't otherwise valid.
+func urlScheme(url string) string {
+	idx := strings.Index(url, ":")
+	if idx < 0 {
+		return ""
+	}
+	return strings.ToLower(url[:idx])
 }
@@ -2,9 +2,16 @@ package feed
 
 import (
 	"fmt"
+	"github.com/wedaly/local-news/internal/command"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadRssFromUrl(t *testing.T) {
@@ -29,7 +36,7 @@ func TestLoadRssFromUrl(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(handler))
 	defer server.Close()
 
-	loader := NewFeedLoader()
+	loader := NewFeedLoader(DefaultLoaderConfig())
 	feed, err := loader.LoadFeedFromUrl(server.URL)
 	if err != nil {
 		t.Fatalf("Error loading feed from test server: %v", err)
@@ -39,3 +46,327 @@ func TestLoadRssFromUrl(t *testing.T) {
 		t.Errorf("Incorrect data from loaded feed (num feed items)")
 	}
 }
+
+func TestLoadFeedFollowsRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `<rss><channel><title>Feed</title></channel></rss>`)
+	})
+	mux.Handle("/moved", http.RedirectHandler("/feed", http.StatusMovedPermanently))
+	mux.Handle("/permanent", http.RedirectHandler("/feed", http.StatusPermanentRedirect))
+	mux.Handle("/found", http.RedirectHandler("/feed", http.StatusFound))
+	mux.Handle("/temporary", http.RedirectHandler("/feed", http.StatusTemporaryRedirect))
+	mux.Handle("/chain", http.RedirectHandler("/found", http.StatusMovedPermanently))
+	mux.Handle("/chain-temp", http.RedirectHandler("/moved", http.StatusFound))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	testCases := []struct {
+		path            string
+		expectedMovedTo string
+	}{
+		{"/feed", ""},
+		{"/moved", server.URL + "/feed"},
+		{"/permanent", server.URL + "/feed"},
+		{"/found", ""},
+		{"/temporary", ""},
+		{"/chain", server.URL + "/found"},
+		{"/chain-temp", ""},
+	}
+
+	loader := NewFeedLoader(DefaultLoaderConfig())
+	for _, tc := range testCases {
+		feed, err := loader.LoadFeedFromUrl(server.URL + tc.path)
+		if err != nil {
+			t.Fatalf("Error loading feed from test server: %v", err)
+		}
+
+		if feed.MovedTo != tc.expectedMovedTo {
+			t.Errorf(
+				"Incorrect moved URL for %v, expected '%v' but got '%v'",
+				tc.path, tc.expectedMovedTo, feed.MovedTo)
+		}
+	}
+}
+
+func TestLoadPage(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<html><body><p>Article</p></body></html>")
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, strings.Repeat("<p>Article</p>", 1000))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "PNG")
+	})
+	mux.Handle("/moved", http.RedirectHandler("/article", http.StatusFound))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	loader := NewFeedLoader(DefaultLoaderConfig())
+	page, err := loader.LoadPage(server.URL+"/moved", LoaderConfig{}, 1000)
+	if err != nil {
+		t.Fatalf("Could not load page: %v", err)
+	}
+
+	if page.Url != server.URL+"/article" || string(page.Html) != "<html><body><p>Article</p></body></html>" {
+		t.Errorf("Unexpected page %v: %q", page.Url, page.Html)
+	}
+
+	if _, err := loader.LoadPage(server.URL+"/large", LoaderConfig{}, 1000); err != ErrPageTooLarge {
+		t.Errorf("Expected page to be too large, but got %v", err)
+	}
+
+	if _, err := loader.LoadPage(server.URL+"/large", LoaderConfig{}, 0); err != nil {
+		t.Errorf("Expected no size limit, but got %v", err)
+	}
+
+	if _, err := loader.LoadPage(server.URL+"/image", LoaderConfig{}, 0); err == nil {
+		t.Errorf("Expected error for an image")
+	}
+
+	if _, err := loader.LoadPage("file:///etc/passwd", LoaderConfig{}, 0); err == nil {
+		t.Errorf("Expected error for a local file")
+	}
+}
+
+func TestLoadFeedWithCredentials(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/basic" {
+			username, password, ok := r.BasicAuth()
+			if !ok || username != "alice" || password != "hunter2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		} else if r.URL.Path == "/bearer" {
+			if r.Header.Get("Authorization") != "Bearer s3cr3t-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if r.Header.Get("X-Api-Key") != "abcd1234" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		fmt.Fprintln(w, `<rss><channel><title>Private</title></channel></rss>`)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	headers := map[string]string{"X-Api-Key": "abcd1234"}
+	testCases := []LoadRequest{
+		LoadRequest{
+			Url:         server.URL + "/basic",
+			Credentials: Credentials{Scheme: AuthBasic, Username: "alice", Secret: "hunter2"},
+			Headers:     headers,
+		},
+		LoadRequest{
+			Url:         server.URL + "/basic",
+			Credentials: Credentials{Scheme: AuthBasic, Username: "alice", SecretCommand: "echo hunter2"},
+			Headers:     headers,
+		},
+		LoadRequest{
+			Url:         server.URL + "/bearer",
+			Credentials: Credentials{Scheme: AuthBearer, Secret: "s3cr3t-token"},
+			Headers:     headers,
+		},
+	}
+
+	loader := NewFeedLoader(DefaultLoaderConfig())
+	for _, req := range testCases {
+		feed, err := loader.LoadFeed(req)
+		if err != nil {
+			t.Errorf("Error loading feed with credentials %v: %v", req.Credentials, err)
+		} else if feed.Name != "Private" {
+			t.Errorf("Incorrect feed name, got %v", feed.Name)
+		}
+	}
+}
+
+func TestLoadFeedRedirectOmitsHeaders(t *testing.T) {
+	receivedKeys := make(map[string]string)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedKeys[r.URL.Path] = r.Header.Get("Private-Token")
+		fmt.Fprintln(w, `<rss><channel><title>Feed</title></channel></rss>`)
+	}))
+	defer target.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle("/same-host", http.RedirectHandler("/feed", http.StatusFound))
+	mux.Handle("/other-host", http.RedirectHandler(target.URL+"/other", http.StatusFound))
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		receivedKeys[r.URL.Path] = r.Header.Get("Private-Token")
+		fmt.Fprintln(w, `<rss><channel><title>Feed</title></channel></rss>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	loader := NewFeedLoader(DefaultLoaderConfig())
+	headers := map[string]string{"Private-Token": "s3cret"}
+	for _, path := range []string{"/same-host", "/other-host"} {
+		if _, err := loader.LoadFeed(LoadRequest{Url: server.URL + path, Headers: headers}); err != nil {
+			t.Fatalf("Could not load feed from %v: %v", path, err)
+		}
+	}
+
+	if receivedKeys["/feed"] != "s3cret" {
+		t.Errorf("Expected headers to be sent after a redirect to the same host")
+	}
+
+	if key, ok := receivedKeys["/other"]; !ok || key != "" {
+		t.Errorf("Expected no headers after a redirect to another host, but got %q", key)
+	}
+}
+
+func TestSecretCommandTimeout(t *testing.T) {
+	req := LoadRequest{
+		Url:         "http://127.0.0.1:1/feed.xml",
+		Credentials: Credentials{Scheme: AuthBearer, SecretCommand: "sleep 10"},
+		Config:      LoaderConfig{Timeout: 50 * time.Millisecond},
+	}
+
+	loader := NewFeedLoader(DefaultLoaderConfig())
+	start := time.Now()
+	_, err := loader.LoadFeed(req)
+	if err == nil || !strings.Contains(err.Error(), command.ErrTimeout.Error()) {
+		t.Errorf("Expected timeout error, but got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Secret command wasn't killed after the timeout, took %v", elapsed)
+	}
+}
+
+func TestLoadFeedErrorOmitsCredentials(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	serverUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Could not parse server URL: %v", err)
+	}
+	serverUrl.User = url.UserPassword("alice", "urlpassword")
+
+	testCases := []LoadRequest{
+		LoadRequest{
+			Url:         serverUrl.String(),
+			Credentials: Credentials{Scheme: AuthBasic, Username: "alice", Secret: "hunter2"},
+		},
+		LoadRequest{
+			Url:         "http://127.0.0.1:1/hunter2",
+			Credentials: Credentials{Scheme: AuthBearer, Secret: "hunter2"},
+		},
+		LoadRequest{
+			Url:         server.URL,
+			Credentials: Credentials{Scheme: AuthBearer, SecretCommand: "echo hunter2; exit 1"},
+		},
+		LoadRequest{
+			Url:         server.URL,
+			Credentials: Credentials{Scheme: AuthBearer, SecretCommand: "echo hunter2 >&2; exit 1"},
+		},
+		LoadRequest{
+			Url:     "http://127.0.0.1:1/hunter2",
+			Headers: map[string]string{"X-Api-Key": "hunter2"},
+		},
+	}
+
+	loader := NewFeedLoader(DefaultLoaderConfig())
+	for _, req := range testCases {
+		_, err := loader.LoadFeed(req)
+		if err == nil {
+			t.Fatalf("Expected error loading feed from %v", req.Url)
+		}
+
+		msg := err.Error()
+		if strings.Contains(msg, "hunter2") || strings.Contains(msg, "urlpassword") {
+			t.Errorf("Error message contains credentials: %v", msg)
+		}
+	}
+}
+
+func TestLoadFeedFromFile(t *testing.T) {
+	feedPath := path.Join(os.TempDir(), "test-loader-feed.xml")
+	defer os.Remove(feedPath)
+	if err := ioutil.WriteFile(feedPath, []byte(minimalRssXml), 0600); err != nil {
+		t.Fatalf("Could not write feed file: %v", err)
+	}
+
+	loader := NewFeedLoader(DefaultLoaderConfig())
+	feed, err := loader.LoadFeedFromUrl("file://" + feedPath)
+	if err != nil {
+		t.Fatalf("Error loading feed from file: %v", err)
+	}
+
+	if feed.Name != "Feed" {
+		t.Errorf("Incorrect feed name, got %v", feed.Name)
+	}
+
+	if _, err := loader.LoadFeedFromUrl("file:///does/not/exist.xml"); err == nil {
+		t.Errorf("Expected error loading missing file")
+	}
+}
+
+func TestLoadFeedFromCommand(t *testing.T) {
+	loader := NewFeedLoader(DefaultLoaderConfig())
+	feed, err := loader.LoadFeedFromUrl("exec:echo '" + minimalRssXml + "'")
+	if err != nil {
+		t.Fatalf("Error loading feed from command: %v", err)
+	}
+
+	if feed.Name != "Feed" {
+		t.Errorf("Incorrect feed name, got %v", feed.Name)
+	}
+
+	_, err = loader.LoadFeedFromUrl("exec:echo 'oops' >&2; exit 3")
+	if err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("Expected error with command output, but got %v", err)
+	}
+
+	req := LoadRequest{
+		Url:    "exec:sleep 10",
+		Config: LoaderConfig{Timeout: 50 * time.Millisecond},
+	}
+	if _, err := loader.LoadFeed(req); err == nil {
+		t.Errorf("Expected timeout error")
+	}
+}
+
+func TestValidateUrl(t *testing.T) {
+	testCases := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://example.com/feed.xml", true},
+		{"http://example.com", true},
+		{"HTTP://example.com", true},
+		{"http://", false},
+		{"example.com/feed.xml", false},
+		{"file:///home/user/feed.xml", true},
+		{"file://localhost/home/user/feed.xml", true},
+		{"file://otherhost/feed.xml", false},
+		{"file://", false},
+		{"exec:~/bin/report --rss", true},
+		{"exec:", false},
+		{"ftp://example.com/feed.xml", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		if valid := ValidateUrl(tc.url); valid != tc.valid {
+			t.Errorf("Expected valid=%v for URL '%v', but got %v", tc.valid, tc.url, valid)
+		}
+	}
+}
@@ -1,14 +1,30 @@
 package feed
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
 	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+	"github.com/mmcdole/gofeed/rss"
 	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 )
 
+// ParseExternalFeed parses an RSS, Atom, or JSON feed into the standardized format.
 func ParseExternalFeed(r io.Reader) (Feed, error) {
+	br := bufio.NewReader(r)
+	if isJsonContent(br) {
+		return parseJsonFeed(br)
+	}
+
+	translator := &refreshHintTranslator{}
 	parser := gofeed.NewParser()
-	rawFeed, err := parser.Parse(r)
+	parser.RSSTranslator = translator
+	rawFeed, err := parser.Parse(br)
 	if err != nil {
 		return Feed{}, err
 	}
@@ -18,10 +34,21 @@ func ParseExternalFeed(r io.Reader) (Feed, error) {
 	}
 
 	feed := Feed{
-		Name:  rawFeed.Title,
-		Items: make([]FeedItem, 0, len(rawFeed.Items)),
+		Name:        rawFeed.Title,
+		Items:       make([]FeedItem, 0, len(rawFeed.Items)),
+		SiteUrl:     rawFeed.Link,
+		Description: rawFeed.Description,
+		Language:    rawFeed.Language,
+	}
+
+	if rawFeed.Image != nil {
+		feed.ImageUrl = rawFeed.Image.URL
 	}
 
+	// The syndication module can appear in both RSS and Atom feeds
+	feed.RefreshHints = translator.hints
+	feed.RefreshHints.UpdateInterval = parseSyndicationInterval(rawFeed.Extensions)
+
 	for _, rawItem := range rawFeed.Items {
 		// GUID isn't required by the RSS specification,
 		// so fallback to using the item URL.
@@ -36,12 +63,157 @@ func ParseExternalFeed(r io.Reader) (Feed, error) {
 			Url:   rawItem.Link,
 			Guid:  guid,
 		}
+
+		if rawItem.UpdatedParsed != nil {
+			item.Updated = *rawItem.UpdatedParsed
+		}
+
+		// RSS feeds often put the full content in the description
+		item.ContentHtml = rawItem.Content
+		if len(item.ContentHtml) == 0 {
+			item.ContentHtml = rawItem.Description
+		}
+
+		if rawItem.Author != nil && len(rawItem.Author.Name)+len(rawItem.Author.Email) > 0 {
+			author := Author{Name: rawItem.Author.Name, Email: rawItem.Author.Email}
+			item.Authors = append(item.Authors, author)
+		}
+
+		for _, category := range rawItem.Categories {
+			if category = strings.TrimSpace(category); len(category) > 0 {
+				item.Categories = append(item.Categories, category)
+			}
+		}
+
+		for _, rawEnclosure := range rawItem.Enclosures {
+			if rawEnclosure == nil || !IsEnclosureUrl(rawEnclosure.URL) {
+				continue
+			}
+
+			length, _ := strconv.ParseInt(rawEnclosure.Length, 10, 64)
+			enclosure := Enclosure{
+				Url:      rawEnclosure.URL,
+				MimeType: rawEnclosure.Type,
+				Length:   length,
+			}
+			item.Enclosures = append(item.Enclosures, enclosure)
+		}
+
 		feed.Items = append(feed.Items, item)
 	}
 
 	return feed, nil
 }
 
+// refreshHintTranslator records the refresh hints of an RSS feed,
+// which aren't included in gofeed's universal format.
+type refreshHintTranslator struct {
+	gofeed.DefaultRSSTranslator
+	hints RefreshHints
+}
+
+func (t *refreshHintTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	if rssFeed, ok := feed.(*rss.Feed); ok {
+		t.hints = RefreshHints{
+			TTL:       parseTTL(rssFeed.TTL),
+			SkipHours: parseSkipHours(rssFeed.SkipHours),
+			SkipDays:  parseSkipDays(rssFeed.SkipDays),
+		}
+	}
+	return t.DefaultRSSTranslator.Translate(feed)
+}
+
+// parseTTL parses the number of minutes in an RSS <ttl> element,
+// returning zero if it is missing or invalid.
+func parseTTL(s string) time.Duration {
+	minutes, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// parseSkipHours parses the hours in an RSS <skipHours> element,
+// ignoring invalid values.  Some feeds use 24 for midnight.
+func parseSkipHours(values []string) []int {
+	var hours []int
+	for _, value := range values {
+		hour, err := strconv.Atoi(strings.TrimSpace(value))
+		if err == nil && hour >= 0 && hour <= 24 {
+			hours = append(hours, hour%24)
+		}
+	}
+	return hours
+}
+
+// parseSkipDays parses the days in an RSS <skipDays> element,
+// ignoring invalid values.
+func parseSkipDays(values []string) []time.Weekday {
+	var days []time.Weekday
+	for _, value := range values {
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.EqualFold(strings.TrimSpace(value), d.String()) {
+				days = append(days, d)
+			}
+		}
+	}
+	return days
+}
+
+// parseSyndicationInterval returns how often the feed is updated according
+// to the syndication module (sy:updatePeriod and sy:updateFrequency),
+// or zero if the feed doesn't say.
+func parseSyndicationInterval(extensions ext.Extensions) time.Duration {
+	sy, ok := extensions["sy"]
+	if !ok {
+		return 0
+	}
+
+	extensionValue := func(name string) string {
+		if values := sy[name]; len(values) > 0 {
+			return strings.TrimSpace(values[0].Value)
+		}
+		return ""
+	}
+
+	periods := map[string]time.Duration{
+		"hourly":  time.Hour,
+		"daily":   24 * time.Hour,
+		"weekly":  7 * 24 * time.Hour,
+		"monthly": 30 * 24 * time.Hour,
+		"yearly":  365 * 24 * time.Hour,
+	}
+
+	// The module's defaults are "daily" and 1
+	periodName, frequencyText := extensionValue("updatePeriod"), extensionValue("updateFrequency")
+	if len(periodName) == 0 && len(frequencyText) == 0 {
+		return 0
+	}
+
+	period, ok := periods[strings.ToLower(periodName)]
+	if !ok {
+		period = periods["daily"]
+	}
+
+	frequency, err := strconv.Atoi(frequencyText)
+	if err != nil || frequency <= 0 {
+		frequency = 1
+	}
+
+	return period / time.Duration(frequency)
+}
+
+// isJsonContent returns whether the content looks like a JSON object,
+// ignoring any byte order mark and leading whitespace.
+// This doesn't consume any input from the reader.
+func isJsonContent(br *bufio.Reader) bool {
+	// The buffer is large enough for any reasonable amount of leading whitespace
+	prefix, _ := br.Peek(512)
+	prefix = bytes.TrimPrefix(prefix, []byte("\xef\xbb\xbf"))
+	prefix = bytes.TrimLeftFunc(prefix, unicode.IsSpace)
+	return len(prefix) > 0 && prefix[0] == '{'
+}
+
 func validateFeed(rawFeed *gofeed.Feed) error {
 	if len(rawFeed.Title) == 0 {
 		return errors.New("Missing feed name")
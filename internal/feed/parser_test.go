@@ -3,6 +3,7 @@ package feed
 import (
 	"bytes"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -36,7 +37,8 @@ func TestParseRssFeed(t *testing.T) {
 	}
 
 	expectedFeed := Feed{
-		Name: "Blog – My RSS Feed",
+		Name:    "Blog – My RSS Feed",
+		SiteUrl: "https://example.com",
 		Items: []FeedItem{
 			FeedItem{
 				Title: "First post!",
@@ -86,3 +88,141 @@ func TestParseFeedIgnoreAtomNamespace(t *testing.T) {
 		t.Errorf("Incorrect URL for item link")
 	}
 }
+
+func TestParseRssFeedMetadata(t *testing.T) {
+	rssXml := `
+		<?xml version="1.0" encoding="UTF-8"?>
+		<rss xmlns:dc="http://purl.org/dc/elements/1.1/">
+			<channel>
+				<title>Podcast</title>
+				<link>https://example.com</link>
+				<description>A show about things</description>
+				<language>en-us</language>
+				<image>
+					<url>https://example.com/logo.png</url>
+					<title>Podcast</title>
+					<link>https://example.com</link>
+				</image>
+				<item>
+					<title>Episode 1</title>
+					<link>https://example.com/1</link>
+					<description>&lt;p&gt;Show notes&lt;/p&gt;</description>
+					<pubDate>Sat, 06 Apr 2019 02:00:22 +0000</pubDate>
+					<dc:creator>Alice</dc:creator>
+					<category>Science</category>
+					<category> History </category>
+					<enclosure url="https://example.com/1.mp3" type="audio/mpeg" length="1024"/>
+				</item>
+			</channel>
+		</rss>`
+
+	r := bytes.NewReader([]byte(rssXml))
+	feed, err := ParseExternalFeed(r)
+	if err != nil {
+		t.Fatalf("Could not parse feed xml: %v", err)
+	}
+
+	expectedFeed := Feed{
+		Name:        "Podcast",
+		SiteUrl:     "https://example.com",
+		Description: "A show about things",
+		Language:    "en-us",
+		ImageUrl:    "https://example.com/logo.png",
+		Items: []FeedItem{
+			FeedItem{
+				Title:       "Episode 1",
+				Date:        time.Unix(1554516022, 0).UTC(),
+				Url:         "https://example.com/1",
+				Guid:        "https://example.com/1",
+				ContentHtml: "<p>Show notes</p>",
+				Authors:     []Author{Author{Name: "Alice"}},
+				Categories:  []string{"Science", "History"},
+				Enclosures: []Enclosure{
+					Enclosure{
+						Url:      "https://example.com/1.mp3",
+						MimeType: "audio/mpeg",
+						Length:   1024,
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(feed, expectedFeed) {
+		t.Errorf(
+			"Incorrect values for feed, expected %+v but got %+v",
+			expectedFeed, feed)
+	}
+}
+
+func TestParseRssFeedIgnoresInvalidEnclosureUrl(t *testing.T) {
+	rssXml := `
+		<rss version="2.0">
+			<channel>
+				<title>Podcast</title>
+				<item>
+					<title>Episode 1</title>
+					<link>https://example.com/1</link>
+					<guid>1</guid>
+					<pubDate>Sat, 06 Apr 2019 02:00:22 +0000</pubDate>
+					<enclosure url="--script=/tmp/x.lua" type="audio/mpeg" length="1024"/>
+				</item>
+			</channel>
+		</rss>`
+
+	feed, err := ParseExternalFeed(bytes.NewReader([]byte(rssXml)))
+	if err != nil {
+		t.Fatalf("Could not parse feed: %v", err)
+	}
+
+	if len(feed.Items) != 1 || len(feed.Items[0].Enclosures) != 0 {
+		t.Errorf("Expected an item without enclosures, but got %v", feed.Items)
+	}
+}
+
+func TestParseRssFeedRefreshHints(t *testing.T) {
+	rssXml := `
+		<?xml version="1.0" encoding="UTF-8"?>
+		<rss xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">
+			<channel>
+				<title>Hints</title>
+				<ttl>90</ttl>
+				<skipHours><hour>0</hour><hour>24</hour><hour>7</hour><hour>bogus</hour></skipHours>
+				<skipDays><day>Saturday</day><day>sunday</day></skipDays>
+				<sy:updatePeriod>daily</sy:updatePeriod>
+				<sy:updateFrequency>4</sy:updateFrequency>
+			</channel>
+		</rss>`
+
+	feed, err := ParseExternalFeed(bytes.NewReader([]byte(rssXml)))
+	if err != nil {
+		t.Fatalf("Could not parse feed xml: %v", err)
+	}
+
+	expectedHints := RefreshHints{
+		TTL:            90 * time.Minute,
+		UpdateInterval: 6 * time.Hour,
+		SkipHours:      []int{0, 0, 7},
+		SkipDays:       []time.Weekday{time.Saturday, time.Sunday},
+	}
+	if !reflect.DeepEqual(feed.RefreshHints, expectedHints) {
+		t.Errorf("Expected hints %v, but got %v", expectedHints, feed.RefreshHints)
+	}
+}
+
+func TestParseAtomFeedSyndicationHints(t *testing.T) {
+	atomXml := `
+		<?xml version="1.0" encoding="utf-8"?>
+		<feed xmlns="http://www.w3.org/2005/Atom" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">
+			<title>Hourly</title>
+			<sy:updatePeriod>hourly</sy:updatePeriod>
+		</feed>`
+
+	feed, err := ParseExternalFeed(bytes.NewReader([]byte(strings.TrimSpace(atomXml))))
+	if err != nil {
+		t.Fatalf("Could not parse feed xml: %v", err)
+	}
+
+	if feed.RefreshHints.UpdateInterval != time.Hour {
+		t.Errorf("Expected hourly updates, but got %v", feed.RefreshHints.UpdateInterval)
+	}
+}
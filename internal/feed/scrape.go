@@ -0,0 +1,195 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"io"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+// ScrapeConfig describes how to extract feed items from an HTML page
+// that isn't a feed.  Each field is a CSS selector, such as "article h2".
+type ScrapeConfig struct {
+	// Selects the element containing each item.
+	// If empty, the page is parsed as a regular feed.
+	ItemSelector string
+
+	// Selects the item's title within the item element.
+	// If empty, the text of the item element is used.
+	TitleSelector string
+
+	// Selects the item's link within the item element.
+	// If empty, the first link in the item element is used.
+	LinkSelector string
+
+	// Selects the item's date within the item element.
+	// If empty, or if the date can't be parsed, the item's date is unknown
+	// (see `FeedItem.Date`).
+	DateSelector string
+}
+
+// IsSet returns whether the config is for a scraped page
+func (c ScrapeConfig) IsSet() bool {
+	return len(c.ItemSelector) > 0
+}
+
+// Validate returns an error if any of the selectors are invalid
+func (c ScrapeConfig) Validate() error {
+	if !c.IsSet() {
+		return nil
+	}
+
+	selectors := []string{c.ItemSelector, c.TitleSelector, c.LinkSelector, c.DateSelector}
+	for _, selector := range selectors {
+		if len(selector) == 0 {
+			continue
+		}
+
+		if _, err := cascadia.Compile(selector); err != nil {
+			return fmt.Errorf("Invalid selector '%v'", selector)
+		}
+	}
+
+	return nil
+}
+
+// scrapeDateLayouts are the date formats recognized in scraped pages,
+// in the order they are tried.
+var scrapeDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"01/02/2006",
+}
+
+// ScrapeFeed extracts feed items from an HTML page.
+// Relative links are resolved against the page's URL, and each item's
+// GUID is its resolved link, so items with the same link are merged.
+func ScrapeFeed(r io.Reader, pageUrl string, config ScrapeConfig) (Feed, error) {
+	if err := config.Validate(); err != nil {
+		return Feed{}, err
+	}
+
+	baseUrl, err := neturl.Parse(pageUrl)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	// The <base> element, if present, overrides the page URL for relative links
+	if baseHref, ok := doc.Find("head base[href]").First().Attr("href"); ok {
+		if u, err := baseUrl.Parse(baseHref); err == nil {
+			baseUrl = u
+		}
+	}
+
+	feed := Feed{
+		Name:  collapseSpace(doc.Find("head title").First().Text()),
+		Items: make([]FeedItem, 0),
+	}
+	if len(feed.Name) == 0 {
+		feed.Name = baseUrl.Host
+	}
+
+	seen := make(map[string]bool, 0)
+	doc.Find(config.ItemSelector).Each(func(_ int, s *goquery.Selection) {
+		item, ok := scrapeItem(s, baseUrl, config)
+		if ok && !seen[item.Guid] {
+			seen[item.Guid] = true
+			feed.Items = append(feed.Items, item)
+		}
+	})
+
+	if len(feed.Items) == 0 {
+		return Feed{}, fmt.Errorf("No items found matching '%v'", config.ItemSelector)
+	}
+
+	return feed, nil
+}
+
+// scrapeItem extracts an item from the selected element.
+// The second return value is false if the element has no usable link.
+func scrapeItem(s *goquery.Selection, baseUrl *neturl.URL, config ScrapeConfig) (FeedItem, bool) {
+	link := scrapeLink(s, config.LinkSelector)
+	if len(link) == 0 {
+		return FeedItem{}, false
+	}
+
+	linkUrl, err := baseUrl.Parse(link)
+	if err != nil || (linkUrl.Scheme != "http" && linkUrl.Scheme != "https") {
+		return FeedItem{}, false
+	}
+	linkUrl.Fragment = ""
+	url := linkUrl.String()
+
+	titleSelection := s
+	if len(config.TitleSelector) > 0 {
+		titleSelection = s.Find(config.TitleSelector).First()
+	}
+	title := collapseSpace(titleSelection.Text())
+	if len(title) == 0 {
+		title = url
+	}
+
+	item := FeedItem{
+		Title: title,
+		Url:   url,
+		Guid:  url,
+	}
+
+	if len(config.DateSelector) > 0 {
+		item.Date, _ = scrapeDate(s.Find(config.DateSelector).First())
+	}
+
+	return item, true
+}
+
+func scrapeLink(s *goquery.Selection, linkSelector string) string {
+	var linkSelection *goquery.Selection
+	if len(linkSelector) > 0 {
+		linkSelection = s.Find(linkSelector).First()
+	} else if s.Is("a[href]") {
+		linkSelection = s
+	} else {
+		linkSelection = s.Find("a[href]").First()
+	}
+
+	href, _ := linkSelection.Attr("href")
+	return strings.TrimSpace(href)
+}
+
+// scrapeDate parses the date in an element, preferring the
+// machine-readable "datetime" attribute of <time> elements.
+func scrapeDate(s *goquery.Selection) (time.Time, error) {
+	text, ok := s.Attr("datetime")
+	if !ok {
+		text = collapseSpace(s.Text())
+	}
+
+	for _, layout := range scrapeDateLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, errors.New("Could not parse date")
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,182 @@
+package feed
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const scrapeHtml = `
+	<!DOCTYPE html>
+	<html>
+		<head><title>  News
+			Page </title></head>
+		<body>
+			<nav><a href="/about">About</a></nav>
+			<article>
+				<h2>First <em>story</em></h2>
+				<a class="more" href="/stories/1#comments">Comments</a>
+				<a class="permalink" href="/stories/1">Read more</a>
+				<time datetime="2019-04-06T02:00:22Z">April 6</time>
+			</article>
+			<article>
+				<h2>Second story</h2>
+				<a class="permalink" href="https://other.example.com/2">Read more</a>
+				<span class="date">Apr 9, 2020</span>
+			</article>
+			<article>
+				<h2>No link</h2>
+			</article>
+			<article>
+				<h2>Duplicate of first</h2>
+				<a class="permalink" href="/stories/1">Read more</a>
+			</article>
+		</body>
+	</html>`
+
+func TestScrapeFeed(t *testing.T) {
+	config := ScrapeConfig{
+		ItemSelector:  "article",
+		TitleSelector: "h2",
+		LinkSelector:  "a.permalink",
+		DateSelector:  "time, .date",
+	}
+
+	r := bytes.NewReader([]byte(scrapeHtml))
+	feed, err := ScrapeFeed(r, "https://example.com/news/", config)
+	if err != nil {
+		t.Fatalf("Could not scrape page: %v", err)
+	}
+
+	expectedFeed := Feed{
+		Name: "News Page",
+		Items: []FeedItem{
+			FeedItem{
+				Title: "First story",
+				Date:  time.Unix(1554516022, 0).UTC(),
+				Url:   "https://example.com/stories/1",
+				Guid:  "https://example.com/stories/1",
+			},
+			FeedItem{
+				Title: "Second story",
+				Date:  time.Date(2020, 4, 9, 0, 0, 0, 0, time.UTC),
+				Url:   "https://other.example.com/2",
+				Guid:  "https://other.example.com/2",
+			},
+		},
+	}
+	if !reflect.DeepEqual(feed, expectedFeed) {
+		t.Errorf(
+			"Incorrect values for feed, expected %v but got %v",
+			expectedFeed, feed)
+	}
+}
+
+func TestScrapeFeedDefaultSelectors(t *testing.T) {
+	html := `
+		<html><body>
+			<ul>
+				<li><a href="one.html">One</a></li>
+				<li><a href="two.html">Two</a></li>
+			</ul>
+		</body></html>`
+
+	config := ScrapeConfig{ItemSelector: "li a"}
+	r := bytes.NewReader([]byte(html))
+	feed, err := ScrapeFeed(r, "https://example.com/list/index.html", config)
+	if err != nil {
+		t.Fatalf("Could not scrape page: %v", err)
+	}
+
+	// Without a <title>, the feed is named after the host
+	expectedFeed := Feed{
+		Name: "example.com",
+		Items: []FeedItem{
+			FeedItem{
+				Title: "One",
+				Url:   "https://example.com/list/one.html",
+				Guid:  "https://example.com/list/one.html",
+			},
+			FeedItem{
+				Title: "Two",
+				Url:   "https://example.com/list/two.html",
+				Guid:  "https://example.com/list/two.html",
+			},
+		},
+	}
+	if !reflect.DeepEqual(feed, expectedFeed) {
+		t.Errorf(
+			"Incorrect values for feed, expected %v but got %v",
+			expectedFeed, feed)
+	}
+}
+
+func TestScrapeFeedNoItems(t *testing.T) {
+	config := ScrapeConfig{ItemSelector: "div.missing"}
+	r := bytes.NewReader([]byte(scrapeHtml))
+	if _, err := ScrapeFeed(r, "https://example.com", config); err == nil {
+		t.Errorf("Expected error when no items match")
+	}
+}
+
+func TestScrapeConfigValidate(t *testing.T) {
+	testCases := []struct {
+		config  ScrapeConfig
+		isValid bool
+	}{
+		{config: ScrapeConfig{}, isValid: true},
+		{config: ScrapeConfig{ItemSelector: "article > h2"}, isValid: true},
+		{config: ScrapeConfig{ItemSelector: "article", DateSelector: "time[datetime]"}, isValid: true},
+		{config: ScrapeConfig{ItemSelector: "article["}, isValid: false},
+		{config: ScrapeConfig{ItemSelector: "article", LinkSelector: "a)"}, isValid: false},
+	}
+
+	for _, tc := range testCases {
+		err := tc.config.Validate()
+		if tc.isValid && err != nil {
+			t.Errorf("Expected %v to be valid, got %v", tc.config, err)
+		} else if !tc.isValid && err == nil {
+			t.Errorf("Expected %v to be invalid", tc.config)
+		}
+	}
+}
+
+func TestLoadScrapedFeedResolvesLinksAfterRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/news/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `<html><body><h2><a href="story">Story</a></h2></body></html>`)
+	})
+	mux.Handle("/old", http.RedirectHandler("/news/", http.StatusFound))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	loader := NewFeedLoader(DefaultLoaderConfig())
+	feed, err := loader.LoadFeed(LoadRequest{
+		Url:    server.URL + "/old",
+		Scrape: ScrapeConfig{ItemSelector: "h2"},
+	})
+	if err != nil {
+		t.Fatalf("Could not load scraped feed: %v", err)
+	}
+
+	expectedUrl := server.URL + "/news/story"
+	if len(feed.Items) != 1 || feed.Items[0].Url != expectedUrl {
+		t.Errorf("Expected one item with URL %v, got %v", expectedUrl, feed.Items)
+	}
+}
+
+func TestLoadScrapedFeedRequiresHttp(t *testing.T) {
+	loader := NewFeedLoader(DefaultLoaderConfig())
+	_, err := loader.LoadFeed(LoadRequest{
+		Url:    "file:///tmp/page.html",
+		Scrape: ScrapeConfig{ItemSelector: "h2"},
+	})
+	if err == nil {
+		t.Errorf("Expected error scraping a file URL")
+	}
+}
@@ -0,0 +1,14 @@
+package feed
+
+// Source retrieves feeds from locations identified by URLs.
+// Each source is registered with a `FeedLoader` for one or more URL schemes.
+type Source interface {
+	// ValidateUrl returns whether the URL identifies
+	// a location the source can load.
+	ValidateUrl(url string) bool
+
+	// Load retrieves and parses the feed specified by the request.
+	// The config is the loader's config merged with the request's config.
+	// This must be thread-safe.
+	Load(r LoadRequest, config LoaderConfig) (Feed, error)
+}
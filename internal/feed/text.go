@@ -0,0 +1,108 @@
+package feed
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"strings"
+)
+
+// HtmlToText converts item content from HTML to plain text for display
+// in the terminal.  Block elements start new lines, list items are
+// prefixed with a bullet, and scripts and styles are removed.
+func HtmlToText(s string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	skipDepth, preDepth := 0, 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			// Either the end of the input or malformed HTML,
+			// so return whatever text was found.
+			return normalizeText(b.String())
+
+		case html.TextToken:
+			if skipDepth > 0 {
+				break
+			}
+
+			// Line breaks in the source are just whitespace,
+			// except in preformatted text.
+			text := string(z.Text())
+			if preDepth == 0 {
+				text = strings.Replace(text, "\n", " ", -1)
+			}
+			b.WriteString(text)
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			a := atom.Lookup(name)
+			switch a {
+			case atom.Script, atom.Style:
+				if tt == html.StartTagToken {
+					skipDepth++
+				}
+			case atom.Br:
+				b.WriteString("\n")
+			case atom.Li:
+				b.WriteString("\n• ")
+			default:
+				if a == atom.Pre && tt == html.StartTagToken {
+					preDepth++
+				}
+				if isBlockElement(a) {
+					b.WriteString("\n\n")
+				}
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			a := atom.Lookup(name)
+			switch a {
+			case atom.Script, atom.Style:
+				if skipDepth > 0 {
+					skipDepth--
+				}
+			default:
+				if a == atom.Pre && preDepth > 0 {
+					preDepth--
+				}
+				if isBlockElement(a) {
+					b.WriteString("\n\n")
+				}
+			}
+		}
+	}
+}
+
+func isBlockElement(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.Blockquote, atom.Pre, atom.Ul, atom.Ol,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Table, atom.Tr, atom.Figure, atom.Hr:
+		return true
+	default:
+		return false
+	}
+}
+
+// normalizeText collapses whitespace within each line and removes
+// blank lines beyond the first between paragraphs.
+func normalizeText(s string) string {
+	lines := strings.Split(s, "\n")
+	result := make([]string, 0, len(lines))
+	blank := true
+	for _, line := range lines {
+		line = collapseSpace(line)
+		if len(line) == 0 {
+			if !blank {
+				result = append(result, "")
+			}
+			blank = true
+			continue
+		}
+		result = append(result, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(result, "\n"))
+}
@@ -0,0 +1,29 @@
+package feed
+
+import "testing"
+
+func TestHtmlToText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{"plain text", "Hello world", "Hello world"},
+		{"entities", "Fish &amp; chips", "Fish & chips"},
+		{"paragraphs", "<p>First\n  paragraph</p><p>Second</p>", "First paragraph\n\nSecond"},
+		{"line breaks", "one<br>two<br/>three", "one\ntwo\nthree"},
+		{"list", "<ul><li>a</li><li>b</li></ul>", "• a\n• b"},
+		{"inline elements", "<p>A <a href=\"x\">link</a> and <b>bold</b></p>", "A link and bold"},
+		{"script and style", "<style>p {}</style><p>Text</p><script>alert(1)</script>", "Text"},
+		{"unclosed tags", "<div><p>Text", "Text"},
+		{"preformatted", "<pre>a\nb</pre>", "a\nb"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if text := HtmlToText(tc.html); text != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, text)
+			}
+		})
+	}
+}
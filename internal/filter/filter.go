@@ -0,0 +1,128 @@
+package filter
+
+import (
+	"errors"
+	"github.com/wedaly/local-news/internal/feed"
+	"regexp"
+	"strings"
+)
+
+// Field is the part of a feed item that a rule matches
+type Field int
+
+const (
+	FieldTitle Field = iota
+	FieldContent
+	FieldAuthor
+	FieldCategory
+	FieldUrl
+)
+
+// Action is what happens to an item that matches a rule
+type Action int
+
+const (
+	ActionHide Action = iota
+	ActionMarkRead
+	ActionHighlight
+)
+
+// Rule matches feed items by a keyword or regular expression
+type Rule struct {
+	Field Field
+
+	// Keyword or regular expression to search for
+	Pattern string
+
+	// Whether the pattern is a regular expression.  Otherwise,
+	// the pattern is a keyword matched anywhere in the field,
+	// ignoring case.
+	Regex bool
+
+	Action Action
+}
+
+// Matcher is a compiled rule
+type Matcher struct {
+	Rule    Rule
+	regex   *regexp.Regexp
+	keyword string
+}
+
+// Compile validates a rule and prepares it for matching
+func Compile(rule Rule) (*Matcher, error) {
+	if len(strings.TrimSpace(rule.Pattern)) == 0 {
+		return nil, errors.New("Pattern must not be empty")
+	}
+
+	if rule.Field < FieldTitle || rule.Field > FieldUrl {
+		return nil, errors.New("Invalid field")
+	}
+
+	if rule.Action < ActionHide || rule.Action > ActionHighlight {
+		return nil, errors.New("Invalid action")
+	}
+
+	m := &Matcher{Rule: rule}
+	if rule.Regex {
+		regex, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		m.regex = regex
+	} else {
+		m.keyword = strings.ToLower(rule.Pattern)
+	}
+
+	return m, nil
+}
+
+// Match returns whether an item matches the rule
+func (m *Matcher) Match(item feed.FeedItem) bool {
+	for _, value := range fieldValues(m.Rule.Field, item) {
+		if m.matchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Matcher) matchString(s string) bool {
+	if m.regex != nil {
+		return m.regex.MatchString(s)
+	}
+	return strings.Contains(strings.ToLower(s), m.keyword)
+}
+
+// fieldValues returns the strings in an item that a rule for the field
+// is matched against.  The rule matches if any of them match.
+func fieldValues(field Field, item feed.FeedItem) []string {
+	switch field {
+	case FieldTitle:
+		return []string{item.Title}
+
+	case FieldContent:
+		// Match the text of the content, not the HTML markup
+		values := []string{item.ContentText}
+		if len(item.ContentHtml) > 0 {
+			values = append(values, feed.HtmlToText(item.ContentHtml))
+		}
+		return values
+
+	case FieldAuthor:
+		values := make([]string, 0, len(item.Authors))
+		for _, author := range item.Authors {
+			values = append(values, author.Name, author.Email)
+		}
+		return values
+
+	case FieldCategory:
+		return item.Categories
+
+	case FieldUrl:
+		return []string{item.Url}
+
+	default:
+		return nil
+	}
+}
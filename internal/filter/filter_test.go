@@ -0,0 +1,66 @@
+package filter
+
+import (
+	"github.com/wedaly/local-news/internal/feed"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	item := feed.FeedItem{
+		Title:       "Sponsored: Buy Widgets Now",
+		Url:         "https://example.com/ads/widgets",
+		ContentHtml: "<p>The <b>best</b> widgets</p>",
+		Authors:     []feed.Author{feed.Author{Name: "Marketing Team", Email: "ads@example.com"}},
+		Categories:  []string{"Promotions", "widgets"},
+	}
+
+	testCases := []struct {
+		name     string
+		rule     Rule
+		expected bool
+	}{
+		{"title keyword ignores case", Rule{Field: FieldTitle, Pattern: "sponsored"}, true},
+		{"title keyword no match", Rule{Field: FieldTitle, Pattern: "golang"}, false},
+		{"title regex", Rule{Field: FieldTitle, Pattern: "^Sponsored:", Regex: true}, true},
+		{"regex is case-sensitive", Rule{Field: FieldTitle, Pattern: "^sponsored", Regex: true}, false},
+		{"content matches text", Rule{Field: FieldContent, Pattern: "the best widgets"}, true},
+		{"content ignores markup", Rule{Field: FieldContent, Pattern: "<b>"}, false},
+		{"author name", Rule{Field: FieldAuthor, Pattern: "marketing"}, true},
+		{"author email", Rule{Field: FieldAuthor, Pattern: "ads@", Regex: true}, true},
+		{"any category", Rule{Field: FieldCategory, Pattern: "^widgets$", Regex: true}, true},
+		{"url", Rule{Field: FieldUrl, Pattern: "/ads/"}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := Compile(tc.rule)
+			if err != nil {
+				t.Fatalf("Could not compile rule: %v", err)
+			}
+
+			if actual := m.Match(item); actual != tc.expected {
+				t.Errorf("Expected match %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCompileInvalidRule(t *testing.T) {
+	testCases := []struct {
+		name string
+		rule Rule
+	}{
+		{"empty pattern", Rule{Field: FieldTitle, Pattern: " "}},
+		{"invalid regex", Rule{Field: FieldTitle, Pattern: "(", Regex: true}},
+		{"invalid field", Rule{Field: Field(99), Pattern: "foo"}},
+		{"invalid action", Rule{Field: FieldTitle, Pattern: "foo", Action: Action(99)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Compile(tc.rule); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package greader
+
+import "strings"
+
+// Identifiers of the Google Reader API, which is served to mobile apps
+// such as NetNewsWire and Reeder, and used to sync with servers such as FreshRSS.
+const (
+	// Item IDs in the long form are this prefix and the ID in hexadecimal
+	ItemIdPrefix = "tag:google.com,2005:reader/item/"
+
+	// Stream ID prefixes of feeds and labels (folders)
+	FeedPrefix  = "feed/"
+	LabelPrefix = "user/-/label/"
+
+	// Stream IDs of the current user's states
+	ReadingList = "user/-/state/com.google/reading-list"
+	Read        = "user/-/state/com.google/read"
+	KeptUnread  = "user/-/state/com.google/kept-unread"
+	Starred     = "user/-/state/com.google/starred"
+)
+
+// NormalizeStreamId replaces the user ID in a stream ID with "-",
+// which means the current user.
+func NormalizeStreamId(streamId string) string {
+	if parts := strings.SplitN(streamId, "/", 3); len(parts) == 3 && parts[0] == "user" {
+		return "user/-/" + parts[2]
+	}
+	return streamId
+}
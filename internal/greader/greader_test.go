@@ -0,0 +1,22 @@
+package greader
+
+import "testing"
+
+func TestNormalizeStreamId(t *testing.T) {
+	testCases := []struct {
+		streamId string
+		expected string
+	}{
+		{"user/1234/state/com.google/read", Read},
+		{"user/-/state/com.google/read", Read},
+		{"user/1234/label/News", LabelPrefix + "News"},
+		{"feed/https://example.com/feed", "feed/https://example.com/feed"},
+		{"user/1234", "user/1234"},
+	}
+
+	for _, tc := range testCases {
+		if actual := NormalizeStreamId(tc.streamId); actual != tc.expected {
+			t.Errorf("Expected %q for %q, got %q", tc.expected, tc.streamId, actual)
+		}
+	}
+}
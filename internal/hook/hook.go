@@ -0,0 +1,156 @@
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/wedaly/local-news/internal/command"
+	"github.com/wedaly/local-news/internal/store"
+	"github.com/wedaly/local-news/internal/task"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is used for hooks that don't set their own timeout
+const DefaultTimeout time.Duration = 30 * time.Second
+
+// Hook is a shell command that receives the new items
+// from each successful sync as JSON on its standard input.
+type Hook struct {
+	Command string
+
+	// Maximum time the command can run, or zero for the default
+	Timeout time.Duration
+}
+
+// Runner runs every hook after each task that inserted new items.
+// Hooks run in the background, one after another, and a hook that fails
+// or times out doesn't affect the other hooks or the sync itself.
+// Failures are recorded in the feed's sync history.
+type Runner struct {
+	hooks     []Hook
+	feedStore *store.FeedStore
+	running   sync.WaitGroup
+}
+
+// NewRunner creates a hook runner, which should be subscribed to a task manager.
+func NewRunner(hooks []Hook, feedStore *store.FeedStore) *Runner {
+	return &Runner{
+		hooks:     hooks,
+		feedStore: feedStore,
+	}
+}
+
+// HandleTaskScheduled implements task.TaskSubscriber
+func (r *Runner) HandleTaskScheduled() {
+	// ignore
+}
+
+// HandleTaskCompleted implements task.TaskSubscriber
+func (r *Runner) HandleTaskCompleted(result task.TaskResult) {
+	if result.Err != nil || len(result.NewItems) == 0 {
+		return
+	}
+
+	r.running.Add(1)
+	go func() {
+		defer r.running.Done()
+		r.runHooks(result.FeedId, result.NewItems)
+	}()
+}
+
+// Wait blocks until all running hooks have exited
+func (r *Runner) Wait() {
+	r.running.Wait()
+}
+
+func (r *Runner) runHooks(feedId store.FeedId, items []store.FeedItemRecord) {
+	// The feed may have been deleted since the task completed
+	feedRecord, err := r.feedStore.RetrieveFeed(feedId)
+	if err != nil {
+		return
+	}
+
+	input, err := json.Marshal(newPayload(feedRecord, items))
+	if err != nil {
+		panic(err)
+	}
+
+	for _, hook := range r.hooks {
+		timeout := hook.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+
+		_, err := command.Run(hook.Command, bytes.NewReader(input), timeout)
+		if err != nil {
+			if err := r.feedStore.AddHookFailureToSyncLog(feedId, hook.Command, err); err != nil {
+				// The feed was deleted while the hook ran,
+				// so there's no sync history left to record failures in.
+				return
+			}
+		}
+	}
+}
+
+// payload is the JSON document sent to each hook
+type payload struct {
+	Feed  feedPayload   `json:"feed"`
+	Items []itemPayload `json:"items"`
+}
+
+type feedPayload struct {
+	Id     int64  `json:"id"`
+	Url    string `json:"url"`
+	Name   string `json:"name"`
+	Folder string `json:"folder,omitempty"`
+}
+
+type itemPayload struct {
+	Id          int64      `json:"id"`
+	Guid        string     `json:"guid"`
+	Url         string     `json:"url"`
+	Title       string     `json:"title"`
+	Date        time.Time  `json:"date"`
+	Updated     *time.Time `json:"updated,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	ContentHtml string     `json:"content_html,omitempty"`
+	ContentText string     `json:"content_text,omitempty"`
+	Read        bool       `json:"read"`
+	Hidden      bool       `json:"hidden"`
+	Highlighted bool       `json:"highlighted"`
+}
+
+func newPayload(feedRecord store.FeedRecord, items []store.FeedItemRecord) payload {
+	p := payload{
+		Feed: feedPayload{
+			Id:     int64(feedRecord.Id),
+			Url:    feedRecord.Url,
+			Name:   feedRecord.DisplayName(),
+			Folder: feedRecord.Folder,
+		},
+		Items: make([]itemPayload, len(items)),
+	}
+
+	for i, item := range items {
+		p.Items[i] = itemPayload{
+			Id:          int64(item.Id),
+			Guid:        item.Guid,
+			Url:         item.Url,
+			Title:       item.Title,
+			Date:        item.Date.UTC(),
+			Author:      item.Author,
+			ContentHtml: item.ContentHtml,
+			ContentText: item.ContentText,
+			Read:        item.Read,
+			Hidden:      item.Hidden,
+			Highlighted: item.Highlighted,
+		}
+
+		if !item.Updated.IsZero() {
+			updated := item.Updated.UTC()
+			p.Items[i].Updated = &updated
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,130 @@
+package hook
+
+import (
+	"encoding/json"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/store"
+	"github.com/wedaly/local-news/internal/task"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestRunHooks(t *testing.T) {
+	dbPath := path.Join(os.TempDir(), "test-hook.db")
+	defer func() { os.Remove(dbPath) }()
+	feedStore := store.NewFeedStore(dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer feedStore.Close()
+
+	outPath := path.Join(os.TempDir(), "test-hook.json")
+	defer func() { os.Remove(outPath) }()
+
+	feedId, err := feedStore.GetOrCreateFeedWithUrl("http://foo.com/feed")
+	if err != nil {
+		t.Fatalf("Could not create feed: %v", err)
+	}
+
+	f := feed.Feed{
+		Name: "Foo",
+		Items: []feed.FeedItem{
+			feed.FeedItem{Title: "New", Date: time.Unix(1, 0), Url: "http://foo.com/1", Guid: "guid.1"},
+		},
+	}
+	newItems, err := feedStore.SyncFeed(feedId, f)
+	if err != nil {
+		t.Fatalf("Could not sync feed: %v", err)
+	}
+
+	// The failing hooks don't prevent the last hook from running
+	runner := NewRunner([]Hook{
+		Hook{Command: "echo broken >&2; exit 2"},
+		Hook{Command: "sleep 10", Timeout: 50 * time.Millisecond},
+		Hook{Command: "cat > " + outPath},
+	}, feedStore)
+
+	runner.HandleTaskScheduled()
+	runner.HandleTaskCompleted(task.TaskResult{FeedId: feedId, NewItems: newItems})
+	runner.Wait()
+
+	data, err := ioutil.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("Could not read hook output: %v", err)
+	}
+
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatalf("Could not parse hook input: %v", err)
+	}
+
+	if p.Feed.Name != "Foo" || p.Feed.Url != "http://foo.com/feed" {
+		t.Errorf("Incorrect feed %+v", p.Feed)
+	}
+
+	if len(p.Items) != 1 || p.Items[0].Title != "New" || !p.Items[0].Date.Equal(time.Unix(1, 0)) {
+		t.Errorf("Incorrect items %+v", p.Items)
+	}
+
+	// Both failures are recorded in the sync history, most recent first
+	entries, err := feedStore.RetrieveFeedSyncLog(feedId)
+	if err != nil {
+		t.Fatalf("Could not retrieve sync log: %v", err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 sync log entries, but got %v", len(entries))
+	}
+
+	if entries[0].Kind != store.SyncLogHookFailed || entries[0].Subject != "sleep 10" {
+		t.Errorf("Expected timeout to be recorded, but got %+v", entries[0])
+	}
+
+	if entries[1].Kind != store.SyncLogHookFailed || entries[1].Detail != "exit status 2: broken" {
+		t.Errorf("Expected failure to be recorded, but got %+v", entries[1])
+	}
+}
+
+func TestRunHooksForDeletedFeed(t *testing.T) {
+	dbPath := path.Join(os.TempDir(), "test-hook-deleted.db")
+	defer func() { os.Remove(dbPath) }()
+	feedStore := store.NewFeedStore(dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer feedStore.Close()
+
+	feedId, err := feedStore.GetOrCreateFeedWithUrl("http://foo.com/feed")
+	if err != nil {
+		t.Fatalf("Could not create feed: %v", err)
+	}
+
+	f := feed.Feed{
+		Name: "Foo",
+		Items: []feed.FeedItem{
+			feed.FeedItem{Title: "New", Date: time.Unix(1, 0), Url: "http://foo.com/1", Guid: "guid.1"},
+		},
+	}
+	newItems, err := feedStore.SyncFeed(feedId, f)
+	if err != nil {
+		t.Fatalf("Could not sync feed: %v", err)
+	}
+
+	// The feed is deleted while the hook runs, so its failure can't be recorded
+	runner := NewRunner([]Hook{Hook{Command: "sleep 0.2; exit 1"}}, feedStore)
+	runner.HandleTaskCompleted(task.TaskResult{FeedId: feedId, NewItems: newItems})
+	if err := feedStore.DeleteFeed(feedId); err != nil {
+		t.Fatalf("Could not delete feed: %v", err)
+	}
+	runner.Wait()
+}
+
+func TestRunHooksWithoutNewItems(t *testing.T) {
+	// Hooks only run if the task inserted items, so this doesn't need a store
+	runner := NewRunner([]Hook{Hook{Command: "exit 1"}}, nil)
+	runner.HandleTaskCompleted(task.TaskResult{FeedId: 1})
+	runner.Wait()
+}
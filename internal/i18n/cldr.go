@@ -0,0 +1,271 @@
+//go:build !libc
+// +build !libc
+
+package i18n
+
+import (
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// formats holds the CLDR data for each category of a localizer's locale.
+// Categories in the POSIX locale are formatted like the C library does.
+type formats struct {
+	timeIsPosix bool
+	dates       cldrDateFormats
+
+	numericIsPosix bool
+	printer        *message.Printer
+
+	// Collators aren't thread-safe, so this is locked while used
+	collateIsPosix bool
+	collateMutex   sync.Mutex
+	collator       *collate.Collator
+}
+
+func newFormats(locale Locale) *formats {
+	return &formats{
+		timeIsPosix:    isPosixLocale(locale.Time),
+		dates:          lookupDateFormats(locale.Time),
+		numericIsPosix: isPosixLocale(locale.Numeric),
+		printer:        message.NewPrinter(localeTag(locale.Numeric)),
+		collateIsPosix: isPosixLocale(locale.Collate),
+		collator:       collate.New(localeTag(locale.Collate)),
+	}
+}
+
+// cldrDateFormats are the Gregorian calendar patterns for a language,
+// from the Unicode CLDR (version 32, like golang.org/x/text v0.3.0).
+// See https://unicode.org/reports/tr35/tr35-dates.html for the syntax.
+type cldrDateFormats struct {
+	mediumDate string
+	shortTime  string
+
+	// Combines the date ({1}) with the time ({0})
+	mediumDateTime string
+
+	// Abbreviated month names, January first
+	months [12]string
+
+	// Abbreviations for before and after noon
+	dayPeriods [2]string
+}
+
+// neutralDateFormats are used for languages without date formats below.
+// They're numeric, like ISO 8601, so they don't show month names
+// or day periods in a language the user may not read.
+var neutralDateFormats = cldrDateFormats{
+	mediumDate:     "y-MM-dd",
+	shortTime:      "HH:mm",
+	mediumDateTime: "{1} {0}",
+	months:         [12]string{"01", "02", "03", "04", "05", "06", "07", "08", "09", "10", "11", "12"},
+	dayPeriods:     [2]string{"AM", "PM"},
+}
+
+var cldrDateFormatsByLanguage = map[string]cldrDateFormats{
+	"en": {
+		mediumDate:     "MMM d, y",
+		shortTime:      "h:mm a",
+		mediumDateTime: "{1}, {0}",
+		months:         [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		dayPeriods:     [2]string{"AM", "PM"},
+	},
+	"de": {
+		mediumDate:     "dd.MM.y",
+		shortTime:      "HH:mm",
+		mediumDateTime: "{1}, {0}",
+		months:         [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sep.", "Okt.", "Nov.", "Dez."},
+		dayPeriods:     [2]string{"AM", "PM"},
+	},
+	"es": {
+		mediumDate:     "d MMM y",
+		shortTime:      "H:mm",
+		mediumDateTime: "{1} {0}",
+		months:         [12]string{"ene.", "feb.", "mar.", "abr.", "may.", "jun.", "jul.", "ago.", "sept.", "oct.", "nov.", "dic."},
+		dayPeriods:     [2]string{"a. m.", "p. m."},
+	},
+	"fr": {
+		mediumDate:     "d MMM y",
+		shortTime:      "HH:mm",
+		mediumDateTime: "{1} 'à' {0}",
+		months:         [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		dayPeriods:     [2]string{"AM", "PM"},
+	},
+	"eo": {
+		mediumDate:     "y-MMM-dd",
+		shortTime:      "HH:mm",
+		mediumDateTime: "{1} {0}",
+		months:         [12]string{"jan", "feb", "mar", "apr", "maj", "jun", "jul", "aŭg", "sep", "okt", "nov", "dec"},
+		dayPeriods:     [2]string{"atm", "ptm"},
+	},
+	"it": {
+		mediumDate:     "d MMM y",
+		shortTime:      "HH:mm",
+		mediumDateTime: "{1}, {0}",
+		months:         [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		dayPeriods:     [2]string{"AM", "PM"},
+	},
+	"nl": {
+		mediumDate:     "d MMM y",
+		shortTime:      "HH:mm",
+		mediumDateTime: "{1} {0}",
+		months:         [12]string{"jan.", "feb.", "mrt.", "apr.", "mei", "jun.", "jul.", "aug.", "sep.", "okt.", "nov.", "dec."},
+		dayPeriods:     [2]string{"a.m.", "p.m."},
+	},
+}
+
+// isPosixLocale returns whether the locale is the C library's default
+// locale, which is formatted like the C library does instead of with CLDR data.
+func isPosixLocale(locale string) bool {
+	return locale == "" || locale == "C" || locale == "POSIX" || strings.HasPrefix(locale, "C.")
+}
+
+// localeLanguage returns the language code of a locale name like "de_DE.UTF-8"
+func localeLanguage(locale string) string {
+	language, _, _, _ := splitLocale(locale)
+	return language
+}
+
+// localeTag converts a locale name like "de_DE.UTF-8" to a language tag.
+// The tag is undefined for the POSIX locale, or if the name isn't valid.
+func localeTag(locale string) language.Tag {
+	if isPosixLocale(locale) {
+		return language.Und
+	}
+
+	lang, territory, _, _ := splitLocale(locale)
+	if len(territory) > 0 {
+		if tag, err := language.Parse(lang + "-" + territory); err == nil {
+			return tag
+		}
+	}
+
+	tag, _ := language.Parse(lang)
+	return tag
+}
+
+// lookupDateFormats returns the date formats for the locale's language
+func lookupDateFormats(locale string) cldrDateFormats {
+	base, _ := localeTag(locale).Base()
+	if formats, ok := cldrDateFormatsByLanguage[base.String()]; ok {
+		return formats
+	}
+	return neutralDateFormats
+}
+
+// formatDatePattern formats the time with a CLDR date pattern like "d MMM y".
+// Only the fields used by `cldrDateFormats` are supported.
+func (f cldrDateFormats) formatDatePattern(pattern string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+
+		// Text in single quotes is literal, and two single quotes
+		// are a quote, whether or not they're in quoted text
+		if c == '\'' {
+			if i+1 < len(pattern) && pattern[i+1] == '\'' {
+				b.WriteByte('\'')
+				i += 2
+				continue
+			}
+
+			for i++; i < len(pattern); i++ {
+				if pattern[i] != '\'' {
+					b.WriteByte(pattern[i])
+				} else if i+1 < len(pattern) && pattern[i+1] == '\'' {
+					b.WriteByte('\'')
+					i++
+				} else {
+					break
+				}
+			}
+			i++
+			continue
+		}
+
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+
+		// Each field is a run of the same letter, such as "MMM"
+		n := 1
+		for i+n < len(pattern) && pattern[i+n] == c {
+			n++
+		}
+		i += n
+
+		switch c {
+		case 'y':
+			if n == 2 {
+				b.WriteString(zeroPad(t.Year()%100, 2))
+			} else {
+				b.WriteString(zeroPad(t.Year(), n))
+			}
+		case 'M':
+			if n >= 3 {
+				b.WriteString(f.months[t.Month()-1])
+			} else {
+				b.WriteString(zeroPad(int(t.Month()), n))
+			}
+		case 'd':
+			b.WriteString(zeroPad(t.Day(), n))
+		case 'H':
+			b.WriteString(zeroPad(t.Hour(), n))
+		case 'h':
+			hour := t.Hour() % 12
+			if hour == 0 {
+				hour = 12
+			}
+			b.WriteString(zeroPad(hour, n))
+		case 'm':
+			b.WriteString(zeroPad(t.Minute(), n))
+		case 's':
+			b.WriteString(zeroPad(t.Second(), n))
+		case 'a':
+			b.WriteString(f.dayPeriods[t.Hour()/12])
+		default:
+			b.WriteString(strings.Repeat(string(c), n))
+		}
+	}
+
+	return b.String()
+}
+
+// formatDateTime combines the medium date and short time
+func (f cldrDateFormats) formatDateTime(t time.Time) string {
+	var b strings.Builder
+	rest := f.mediumDateTime
+	for len(rest) > 0 {
+		// The text around the placeholders can contain quoted literals, such as "'à'"
+		i := strings.IndexByte(rest, '{')
+		if i < 0 || i+2 >= len(rest) || rest[i+2] != '}' {
+			b.WriteString(f.formatDatePattern(rest, t))
+			break
+		}
+
+		b.WriteString(f.formatDatePattern(rest[:i], t))
+		switch rest[i+1] {
+		case '0':
+			b.WriteString(f.formatDatePattern(f.shortTime, t))
+		case '1':
+			b.WriteString(f.formatDatePattern(f.mediumDate, t))
+		}
+		rest = rest[i+3:]
+	}
+	return b.String()
+}
+
+func zeroPad(value int, width int) string {
+	s := strconv.Itoa(value)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+	return s
+}
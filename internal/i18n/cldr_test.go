@@ -0,0 +1,107 @@
+//go:build !libc
+// +build !libc
+
+package i18n
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDatetimeCldr(t *testing.T) {
+	testCases := []struct {
+		locale   string
+		date     string
+		datetime string
+	}{
+		{"en_US.UTF-8", "Jan 2, 2020", "Jan 2, 2020, 3:04 AM"},
+		{"de_DE.UTF-8", "02.01.2020", "02.01.2020, 03:04"},
+		{"es_ES.UTF-8", "2 ene. 2020", "2 ene. 2020 3:04"},
+		{"fr_FR.UTF-8", "2 janv. 2020", "2 janv. 2020 à 03:04"},
+		{"eo", "2020-jan-02", "2020-jan-02 03:04"},
+		{"it_IT.UTF-8", "2 gen 2020", "2 gen 2020, 03:04"},
+		{"nl_NL.UTF-8", "2 jan. 2020", "2 jan. 2020 03:04"},
+
+		// Languages without date formats use numeric formats
+		{"fi_FI.UTF-8", "2020-01-02", "2020-01-02 03:04"},
+	}
+
+	// Every language with date formats is tested
+	for language := range cldrDateFormatsByLanguage {
+		found := false
+		for _, tc := range testCases {
+			found = found || localeLanguage(tc.locale) == language
+		}
+		if !found {
+			t.Errorf("No test case for language %v", language)
+		}
+	}
+
+	d := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	for _, tc := range testCases {
+		l := newTestLocalizer(t, tc.locale)
+		if result := l.FormatDate(d); result != tc.date {
+			t.Errorf("Wrong date for %v, expected %v but got %v", tc.locale, tc.date, result)
+		}
+		if result := l.FormatDatetime(d); result != tc.datetime {
+			t.Errorf("Wrong datetime for %v, expected %v but got %v", tc.locale, tc.datetime, result)
+		}
+	}
+}
+
+func TestFormatDatePattern(t *testing.T) {
+	formats := lookupDateFormats("en_US")
+	d := time.Date(2009, 12, 31, 0, 5, 9, 0, time.UTC)
+	testCases := map[string]string{
+		"y-MM-dd":              "2009-12-31",
+		"yy/M/d":               "09/12/31",
+		"MMM d 'at' h:mm:ss a": "Dec 31 at 12:05:09 AM",
+		"HH:mm 'o''clock'":     "00:05 o'clock",
+		"''H''":                "'0'",
+	}
+
+	for pattern, expected := range testCases {
+		if result := formats.formatDatePattern(pattern, d); result != expected {
+			t.Errorf("Wrong result for '%v', expected %v but got %v", pattern, expected, result)
+		}
+	}
+}
+
+func TestFormatNumberCldr(t *testing.T) {
+	testCases := []struct {
+		locale   string
+		expected string
+	}{
+		{"en_US.UTF-8", "-1,234,567"},
+		{"de_DE.UTF-8", "-1.234.567"},
+		{"fr_FR.UTF-8", "-1 234 567"},
+	}
+
+	for _, tc := range testCases {
+		l := newTestLocalizer(t, tc.locale)
+		if result := l.FormatNumber(-1234567); result != tc.expected {
+			t.Errorf("Wrong number for %v, expected %q but got %q", tc.locale, tc.expected, result)
+		}
+	}
+}
+
+func TestNewLocalizerCategories(t *testing.T) {
+	locale := Locale{Messages: "de_DE.UTF-8", Time: "fr_FR.UTF-8", Numeric: "C", Collate: "C"}
+	l, err := NewLocalizer(locale, "localnews", nil)
+	if err != nil {
+		t.Fatalf("Could not create localizer: %v", err)
+	}
+
+	d := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	if result := l.FormatDate(d); result != "2 janv. 2020" {
+		t.Errorf("Expected date from the time locale, but got %v", result)
+	}
+
+	if result := l.FormatNumber(-1234567); result != "-1234567" {
+		t.Errorf("Expected number from the numeric locale, but got %v", result)
+	}
+
+	if _, err := NewLocalizer(NewLocale("not a locale!"), "localnews", nil); err == nil {
+		t.Errorf("Expected error for invalid locale")
+	}
+}
@@ -1,21 +1,21 @@
-package i18n
-
-/*
-#include <stdlib.h>
-#include <string.h>
-*/
-import "C"
+//go:build !libc
+// +build !libc
 
-import "unsafe"
+package i18n
 
 // CompareStrings compares two strings according to the locale-specific collation order.
 // It returns true if and only if
 // the first string is strictly less than the second string.
-func CompareStrings(s1 string, s2 string) bool {
-	cstr1 := C.CString(s1)
-	cstr2 := C.CString(s2)
-	result := C.strcoll(cstr1, cstr2)
-	C.free(unsafe.Pointer(cstr1))
-	C.free(unsafe.Pointer(cstr2))
-	return result < 0
+func (l *Localizer) CompareStrings(s1 string, s2 string) bool {
+	f := l.formats
+	if f.collateIsPosix {
+		// Compare bytes, like strcoll in the POSIX locale
+		return s1 < s2
+	}
+
+	f.collateMutex.Lock()
+	defer f.collateMutex.Unlock()
+	{
+		return f.collator.CompareString(s1, s2) < 0
+	}
 }
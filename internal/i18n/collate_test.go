@@ -7,7 +7,7 @@ import (
 )
 
 func TestCollation(t *testing.T) {
-	SetLocale("C")
+	l := newTestLocalizer(t, "C")
 	items := []string{
 		"inconclusive",
 		"van",
@@ -24,7 +24,7 @@ func TestCollation(t *testing.T) {
 	}
 
 	sort.SliceStable(items, func(i, j int) bool {
-		return CompareStrings(items[i], items[j])
+		return l.CompareStrings(items[i], items[j])
 	})
 
 	if !sort.StringsAreSorted(items) {
@@ -33,9 +33,7 @@ func TestCollation(t *testing.T) {
 }
 
 func TestCollationLocalizedEnglish(t *testing.T) {
-	if err := SetLocale("en_US.UTF-8"); err != nil {
-		t.Fatalf("Could not set locale")
-	}
+	l := newTestLocalizer(t, "en_US.UTF-8")
 
 	items := []string{
 		"ñandú",
@@ -49,7 +47,7 @@ func TestCollationLocalizedEnglish(t *testing.T) {
 	}
 
 	sort.SliceStable(items, func(i, j int) bool {
-		return CompareStrings(items[i], items[j])
+		return l.CompareStrings(items[i], items[j])
 	})
 
 	// Matches output of `LC_ALL=en_US.UTF-8 sort input.txt`
@@ -71,9 +69,7 @@ func TestCollationLocalizedEnglish(t *testing.T) {
 }
 
 func TestCollationLocalizedSpanish(t *testing.T) {
-	if err := SetLocale("es_ES.UTF-8"); err != nil {
-		t.Fatalf("Could not set locale")
-	}
+	l := newTestLocalizer(t, "es_ES.UTF-8")
 
 	items := []string{
 		"ñandú",
@@ -87,7 +83,7 @@ func TestCollationLocalizedSpanish(t *testing.T) {
 	}
 
 	sort.SliceStable(items, func(i, j int) bool {
-		return CompareStrings(items[i], items[j])
+		return l.CompareStrings(items[i], items[j])
 	})
 
 	// Matches output of `LC_ALL=es_ES.UTF-8 sort input.txt`
@@ -18,6 +18,8 @@ type Config struct {
 	FormErrorTextColor        string `xml:"colors>formErrorText"`
 	ModalTextColor            string `xml:"colors>modalText"`
 	ModalBackgroundColor      string `xml:"colors>modalBackground"`
+	HighlightTextColor        string `xml:"colors>highlightText"`
+	ReadTextColor             string `xml:"colors>readText"`
 }
 
 // DefaultConfig returns a default configuration, used in case
@@ -33,6 +35,8 @@ func DefaultConfig() Config {
 		FormErrorTextColor:        "white",
 		ModalBackgroundColor:      "blue",
 		ModalTextColor:            "black",
+		HighlightTextColor:        "yellow",
+		ReadTextColor:             "gray",
 	}
 }
 
@@ -50,10 +54,10 @@ func ParseConfigXml(r io.Reader) Config {
 // LoadConfig locates and loads a locale-specific configuration file.
 // Search paths are directories to search in order.
 // When an XML file is found at {SEARCHPATH}/{LOCALE}/config.xml,
-// it is parsed and returned.
+// where the locale is the localizer's messages locale, it is parsed and returned.
 // If no locale-specific config can be found, then the default config is returned.
-func LoadConfig(searchPaths []string) Config {
-	locale := GetMessageLocaleFromEnv()
+func LoadConfig(localizer *Localizer, searchPaths []string) Config {
+	locale := localizer.Locale().Messages
 	for _, dir := range searchPaths {
 		path := path.Join(dir, locale, "config.xml")
 		if fileInfo, err := os.Stat(path); err == nil && !fileInfo.IsDir() {
@@ -1,62 +1,28 @@
-package i18n
-
-/*
-#include <stdlib.h>
-#include <stdio.h>
-#include <langinfo.h>
-#include <time.h>
-#include <nl_types.h>
-
-char* formatDatetime(char* fmt, long unixTs) {
-	time_t t = (time_t)(unixTs);
-	struct tm *tm = localtime(&t);
-	if (tm == NULL) {
-		return NULL;
-	}
-
-	size_t sz = 512;
-	char* s = (char *)malloc(sz);
-	if (s != NULL ) {
-		strftime(s, sz, fmt, tm);
-		s[sz - 1] = '\0';
-	}
-	return s;
-}
-*/
-import "C"
+//go:build !libc
+// +build !libc
 
-import (
-	"time"
-	"unsafe"
-)
-
-var dateFmt (*C.char)
-var datetimeFmt (*C.char)
+package i18n
 
-func InitDateFormats() {
-	dateFmt = C.nl_langinfo(C.D_FMT)
-	datetimeFmt = C.nl_langinfo(C.D_T_FMT)
-}
+import "time"
 
 // FormatDate converts the specified date (year, month, and day)
-// according to the current locale
-func FormatDate(t time.Time) string {
-	return formatDatetime(dateFmt, t)
+// according to the localizer's locale
+func (l *Localizer) FormatDate(t time.Time) string {
+	t = t.Local()
+	if l.formats.timeIsPosix {
+		// Same as "%x" in the C library's POSIX locale
+		return t.Format("01/02/06")
+	}
+	return l.formats.dates.formatDatePattern(l.formats.dates.mediumDate, t)
 }
 
 // FormatDatetime formats the specified datetime (date, hour, and minute)
-// according to the current locale.
-func FormatDatetime(t time.Time) string {
-	return formatDatetime(datetimeFmt, t)
-}
-
-func formatDatetime(fmt *C.char, t time.Time) string {
-	cstr := C.formatDatetime(fmt, C.long(t.Unix()))
-	if cstr == nil {
-		return ""
+// according to the localizer's locale.
+func (l *Localizer) FormatDatetime(t time.Time) string {
+	t = t.Local()
+	if l.formats.timeIsPosix {
+		// Same as "%c" in the C library's POSIX locale
+		return t.Format("Mon Jan _2 15:04:05 2006")
 	}
-
-	result := C.GoString(cstr)
-	C.free(unsafe.Pointer(cstr))
-	return result
+	return l.formats.dates.formatDateTime(t)
 }
@@ -0,0 +1,75 @@
+//go:build libc
+// +build libc
+
+package i18n
+
+/*
+#include <stdlib.h>
+#include <stdio.h>
+#include <langinfo.h>
+#include <time.h>
+#include <nl_types.h>
+
+char* formatDatetime(char* fmt, long unixTs) {
+	time_t t = (time_t)(unixTs);
+	struct tm *tm = localtime(&t);
+	if (tm == NULL) {
+		return NULL;
+	}
+
+	size_t sz = 512;
+	char* s = (char *)malloc(sz);
+	if (s != NULL ) {
+		strftime(s, sz, fmt, tm);
+		s[sz - 1] = '\0';
+	}
+	return s;
+}
+*/
+import "C"
+
+import (
+	"time"
+	"unsafe"
+)
+
+// formats holds the C library's formats for the localizer's locale
+type formats struct {
+	dateFmt     string
+	datetimeFmt string
+}
+
+// newFormats loads the formats after `setLocale`, copying them
+// because the C library overwrites them when the locale changes.
+func newFormats(locale Locale) *formats {
+	return &formats{
+		dateFmt:     C.GoString(C.nl_langinfo(C.D_FMT)),
+		datetimeFmt: C.GoString(C.nl_langinfo(C.D_T_FMT)),
+	}
+}
+
+// FormatDate converts the specified date (year, month, and day)
+// according to the localizer's locale
+func (l *Localizer) FormatDate(t time.Time) string {
+	return formatDatetime(l.formats.dateFmt, t)
+}
+
+// FormatDatetime formats the specified datetime (date, hour, and minute)
+// according to the localizer's locale.
+func (l *Localizer) FormatDatetime(t time.Time) string {
+	return formatDatetime(l.formats.datetimeFmt, t)
+}
+
+func formatDatetime(fmt string, t time.Time) string {
+	fmtCStr := C.CString(fmt)
+	defer C.free(unsafe.Pointer(fmtCStr))
+
+	cstr := C.formatDatetime(fmtCStr, C.long(t.Unix()))
+	if cstr == nil {
+		return ""
+	}
+
+	result := C.GoString(cstr)
+	C.free(unsafe.Pointer(cstr))
+	return result
+}
@@ -0,0 +1,22 @@
+//go:build libc
+// +build libc
+
+package i18n
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatDatetimeLocalized(t *testing.T) {
+	l := newTestLocalizer(t, "de_DE.UTF-8")
+	d := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	result := l.FormatDatetime(d)
+	expected := "Do 02 Jan 2020 03:04:05"
+	// Check prefix instead of the full string to avoid
+	// false positives due to system timezone differences.
+	if !strings.HasPrefix(result, expected) {
+		t.Errorf("Wrong date, expected %v but got %v", expected, result)
+	}
+}
@@ -1,51 +1,31 @@
-package i18n
-
-/*
-#include <stdlib.h>
-#include <locale.h>
-
-int setAllLocaleCategories(const char *locale) {
-	return (setlocale(LC_ALL, locale) != NULL);
-}
-
-int setAllLocaleCategoriesFromEnv() {
-	return setAllLocaleCategories("");
-}
+//go:build !libc && !libintl
+// +build !libc,!libintl
 
-*/
-import "C"
+package i18n
 
 import (
 	"errors"
-	"unsafe"
+	"golang.org/x/text/language"
 )
 
-// GetMessageLocaleFromEnv loads the current effective locale
-// for user-facing messages.
-func GetMessageLocaleFromEnv() string {
-	locale := C.setlocale(C.LC_MESSAGES, nil)
-	return C.GoString(locale)
-}
-
-// SetLocaleFromEnv sets the current locale based on environment variables
-// such as LANG and LC_ALL.  See the setlocale man page for details.
-// This should be called at program startup.
-func SetLocaleFromEnv() error {
-	result := C.setAllLocaleCategoriesFromEnv()
-	if int(result) == 0 {
-		return errors.New("Could not set locale")
+// setLocale checks that every category of the locale is valid.
+// Formatting uses the CLDR data for the locale's language, so unlike
+// the C library, this doesn't require the locales to be installed.
+func setLocale(locale Locale) error {
+	for _, name := range []string{locale.Messages, locale.Time, locale.Numeric, locale.Collate} {
+		if !isValidLocale(name) {
+			return errors.New("Could not set locale")
+		}
 	}
 	return nil
 }
 
-// SetLocale sets the current locale explicitly.
-// This is useful for testing.
-func SetLocale(locale string) error {
-	localeCStr := C.CString(locale)
-	result := C.setAllLocaleCategories(localeCStr)
-	C.free(unsafe.Pointer(localeCStr))
-	if int(result) == 0 {
-		return errors.New("Could not set locale")
+// isValidLocale returns whether the locale is "C", "POSIX",
+// or a name like "de_DE.UTF-8" with a known language code.
+func isValidLocale(locale string) bool {
+	if isPosixLocale(locale) {
+		return true
 	}
-	return nil
+	_, err := language.ParseBase(localeLanguage(locale))
+	return err == nil
 }
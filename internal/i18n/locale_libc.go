@@ -0,0 +1,51 @@
+//go:build libc || libintl
+// +build libc libintl
+
+package i18n
+
+// This backend sets the locale with the C library's setlocale, which is
+// needed by the C library's formatting functions and libintl.  Locales
+// must be installed on the system (see `locale -a`).  The C library's
+// locale is shared by the whole process, so only the most recently
+// created localizer is used for these.
+
+/*
+#include <stdlib.h>
+#include <locale.h>
+
+int setLocaleCategory(int category, const char *locale) {
+	return (setlocale(category, locale) != NULL);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// setLocale sets each category of the C library's locale.
+// The character type follows the messages, which libintl
+// uses to convert translations to the right codeset.
+func setLocale(locale Locale) error {
+	categories := []struct {
+		category C.int
+		name     string
+	}{
+		{C.LC_CTYPE, locale.Messages},
+		{C.LC_MESSAGES, locale.Messages},
+		{C.LC_TIME, locale.Time},
+		{C.LC_NUMERIC, locale.Numeric},
+		{C.LC_COLLATE, locale.Collate},
+	}
+
+	for _, c := range categories {
+		nameCStr := C.CString(c.name)
+		result := C.setLocaleCategory(c.category, nameCStr)
+		C.free(unsafe.Pointer(nameCStr))
+		if int(result) == 0 {
+			return errors.New("Could not set locale")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package i18n
+
+import "strings"
+
+// splitLocale splits a locale name of the form
+// "language[_territory][.codeset][@modifier]" into its parts.
+func splitLocale(locale string) (string, string, string, string) {
+	var territory, codeset, modifier string
+	if i := strings.IndexByte(locale, '@'); i >= 0 {
+		locale, modifier = locale[:i], locale[i+1:]
+	}
+	if i := strings.IndexByte(locale, '.'); i >= 0 {
+		locale, codeset = locale[:i], locale[i+1:]
+	}
+	if i := strings.IndexByte(locale, '_'); i >= 0 {
+		locale, territory = locale[:i], locale[i+1:]
+	}
+	return locale, territory, codeset, modifier
+}
@@ -0,0 +1,82 @@
+package i18n
+
+import "os"
+
+// Locale names the locale of each category of localized data,
+// such as "de_DE.UTF-8", like the C library's LC_* categories.
+type Locale struct {
+	// Language of user-facing messages
+	Messages string
+
+	// Format of dates and times
+	Time string
+
+	// Format of numbers
+	Numeric string
+
+	// Sort order of strings
+	Collate string
+
+	// Colon-separated list of languages for messages, which takes
+	// precedence over the messages locale (see gettext documentation)
+	Languages string
+}
+
+// NewLocale returns a locale that uses the same name for every category.
+func NewLocale(name string) Locale {
+	return Locale{Messages: name, Time: name, Numeric: name, Collate: name}
+}
+
+// LocaleFromEnv returns the locale set by environment variables.
+// Like the C library's setlocale, each category uses the first
+// non-empty variable of LC_ALL, the category (e.g. LC_TIME), and LANG,
+// or the "C" locale if none are set.  The languages are from LANGUAGE.
+func LocaleFromEnv() Locale {
+	return Locale{
+		Messages:  localeFromEnv("LC_MESSAGES"),
+		Time:      localeFromEnv("LC_TIME"),
+		Numeric:   localeFromEnv("LC_NUMERIC"),
+		Collate:   localeFromEnv("LC_COLLATE"),
+		Languages: os.Getenv("LANGUAGE"),
+	}
+}
+
+func localeFromEnv(category string) string {
+	for _, name := range []string{"LC_ALL", category, "LANG"} {
+		if value := os.Getenv(name); len(value) > 0 {
+			return value
+		}
+	}
+	return "C"
+}
+
+// Localizer translates user-facing messages and formats dates, numbers,
+// and sort order for a locale.  Localizers for different locales can be
+// used side by side, unless built with the "libc" or "libintl" tags,
+// which use the C library's process-wide locale.  This is thread-safe.
+type Localizer struct {
+	locale       Locale
+	translations *translations
+	formats      *formats
+}
+
+// NewLocalizer creates a localizer for a locale.
+// The `domain` uniquely identifies this application (see gettext documentation for details)
+// Search paths is a list of paths containing translations in priority order.
+// If none of the search paths exist, the system default will be used instead.
+func NewLocalizer(locale Locale, domain string, searchPaths []string) (*Localizer, error) {
+	if err := setLocale(locale); err != nil {
+		return nil, err
+	}
+
+	return &Localizer{
+		locale:       locale,
+		translations: newTranslations(locale, domain, searchPaths),
+		formats:      newFormats(locale),
+	}, nil
+}
+
+// Locale returns the locale of the localizer
+func (l *Localizer) Locale() Locale {
+	return l.locale
+}
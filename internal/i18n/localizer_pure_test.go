@@ -0,0 +1,42 @@
+//go:build !libc && !libintl
+// +build !libc,!libintl
+
+package i18n
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocalizersSideBySide(t *testing.T) {
+	testCases := []struct {
+		locale   string
+		message  string
+		datetime string
+		number   string
+	}{
+		{"C", "All Feeds", "Thu Jan  2 03:04:05 2020", "1234567"},
+		{"de_DE.UTF-8", "All Feeds", "02.01.2020, 03:04", "1.234.567"},
+		{"eo", "[Ⱥłł Fɇɇđs łøɍɇm ɨᵽsᵾm]", "2020-jan-02 03:04", "1\u00a0234\u00a0567"},
+	}
+
+	d := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.locale, func(t *testing.T) {
+			t.Parallel()
+			l := newTestLocalizer(t, tc.locale)
+			for i := 0; i < 100; i++ {
+				if result := l.Gettext("All Feeds"); result != tc.message {
+					t.Fatalf("Expected message %v, but got %v", tc.message, result)
+				}
+				if result := l.FormatDatetime(d); result != tc.datetime {
+					t.Fatalf("Expected datetime %v, but got %v", tc.datetime, result)
+				}
+				if result := l.FormatNumber(1234567); result != tc.number {
+					t.Fatalf("Expected number %v, but got %v", tc.number, result)
+				}
+			}
+		})
+	}
+}
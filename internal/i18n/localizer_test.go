@@ -0,0 +1,47 @@
+package i18n
+
+import (
+	"os"
+	"testing"
+)
+
+// newTestLocalizer creates a localizer for the locale
+// with the translations in this repository
+func newTestLocalizer(t *testing.T, locale string) *Localizer {
+	l, err := NewLocalizer(NewLocale(locale), "localnews", []string{"../../configs/locale"})
+	if err != nil {
+		t.Fatalf("Could not set locale to %v", locale)
+	}
+	return l
+}
+
+func TestLocaleFromEnv(t *testing.T) {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LC_TIME", "LC_NUMERIC", "LC_COLLATE", "LANG", "LANGUAGE"} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+
+	if locale := LocaleFromEnv(); locale != NewLocale("C") {
+		t.Errorf("Expected C locale by default, but got %v", locale)
+	}
+
+	os.Setenv("LANG", "de_DE.UTF-8")
+	os.Setenv("LC_TIME", "fr_FR.UTF-8")
+	os.Setenv("LANGUAGE", "eo:de")
+	expected := Locale{
+		Messages:  "de_DE.UTF-8",
+		Time:      "fr_FR.UTF-8",
+		Numeric:   "de_DE.UTF-8",
+		Collate:   "de_DE.UTF-8",
+		Languages: "eo:de",
+	}
+	if locale := LocaleFromEnv(); locale != expected {
+		t.Errorf("Expected %v, but got %v", expected, locale)
+	}
+
+	// LC_ALL overrides every category
+	os.Setenv("LC_ALL", "C")
+	if locale := LocaleFromEnv(); locale.Time != "C" || locale.Messages != "C" {
+		t.Errorf("Expected C locale from LC_ALL, but got %v", locale)
+	}
+}
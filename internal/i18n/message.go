@@ -0,0 +1,4 @@
+package i18n
+
+// MsgId identifies a user-facing string that can be translated.
+type MsgId string
@@ -0,0 +1,180 @@
+package i18n
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Magic number at the start of every ".mo" file, in the file's byte order
+const moMagic = 0x950412de
+
+// catalog contains the translations loaded from a ".mo" file
+type catalog struct {
+	// Translations of messages without plural forms
+	messages map[string]string
+
+	// Translations of messages with plural forms, keyed by the singular message ID
+	plurals map[string][]string
+
+	// Selects the index of the plural form for a count
+	pluralForm pluralFunc
+}
+
+// loadCatalog reads and parses a ".mo" file
+func loadCatalog(path string) (*catalog, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCatalog(data)
+}
+
+// parseCatalog parses the contents of a ".mo" file.  The messages must be
+// encoded as UTF-8; unlike libintl, the translations aren't converted
+// to the locale's character set.
+func parseCatalog(data []byte) (*catalog, error) {
+	if len(data) < 20 {
+		return nil, errors.New("Invalid .mo file: too short")
+	}
+
+	var order binary.ByteOrder
+	switch {
+	case binary.LittleEndian.Uint32(data) == moMagic:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(data) == moMagic:
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("Invalid .mo file: bad magic number")
+	}
+
+	if revision := order.Uint32(data[4:]); revision>>16 > 1 {
+		return nil, fmt.Errorf("Unsupported .mo file revision %v", revision)
+	}
+
+	numStrings := int(order.Uint32(data[8:]))
+	origTable := int(order.Uint32(data[12:]))
+	transTable := int(order.Uint32(data[16:]))
+
+	// The count is untrusted, so check that both tables fit in the data
+	// before allocating anything for the strings.  Each entry is 8 bytes.
+	if numStrings > len(data)/8 {
+		return nil, errors.New("Invalid .mo file: too many strings")
+	}
+	for _, table := range []int{origTable, transTable} {
+		if table < 0 || table+8*numStrings > len(data) {
+			return nil, errors.New("Invalid .mo file: string table out of range")
+		}
+	}
+
+	// Each table contains the length and offset of every string
+	readString := func(table int, i int) (string, error) {
+		entry := table + 8*i
+		if entry < 0 || entry+8 > len(data) {
+			return "", errors.New("Invalid .mo file: string table out of range")
+		}
+
+		length := int(order.Uint32(data[entry:]))
+		offset := int(order.Uint32(data[entry+4:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return "", errors.New("Invalid .mo file: string out of range")
+		}
+
+		return string(data[offset : offset+length]), nil
+	}
+
+	c := &catalog{
+		messages:   make(map[string]string, numStrings),
+		plurals:    make(map[string][]string, 0),
+		pluralForm: germanicPluralForm,
+	}
+
+	for i := 0; i < numStrings; i++ {
+		msgId, err := readString(origTable, i)
+		if err != nil {
+			return nil, err
+		}
+
+		msgStr, err := readString(transTable, i)
+		if err != nil {
+			return nil, err
+		}
+
+		// The header is the translation of the empty message ID
+		if len(msgId) == 0 {
+			if err := c.parseHeader(msgStr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Messages with plural forms separate the singular and plural
+		// message IDs, and each translated form, with NUL characters
+		if sep := strings.IndexByte(msgId, 0); sep >= 0 {
+			c.plurals[msgId[:sep]] = strings.Split(msgStr, "\x00")
+		} else {
+			c.messages[msgId] = msgStr
+		}
+	}
+
+	return c, nil
+}
+
+// parseHeader reads the plural forms from the catalog's header,
+// such as "Plural-Forms: nplurals=2; plural=(n != 1);"
+func (c *catalog) parseHeader(header string) error {
+	for _, line := range strings.Split(header, "\n") {
+		sep := strings.IndexByte(line, ':')
+		if sep < 0 || !strings.EqualFold(strings.TrimSpace(line[:sep]), "Plural-Forms") {
+			continue
+		}
+
+		numPlurals := 0
+		expr := ""
+		for _, field := range strings.Split(line[sep+1:], ";") {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "nplurals=") {
+				numPlurals, _ = strconv.Atoi(strings.TrimPrefix(field, "nplurals="))
+			} else if strings.HasPrefix(field, "plural=") {
+				expr = strings.TrimPrefix(field, "plural=")
+			}
+		}
+
+		if numPlurals < 1 || len(expr) == 0 {
+			return fmt.Errorf("Invalid plural forms '%v'", line)
+		}
+
+		pluralForm, err := parsePluralForm(expr)
+		if err != nil {
+			return err
+		}
+		c.pluralForm = pluralForm
+	}
+
+	return nil
+}
+
+// gettext returns the translation of a message, if the catalog has one
+func (c *catalog) gettext(msgId string) (string, bool) {
+	msgStr, ok := c.messages[msgId]
+	return msgStr, ok && len(msgStr) > 0
+}
+
+// ngettext returns the plural form of a message's translation for
+// the count, if the catalog has one
+func (c *catalog) ngettext(singularMsgId string, count uint64) (string, bool) {
+	forms, ok := c.plurals[singularMsgId]
+	if !ok {
+		return "", false
+	}
+
+	i := c.pluralForm(count)
+	if i >= uint64(len(forms)) || len(forms[i]) == 0 {
+		return "", false
+	}
+
+	return forms[i], true
+}
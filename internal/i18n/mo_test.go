@@ -0,0 +1,110 @@
+package i18n
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildMo encodes messages as a ".mo" file.  Message IDs are sorted
+// in the file, but the parser doesn't depend on the order.
+func buildMo(order binary.ByteOrder, msgIds []string, msgStrs []string) []byte {
+	const headerSize = 28
+	origTable := headerSize
+	transTable := origTable + 8*len(msgIds)
+	offset := transTable + 8*len(msgIds)
+
+	var strs bytes.Buffer
+	writeTable := func(buf *bytes.Buffer, values []string) {
+		for _, s := range values {
+			binary.Write(buf, order, uint32(len(s)))
+			binary.Write(buf, order, uint32(offset+strs.Len()))
+			strs.WriteString(s)
+			strs.WriteByte(0)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, v := range []uint32{moMagic, 0, uint32(len(msgIds)), uint32(origTable), uint32(transTable), 0, 0} {
+		binary.Write(&buf, order, v)
+	}
+	writeTable(&buf, msgIds)
+	writeTable(&buf, msgStrs)
+	buf.Write(strs.Bytes())
+	return buf.Bytes()
+}
+
+func TestParseCatalog(t *testing.T) {
+	header := "Content-Type: text/plain; charset=UTF-8\n" +
+		"Plural-Forms: nplurals=3; plural=(n==1 ? 0 : n>=2 && n<=4 ? 1 : 2);\n"
+	msgIds := []string{"", "All Feeds", "Empty", "%v feed\x00%v feeds"}
+	msgStrs := []string{header, "Všechny kanály", "", "%v kanál\x00%v kanály\x00%v kanálů"}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		c, err := parseCatalog(buildMo(order, msgIds, msgStrs))
+		if err != nil {
+			t.Fatalf("Could not parse catalog: %v", err)
+		}
+
+		if msgStr, ok := c.gettext("All Feeds"); !ok || msgStr != "Všechny kanály" {
+			t.Errorf("Expected translation, but got %v", msgStr)
+		}
+
+		// Empty translations are treated as missing, like in libintl
+		if _, ok := c.gettext("Empty"); ok {
+			t.Errorf("Expected empty translation to be missing")
+		}
+
+		if _, ok := c.gettext("Missing"); ok {
+			t.Errorf("Expected missing translation")
+		}
+
+		expected := map[uint64]string{0: "%v kanálů", 1: "%v kanál", 3: "%v kanály", 5: "%v kanálů"}
+		for n, expectedMsgStr := range expected {
+			if msgStr, ok := c.ngettext("%v feed", n); !ok || msgStr != expectedMsgStr {
+				t.Errorf("Expected '%v' for %v, but got '%v'", expectedMsgStr, n, msgStr)
+			}
+		}
+	}
+}
+
+func TestParseCatalogInvalid(t *testing.T) {
+	valid := buildMo(binary.LittleEndian, []string{"a"}, []string{"b"})
+
+	badMagic := append([]byte{}, valid...)
+	badMagic[0] = 0
+
+	outOfRange := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(outOfRange[32:], 1000)
+
+	// The string count is much larger than the file
+	tooManyStrings := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(tooManyStrings[8:], 0xffffffff)
+
+	// The translation table starts past the end of the file
+	tableOutOfRange := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(tableOutOfRange[16:], uint32(len(valid)))
+
+	badPlural := buildMo(binary.LittleEndian, []string{""}, []string{"Plural-Forms: nplurals=2; plural=n +;\n"})
+
+	for _, data := range [][]byte{nil, valid[:10], badMagic, outOfRange, tooManyStrings, tableOutOfRange, badPlural} {
+		if _, err := parseCatalog(data); err == nil {
+			t.Errorf("Expected error parsing %v", data)
+		}
+	}
+}
+
+func TestLoadCatalogPseudo(t *testing.T) {
+	c, err := loadCatalog("../../configs/locale/eo/LC_MESSAGES/localnews.mo")
+	if err != nil {
+		t.Fatalf("Could not load catalog: %v", err)
+	}
+
+	if msgStr, _ := c.gettext("All Feeds"); msgStr != "[Ⱥłł Fɇɇđs łøɍɇm ɨᵽsᵾm]" {
+		t.Errorf("Unexpected translation %v", msgStr)
+	}
+
+	if msgStr, _ := c.ngettext("Refreshing %v feed...", 2); msgStr != "[Ɍɇfɍɇsħɨnǥ %v fɇɇđs... łøɍɇm ɨᵽsᵾm]" {
+		t.Errorf("Unexpected plural translation %v", msgStr)
+	}
+}
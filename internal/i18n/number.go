@@ -1,32 +1,17 @@
-package i18n
-
-/*
-#include <stdlib.h>
-#include <stdio.h>
+//go:build !libc
+// +build !libc
 
-char* formatNumber(size_t sz, int val) {
-	char* s = malloc(sz);
-	if (s != NULL) {
-		snprintf(s, sz, "%'d", val);
-		s[sz - 1] = '\0';  // ensure NULL termination
-	}
-	return s;
-}
-*/
-import "C"
+package i18n
 
-import "unsafe"
+import "strconv"
 
 const MaxDigits int = 127
 
 // FormatNumber formats the integer to a numeric string
-// according to the current locale.
-func FormatNumber(val int) string {
-	cstr := C.formatNumber(C.ulong(MaxDigits+1), C.int(val))
-	if cstr == nil {
-		return ""
+// according to the localizer's locale.
+func (l *Localizer) FormatNumber(val int) string {
+	if l.formats.numericIsPosix {
+		return strconv.Itoa(val)
 	}
-	result := C.GoString(cstr)
-	C.free(unsafe.Pointer(cstr))
-	return result
+	return l.formats.printer.Sprintf("%d", val)
 }
@@ -6,16 +6,14 @@ import (
 )
 
 func TestFormatNumber(t *testing.T) {
-	if err := SetLocale("C"); err != nil {
-		t.Fatalf("Could not set locale")
-	}
+	l := newTestLocalizer(t, "C")
 
 	// Each value has one more digit than the previous val
 	// If MaxDigits is sufficiently large, val will wrap around due to
 	// integer overflow, which is okay for this test.
 	for d := 0; d < MaxDigits; d++ {
 		val := d * 10
-		result := FormatNumber(val)
+		result := l.FormatNumber(val)
 		if result != strconv.Itoa(val) {
 			t.Errorf("Incorrect value, expected %v but got %v", val, result)
 		}
@@ -23,11 +21,9 @@ func TestFormatNumber(t *testing.T) {
 }
 
 func TestFormatNumberWithLocale(t *testing.T) {
-	if err := SetLocale("de_DE.UTF-8"); err != nil {
-		t.Fatalf("Could not set locale")
-	}
+	l := newTestLocalizer(t, "de_DE.UTF-8")
 	val := 123456789
-	result := FormatNumber(val)
+	result := l.FormatNumber(val)
 	expected := "123.456.789"
 	if result != expected {
 		t.Errorf(
@@ -0,0 +1,238 @@
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// pluralFunc selects the index of a message's plural form for a count
+type pluralFunc func(n uint64) uint64
+
+// germanicPluralForm is used by catalogs without plural forms in their
+// header, and for messages that aren't translated: one singular form,
+// and one plural form for every other count.
+func germanicPluralForm(n uint64) uint64 {
+	if n == 1 {
+		return 0
+	}
+	return 1
+}
+
+// parsePluralForm parses the C expression in a catalog's
+// "Plural-Forms" header, such as "(n != 1)" or
+// "n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2".
+// The expression can use the variable n, integer constants, parentheses,
+// and the operators "?:", "||", "&&", "==", "!=", "<", ">", "<=", ">=",
+// "+", "-", "*", "/", "%", and "!".
+func parsePluralForm(expr string) (pluralFunc, error) {
+	p := &pluralParser{input: expr}
+	f, err := p.parseConditional()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.skipSpace(); p.pos < len(p.input) {
+		return nil, p.errorf("unexpected '%v'", p.input[p.pos:])
+	}
+
+	return f, nil
+}
+
+// pluralParser is a recursive descent parser for plural form expressions,
+// with the same operator precedence as C.
+type pluralParser struct {
+	input string
+	pos   int
+}
+
+// binaryOperators are grouped by precedence, from lowest to highest.
+// Longer operators come first, so "<=" isn't parsed as "<".
+var binaryOperators = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *pluralParser) parseConditional() (pluralFunc, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.consume("?") {
+		return cond, nil
+	}
+
+	ifTrue, err := p.parseConditional()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.consume(":") {
+		return nil, p.errorf("expected ':'")
+	}
+
+	ifFalse, err := p.parseConditional()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(n uint64) uint64 {
+		if cond(n) != 0 {
+			return ifTrue(n)
+		}
+		return ifFalse(n)
+	}, nil
+}
+
+func (p *pluralParser) parseBinary(level int) (pluralFunc, error) {
+	if level == len(binaryOperators) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.consumeAny(binaryOperators[level])
+		if !ok {
+			return left, nil
+		}
+
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		left = binaryOperation(op, left, right)
+	}
+}
+
+func (p *pluralParser) parseUnary() (pluralFunc, error) {
+	if p.consume("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(n uint64) uint64 { return boolToUint(operand(n) == 0) }, nil
+	}
+
+	if p.consume("(") {
+		f, err := p.parseConditional()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return f, nil
+	}
+
+	if p.consume("n") {
+		return func(n uint64) uint64 { return n }, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+		p.pos++
+	}
+
+	if p.pos == start {
+		if p.pos == len(p.input) {
+			return nil, p.errorf("unexpected end of expression")
+		}
+		return nil, p.errorf("unexpected '%c'", p.input[p.pos])
+	}
+
+	value, err := strconv.ParseUint(p.input[start:p.pos], 10, 64)
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	return func(uint64) uint64 { return value }, nil
+}
+
+func binaryOperation(op string, left pluralFunc, right pluralFunc) pluralFunc {
+	switch op {
+	case "||":
+		return func(n uint64) uint64 { return boolToUint(left(n) != 0 || right(n) != 0) }
+	case "&&":
+		return func(n uint64) uint64 { return boolToUint(left(n) != 0 && right(n) != 0) }
+	case "==":
+		return func(n uint64) uint64 { return boolToUint(left(n) == right(n)) }
+	case "!=":
+		return func(n uint64) uint64 { return boolToUint(left(n) != right(n)) }
+	case "<":
+		return func(n uint64) uint64 { return boolToUint(left(n) < right(n)) }
+	case ">":
+		return func(n uint64) uint64 { return boolToUint(left(n) > right(n)) }
+	case "<=":
+		return func(n uint64) uint64 { return boolToUint(left(n) <= right(n)) }
+	case ">=":
+		return func(n uint64) uint64 { return boolToUint(left(n) >= right(n)) }
+	case "+":
+		return func(n uint64) uint64 { return left(n) + right(n) }
+	case "-":
+		return func(n uint64) uint64 { return left(n) - right(n) }
+	case "*":
+		return func(n uint64) uint64 { return left(n) * right(n) }
+	case "/":
+		// Division by zero selects the first form, rather than crashing
+		return func(n uint64) uint64 {
+			if d := right(n); d != 0 {
+				return left(n) / d
+			}
+			return 0
+		}
+	case "%":
+		return func(n uint64) uint64 {
+			if d := right(n); d != 0 {
+				return left(n) % d
+			}
+			return 0
+		}
+	default:
+		panic("Unknown operator " + op)
+	}
+}
+
+func (p *pluralParser) skipSpace() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// consume advances past the token if it's next in the input
+func (p *pluralParser) consume(token string) bool {
+	p.skipSpace()
+	if len(p.input)-p.pos >= len(token) && p.input[p.pos:p.pos+len(token)] == token {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *pluralParser) consumeAny(tokens []string) (string, bool) {
+	for _, token := range tokens {
+		if p.consume(token) {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+func (p *pluralParser) errorf(format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	return fmt.Errorf("Invalid plural form expression '%v' at position %v: %v", p.input, p.pos, msg)
+}
+
+func boolToUint(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
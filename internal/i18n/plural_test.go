@@ -0,0 +1,57 @@
+package i18n
+
+import "testing"
+
+func TestParsePluralForm(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected []uint64 // for n = 0, 1, 2, ...
+	}{
+		{"0", []uint64{0, 0, 0}},
+		{"(n != 1)", []uint64{1, 0, 1}},
+		{"n>1", []uint64{0, 0, 1}},
+		{"n==1 ? 0 : n==2 ? 1 : 2", []uint64{2, 0, 1, 2}},
+		{"!(n%2)", []uint64{1, 0, 1, 0}},
+		{"(n+1)*2/3 - (n>2)", []uint64{0, 1, 2, 1}},
+		{"n/0 + n%0", []uint64{0, 0}},
+		{"n<=1 || n>=3 && n<4", []uint64{1, 1, 0, 1, 0}},
+	}
+
+	for _, tc := range testCases {
+		f, err := parsePluralForm(tc.expr)
+		if err != nil {
+			t.Errorf("Could not parse '%v': %v", tc.expr, err)
+			continue
+		}
+
+		for n, expected := range tc.expected {
+			if result := f(uint64(n)); result != expected {
+				t.Errorf("Expected '%v' to be %v for n = %v, but got %v", tc.expr, expected, n, result)
+			}
+		}
+	}
+}
+
+func TestParsePluralFormPolish(t *testing.T) {
+	expr := "(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)"
+	f, err := parsePluralForm(expr)
+	if err != nil {
+		t.Fatalf("Could not parse expression: %v", err)
+	}
+
+	expected := map[uint64]uint64{0: 2, 1: 0, 2: 1, 4: 1, 5: 2, 12: 2, 14: 2, 22: 1, 25: 2, 112: 2, 122: 1}
+	for n, form := range expected {
+		if result := f(n); result != form {
+			t.Errorf("Expected form %v for n = %v, but got %v", form, n, result)
+		}
+	}
+}
+
+func TestParsePluralFormInvalid(t *testing.T) {
+	exprs := []string{"", "n +", "(n", "n ? 1", "x", "n = 1", "n 1", "99999999999999999999"}
+	for _, expr := range exprs {
+		if _, err := parsePluralForm(expr); err == nil {
+			t.Errorf("Expected error parsing '%v'", expr)
+		}
+	}
+}
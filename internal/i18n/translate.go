@@ -1,66 +1,179 @@
-package i18n
+//go:build !libintl
+// +build !libintl
 
-/*
-#include <stdlib.h>
-#include <libintl.h>
-*/
-import "C"
+package i18n
 
 import (
 	"os"
-	"unsafe"
+	"path"
+	"strings"
 )
 
-// MsgId identifies a user-facing string that can be translated.
-type MsgId string
-
-// InitTranslations prepares the application to load translation strings
-// The `domain` uniquely identifies this application (see gettext documentation for details)
-// Search paths is a list of paths containing locale information in priority order.
-// If none of the search paths exist, the system default will be used instead.
-func InitTranslations(domain string, searchPaths []string) {
-	// Set the text domain (should equal the name of the basename of the ".mo" files)
-	domainCStr := C.CString(domain)
-	defer C.free(unsafe.Pointer(domainCStr))
-	if C.textdomain(domainCStr) == nil {
-		panic("Could not set text domain")
-	}
+// The directory containing translations if none of the search paths exist
+const defaultLocaleDir = "/usr/share/locale"
 
+// translations are the catalogs for a localizer's languages,
+// in priority order (see `messageLanguages`)
+type translations struct {
+	catalogs []*catalog
+}
+
+// newTranslations loads the catalogs for the locale's languages
+// from "{path}/{language}/LC_MESSAGES/{domain}.mo", where the path is
+// the first search path that exists.
+func newTranslations(locale Locale, domain string, searchPaths []string) *translations {
 	// Prefer "./configs/locale" to the system locale directory
 	// if it exists.  This is useful for development so we can
 	// test translations without installing them in /usr/share
-	for _, dir := range searchPaths {
-		if fileInfo, err := os.Stat(dir); err == nil && fileInfo.IsDir() {
-			dirnameCStr := C.CString(dir)
-			defer C.free(unsafe.Pointer(dirnameCStr))
-			if C.bindtextdomain(domainCStr, dirnameCStr) == nil {
-				panic("Could not bind text domain")
-			}
+	dir := defaultLocaleDir
+	for _, searchPath := range searchPaths {
+		if fileInfo, err := os.Stat(searchPath); err == nil && fileInfo.IsDir() {
+			dir = searchPath
 			break
 		}
 	}
+
+	languages := messageLanguages(locale.Messages, locale.Languages)
+	return &translations{loadCatalogs(dir, domain, languages)}
 }
 
-// Gettext translates a message using the currently configured locale
+// Gettext translates a message using the localizer's locale
 // If no translation is found, it returns the message ID untranslated.
-func Gettext(msgId MsgId) string {
-	msgIdCStr := C.CString(string(msgId))
-	defer C.free(unsafe.Pointer(msgIdCStr))
-	resultCStr := C.gettext(msgIdCStr)
-	result := C.GoString(resultCStr)
-	return result
+func (l *Localizer) Gettext(msgId MsgId) string {
+	for _, c := range l.translations.catalogs {
+		if msgStr, ok := c.gettext(string(msgId)); ok {
+			return msgStr
+		}
+	}
+	return string(msgId)
 }
 
 // NGettext translates a message into either the singular or plural form
-// using the currently configured locale.
-func NGettext(singularMsgId MsgId, pluralMsgId MsgId, count int) string {
-	singularMsgIdCStr := C.CString(string(singularMsgId))
-	defer C.free(unsafe.Pointer(singularMsgIdCStr))
+// using the localizer's locale.
+func (l *Localizer) NGettext(singularMsgId MsgId, pluralMsgId MsgId, count int) string {
+	n := uint64(count)
+	if count < 0 {
+		n = uint64(-count)
+	}
+
+	for _, c := range l.translations.catalogs {
+		if msgStr, ok := c.ngettext(string(singularMsgId), n); ok {
+			return msgStr
+		}
+	}
+
+	if germanicPluralForm(n) == 0 {
+		return string(singularMsgId)
+	}
+	return string(pluralMsgId)
+}
+
+// loadCatalogs loads the first catalog found for each language.
+// Catalogs that are missing or can't be parsed are skipped.
+func loadCatalogs(dir string, domain string, languages []string) []*catalog {
+	catalogs := make([]*catalog, 0, len(languages))
+	for _, language := range languages {
+		for _, name := range localeVariants(language) {
+			p := path.Join(dir, name, "LC_MESSAGES", domain+".mo")
+			if c, err := loadCatalog(p); err == nil {
+				catalogs = append(catalogs, c)
+				break
+			}
+		}
+	}
+	return catalogs
+}
 
-	pluralMsgIdCStr := C.CString(string(pluralMsgId))
-	defer C.free(unsafe.Pointer(pluralMsgIdCStr))
+// messageLanguages returns the languages to translate messages into, in
+// priority order.  Like GNU gettext, the colon-separated list in the
+// LANGUAGE environment variable takes precedence over the locale,
+// except in the "C" locale, where messages are never translated.
+func messageLanguages(locale string, languageEnv string) []string {
+	if locale == "" || locale == "C" || locale == "POSIX" || strings.HasPrefix(locale, "C.") {
+		return nil
+	}
 
-	resultCStr := C.ngettext(singularMsgIdCStr, pluralMsgIdCStr, C.ulong(count))
-	result := C.GoString(resultCStr)
-	return result
+	languages := make([]string, 0)
+	for _, language := range strings.Split(languageEnv, ":") {
+		if len(language) > 0 {
+			languages = append(languages, language)
+		}
+	}
+
+	if len(languages) == 0 {
+		languages = append(languages, locale)
+	}
+
+	return languages
+}
+
+// localeVariants returns the names of the directories that may contain
+// a locale's catalog, from most to least specific.  For example,
+// "de_DE.UTF-8@euro" may use a catalog for "de_DE.utf8@euro", "de_DE@euro",
+// "de@euro", "de_DE.UTF-8", "de_DE.utf8", "de_DE", or "de".
+func localeVariants(locale string) []string {
+	language, territory, codeset, modifier := splitLocale(locale)
+
+	territories := []string{territory}
+	if len(territory) > 0 {
+		territories = append(territories, "")
+	}
+
+	codesets := []string{codeset}
+	if len(codeset) > 0 {
+		if normalized := normalizeCodeset(codeset); normalized != codeset {
+			codesets = append(codesets, normalized)
+		}
+		codesets = append(codesets, "")
+	}
+
+	modifiers := []string{modifier}
+	if len(modifier) > 0 {
+		modifiers = append(modifiers, "")
+	}
+
+	variants := make([]string, 0)
+	for _, m := range modifiers {
+		for _, t := range territories {
+			for _, c := range codesets {
+				name := language
+				if len(t) > 0 {
+					name += "_" + t
+				}
+				if len(c) > 0 {
+					name += "." + c
+				}
+				if len(m) > 0 {
+					name += "@" + m
+				}
+				variants = append(variants, name)
+			}
+		}
+	}
+
+	return variants
+}
+
+// normalizeCodeset converts a codeset name like glibc does,
+// e.g. "UTF-8" becomes "utf8".
+func normalizeCodeset(codeset string) string {
+	var b strings.Builder
+	onlyDigits := true
+	for _, r := range codeset {
+		switch {
+		case r >= 'a' && r <= 'z':
+			b.WriteRune(r)
+			onlyDigits = false
+		case r >= 'A' && r <= 'Z':
+			b.WriteRune(r - 'A' + 'a')
+			onlyDigits = false
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		}
+	}
+
+	if onlyDigits {
+		return "iso" + b.String()
+	}
+	return b.String()
 }
@@ -0,0 +1,62 @@
+//go:build !libintl
+// +build !libintl
+
+package i18n
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMessageLanguages(t *testing.T) {
+	testCases := []struct {
+		locale      string
+		languageEnv string
+		expected    []string
+	}{
+		{"C", "eo", nil},
+		{"POSIX", "", nil},
+		{"C.UTF-8", "", nil},
+		{"de_DE.UTF-8", "", []string{"de_DE.UTF-8"}},
+		{"de_DE.UTF-8", "eo:fr::de", []string{"eo", "fr", "de"}},
+	}
+
+	for _, tc := range testCases {
+		languages := messageLanguages(tc.locale, tc.languageEnv)
+		if !reflect.DeepEqual(languages, tc.expected) {
+			t.Errorf("Expected %v for %v and %v, but got %v", tc.expected, tc.locale, tc.languageEnv, languages)
+		}
+	}
+}
+
+func TestLocaleVariants(t *testing.T) {
+	expected := []string{
+		"de_DE.UTF-8@euro", "de_DE.utf8@euro", "de_DE@euro",
+		"de.UTF-8@euro", "de.utf8@euro", "de@euro",
+		"de_DE.UTF-8", "de_DE.utf8", "de_DE",
+		"de.UTF-8", "de.utf8", "de",
+	}
+	if variants := localeVariants("de_DE.UTF-8@euro"); !reflect.DeepEqual(variants, expected) {
+		t.Errorf("Expected %v, but got %v", expected, variants)
+	}
+
+	if variants := localeVariants("eo"); !reflect.DeepEqual(variants, []string{"eo"}) {
+		t.Errorf("Expected only 'eo', but got %v", variants)
+	}
+
+	if codeset := normalizeCodeset("8859-1"); codeset != "iso88591" {
+		t.Errorf("Expected iso88591, but got %v", codeset)
+	}
+}
+
+func TestLoadCatalogsFromSearchPath(t *testing.T) {
+	locale := Locale{Messages: "de_DE.UTF-8", Languages: "xx_YY:eo_XX.UTF-8"}
+	catalogs := newTranslations(locale, "localnews", []string{"/does/not/exist", "../../configs/locale"}).catalogs
+	if len(catalogs) != 1 {
+		t.Fatalf("Expected only the pseudo language catalog, but got %v", len(catalogs))
+	}
+
+	if msgStr, _ := catalogs[0].gettext("All Feeds"); msgStr != "[Ⱥłł Fɇɇđs łøɍɇm ɨᵽsᵾm]" {
+		t.Errorf("Unexpected translation %v", msgStr)
+	}
+}
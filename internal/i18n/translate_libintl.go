@@ -0,0 +1,73 @@
+//go:build libintl
+// +build libintl
+
+package i18n
+
+// This backend translates messages with the C library's libintl,
+// which must be available when building with cgo.  By default,
+// catalogs are loaded by the pure-Go backend in "translate.go".
+
+/*
+#include <stdlib.h>
+#include <libintl.h>
+*/
+import "C"
+
+import (
+	"os"
+	"unsafe"
+)
+
+// translations has no state, because libintl uses the C library's locale
+// and the process-wide text domain.
+type translations struct{}
+
+// newTranslations sets the text domain and its directory,
+// which is the first search path that exists.
+func newTranslations(locale Locale, domain string, searchPaths []string) *translations {
+	// Set the text domain (should equal the name of the basename of the ".mo" files)
+	domainCStr := C.CString(domain)
+	defer C.free(unsafe.Pointer(domainCStr))
+	if C.textdomain(domainCStr) == nil {
+		panic("Could not set text domain")
+	}
+
+	// Prefer "./configs/locale" to the system locale directory
+	// if it exists.  This is useful for development so we can
+	// test translations without installing them in /usr/share
+	for _, dir := range searchPaths {
+		if fileInfo, err := os.Stat(dir); err == nil && fileInfo.IsDir() {
+			dirnameCStr := C.CString(dir)
+			defer C.free(unsafe.Pointer(dirnameCStr))
+			if C.bindtextdomain(domainCStr, dirnameCStr) == nil {
+				panic("Could not bind text domain")
+			}
+			break
+		}
+	}
+	return &translations{}
+}
+
+// Gettext translates a message using the localizer's locale
+// If no translation is found, it returns the message ID untranslated.
+func (l *Localizer) Gettext(msgId MsgId) string {
+	msgIdCStr := C.CString(string(msgId))
+	defer C.free(unsafe.Pointer(msgIdCStr))
+	resultCStr := C.gettext(msgIdCStr)
+	result := C.GoString(resultCStr)
+	return result
+}
+
+// NGettext translates a message into either the singular or plural form
+// using the localizer's locale.
+func (l *Localizer) NGettext(singularMsgId MsgId, pluralMsgId MsgId, count int) string {
+	singularMsgIdCStr := C.CString(string(singularMsgId))
+	defer C.free(unsafe.Pointer(singularMsgIdCStr))
+
+	pluralMsgIdCStr := C.CString(string(pluralMsgId))
+	defer C.free(unsafe.Pointer(pluralMsgIdCStr))
+
+	resultCStr := C.ngettext(singularMsgIdCStr, pluralMsgIdCStr, C.ulong(count))
+	result := C.GoString(resultCStr)
+	return result
+}
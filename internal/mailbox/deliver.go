@@ -0,0 +1,41 @@
+package mailbox
+
+import (
+	"bytes"
+	"github.com/wedaly/local-news/internal/store"
+)
+
+// DeliverItems writes the items not yet delivered to the destination
+// (which identifies the mailbox) as messages from the specified address.
+// Each delivery is recorded right away, so an item is never written twice
+// even if a later delivery fails.  This returns the number of items delivered.
+func DeliverItems(feedStore *store.FeedStore, destination string, box Mailbox, fromAddress string) (int, error) {
+	records, err := feedStore.RetrieveUndeliveredItems(destination)
+	if err != nil {
+		return 0, err
+	}
+
+	feeds, err := feedStore.RetrieveFeeds()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, message := range NewMessages(records, feeds) {
+		var buf bytes.Buffer
+		if err := message.Write(&buf, fromAddress); err != nil {
+			return n, err
+		}
+
+		if err := box.Deliver(buf.Bytes(), message.Record.Read); err != nil {
+			return n, err
+		}
+
+		if err := feedStore.MarkItemsDelivered(destination, []store.FeedItemId{message.Record.Id}); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
@@ -0,0 +1,53 @@
+package mailbox
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Format is an on-disk mailbox format read by mail clients such as mutt
+type Format int
+
+const (
+	// FormatMaildir writes each message to its own file in a Maildir
+	FormatMaildir Format = iota
+
+	// FormatMbox appends messages to a single mbox file (mboxrd variant)
+	FormatMbox
+)
+
+// formatNames are the names of the formats, e.g. for tracking deliveries
+var formatNames = []string{"maildir", "mbox"}
+
+func (f Format) String() string {
+	return formatNames[f]
+}
+
+// Mailbox stores messages for a mail client
+type Mailbox interface {
+	// Deliver stores a message written by `Message.Write`.
+	// Seen messages are flagged as already read.
+	Deliver(data []byte, seen bool) error
+
+	// Close releases the mailbox.  Messages are stored
+	// as they are delivered, not when the mailbox is closed.
+	Close() error
+}
+
+// Open opens a mailbox in the specified format, creating it if necessary
+func Open(format Format, path string) (Mailbox, error) {
+	switch format {
+	case FormatMaildir:
+		return openMaildir(path)
+	case FormatMbox:
+		return openMbox(path)
+	default:
+		return nil, fmt.Errorf("Unknown format %d", format)
+	}
+}
+
+// toLocalLineEndings converts a message's CRLF line endings to LF,
+// which mail clients expect in mailbox files.
+func toLocalLineEndings(data []byte) []byte {
+	return bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)
+}
@@ -0,0 +1,260 @@
+package mailbox
+
+import (
+	"bytes"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/store"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testMessage() Message {
+	return Message{
+		Feed: store.FeedRecord{
+			Id:         7,
+			Url:        "https://example.com/feed.xml",
+			Name:       "Blog",
+			CustomName: `Ünïcode "Blog"`,
+		},
+		Record: store.FeedItemRecord{
+			FeedId:      7,
+			Title:       "Hello\nwelcome ☕",
+			Url:         "https://example.com/hello",
+			Guid:        "1",
+			Date:        time.Date(2020, time.April, 6, 2, 0, 22, 0, time.UTC),
+			ContentHtml: "<p>Hi <b>there</b></p>\n<p>From here</p>",
+			Author:      "Alice",
+		},
+	}
+}
+
+func TestMessageWrite(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testMessage().Write(&buf, ""); err != nil {
+		t.Fatalf("Could not write message: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(&buf)
+	if err != nil {
+		t.Fatalf("Could not parse message: %v", err)
+	}
+
+	from, err := msg.Header.AddressList("From")
+	if err != nil || len(from) != 1 {
+		t.Fatalf("Could not parse sender: %v", err)
+	}
+
+	if from[0].Name != `Alice (Ünïcode "Blog")` || from[0].Address != DefaultFrom {
+		t.Errorf("Unexpected sender %v", from[0])
+	}
+
+	decoder := new(mime.WordDecoder)
+	if subject, err := decoder.DecodeHeader(msg.Header.Get("Subject")); err != nil || subject != "Hello welcome ☕" {
+		t.Errorf("Unexpected subject %q (%v)", subject, err)
+	}
+
+	if date, err := msg.Header.Date(); err != nil || !date.Equal(testMessage().Record.Date) {
+		t.Errorf("Unexpected date %v (%v)", date, err)
+	}
+
+	if listId, err := decoder.DecodeHeader(msg.Header.Get("List-Id")); err != nil || listId != `Ünïcode "Blog" <7.feed.localnews>` {
+		t.Errorf("Unexpected List-Id %q (%v)", listId, err)
+	}
+
+	if id := msg.Header.Get("Message-ID"); id != testMessage().MessageId() || !strings.HasSuffix(id, "@localnews>") {
+		t.Errorf("Unexpected Message-ID %q", id)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Unexpected content type %v (%v)", mediaType, err)
+	}
+
+	// The multipart reader decodes quoted-printable parts
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	expected := []struct{ mediaType, content string }{
+		{"text/plain", "Hi there\r\n\r\nFrom here\r\n\r\nhttps://example.com/hello\r\n"},
+		{"text/html", `<h1><a href="https://example.com/hello">Hello welcome ☕</a></h1>`},
+	}
+	for _, e := range expected {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("Could not read %v part: %v", e.mediaType, err)
+		}
+
+		if !strings.HasPrefix(part.Header.Get("Content-Type"), e.mediaType) {
+			t.Errorf("Expected %v part, but got %v", e.mediaType, part.Header.Get("Content-Type"))
+		}
+
+		content, _ := ioutil.ReadAll(part)
+		if !strings.Contains(string(content), e.content) {
+			t.Errorf("Expected %v part to contain %q, but got %q", e.mediaType, e.content, content)
+		}
+	}
+}
+
+func TestMessageIdFromGuid(t *testing.T) {
+	m := testMessage()
+	id := m.MessageId()
+
+	m.Record.Title = "Changed"
+	if m.MessageId() != id {
+		t.Errorf("Expected the Message-ID to depend only on the GUID")
+	}
+
+	m.Record.Guid = "2"
+	if m.MessageId() == id {
+		t.Errorf("Expected a different Message-ID for a different GUID")
+	}
+}
+
+func withTempDir(t *testing.T, f func(dir string)) {
+	dir, err := ioutil.TempDir("", "localnews-mailbox-")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	f(dir)
+}
+
+func deliver(t *testing.T, format Format, path string, data []string, seen []bool) {
+	box, err := Open(format, path)
+	if err != nil {
+		t.Fatalf("Could not open mailbox: %v", err)
+	}
+	defer box.Close()
+
+	for i := range data {
+		if err := box.Deliver([]byte(data[i]), seen[i]); err != nil {
+			t.Fatalf("Could not deliver message: %v", err)
+		}
+	}
+}
+
+func TestMaildir(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		path := filepath.Join(dir, "feeds")
+		deliver(t, FormatMaildir, path, []string{"Subject: a\r\n\r\nA\r\n", "Subject: b\r\n\r\nB\r\n"}, []bool{false, true})
+
+		newFiles, _ := filepath.Glob(filepath.Join(path, "new", "*"))
+		curFiles, _ := filepath.Glob(filepath.Join(path, "cur", "*"))
+		tmpFiles, _ := filepath.Glob(filepath.Join(path, "tmp", "*"))
+		if len(newFiles) != 1 || len(curFiles) != 1 || len(tmpFiles) != 0 {
+			t.Fatalf("Unexpected files new=%v cur=%v tmp=%v", newFiles, curFiles, tmpFiles)
+		}
+
+		if data, _ := ioutil.ReadFile(newFiles[0]); string(data) != "Subject: a\n\nA\n" {
+			t.Errorf("Unexpected message %q", data)
+		}
+
+		if !strings.HasSuffix(curFiles[0], ":2,S") {
+			t.Errorf("Expected seen message to be flagged, but got %v", curFiles[0])
+		}
+	})
+}
+
+func TestMbox(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		path := filepath.Join(dir, "feeds.mbox")
+		deliver(t, FormatMbox, path, []string{"Subject: a\r\n\r\nFrom me\r\n>From you", "Subject: b\r\n\r\nB\r\n"}, []bool{false, true})
+
+		// Appends to the existing file
+		deliver(t, FormatMbox, path, []string{"Subject: c\r\n\r\nC\r\n"}, []bool{false})
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Could not read mbox: %v", err)
+		}
+
+		messages := strings.Split(string(data), "From MAILER-DAEMON ")
+		if len(messages) != 4 || len(messages[0]) != 0 {
+			t.Fatalf("Expected 3 messages, but got %q", data)
+		}
+
+		expected := []string{
+			"Subject: a\n\n>From me\n>>From you\n\n",
+			"Subject: b\nStatus: RO\n\nB\n\n",
+			"Subject: c\n\nC\n\n",
+		}
+		for i, e := range expected {
+			if !strings.HasSuffix(messages[i+1], "\n"+e) {
+				t.Errorf("Expected message %v to end with %q, but got %q", i, e, messages[i+1])
+			}
+		}
+	})
+}
+
+func TestDeliverItemsAfterMerge(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		feedStore := store.NewFeedStore(filepath.Join(dir, "test.db"))
+		if err := feedStore.Initialize(); err != nil {
+			t.Fatalf("Could not initialize store: %v", err)
+		}
+		defer feedStore.Close()
+
+		syncFeed := func(url string, guids ...string) store.FeedId {
+			feedId, err := feedStore.GetOrCreateFeedWithUrl(url)
+			if err != nil {
+				t.Fatalf("Could not create feed: %v", err)
+			}
+
+			f := feed.Feed{Name: url}
+			for i, guid := range guids {
+				item := feed.FeedItem{Title: guid, Url: url + "/" + guid, Guid: guid, Date: time.Unix(int64(i), 0)}
+				f.Items = append(f.Items, item)
+			}
+			if _, err := feedStore.SyncFeed(feedId, f); err != nil {
+				t.Fatalf("Could not sync feed: %v", err)
+			}
+			return feedId
+		}
+
+		movedId := syncFeed("http://foo.com", "1", "2")
+		syncFeed("http://moved.com", "2")
+
+		path := filepath.Join(dir, "feeds.mbox")
+		destination := "mbox:" + path
+		deliverItems := func() int {
+			box, err := Open(FormatMbox, path)
+			if err != nil {
+				t.Fatalf("Could not open mailbox: %v", err)
+			}
+			defer box.Close()
+
+			n, err := DeliverItems(feedStore, destination, box, DefaultFrom)
+			if err != nil {
+				t.Fatalf("Could not deliver items: %v", err)
+			}
+			return n
+		}
+
+		if n := deliverItems(); n != 3 {
+			t.Fatalf("Expected 3 items delivered, but got %v", n)
+		}
+
+		// Merging the feeds after a redirect doesn't deliver the items again
+		if _, err := feedStore.MoveFeed(movedId, "http://moved.com"); err != nil {
+			t.Fatalf("Could not move feed: %v", err)
+		}
+
+		if n := deliverItems(); n != 0 {
+			t.Errorf("Expected no items delivered after merge, but got %v", n)
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Could not read mbox: %v", err)
+		}
+
+		if n := strings.Count(string(data), "From MAILER-DAEMON "); n != 3 {
+			t.Errorf("Expected 3 messages, but got %v", n)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package mailbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// maildirCounter makes file names unique within this process
+var maildirCounter uint64
+
+// maildir delivers each message to a new file, following the
+// protocol at https://cr.yp.to/proto/maildir.html: messages are
+// written to "tmp" and moved to "new" (or "cur" if seen) once complete.
+type maildir struct {
+	path     string
+	hostname string
+}
+
+func openMaildir(path string) (*maildir, error) {
+	for _, dir := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(path, dir), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	// Slashes and colons have special meanings in file names
+	hostname = strings.NewReplacer("/", `\057`, ":", `\072`).Replace(hostname)
+	return &maildir{path, hostname}, nil
+}
+
+func (m *maildir) Deliver(data []byte, seen bool) error {
+	now := time.Now()
+	name := fmt.Sprintf("%d.M%dP%dQ%d.%s",
+		now.Unix(), now.Nanosecond()/1000, os.Getpid(), atomic.AddUint64(&maildirCounter, 1), m.hostname)
+
+	tmpPath := filepath.Join(m.path, "tmp", name)
+	if err := writeFileSync(tmpPath, toLocalLineEndings(data)); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	destPath := filepath.Join(m.path, "new", name)
+	if seen {
+		destPath = filepath.Join(m.path, "cur", name+":2,S")
+	}
+
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+func (m *maildir) Close() error {
+	return nil
+}
+
+// writeFileSync writes a file and flushes it to disk,
+// so a delivered message is never truncated by a crash.
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,66 @@
+package mailbox
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"syscall"
+	"time"
+)
+
+// fromLinePattern matches body lines that must be escaped in an mbox,
+// since "From " at the start of a line begins the next message.
+// The mboxrd variant also escapes lines that were already escaped,
+// so readers can reverse the escaping exactly.
+var fromLinePattern = regexp.MustCompile(`(?m)^>*From `)
+
+// mbox appends messages to a single file.  The file is locked while
+// appending, so mail clients that also lock it (with flock) never
+// read a partially written message.
+type mbox struct {
+	file *os.File
+}
+
+func openMbox(path string) (*mbox, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &mbox{f}, nil
+}
+
+func (m *mbox) Deliver(data []byte, seen bool) error {
+	if err := syscall.Flock(int(m.file.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(m.file.Fd()), syscall.LOCK_UN)
+
+	data = toLocalLineEndings(data)
+	header, body := data, []byte{}
+	if i := bytes.Index(data, []byte("\n\n")); i >= 0 {
+		header, body = data[:i+1], data[i+2:]
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("From MAILER-DAEMON " + time.Now().UTC().Format(time.ANSIC) + "\n")
+	buf.Write(header)
+	if seen {
+		buf.WriteString("Status: RO\n")
+	}
+	buf.WriteString("\n")
+	buf.Write(fromLinePattern.ReplaceAll(body, []byte(">$0")))
+	if len(body) > 0 && !bytes.HasSuffix(body, []byte("\n")) {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+
+	// Write the whole message at once, so it's either appended or not
+	if _, err := m.file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return m.file.Sync()
+}
+
+func (m *mbox) Close() error {
+	return m.file.Close()
+}
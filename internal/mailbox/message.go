@@ -0,0 +1,185 @@
+package mailbox
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/publish"
+	"github.com/wedaly/local-news/internal/store"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// DefaultFrom is the sender address used when none is configured
+const DefaultFrom string = "localnews@localhost"
+
+// Message is a feed item delivered as an email, and the feed it came from
+type Message struct {
+	Feed   store.FeedRecord
+	Record store.FeedItemRecord
+}
+
+// NewMessages looks up the feed of each item
+func NewMessages(records []store.FeedItemRecord, feeds []store.FeedRecord) []Message {
+	feedsById := make(map[store.FeedId]store.FeedRecord, len(feeds))
+	for _, feed := range feeds {
+		feedsById[feed.Id] = feed
+	}
+
+	messages := make([]Message, len(records))
+	for i, record := range records {
+		messages[i] = Message{feedsById[record.FeedId], record}
+	}
+	return messages
+}
+
+// MessageId returns the Message-ID for an item, derived from its GUID.
+// An item has the same ID every time it's delivered, so mail clients
+// can recognize duplicates.
+func (m Message) MessageId() string {
+	sum := sha1.Sum([]byte(publish.EntryId(m.Feed.Url, m.Record.Guid)))
+	return "<" + hex.EncodeToString(sum[:]) + "@localnews>"
+}
+
+// Write formats the message as an RFC 5322 email with CRLF line endings.
+// The sender's name is the item's author and the feed's name, and the
+// body has both plain text and HTML versions of the item's content.
+func (m Message) Write(w io.Writer, fromAddress string) error {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	if err := writePart(parts, "text/plain", m.text()); err != nil {
+		return err
+	}
+	if err := writePart(parts, "text/html", m.html()); err != nil {
+		return err
+	}
+	if err := parts.Close(); err != nil {
+		return err
+	}
+
+	header := []struct{ name, value string }{
+		{"From", m.from(fromAddress)},
+		{"Date", m.date().Format(time.RFC1123Z)},
+		{"Subject", mime.QEncoding.Encode("utf-8", m.subject())},
+		{"Message-ID", m.MessageId()},
+		{"List-Id", fmt.Sprintf("%s <%d.feed.localnews>", encodePhrase(m.Feed.DisplayName()), m.Feed.Id)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": parts.Boundary()})},
+		{"X-RSS-Feed", m.Feed.Url},
+		{"X-RSS-URL", m.Record.Url},
+	}
+
+	var buf bytes.Buffer
+	for _, field := range header {
+		if len(field.value) > 0 {
+			fmt.Fprintf(&buf, "%s: %s\r\n", field.name, stripNewlines(field.value))
+		}
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// from returns the sender, named after the item's author and the feed
+func (m Message) from(address string) string {
+	if len(address) == 0 {
+		address = DefaultFrom
+	}
+
+	name := m.Feed.DisplayName()
+	if len(m.Record.Author) > 0 {
+		name = fmt.Sprintf("%s (%s)", m.Record.Author, name)
+	}
+	return (&mail.Address{Name: name, Address: address}).String()
+}
+
+func (m Message) date() time.Time {
+	if m.Record.Date.IsZero() {
+		return m.Record.Updated
+	}
+	return m.Record.Date
+}
+
+// subject returns the item's title on one line, or its URL if untitled
+func (m Message) subject() string {
+	if title := strings.Join(strings.Fields(m.Record.Title), " "); len(title) > 0 {
+		return title
+	}
+	return m.Record.Url
+}
+
+// text returns the item's content as plain text, followed by its link
+func (m Message) text() string {
+	text := m.Record.ContentText
+	if len(text) == 0 {
+		text = feed.HtmlToText(m.Record.ContentHtml)
+	}
+
+	if len(m.Record.Url) > 0 {
+		if len(text) > 0 {
+			text += "\n\n"
+		}
+		text += m.Record.Url
+	}
+	return strings.Replace(text, "\n", "\r\n", -1) + "\r\n"
+}
+
+// html returns the item's content as an HTML document, headed by its linked title
+func (m Message) html() string {
+	content := m.Record.ContentHtml
+	if len(content) == 0 {
+		content = "<pre>" + html.EscapeString(m.Record.ContentText) + "</pre>"
+	}
+
+	title := html.EscapeString(m.subject())
+	if len(m.Record.Url) > 0 {
+		title = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(m.Record.Url), title)
+	}
+
+	return fmt.Sprintf(
+		"<!DOCTYPE html>\r\n<html>\r\n<head><meta charset=\"utf-8\"></head>\r\n<body>\r\n<h1>%s</h1>\r\n%s\r\n</body>\r\n</html>\r\n",
+		title, content)
+}
+
+// writePart adds a UTF-8 part to a multipart body, quoted-printable encoded
+// so long lines and non-ASCII text survive any mail client or transport.
+func writePart(parts *multipart.Writer, mediaType string, content string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mediaType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	w, err := parts.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, content); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// encodePhrase formats a display name for a header, quoting
+// ASCII names and encoding others as RFC 2047 encoded words.
+func encodePhrase(s string) string {
+	if encoded := mime.QEncoding.Encode("utf-8", s); encoded != s {
+		return encoded
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// stripNewlines prevents values from the feed from adding header fields
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(s)
+}
@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"fmt"
+	"github.com/wedaly/local-news/internal/command"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/store"
+	"github.com/wedaly/local-news/internal/task"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config controls how the user is notified about new items
+type Config struct {
+	// Shell command to run for each notification, or empty for none.
+	// The notification's title and body are appended as arguments,
+	// so a command like "notify-send" shows a desktop notification.
+	Command string
+
+	// Whether to ring the terminal bell
+	Bell bool
+
+	// Whether to notify only about items highlighted by a filter rule
+	HighlightedOnly bool
+}
+
+// Enabled returns whether the config notifies the user in any way
+func (c Config) Enabled() bool {
+	return len(c.Command) > 0 || c.Bell
+}
+
+// commandTimeout is the maximum time a notification command can run
+const commandTimeout time.Duration = 30 * time.Second
+
+// maxItemsInBody is the number of item titles listed in a notification
+const maxItemsInBody int = 5
+
+// Notifier notifies the user when refreshing feeds brings in new items.
+// New items from tasks scheduled around the same time (for example,
+// refreshing every feed) are batched into one notification, which is
+// sent once no tasks are pending.  Items in muted feeds and items
+// hidden by filter rules are ignored.
+type Notifier struct {
+	config     Config
+	localizer  *i18n.Localizer
+	feedStore  *store.FeedStore
+	bell       func()
+	mutex      sync.Mutex
+	numPending int
+	batch      []newItem
+	sending    sync.WaitGroup
+}
+
+type newItem struct {
+	feedName string
+	item     store.FeedItemRecord
+}
+
+// NewNotifier creates a notifier, which should be subscribed to a task manager.
+// If the config enables the bell, `bell` is invoked to ring it, such as
+// `AppController.Beep`, which must be thread-safe.
+func NewNotifier(config Config, localizer *i18n.Localizer, feedStore *store.FeedStore, bell func()) *Notifier {
+	return &Notifier{
+		config:    config,
+		localizer: localizer,
+		feedStore: feedStore,
+		bell:      bell,
+	}
+}
+
+// HandleTaskScheduled implements task.TaskSubscriber
+func (n *Notifier) HandleTaskScheduled() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.numPending++
+}
+
+// HandleTaskCompleted implements task.TaskSubscriber
+func (n *Notifier) HandleTaskCompleted(r task.TaskResult) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.numPending > 0 {
+		n.numPending--
+	}
+
+	if r.Err == nil && len(r.NewItems) > 0 {
+		n.addToBatch(r.FeedId, r.NewItems)
+	}
+
+	if n.numPending > 0 || len(n.batch) == 0 {
+		return
+	}
+
+	// Send in the background, so a slow command doesn't delay
+	// notifications to other subscribers.
+	batch := n.batch
+	n.batch = nil
+	n.sending.Add(1)
+	go func() {
+		defer n.sending.Done()
+		n.send(batch)
+	}()
+}
+
+// Wait blocks until any notifications being sent are complete
+func (n *Notifier) Wait() {
+	n.sending.Wait()
+}
+
+// addToBatch adds the items that should be notified to the current batch.
+// The caller must hold the mutex.
+func (n *Notifier) addToBatch(feedId store.FeedId, items []store.FeedItemRecord) {
+	// The feed may have been deleted since the task completed
+	feedRecord, err := n.feedStore.RetrieveFeed(feedId)
+	if err != nil || feedRecord.Muted {
+		return
+	}
+
+	for _, item := range items {
+		if item.Hidden || (n.config.HighlightedOnly && !item.Highlighted) {
+			continue
+		}
+		n.batch = append(n.batch, newItem{feedRecord.DisplayName(), item})
+	}
+}
+
+// send rings the bell and runs the command.  Notifications are best-effort,
+// so errors are ignored rather than interrupting the user.
+func (n *Notifier) send(batch []newItem) {
+	if n.config.Bell && n.bell != nil {
+		n.bell()
+	}
+
+	if len(n.config.Command) > 0 {
+		title, body := formatNotification(n.localizer, batch)
+		command.RunWithArgs(n.config.Command, []string{title, body}, nil, commandTimeout)
+	}
+}
+
+// formatNotification summarizes the number of new items in the title,
+// and lists the first few items in the body.
+func formatNotification(localizer *i18n.Localizer, batch []newItem) (string, string) {
+	title := fmt.Sprintf(
+		// translators: the argument is the number of new items
+		localizer.NGettext("%v new item", "%v new items", len(batch)),
+		localizer.FormatNumber(len(batch)))
+
+	lines := make([]string, 0, maxItemsInBody+1)
+	for i, n := range batch {
+		if i == maxItemsInBody {
+			lines = append(lines, fmt.Sprintf(
+				// translators: the argument is the number of new items not listed
+				localizer.Gettext("and %v more"),
+				localizer.FormatNumber(len(batch)-maxItemsInBody)))
+			break
+		}
+
+		lines = append(lines, fmt.Sprintf(
+			// translators: [1] is the feed name and [2] is the item title
+			localizer.Gettext("%[1]v: %[2]v"),
+			n.feedName,
+			n.item.Title))
+	}
+
+	return title, strings.Join(lines, "\n")
+}
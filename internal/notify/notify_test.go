@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"fmt"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/i18n"
+	"github.com/wedaly/local-news/internal/store"
+	"github.com/wedaly/local-news/internal/task"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+)
+
+func newLocalizer(t *testing.T) *i18n.Localizer {
+	localizer, err := i18n.NewLocalizer(i18n.NewLocale("C"), "localnews", nil)
+	if err != nil {
+		t.Fatalf("Could not create localizer: %v", err)
+	}
+	return localizer
+}
+
+func execWithNotifier(t *testing.T, config Config, f func(*Notifier, *store.FeedStore)) {
+	dbPath := path.Join(os.TempDir(), "test-notify.db")
+	defer func() { os.Remove(dbPath) }()
+	feedStore := store.NewFeedStore(dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer feedStore.Close()
+
+	f(NewNotifier(config, newLocalizer(t), feedStore, nil), feedStore)
+}
+
+func createFeed(t *testing.T, feedStore *store.FeedStore, url string, muted bool) store.FeedId {
+	feedId, err := feedStore.GetOrCreateFeedWithUrl(url)
+	if err != nil {
+		t.Fatalf("Could not create feed: %v", err)
+	}
+
+	settings := store.FeedSettings{Url: url, CustomName: url, Muted: muted}
+	if err := feedStore.UpdateFeedSettings(feedId, settings); err != nil {
+		t.Fatalf("Could not update feed settings: %v", err)
+	}
+	return feedId
+}
+
+func TestNotifyBatchesNewItems(t *testing.T) {
+	outPath := path.Join(os.TempDir(), "test-notify.out")
+	defer func() { os.Remove(outPath) }()
+	os.Remove(outPath)
+
+	config := Config{Command: "printf '%s\\n%s' >" + outPath, Bell: true}
+	execWithNotifier(t, config, func(n *Notifier, feedStore *store.FeedStore) {
+		rings := 0
+		n.bell = func() { rings++ }
+
+		newsId := createFeed(t, feedStore, "news", false)
+		mutedId := createFeed(t, feedStore, "muted", true)
+
+		n.HandleTaskScheduled()
+		n.HandleTaskScheduled()
+		n.HandleTaskScheduled()
+
+		n.HandleTaskCompleted(task.TaskResult{
+			FeedId: newsId,
+			NewItems: []store.FeedItemRecord{
+				store.FeedItemRecord{Title: "First"},
+				store.FeedItemRecord{Title: "Hidden", Hidden: true},
+				store.FeedItemRecord{Title: "Second"},
+			},
+		})
+		n.HandleTaskCompleted(task.TaskResult{
+			FeedId:   mutedId,
+			NewItems: []store.FeedItemRecord{store.FeedItemRecord{Title: "Muted"}},
+		})
+
+		// Nothing is sent while a task is pending
+		n.Wait()
+		if _, err := os.Stat(outPath); err == nil {
+			t.Fatalf("Expected no notification before every task completed")
+		}
+
+		n.HandleTaskCompleted(task.TaskResult{FeedId: newsId})
+		n.Wait()
+
+		out, err := ioutil.ReadFile(outPath)
+		if err != nil {
+			t.Fatalf("Could not read command output: %v", err)
+		}
+
+		expected := "2 new items\nnews: First\nnews: Second"
+		if string(out) != expected {
+			t.Errorf("Expected notification %q, but got %q", expected, out)
+		}
+
+		if rings != 1 {
+			t.Errorf("Expected the bell to ring once, but it rang %v times", rings)
+		}
+	})
+}
+
+// completionCounter reports each completed task
+type completionCounter struct {
+	completed chan task.TaskResult
+}
+
+func (c *completionCounter) HandleTaskScheduled() {}
+
+func (c *completionCounter) HandleTaskCompleted(r task.TaskResult) {
+	c.completed <- r
+}
+
+func TestNotifyBatchesStartupRefresh(t *testing.T) {
+	execWithNotifier(t, Config{Bell: true}, func(n *Notifier, feedStore *store.FeedStore) {
+		rings := 0
+		n.bell = func() { rings++ }
+
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `
+				<rss>
+					<channel>
+						<title>Blog</title>
+						<item>
+							<title>Post</title>
+							<link>https://example.com%[1]v</link>
+							<guid>%[1]v</guid>
+							<pubDate>Sat, 06 Apr 2019 02:00:22 +0000</pubDate>
+						</item>
+					</channel>
+				</rss>`, r.URL.Path)
+		}
+		server := httptest.NewServer(http.HandlerFunc(handler))
+		defer server.Close()
+
+		feedIds := make([]store.FeedId, 3)
+		for i := range feedIds {
+			feedIds[i] = createFeed(t, feedStore, fmt.Sprintf("%v/%v", server.URL, i), false)
+		}
+
+		// The notifier is subscribed before every feed is refreshed at startup
+		tm := task.NewTaskManager(feedStore, feed.DefaultLoaderConfig())
+		tm.Subscribe(n)
+		counter := &completionCounter{make(chan task.TaskResult, len(feedIds))}
+		tm.Subscribe(counter)
+
+		for _, feedId := range feedIds {
+			tm.ScheduleLoadFeedTask(feedId)
+		}
+
+		for range feedIds {
+			if r := <-counter.completed; r.Err != nil || len(r.NewItems) != 1 {
+				t.Fatalf("Unexpected result %v", r)
+			}
+		}
+		n.Wait()
+
+		if rings != 1 {
+			t.Errorf("Expected one notification for the refresh, but the bell rang %v times", rings)
+		}
+	})
+}
+
+func TestNotifyHighlightedOnly(t *testing.T) {
+	config := Config{Bell: true, HighlightedOnly: true}
+	execWithNotifier(t, config, func(n *Notifier, feedStore *store.FeedStore) {
+		rings := 0
+		n.bell = func() { rings++ }
+
+		feedId := createFeed(t, feedStore, "news", false)
+
+		n.HandleTaskScheduled()
+		n.HandleTaskCompleted(task.TaskResult{
+			FeedId:   feedId,
+			NewItems: []store.FeedItemRecord{store.FeedItemRecord{Title: "Plain"}},
+		})
+		n.Wait()
+		if rings > 0 {
+			t.Errorf("Expected no notification for items that aren't highlighted")
+		}
+
+		n.HandleTaskScheduled()
+		n.HandleTaskCompleted(task.TaskResult{
+			FeedId:   feedId,
+			NewItems: []store.FeedItemRecord{store.FeedItemRecord{Title: "Match", Highlighted: true}},
+		})
+		n.Wait()
+		if rings != 1 {
+			t.Errorf("Expected a notification for the highlighted item")
+		}
+	})
+}
+
+func TestFormatNotificationLimitsItems(t *testing.T) {
+	batch := make([]newItem, 7)
+	for i := range batch {
+		batch[i] = newItem{"feed", store.FeedItemRecord{Title: "item"}}
+	}
+
+	title, body := formatNotification(newLocalizer(t), batch)
+	if title != "7 new items" {
+		t.Errorf("Incorrect title %q", title)
+	}
+
+	expectedBody := "feed: item\nfeed: item\nfeed: item\nfeed: item\nfeed: item\nand 2 more"
+	if body != expectedBody {
+		t.Errorf("Incorrect body %q", body)
+	}
+}
@@ -0,0 +1,395 @@
+package publish
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"github.com/wedaly/local-news/internal/choice"
+	"github.com/wedaly/local-news/internal/store"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Format is a syndication format for published feeds
+type Format int
+
+const (
+	// FormatAtom writes an Atom 1.0 feed document
+	FormatAtom Format = iota
+
+	// FormatRss writes an RSS 2.0 feed document
+	FormatRss
+)
+
+// formatNames are the names used to choose a format, e.g. on the command line
+var formatNames = []string{"atom", "rss"}
+
+// ParseFormat returns the format with the specified name, either "atom" or "rss".
+func ParseFormat(name string) (Format, error) {
+	i, err := choice.Parse(name, formatNames)
+	return Format(i), err
+}
+
+func (f Format) String() string {
+	return formatNames[f]
+}
+
+// Channel describes the published feed as a whole
+type Channel struct {
+	// Title of the published feed
+	Title string
+
+	// Where the published feed can be retrieved, if known.
+	// This is also the feed's ID, so it should not change.
+	Url string
+
+	// Website associated with the published feed (optional)
+	Link string
+
+	// Description of the published feed (optional)
+	Description string
+}
+
+// Item is a published feed item and the feed it came from
+type Item struct {
+	Feed   store.FeedRecord
+	Record store.FeedItemRecord
+}
+
+// NewItems looks up the feed of each item
+func NewItems(records []store.FeedItemRecord, feeds []store.FeedRecord) []Item {
+	feedsById := make(map[store.FeedId]store.FeedRecord, len(feeds))
+	for _, feed := range feeds {
+		feedsById[feed.Id] = feed
+	}
+
+	items := make([]Item, len(records))
+	for i, record := range records {
+		items[i] = Item{feedsById[record.FeedId], record}
+	}
+	return items
+}
+
+// Write publishes the items in the specified format
+func Write(w io.Writer, format Format, channel Channel, items []Item) error {
+	var doc interface{}
+	switch format {
+	case FormatAtom:
+		doc = atomDocument(channel, items)
+	case FormatRss:
+		doc = rssDocument(channel, items)
+	default:
+		return fmt.Errorf("Unknown format %d", format)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// EntryId returns a stable, globally unique ID for an item.
+// The item's GUID is used as-is if it's already a URI, so the same
+// item has the same ID no matter which feed it was retrieved from.
+// Otherwise the ID is derived from the feed URL and the GUID,
+// which are unique together.
+func EntryId(feedUrl string, guid string) string {
+	if u, err := url.Parse(guid); err == nil && u.IsAbs() {
+		return guid
+	}
+	return hashUrn(feedUrl + "\n" + guid)
+}
+
+// channelId returns the ID of the published feed, which is
+// its URL if known, or else derived from its title.
+func channelId(channel Channel) string {
+	if len(channel.Url) > 0 {
+		return channel.Url
+	}
+	return hashUrn(channel.Title)
+}
+
+// credentialParams are substrings of the names of query parameters
+// that usually contain credentials, such as "api_key" or "token".
+var credentialParams = []string{"key", "token", "secret", "password", "passwd", "auth", "session", "sig"}
+
+// publicUrl returns an HTTP(S) URL without its user info and
+// query parameters that look like credentials, so it can be published.
+// Other URLs (such as "exec:" commands and "file:" paths) return false.
+func publicUrl(rawUrl string) (string, bool) {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return "", false
+	}
+
+	u.User = nil
+	if len(u.RawQuery) > 0 {
+		query := u.Query()
+		for name := range query {
+			lowerName := strings.ToLower(name)
+			for _, param := range credentialParams {
+				if strings.Contains(lowerName, param) {
+					query.Del(name)
+					break
+				}
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), true
+}
+
+// sourceUrl returns the published URL of the feed an item came from.
+// This is the feed's URL if it can be published, or else the feed's website.
+func sourceUrl(feed store.FeedRecord) (string, bool) {
+	if feedUrl, ok := publicUrl(feed.Url); ok {
+		return feedUrl, true
+	}
+	return publicUrl(feed.SiteUrl)
+}
+
+func hashUrn(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return "urn:sha1:" + hex.EncodeToString(sum[:])
+}
+
+// itemUpdated returns when the item was last modified, or else published
+func itemUpdated(item Item) time.Time {
+	if !item.Record.Updated.IsZero() {
+		return item.Record.Updated
+	}
+	return item.Record.Date
+}
+
+// lastUpdated returns the date of the most recently modified item.
+// It depends only on the items, so publishing the same items again
+// produces the same document.
+func lastUpdated(items []Item) time.Time {
+	var latest time.Time
+	for _, item := range items {
+		if updated := itemUpdated(item); updated.After(latest) {
+			latest = updated
+		}
+	}
+	return latest
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Id       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Author   atomPerson  `xml:"author"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Id        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published"`
+	Authors   []atomPerson `xml:"author"`
+	Links     []atomLink   `xml:"link"`
+	Content   *atomText    `xml:"content"`
+	Source    atomSource   `xml:"source"`
+}
+
+type atomSource struct {
+	Id    string     `xml:"id"`
+	Title string     `xml:"title"`
+	Links []atomLink `xml:"link"`
+}
+
+func atomDocument(channel Channel, items []Item) atomFeed {
+	doc := atomFeed{
+		Id:       channelId(channel),
+		Title:    channel.Title,
+		Subtitle: channel.Description,
+		Updated:  formatAtomDate(lastUpdated(items)),
+
+		// Entries without their own author inherit this one
+		Author:  atomPerson{channel.Title},
+		Entries: make([]atomEntry, len(items)),
+	}
+
+	if len(channel.Url) > 0 {
+		doc.Links = append(doc.Links, atomLink{"self", channel.Url})
+	}
+	if len(channel.Link) > 0 {
+		doc.Links = append(doc.Links, atomLink{"alternate", channel.Link})
+	}
+
+	for i, item := range items {
+		entry := atomEntry{
+			Id:        EntryId(item.Feed.Url, item.Record.Guid),
+			Title:     item.Record.Title,
+			Updated:   formatAtomDate(itemUpdated(item)),
+			Published: formatAtomDate(item.Record.Date),
+			Source: atomSource{
+				Id:    hashUrn(item.Feed.Url),
+				Title: item.Feed.DisplayName(),
+			},
+		}
+
+		// Feeds with an unpublishable URL are identified by its hash
+		if feedUrl, ok := publicUrl(item.Feed.Url); ok {
+			entry.Source.Id = feedUrl
+			entry.Source.Links = []atomLink{{"self", feedUrl}}
+		}
+
+		if len(item.Record.Author) > 0 {
+			entry.Authors = []atomPerson{{item.Record.Author}}
+		}
+
+		if len(item.Record.Url) > 0 {
+			entry.Links = []atomLink{{"alternate", item.Record.Url}}
+		}
+
+		if len(item.Record.ContentHtml) > 0 {
+			entry.Content = &atomText{"html", item.Record.ContentHtml}
+		} else if len(item.Record.ContentText) > 0 {
+			entry.Content = &atomText{"text", item.Record.ContentText}
+		}
+
+		if siteUrl, ok := publicUrl(item.Feed.SiteUrl); ok {
+			entry.Source.Links = append(entry.Source.Links, atomLink{"alternate", siteUrl})
+		}
+
+		doc.Entries[i] = entry
+	}
+
+	return doc
+}
+
+func formatAtomDate(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNs  string     `xml:"xmlns:atom,attr"`
+	DcNs    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	PubDate     string    `xml:"pubDate,omitempty"`
+	Generator   string    `xml:"generator"`
+	SelfLink    *rssLink  `xml:"atom:link"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string     `xml:"title"`
+	Link        string     `xml:"link,omitempty"`
+	Description string     `xml:"description,omitempty"`
+	Creator     string     `xml:"dc:creator,omitempty"`
+	PubDate     string     `xml:"pubDate"`
+	Guid        rssGuid    `xml:"guid"`
+	Source      *rssSource `xml:"source"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Id          string `xml:",chardata"`
+}
+
+type rssSource struct {
+	Url  string `xml:"url,attr"`
+	Name string `xml:",chardata"`
+}
+
+func rssDocument(channel Channel, items []Item) rss {
+	doc := rss{
+		Version: "2.0",
+		AtomNs:  "http://www.w3.org/2005/Atom",
+		DcNs:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       channel.Title,
+			Link:        channel.Link,
+			Description: channel.Description,
+			Generator:   "localnews",
+			Items:       make([]rssItem, len(items)),
+		},
+	}
+
+	// RSS requires a link and description for the channel
+	if len(doc.Channel.Link) == 0 {
+		doc.Channel.Link = channel.Url
+	}
+	if len(doc.Channel.Description) == 0 {
+		doc.Channel.Description = channel.Title
+	}
+
+	if len(channel.Url) > 0 {
+		doc.Channel.SelfLink = &rssLink{"self", channel.Url, "application/rss+xml"}
+	}
+
+	if len(items) > 0 {
+		doc.Channel.PubDate = formatRssDate(lastUpdated(items))
+	}
+
+	for i, item := range items {
+		description := item.Record.ContentHtml
+		if len(description) == 0 {
+			description = item.Record.ContentText
+		}
+
+		doc.Channel.Items[i] = rssItem{
+			Title:       item.Record.Title,
+			Link:        item.Record.Url,
+			Description: description,
+			Creator:     item.Record.Author,
+			PubDate:     formatRssDate(item.Record.Date),
+			Guid:        rssGuid{false, EntryId(item.Feed.Url, item.Record.Guid)},
+		}
+
+		// RSS requires a URL for the source, so it's omitted if there's none to publish
+		if feedUrl, ok := sourceUrl(item.Feed); ok {
+			doc.Channel.Items[i].Source = &rssSource{feedUrl, item.Feed.DisplayName()}
+		}
+	}
+
+	return doc
+}
+
+func formatRssDate(t time.Time) string {
+	return t.UTC().Format(time.RFC1123Z)
+}
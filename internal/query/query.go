@@ -0,0 +1,232 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Field is what a search term matches
+type Field int
+
+const (
+	// FieldText matches the item's title or content
+	FieldText Field = iota
+	FieldTitle
+	FieldContent
+	FieldAuthor
+	FieldCategory
+	FieldUrl
+
+	// FieldFeed matches the name of the item's feed
+	FieldFeed
+
+	// FieldFolder matches the folder of the item's feed exactly (ignoring case)
+	FieldFolder
+
+	// FieldIs matches the item's state, for example "unread"
+	FieldIs
+)
+
+// States that can be matched with "is:"
+const (
+	StateRead        = "read"
+	StateUnread      = "unread"
+	StateHighlighted = "highlighted"
+	StateStarred     = "starred"
+)
+
+// fieldNames maps the prefixes of search terms to fields
+var fieldNames = map[string]Field{
+	"title":    FieldTitle,
+	"content":  FieldContent,
+	"author":   FieldAuthor,
+	"category": FieldCategory,
+	"tag":      FieldCategory,
+	"url":      FieldUrl,
+	"feed":     FieldFeed,
+	"folder":   FieldFolder,
+	"is":       FieldIs,
+}
+
+// Term is a condition that matching items must satisfy
+type Term struct {
+	Field Field
+
+	// Text to search for, ignoring case.  For FieldIs, this is a state.
+	Value string
+
+	// Whether items must NOT match the term
+	Negate bool
+}
+
+// Query selects feed items matching every term
+type Query struct {
+	Terms []Term
+
+	// Only items published within this duration match,
+	// or any item if zero.
+	MaxAge time.Duration
+}
+
+// Parse parses a query such as:
+//
+//	title:CVE folder:Security newer:7d is:unread
+//
+// Terms are separated by spaces, and every term must match.
+// A term is either a word (matched against the title and content)
+// or "field:value", where the value may be in double quotes
+// to include spaces.  Prefixing a term with "-" negates it.
+// "newer:" accepts a number of hours, days, or weeks, e.g. "12h" or "2w".
+func Parse(s string) (Query, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return Query{}, err
+	}
+
+	if len(tokens) == 0 {
+		return Query{}, errors.New("Query must not be empty")
+	}
+
+	q := Query{Terms: make([]Term, 0, len(tokens))}
+	for _, token := range tokens {
+		negate := false
+		if strings.HasPrefix(token.text, "-") && !token.quoted {
+			negate = true
+			token.text = token.text[1:]
+		}
+
+		name, value := "", unquote(token.text)
+		if !token.quoted && !strings.HasPrefix(token.text, "\"") {
+			if idx := strings.Index(token.text, ":"); idx > 0 {
+				name, value = strings.ToLower(token.text[:idx]), unquote(token.text[idx+1:])
+			}
+		}
+
+		if len(value) == 0 {
+			return Query{}, fmt.Errorf("Missing value in %q", token.text)
+		}
+
+		if name == "newer" {
+			if negate {
+				return Query{}, errors.New("\"newer:\" can't be negated")
+			}
+
+			maxAge, err := parseAge(value)
+			if err != nil {
+				return Query{}, err
+			}
+			q.MaxAge = maxAge
+			continue
+		}
+
+		field := FieldText
+		if len(name) > 0 {
+			var ok bool
+			if field, ok = fieldNames[name]; !ok {
+				return Query{}, fmt.Errorf("Unknown field %q", name)
+			}
+		}
+
+		if field == FieldIs {
+			value = strings.ToLower(value)
+			if value != StateRead && value != StateUnread && value != StateHighlighted && value != StateStarred {
+				return Query{}, fmt.Errorf("Unknown state %q", value)
+			}
+		}
+
+		q.Terms = append(q.Terms, Term{Field: field, Value: value, Negate: negate})
+	}
+
+	return q, nil
+}
+
+type token struct {
+	text string
+
+	// Whether the whole token was in quotes, so it can't have a field prefix
+	quoted bool
+}
+
+// tokenize splits a query on spaces, except within double quotes
+func tokenize(s string) ([]token, error) {
+	tokens := make([]token, 0)
+	var current strings.Builder
+	inQuotes, quotedToken, hasToken := false, false, false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			if !hasToken {
+				quotedToken = true
+			}
+			inQuotes = !inQuotes
+			hasToken = true
+			current.WriteRune(r)
+
+		case unicode.IsSpace(r) && !inQuotes:
+			if hasToken {
+				tokens = append(tokens, newToken(current.String(), quotedToken))
+			}
+			current.Reset()
+			quotedToken, hasToken = false, false
+
+		default:
+			hasToken = true
+			current.WriteRune(r)
+		}
+	}
+
+	if inQuotes {
+		return nil, errors.New("Missing closing quote")
+	}
+
+	if hasToken {
+		tokens = append(tokens, newToken(current.String(), quotedToken))
+	}
+
+	return tokens, nil
+}
+
+func newToken(text string, quoted bool) token {
+	if quoted {
+		text = unquote(text)
+	}
+	return token{text, quoted}
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && strings.HasPrefix(s, "\"") && strings.HasSuffix(s, "\"") {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseAge parses a number of hours, days, or weeks, e.g. "7d"
+func parseAge(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+	}
+
+	unit, ok := units[s[len(s)-1]]
+	if !ok {
+		return 0, fmt.Errorf("Invalid age %q, expected a number followed by h, d, or w", s)
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("Invalid age %q, expected a number followed by h, d, or w", s)
+	}
+
+	if int64(n) > math.MaxInt64/int64(unit) {
+		return 0, fmt.Errorf("Invalid age %q, the number is too large", s)
+	}
+
+	return time.Duration(n) * unit, nil
+}
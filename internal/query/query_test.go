@@ -0,0 +1,106 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected Query
+	}{
+		{
+			name:  "words",
+			input: "golang  generics",
+			expected: Query{Terms: []Term{
+				Term{Field: FieldText, Value: "golang"},
+				Term{Field: FieldText, Value: "generics"},
+			}},
+		},
+		{
+			name:  "fields",
+			input: "title:CVE folder:Security newer:7d is:Unread",
+			expected: Query{
+				Terms: []Term{
+					Term{Field: FieldTitle, Value: "CVE"},
+					Term{Field: FieldFolder, Value: "Security"},
+					Term{Field: FieldIs, Value: StateUnread},
+				},
+				MaxAge: 7 * 24 * time.Hour,
+			},
+		},
+		{
+			name:  "quoted values",
+			input: `author:"Jane Doe" "release notes" tag:go`,
+			expected: Query{Terms: []Term{
+				Term{Field: FieldAuthor, Value: "Jane Doe"},
+				Term{Field: FieldText, Value: "release notes"},
+				Term{Field: FieldCategory, Value: "go"},
+			}},
+		},
+		{
+			name:  "negation",
+			input: `-is:read -url:ads -"sponsored post" "-a:b"`,
+			expected: Query{Terms: []Term{
+				Term{Field: FieldIs, Value: StateRead, Negate: true},
+				Term{Field: FieldUrl, Value: "ads", Negate: true},
+				Term{Field: FieldText, Value: "sponsored post", Negate: true},
+				Term{Field: FieldText, Value: "-a:b"},
+			}},
+		},
+		{
+			name:  "starred",
+			input: "is:starred",
+			expected: Query{Terms: []Term{
+				Term{Field: FieldIs, Value: StateStarred},
+			}},
+		},
+		{
+			name:     "age in hours",
+			input:    "newer:12h",
+			expected: Query{Terms: []Term{}, MaxAge: 12 * time.Hour},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := Parse(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(q, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, q)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{"empty", "  "},
+		{"unknown field", "color:red"},
+		{"unknown state", "is:pinned"},
+		{"missing value", "title:"},
+		{"unclosed quote", `title:"foo`},
+		{"invalid age", "newer:7x"},
+		{"negative age", "newer:-1d"},
+		{"age too large", "newer:999999999999d"},
+		{"age just too large", "newer:15251w"},
+		{"negated age", "-newer:7d"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse(tc.input); err == nil {
+				t.Errorf("Expected error for %q", tc.input)
+			}
+		})
+	}
+}
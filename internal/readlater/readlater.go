@@ -0,0 +1,205 @@
+package readlater
+
+import (
+	"fmt"
+	"github.com/wedaly/local-news/internal/store"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultRetryInterval is how often unsent articles are retried
+// when the config doesn't set an interval.
+const DefaultRetryInterval time.Duration = 5 * time.Minute
+
+// Config identifies a read-later service and the user's account
+type Config struct {
+	// Base URL of a Wallabag-compatible server
+	Url string
+
+	// OAuth2 client created in the server's "API clients management" page
+	ClientId     string
+	ClientSecret string
+
+	Username string
+	Password string
+
+	// How often unsent articles are retried, or zero for the default
+	RetryInterval time.Duration
+}
+
+// Enabled returns whether a read-later service is configured
+func (c Config) Enabled() bool {
+	return len(c.Url) > 0
+}
+
+// Client saves articles to a read-later service
+type Client interface {
+	Save(url string, title string) error
+}
+
+// RejectedError means the service will never accept an article,
+// so retrying won't help.
+type RejectedError struct {
+	StatusCode int
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("The read-later service rejected the article with status %v", e.StatusCode)
+}
+
+// Result describes an attempt to send an article from the outbox
+type Result struct {
+	Entry store.OutboxEntryRecord
+
+	// Why the article couldn't be sent, or nil if it was sent
+	Err error
+
+	// Whether the article was removed from the outbox without being
+	// sent, because the service rejected it
+	Dropped bool
+
+	// Number of articles still waiting in the outbox
+	Pending int
+}
+
+// Subscriber receives notifications about articles sent from the outbox
+type Subscriber interface {
+	// HandleReadLaterResult is invoked after each attempt to send
+	// an article.  This must be thread-safe.
+	HandleReadLaterResult(Result)
+}
+
+// Outbox sends articles to a read-later service in the background.
+// Articles are stored in the database until they're sent, so articles
+// saved while the service is unreachable are sent once it's back,
+// even if the program restarted in the meantime.
+type Outbox struct {
+	client           Client
+	feedStore        *store.FeedStore
+	retryInterval    time.Duration
+	wakeChan         chan struct{}
+	stopChan         chan struct{}
+	stopped          sync.WaitGroup
+	subscribersMutex sync.Mutex
+	subscribers      []Subscriber
+}
+
+// NewOutbox creates an outbox for a client, which is
+// inactive until started.
+func NewOutbox(client Client, feedStore *store.FeedStore, retryInterval time.Duration) *Outbox {
+	if retryInterval <= 0 {
+		retryInterval = DefaultRetryInterval
+	}
+
+	return &Outbox{
+		client:        client,
+		feedStore:     feedStore,
+		retryInterval: retryInterval,
+		wakeChan:      make(chan struct{}, 1),
+		stopChan:      make(chan struct{}),
+		subscribers:   make([]Subscriber, 0),
+	}
+}
+
+// NewWallabagOutbox creates an outbox for the Wallabag server in the config
+func NewWallabagOutbox(config Config, httpClient *http.Client, feedStore *store.FeedStore) *Outbox {
+	return NewOutbox(NewWallabagClient(config, httpClient), feedStore, config.RetryInterval)
+}
+
+func (o *Outbox) Subscribe(s Subscriber) {
+	o.subscribersMutex.Lock()
+	defer o.subscribersMutex.Unlock()
+	{
+		o.subscribers = append(o.subscribers, s)
+	}
+}
+
+// Start sends the articles left in the outbox, then waits in the
+// background for new articles, retrying periodically until stopped.
+func (o *Outbox) Start() {
+	o.stopped.Add(1)
+	go func() {
+		defer o.stopped.Done()
+		ticker := time.NewTicker(o.retryInterval)
+		defer ticker.Stop()
+
+		for {
+			o.sendPending()
+			select {
+			case <-o.wakeChan:
+			case <-ticker.C:
+			case <-o.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop waits for the article being sent, if any, and stops the outbox.
+// Unsent articles remain in the database.
+func (o *Outbox) Stop() {
+	close(o.stopChan)
+	o.stopped.Wait()
+}
+
+// Save adds an article to the outbox, to be sent as soon as possible
+func (o *Outbox) Save(url string, title string) error {
+	if _, err := o.feedStore.AddOutboxEntry(url, title); err != nil {
+		return err
+	}
+
+	// Wake the outbox unless it's already awake
+	select {
+	case o.wakeChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// sendPending sends articles in the order they were saved.
+// If the service is unreachable, the remaining articles are
+// retried later.
+func (o *Outbox) sendPending() {
+	entries, err := o.feedStore.RetrieveOutboxEntries()
+	if err != nil {
+		panic(err)
+	}
+
+	for i, entry := range entries {
+		select {
+		case <-o.stopChan:
+			return
+		default:
+		}
+
+		result := Result{Entry: entry, Pending: len(entries) - i - 1}
+		result.Err = o.client.Save(entry.Url, entry.Title)
+		if _, rejected := result.Err.(*RejectedError); result.Err == nil || rejected {
+			result.Dropped = rejected
+			if err := o.feedStore.DeleteOutboxEntry(entry.Id); err != nil {
+				panic(err)
+			}
+		} else {
+			if err := o.feedStore.SetOutboxEntryError(entry.Id, result.Err); err != nil {
+				panic(err)
+			}
+			result.Pending++
+		}
+
+		o.notifyResult(result)
+		if result.Err != nil && !result.Dropped {
+			return
+		}
+	}
+}
+
+func (o *Outbox) notifyResult(r Result) {
+	o.subscribersMutex.Lock()
+	defer o.subscribersMutex.Unlock()
+	{
+		for _, s := range o.subscribers {
+			s.HandleReadLaterResult(r)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package readlater
+
+import (
+	"github.com/wedaly/local-news/internal/store"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func execWithStore(t *testing.T, f func(*store.FeedStore)) {
+	dbPath := path.Join(os.TempDir(), "test-readlater.db")
+	os.Remove(dbPath)
+	defer os.Remove(dbPath)
+
+	feedStore := store.NewFeedStore(dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer feedStore.Close()
+	f(feedStore)
+}
+
+type stubSubscriber struct {
+	resultChan chan Result
+}
+
+func (s *stubSubscriber) HandleReadLaterResult(r Result) {
+	s.resultChan <- r
+}
+
+func (s *stubSubscriber) waitForResult(t *testing.T) Result {
+	select {
+	case r := <-s.resultChan:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for result")
+		return Result{}
+	}
+}
+
+func TestOutboxQueuesWhileOffline(t *testing.T) {
+	execWithStore(t, func(feedStore *store.FeedStore) {
+		fake := &fakeWallabag{expiresIn: 3600, unavailable: true, rejectUrl: "invalid"}
+		client, server := newWallabagStandIn(fake)
+		defer server.Close()
+
+		// An article left over from the last run is sent first
+		if _, err := feedStore.AddOutboxEntry("http://foo.com/1", "First"); err != nil {
+			t.Fatalf("Could not add outbox entry: %v", err)
+		}
+
+		outbox := NewOutbox(client, feedStore, time.Hour)
+		s := &stubSubscriber{make(chan Result, 10)}
+		outbox.Subscribe(s)
+		outbox.Start()
+		defer outbox.Stop()
+
+		if r := s.waitForResult(t); r.Err == nil || r.Entry.Url != "http://foo.com/1" || r.Pending != 1 {
+			t.Fatalf("Expected failure with 1 pending article, but got %+v", r)
+		}
+
+		if err := outbox.Save("http://foo.com/2", "Second"); err != nil {
+			t.Fatalf("Could not save article: %v", err)
+		}
+
+		if r := s.waitForResult(t); r.Err == nil || r.Pending != 2 {
+			t.Fatalf("Expected failure with 2 pending articles, but got %+v", r)
+		}
+
+		// Everything is sent in order once the service is back
+		fake.Lock()
+		fake.unavailable = false
+		fake.Unlock()
+
+		if err := outbox.Save("invalid", "Rejected"); err != nil {
+			t.Fatalf("Could not save article: %v", err)
+		}
+
+		expectedPending := []int{2, 1, 0}
+		for i, pending := range expectedPending {
+			r := s.waitForResult(t)
+			if r.Pending != pending {
+				t.Errorf("Expected %v pending articles, but got %+v", pending, r)
+			}
+
+			// The service rejected the last article, so it's dropped
+			if rejected := i == 2; rejected != r.Dropped || rejected != (r.Err != nil) {
+				t.Errorf("Unexpected result %+v", r)
+			}
+		}
+
+		fake.Lock()
+		entries := fake.entries
+		fake.Unlock()
+		if len(entries) != 2 || entries[0].url != "http://foo.com/1" || entries[1].url != "http://foo.com/2" {
+			t.Errorf("Unexpected saved articles %v", entries)
+		}
+
+		remaining, err := feedStore.RetrieveOutboxEntries()
+		if err != nil {
+			t.Fatalf("Could not retrieve outbox entries: %v", err)
+		}
+
+		if len(remaining) != 0 {
+			t.Errorf("Expected empty outbox, but got %v", remaining)
+		}
+	})
+}
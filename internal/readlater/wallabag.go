@@ -0,0 +1,157 @@
+package readlater
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errUnauthorized means the server rejected the access token
+var errUnauthorized = errors.New("The read-later service rejected the credentials")
+
+// tokenExpiryMargin renews access tokens shortly before they expire
+const tokenExpiryMargin time.Duration = 30 * time.Second
+
+// wallabagClient saves articles using the Wallabag API
+// (https://doc.wallabag.org/en/developer/api/oauth.html),
+// authenticating with the OAuth2 password grant.
+type wallabagClient struct {
+	config     Config
+	baseUrl    string
+	httpClient *http.Client
+
+	mutex        sync.Mutex
+	accessToken  string
+	refreshToken string
+	expires      time.Time
+}
+
+// NewWallabagClient creates a client for a Wallabag-compatible server
+func NewWallabagClient(config Config, httpClient *http.Client) Client {
+	return &wallabagClient{
+		config:     config,
+		baseUrl:    strings.TrimSuffix(config.Url, "/"),
+		httpClient: httpClient,
+	}
+}
+
+func (c *wallabagClient) Save(articleUrl string, title string) error {
+	form := url.Values{"url": {articleUrl}}
+	if len(title) > 0 {
+		form.Set("title", title)
+	}
+
+	err := c.postEntry(form, false)
+	if err == errUnauthorized {
+		// The token may have been revoked, so try once more with a new one
+		err = c.postEntry(form, true)
+	}
+	return err
+}
+
+func (c *wallabagClient) postEntry(form url.Values, renew bool) error {
+	token, err := c.token(renew)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.baseUrl+"/api/entries.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
+}
+
+// token returns an access token, requesting a new one if it expired.
+// Expired tokens are refreshed if possible, or else the client logs in again.
+func (c *wallabagClient) token(renew bool) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !renew && len(c.accessToken) > 0 && time.Now().Before(c.expires) {
+		return c.accessToken, nil
+	}
+
+	if len(c.refreshToken) > 0 {
+		err := c.requestToken(url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {c.refreshToken},
+		})
+		if err == nil {
+			return c.accessToken, nil
+		}
+	}
+
+	err := c.requestToken(url.Values{
+		"grant_type": {"password"},
+		"username":   {c.config.Username},
+		"password":   {c.config.Password},
+	})
+	return c.accessToken, err
+}
+
+// requestToken requests a new access token with the specified grant.
+// This must be called with the mutex held.
+func (c *wallabagClient) requestToken(form url.Values) error {
+	form.Set("client_id", c.config.ClientId)
+	form.Set("client_secret", c.config.ClientSecret)
+
+	resp, err := c.httpClient.PostForm(c.baseUrl+"/oauth/v2/token", form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The token endpoint responds with 400 for invalid credentials
+	if resp.StatusCode == http.StatusBadRequest {
+		return errUnauthorized
+	} else if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	var result struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	} else if len(result.AccessToken) == 0 {
+		return errors.New("The read-later service didn't provide an access token")
+	}
+
+	c.accessToken = result.AccessToken
+	c.refreshToken = result.RefreshToken
+	c.expires = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - tokenExpiryMargin)
+	return nil
+}
+
+// checkResponse returns an error if the server responded unsuccessfully.
+// Only invalid requests are rejected permanently.  Other client errors,
+// such as a wrong base URL (404) or a proxy error (407), are retried,
+// since fixing the settings lets the queued articles be sent.
+func checkResponse(resp *http.Response) error {
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return errUnauthorized
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity:
+		return &RejectedError{resp.StatusCode}
+	default:
+		return fmt.Errorf("Server responded with status %v", resp.Status)
+	}
+}
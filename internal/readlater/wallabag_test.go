@@ -0,0 +1,170 @@
+package readlater
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type savedEntry struct {
+	url   string
+	title string
+}
+
+// fakeWallabag is a stand-in for a Wallabag server
+type fakeWallabag struct {
+	sync.Mutex
+	tokenCount  int
+	grants      []string
+	validToken  string
+	expiresIn   int
+	entries     []savedEntry
+	rejectUrl   string
+	unavailable bool
+}
+
+func (f *fakeWallabag) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.unavailable {
+		http.Error(w, "Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/oauth/v2/token":
+		if r.FormValue("client_id") != "client" || r.FormValue("client_secret") != "secret" {
+			http.Error(w, `{"error": "invalid_client"}`, http.StatusBadRequest)
+			return
+		}
+
+		grant := r.FormValue("grant_type")
+		switch {
+		case grant == "password" && r.FormValue("username") == "user" && r.FormValue("password") == "pass":
+		case grant == "refresh_token" && r.FormValue("refresh_token") == "refresh":
+		default:
+			http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		f.tokenCount++
+		f.grants = append(f.grants, grant)
+		f.validToken = fmt.Sprintf("token%d", f.tokenCount)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  f.validToken,
+			"refresh_token": "refresh",
+			"expires_in":    f.expiresIn,
+			"token_type":    "bearer",
+		})
+
+	case "/api/entries.json":
+		if r.Header.Get("Authorization") != "Bearer "+f.validToken {
+			http.Error(w, `{"error": "invalid_grant"}`, http.StatusUnauthorized)
+			return
+		}
+
+		if r.FormValue("url") == f.rejectUrl {
+			http.Error(w, "Invalid URL", http.StatusBadRequest)
+			return
+		}
+
+		f.entries = append(f.entries, savedEntry{r.FormValue("url"), r.FormValue("title")})
+		fmt.Fprint(w, `{"id": 1}`)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newWallabagStandIn(fake *fakeWallabag) (Client, *httptest.Server) {
+	server := httptest.NewServer(fake)
+	config := Config{
+		Url:          server.URL + "/",
+		ClientId:     "client",
+		ClientSecret: "secret",
+		Username:     "user",
+		Password:     "pass",
+	}
+	return NewWallabagClient(config, server.Client()), server
+}
+
+func TestWallabagSave(t *testing.T) {
+	fake := &fakeWallabag{expiresIn: 3600}
+	client, server := newWallabagStandIn(fake)
+	defer server.Close()
+
+	for i := 1; i <= 2; i++ {
+		if err := client.Save(fmt.Sprintf("http://foo.com/%d", i), "Foo"); err != nil {
+			t.Fatalf("Could not save article: %v", err)
+		}
+	}
+
+	// The token is reused until it expires
+	if fake.tokenCount != 1 {
+		t.Errorf("Expected 1 token request, but got %v", fake.tokenCount)
+	}
+
+	expected := []savedEntry{{"http://foo.com/1", "Foo"}, {"http://foo.com/2", "Foo"}}
+	if len(fake.entries) != 2 || fake.entries[0] != expected[0] || fake.entries[1] != expected[1] {
+		t.Errorf("Expected %v, but got %v", expected, fake.entries)
+	}
+}
+
+func TestWallabagRenewToken(t *testing.T) {
+	// Tokens expire immediately, so they're refreshed before each request
+	fake := &fakeWallabag{expiresIn: 0}
+	client, server := newWallabagStandIn(fake)
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := client.Save("http://foo.com", ""); err != nil {
+			t.Fatalf("Could not save article: %v", err)
+		}
+	}
+
+	// A revoked token is replaced
+	fake.validToken = "revoked"
+	fake.expiresIn = 3600
+	if err := client.Save("http://foo.com", ""); err != nil {
+		t.Fatalf("Could not save article: %v", err)
+	}
+
+	expected := []string{"password", "refresh_token", "refresh_token"}
+	if fmt.Sprint(fake.grants) != fmt.Sprint(expected) {
+		t.Errorf("Expected grants %v, but got %v", expected, fake.grants)
+	}
+}
+
+func TestWallabagErrors(t *testing.T) {
+	fake := &fakeWallabag{expiresIn: 3600, rejectUrl: "invalid"}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	config := Config{Url: server.URL, ClientId: "client", ClientSecret: "secret", Username: "user", Password: "wrong"}
+	if err := NewWallabagClient(config, server.Client()).Save("http://foo.com", ""); err != errUnauthorized {
+		t.Errorf("Expected unauthorized error, but got %v", err)
+	}
+
+	config.Password = "pass"
+	client := NewWallabagClient(config, server.Client())
+	if err, ok := client.Save("invalid", "").(*RejectedError); !ok || err.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected rejected error, but got %v", err)
+	}
+
+	// A wrong base URL isn't a permanent rejection
+	config.Url = server.URL + "/wrong"
+	err := NewWallabagClient(config, server.Client()).Save("http://foo.com", "")
+	if _, rejected := err.(*RejectedError); err == nil || rejected {
+		t.Errorf("Expected temporary error for wrong URL, but got %v", err)
+	}
+
+	fake.unavailable = true
+	err = client.Save("http://foo.com", "")
+	if _, rejected := err.(*RejectedError); err == nil || rejected {
+		t.Errorf("Expected temporary error, but got %v", err)
+	}
+}
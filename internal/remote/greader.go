@@ -0,0 +1,414 @@
+package remote
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/greader"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Paging of the Google Reader API
+const (
+	// Number of items requested at once, which is the most many servers allow
+	greaderPageSize int = 1000
+)
+
+// errUnauthorized is returned when the server rejects the auth token
+var errUnauthorized = errors.New("The server rejected the username or password")
+
+// greaderClient uses the Google Reader API as implemented by FreshRSS
+// and others.  It logs in with the username and password when needed.
+type greaderClient struct {
+	account    Account
+	baseUrl    string
+	httpClient *http.Client
+
+	// Guards the auth token, since tasks use the client concurrently
+	mutex     sync.Mutex
+	authToken string
+}
+
+func newGReaderClient(account Account, baseUrl string, httpClient *http.Client) *greaderClient {
+	return &greaderClient{account: account, baseUrl: baseUrl, httpClient: httpClient}
+}
+
+type greaderItem struct {
+	Id        string `json:"id"`
+	Published int64  `json:"published"`
+	Updated   int64  `json:"updated"`
+	Title     string `json:"title"`
+	Author    string `json:"author"`
+	Canonical []struct {
+		Href string `json:"href"`
+	} `json:"canonical"`
+	Alternate []struct {
+		Href string `json:"href"`
+	} `json:"alternate"`
+	Summary struct {
+		Content string `json:"content"`
+	} `json:"summary"`
+	Content struct {
+		Content string `json:"content"`
+	} `json:"content"`
+	Categories []string `json:"categories"`
+	Enclosure  []struct {
+		Href   string `json:"href"`
+		Type   string `json:"type"`
+		Length string `json:"length"`
+	} `json:"enclosure"`
+}
+
+func (c *greaderClient) Subscriptions() ([]Subscription, error) {
+	var resp struct {
+		Subscriptions []struct {
+			Id         string `json:"id"`
+			Title      string `json:"title"`
+			Url        string `json:"url"`
+			HtmlUrl    string `json:"htmlUrl"`
+			Categories []struct {
+				Id    string `json:"id"`
+				Label string `json:"label"`
+			} `json:"categories"`
+		} `json:"subscriptions"`
+	}
+	if err := c.get("subscription/list", url.Values{}, &resp); err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]Subscription, len(resp.Subscriptions))
+	for i, s := range resp.Subscriptions {
+		subscriptions[i] = Subscription{
+			Id:      s.Id,
+			Title:   s.Title,
+			FeedUrl: s.Url,
+			SiteUrl: s.HtmlUrl,
+		}
+
+		// Folders are labels, and a feed can only be in one folder
+		if len(s.Categories) > 0 {
+			subscriptions[i].Category = s.Categories[0].Label
+			if len(subscriptions[i].Category) == 0 {
+				subscriptions[i].Category = labelName(s.Categories[0].Id)
+			}
+		}
+
+		// Some servers only include the URL in the ID
+		if len(s.Url) == 0 {
+			subscriptions[i].FeedUrl = strings.TrimPrefix(s.Id, "feed/")
+		}
+	}
+	return subscriptions, nil
+}
+
+func (c *greaderClient) Entries(subscriptionId string, since time.Time) ([]Entry, error) {
+	params := url.Values{"n": {strconv.Itoa(greaderPageSize)}, "r": {"o"}}
+	if !since.IsZero() {
+		params.Set("ot", strconv.FormatInt(since.Unix(), 10))
+	}
+
+	entries := make([]Entry, 0)
+	seen := make(map[string]bool, 0)
+	for {
+		var page struct {
+			Items        []greaderItem `json:"items"`
+			Continuation string        `json:"continuation"`
+		}
+		if err := c.get("stream/contents/"+url.PathEscape(subscriptionId), params, &page); err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			entry, err := newGReaderEntry(item)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+			seen[entry.Id] = true
+		}
+
+		if len(page.Continuation) == 0 || len(page.Items) == 0 {
+			break
+		}
+		params.Set("c", page.Continuation)
+	}
+
+	// The server doesn't report when states changed, so retrieve
+	// the state of the most recent entries in the subscription.
+	recentIds, err := c.itemIds(url.Values{"s": {subscriptionId}})
+	if err != nil {
+		return nil, err
+	}
+
+	unreadIds, err := c.itemIds(url.Values{"s": {subscriptionId}, "xt": {greader.Read}})
+	if err != nil {
+		return nil, err
+	}
+
+	starredIds, err := c.itemIds(url.Values{"s": {subscriptionId}, "it": {greader.Starred}})
+	if err != nil {
+		return nil, err
+	}
+
+	unread := make(map[string]bool, len(unreadIds))
+	for _, id := range unreadIds {
+		unread[id] = true
+	}
+
+	starred := make(map[string]bool, len(starredIds))
+	for _, id := range starredIds {
+		starred[id] = true
+	}
+
+	for _, id := range recentIds {
+		if !seen[id] {
+			entries = append(entries, Entry{Id: id, Read: !unread[id], Starred: starred[id], StateOnly: true})
+		}
+	}
+	return entries, nil
+}
+
+// itemIds retrieves the decimal IDs of the most recent items in a stream
+func (c *greaderClient) itemIds(params url.Values) ([]string, error) {
+	params.Set("n", strconv.Itoa(greaderPageSize))
+	var resp struct {
+		ItemRefs []struct {
+			Id string `json:"id"`
+		} `json:"itemRefs"`
+	}
+	if err := c.get("stream/items/ids", params, &resp); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(resp.ItemRefs))
+	for _, ref := range resp.ItemRefs {
+		id, err := parseGReaderItemId(ref.Id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func newGReaderEntry(item greaderItem) (Entry, error) {
+	id, err := parseGReaderItemId(item.Id)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{
+		Id: id,
+		Item: feed.FeedItem{
+			Title:       item.Title,
+			ContentHtml: item.Content.Content,
+		},
+	}
+
+	if item.Published > 0 {
+		entry.Item.Date = time.Unix(item.Published, 0)
+	}
+
+	if item.Updated > item.Published {
+		entry.Item.Updated = time.Unix(item.Updated, 0)
+	}
+
+	if len(entry.Item.ContentHtml) == 0 {
+		entry.Item.ContentHtml = item.Summary.Content
+	}
+
+	if len(item.Canonical) > 0 {
+		entry.Item.Url = item.Canonical[0].Href
+	} else if len(item.Alternate) > 0 {
+		entry.Item.Url = item.Alternate[0].Href
+	}
+
+	if len(item.Author) > 0 {
+		entry.Item.Authors = []feed.Author{{Name: item.Author}}
+	}
+
+	for _, enclosure := range item.Enclosure {
+		length, _ := strconv.ParseInt(enclosure.Length, 10, 64)
+		entry.Item.Enclosures = append(entry.Item.Enclosures, feed.Enclosure{
+			Url:      enclosure.Href,
+			MimeType: enclosure.Type,
+			Length:   length,
+		})
+	}
+
+	// States and labels are categories too, but aren't the item's own categories
+	for _, category := range item.Categories {
+		switch greader.NormalizeStreamId(category) {
+		case greader.Read:
+			entry.Read = true
+		case greader.Starred:
+			entry.Starred = true
+		default:
+			if !strings.HasPrefix(category, "user/") {
+				entry.Item.Categories = append(entry.Item.Categories, category)
+			}
+		}
+	}
+
+	return entry, nil
+}
+
+func (c *greaderClient) SetRead(entryIds []string, read bool) error {
+	return c.editTag(entryIds, greader.Read, read)
+}
+
+func (c *greaderClient) SetStarred(entryIds []string, starred bool) error {
+	return c.editTag(entryIds, greader.Starred, starred)
+}
+
+// editTag adds or removes a state for entries
+func (c *greaderClient) editTag(entryIds []string, tag string, add bool) error {
+	if len(entryIds) == 0 {
+		return nil
+	}
+
+	// Changes require a short-lived token to prevent forgery
+	token, err := c.request("GET", "token", nil, nil)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{"i": entryIds, "T": {strings.TrimSpace(string(token))}}
+	if add {
+		form.Set("a", tag)
+	} else {
+		form.Set("r", tag)
+	}
+
+	_, err = c.request("POST", "edit-tag", nil, form)
+	return err
+}
+
+// get sends a GET request to an API endpoint and decodes the JSON response
+func (c *greaderClient) get(endpoint string, params url.Values, result interface{}) error {
+	params.Set("output", "json")
+	body, err := c.request("GET", endpoint, params, nil)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, result)
+}
+
+// request sends a request to an API endpoint, logging in if necessary,
+// and returns the response body.
+func (c *greaderClient) request(method string, endpoint string, params url.Values, form url.Values) ([]byte, error) {
+	body, err := c.requestWithToken(method, endpoint, params, form, false)
+	if err == errUnauthorized {
+		// The token may have expired, so log in again
+		body, err = c.requestWithToken(method, endpoint, params, form, true)
+	}
+	return body, err
+}
+
+func (c *greaderClient) requestWithToken(method string, endpoint string, params url.Values, form url.Values, renew bool) ([]byte, error) {
+	authToken, err := c.login(renew)
+	if err != nil {
+		return nil, err
+	}
+
+	u := c.baseUrl + "/reader/api/0/" + endpoint
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+
+	var reqBody io.Reader
+	if form != nil {
+		reqBody = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequest(method, u, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Authorization", "GoogleLogin auth="+authToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, errUnauthorized
+	} else if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// login returns the auth token, logging in with the username and
+// password if there isn't one yet or if it must be renewed.
+func (c *greaderClient) login(renew bool) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(c.authToken) > 0 && !renew {
+		return c.authToken, nil
+	}
+
+	form := url.Values{"Email": {c.account.Username}, "Passwd": {c.account.Password}}
+	resp, err := c.httpClient.PostForm(c.baseUrl+"/accounts/ClientLogin", form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", errUnauthorized
+	} else if err := checkResponse(resp); err != nil {
+		return "", err
+	}
+
+	// The response has lines like "Auth=token"
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "Auth=") {
+			c.authToken = strings.TrimPrefix(line, "Auth=")
+			return c.authToken, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New("The server's login response didn't include a token")
+}
+
+// parseGReaderItemId converts an item ID to the short, decimal form.
+// The long form is a tag URI ending in the ID in hexadecimal.
+func parseGReaderItemId(s string) (string, error) {
+	if !strings.HasPrefix(s, greader.ItemIdPrefix) {
+		if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+			return "", errors.New("Invalid item ID '" + s + "'")
+		}
+		return s, nil
+	}
+
+	id, err := strconv.ParseUint(strings.TrimPrefix(s, greader.ItemIdPrefix), 16, 64)
+	if err != nil {
+		return "", errors.New("Invalid item ID '" + s + "'")
+	}
+	return strconv.FormatInt(int64(id), 10), nil
+}
+
+// labelName returns the name of a label stream, e.g. "user/-/label/News"
+func labelName(streamId string) string {
+	return strings.TrimPrefix(greader.NormalizeStreamId(streamId), greader.LabelPrefix)
+}
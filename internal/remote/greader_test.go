@@ -0,0 +1,222 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/wedaly/local-news/internal/greader"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGReader is a stand-in for a Google Reader API server
+type fakeGReader struct {
+	sync.Mutex
+	authToken string
+	logins    int
+	read      map[string]bool
+	starred   map[string]bool
+}
+
+func newFakeGReader() *fakeGReader {
+	return &fakeGReader{
+		authToken: "token1",
+		read:      map[string]bool{"2": true},
+		starred:   map[string]bool{"3": true},
+	}
+}
+
+func (f *fakeGReader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	r.ParseForm()
+
+	if r.URL.Path == "/api/accounts/ClientLogin" {
+		if r.FormValue("Email") != "user" || r.FormValue("Passwd") != "pass" {
+			http.Error(w, "Error=BadAuthentication", http.StatusForbidden)
+			return
+		}
+		f.logins++
+		fmt.Fprintf(w, "SID=x\nLSID=x\nAuth=%s\n", f.authToken)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "GoogleLogin auth="+f.authToken {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Items 1-3 are in feed/1, and the newest is item 3
+	switch strings.TrimPrefix(r.URL.Path, "/api/reader/api/0/") {
+	case "subscription/list":
+		fmt.Fprint(w, `{"subscriptions": [{"id": "feed/1", "title": "Foo", "url": "http://foo.com/feed",
+			"htmlUrl": "http://foo.com", "categories": [{"id": "user/1/label/News", "label": "News"}]}]}`)
+
+	case "stream/contents/feed/1":
+		// Only the newest item was published after the last sync
+		if r.FormValue("ot") != "250" {
+			http.Error(w, "Unexpected time", http.StatusBadRequest)
+			return
+		}
+		item := map[string]interface{}{
+			"id":         "tag:google.com,2005:reader/item/0000000000000003",
+			"published":  300,
+			"title":      "Third",
+			"canonical":  []map[string]string{{"href": "http://foo.com/3"}},
+			"summary":    map[string]string{"content": "<p>Hi</p>"},
+			"author":     "Ann",
+			"categories": []string{"user/1/state/com.google/reading-list", "user/1/state/com.google/starred", "tech"},
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": []interface{}{item}})
+
+	case "stream/items/ids":
+		refs := make([]map[string]string, 0)
+		for _, id := range []string{"3", "2", "1"} {
+			include := true
+			if r.FormValue("xt") == greader.Read {
+				include = !f.read[id]
+			} else if r.FormValue("it") == greader.Starred {
+				include = f.starred[id]
+			}
+			if include {
+				refs = append(refs, map[string]string{"id": id})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"itemRefs": refs})
+
+	case "token":
+		fmt.Fprint(w, "edit-token\n")
+
+	case "edit-tag":
+		if r.FormValue("T") != "edit-token" {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+		states := map[string]map[string]bool{greader.Read: f.read, greader.Starred: f.starred}
+		for _, id := range r.Form["i"] {
+			if tag := r.FormValue("a"); len(tag) > 0 {
+				states[tag][id] = true
+			}
+			if tag := r.FormValue("r"); len(tag) > 0 {
+				delete(states[tag], id)
+			}
+		}
+		fmt.Fprint(w, "OK")
+
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+func newGReaderStandIn(t *testing.T, fake *fakeGReader) (Client, func()) {
+	server := httptest.NewServer(fake)
+	account := Account{Name: "home", Kind: KindGReader, Url: server.URL + "/api", Username: "user", Password: "pass"}
+	client, err := NewClient(account, server.Client())
+	if err != nil {
+		t.Fatalf("Could not create client: %v", err)
+	}
+	return client, server.Close
+}
+
+func TestGReaderSubscriptions(t *testing.T) {
+	fake := newFakeGReader()
+	client, closeServer := newGReaderStandIn(t, fake)
+	defer closeServer()
+
+	subscriptions, err := client.Subscriptions()
+	if err != nil {
+		t.Fatalf("Could not retrieve subscriptions: %v", err)
+	}
+
+	expected := []Subscription{{
+		Id:       "feed/1",
+		Title:    "Foo",
+		FeedUrl:  "http://foo.com/feed",
+		SiteUrl:  "http://foo.com",
+		Category: "News",
+	}}
+	if !reflect.DeepEqual(subscriptions, expected) {
+		t.Errorf("Expected %v, but got %v", expected, subscriptions)
+	}
+}
+
+func TestGReaderEntries(t *testing.T) {
+	fake := newFakeGReader()
+	client, closeServer := newGReaderStandIn(t, fake)
+	defer closeServer()
+
+	entries, err := client.Entries("feed/1", time.Unix(250, 0))
+	if err != nil {
+		t.Fatalf("Could not retrieve entries: %v", err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, but got %v", entries)
+	}
+
+	e := entries[0]
+	if e.Id != "3" || e.StateOnly || e.Read || !e.Starred || !e.Changed.IsZero() {
+		t.Errorf("Unexpected entry %v", e)
+	}
+
+	if e.Item.Title != "Third" || e.Item.Url != "http://foo.com/3" || e.Item.ContentHtml != "<p>Hi</p>" ||
+		!e.Item.Date.Equal(time.Unix(300, 0)) || len(e.Item.Authors) != 1 ||
+		!reflect.DeepEqual(e.Item.Categories, []string{"tech"}) {
+		t.Errorf("Unexpected item %v", e.Item)
+	}
+
+	// The older entries are only retrieved for their state
+	expected := []Entry{{Id: "2", Read: true, StateOnly: true}, {Id: "1", StateOnly: true}}
+	if !reflect.DeepEqual(entries[1:], expected) {
+		t.Errorf("Expected %v, but got %v", expected, entries[1:])
+	}
+}
+
+func TestGReaderSetState(t *testing.T) {
+	fake := newFakeGReader()
+	client, closeServer := newGReaderStandIn(t, fake)
+	defer closeServer()
+
+	if err := client.SetRead([]string{"1", "3"}, true); err != nil {
+		t.Fatalf("Could not mark entries read: %v", err)
+	}
+
+	// Log in again when the token expires
+	fake.authToken = "token2"
+	if err := client.SetStarred([]string{"3"}, false); err != nil {
+		t.Fatalf("Could not unstar entry: %v", err)
+	}
+
+	if fake.logins != 2 {
+		t.Errorf("Expected to log in twice, but logged in %v times", fake.logins)
+	}
+
+	read := make([]string, 0)
+	for id := range fake.read {
+		read = append(read, id)
+	}
+	sort.Strings(read)
+	if !reflect.DeepEqual(read, []string{"1", "2", "3"}) || len(fake.starred) != 0 {
+		t.Errorf("Unexpected read %v and starred %v entries", read, fake.starred)
+	}
+}
+
+func TestGReaderBadPassword(t *testing.T) {
+	server := httptest.NewServer(newFakeGReader())
+	defer server.Close()
+
+	account := Account{Kind: KindGReader, Url: server.URL + "/api", Username: "user", Password: "wrong"}
+	client, err := NewClient(account, server.Client())
+	if err != nil {
+		t.Fatalf("Could not create client: %v", err)
+	}
+
+	if _, err := client.Subscriptions(); err != errUnauthorized {
+		t.Errorf("Expected unauthorized error, but got %v", err)
+	}
+}
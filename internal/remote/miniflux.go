@@ -0,0 +1,254 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/wedaly/local-news/internal/feed"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// minifluxPageSize is the number of entries retrieved per request
+const minifluxPageSize int = 100
+
+// minifluxClient uses Miniflux's REST API (https://miniflux.app/docs/api.html)
+type minifluxClient struct {
+	account    Account
+	baseUrl    string
+	httpClient *http.Client
+}
+
+func newMinifluxClient(account Account, baseUrl string, httpClient *http.Client) *minifluxClient {
+	return &minifluxClient{account, baseUrl, httpClient}
+}
+
+type minifluxFeed struct {
+	Id       int64  `json:"id"`
+	Title    string `json:"title"`
+	FeedUrl  string `json:"feed_url"`
+	SiteUrl  string `json:"site_url"`
+	Category struct {
+		Title string `json:"title"`
+	} `json:"category"`
+}
+
+type minifluxEntry struct {
+	Id          int64     `json:"id"`
+	Status      string    `json:"status"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	Author      string    `json:"author"`
+	Content     string    `json:"content"`
+	PublishedAt time.Time `json:"published_at"`
+	ChangedAt   time.Time `json:"changed_at"`
+	Starred     bool      `json:"starred"`
+	Tags        []string  `json:"tags"`
+	Enclosures  []struct {
+		Url      string `json:"url"`
+		MimeType string `json:"mime_type"`
+		Size     int64  `json:"size"`
+	} `json:"enclosures"`
+}
+
+func (c *minifluxClient) Subscriptions() ([]Subscription, error) {
+	var feeds []minifluxFeed
+	if err := c.do("GET", "/v1/feeds", nil, &feeds); err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]Subscription, len(feeds))
+	for i, f := range feeds {
+		subscriptions[i] = Subscription{
+			Id:       strconv.FormatInt(f.Id, 10),
+			Title:    f.Title,
+			FeedUrl:  f.FeedUrl,
+			SiteUrl:  f.SiteUrl,
+			Category: f.Category.Title,
+		}
+	}
+	return subscriptions, nil
+}
+
+func (c *minifluxClient) Entries(subscriptionId string, since time.Time) ([]Entry, error) {
+	params := url.Values{
+		"order":     {"id"},
+		"direction": {"asc"},
+		"limit":     {strconv.Itoa(minifluxPageSize)},
+	}
+
+	// Miniflux updates an entry's "changed_at" when its status changes
+	if !since.IsZero() {
+		params.Set("changed_after", strconv.FormatInt(since.Unix(), 10))
+	}
+
+	entries := make([]Entry, 0)
+	for {
+		params.Set("offset", strconv.Itoa(len(entries)))
+		var page struct {
+			Total   int             `json:"total"`
+			Entries []minifluxEntry `json:"entries"`
+		}
+		path := fmt.Sprintf("/v1/feeds/%s/entries?%s", url.PathEscape(subscriptionId), params.Encode())
+		if err := c.do("GET", path, nil, &page); err != nil {
+			return nil, err
+		}
+
+		for _, e := range page.Entries {
+			entries = append(entries, newMinifluxEntry(e))
+		}
+
+		if len(page.Entries) == 0 || len(entries) >= page.Total {
+			return entries, nil
+		}
+	}
+}
+
+func newMinifluxEntry(e minifluxEntry) Entry {
+	item := feed.FeedItem{
+		Title:       e.Title,
+		Date:        e.PublishedAt,
+		Url:         e.Url,
+		ContentHtml: e.Content,
+		Categories:  e.Tags,
+	}
+
+	if len(e.Author) > 0 {
+		item.Authors = []feed.Author{{Name: e.Author}}
+	}
+
+	for _, enclosure := range e.Enclosures {
+		item.Enclosures = append(item.Enclosures, feed.Enclosure{
+			Url:      enclosure.Url,
+			MimeType: enclosure.MimeType,
+			Length:   enclosure.Size,
+		})
+	}
+
+	return Entry{
+		Id:      strconv.FormatInt(e.Id, 10),
+		Item:    item,
+		Read:    e.Status == "read",
+		Starred: e.Starred,
+		Changed: e.ChangedAt,
+	}
+}
+
+func (c *minifluxClient) SetRead(entryIds []string, read bool) error {
+	ids, err := parseEntryIds(entryIds)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	status := "unread"
+	if read {
+		status = "read"
+	}
+
+	body := map[string]interface{}{"entry_ids": ids, "status": status}
+	return c.do("PUT", "/v1/entries", body, nil)
+}
+
+func (c *minifluxClient) SetStarred(entryIds []string, starred bool) error {
+	ids, err := parseEntryIds(entryIds)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	// Miniflux can only toggle the bookmark, so check the current state first
+	starredIds, err := c.starredIds()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if starredIds[id] != starred {
+			if err := c.do("PUT", fmt.Sprintf("/v1/entries/%d/bookmark", id), nil, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// starredIds retrieves the IDs of every starred entry
+func (c *minifluxClient) starredIds() (map[int64]bool, error) {
+	params := url.Values{"starred": {"true"}, "limit": {strconv.Itoa(minifluxPageSize)}}
+	ids := make(map[int64]bool, 0)
+	for {
+		params.Set("offset", strconv.Itoa(len(ids)))
+		var page struct {
+			Total   int             `json:"total"`
+			Entries []minifluxEntry `json:"entries"`
+		}
+		if err := c.do("GET", "/v1/entries?"+params.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+
+		for _, e := range page.Entries {
+			ids[e.Id] = true
+		}
+
+		if len(page.Entries) == 0 || len(ids) >= page.Total {
+			return ids, nil
+		}
+	}
+}
+
+// do sends a request with an optional JSON body, and decodes
+// the JSON response into the result, if not nil.
+func (c *minifluxClient) do(method string, path string, body interface{}, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseUrl+path, reqBody)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if len(c.account.Token) > 0 {
+		req.Header.Set("X-Auth-Token", c.account.Token)
+	} else {
+		req.SetBasicAuth(c.account.Username, c.account.Password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func parseEntryIds(entryIds []string) ([]int64, error) {
+	ids := make([]int64, len(entryIds))
+	for i, s := range entryIds {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid entry ID '%v'", s)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
@@ -0,0 +1,217 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMiniflux is a stand-in for a Miniflux server
+type fakeMiniflux struct {
+	sync.Mutex
+	feeds   []minifluxFeed
+	entries []fakeMinifluxEntry
+}
+
+type fakeMinifluxEntry struct {
+	feedId int64
+	minifluxEntry
+}
+
+func (f *fakeMiniflux) addFeed(id int64, feedUrl string, category string) {
+	mf := minifluxFeed{Id: id, Title: fmt.Sprintf("Feed %d", id), FeedUrl: feedUrl}
+	mf.Category.Title = category
+	f.feeds = append(f.feeds, mf)
+}
+
+func (f *fakeMiniflux) addEntry(feedId int64, id int64, status string, changed time.Time) {
+	e := minifluxEntry{
+		Id:          id,
+		Status:      status,
+		Title:       fmt.Sprintf("Entry %d", id),
+		Url:         fmt.Sprintf("http://example.com/%d", id),
+		PublishedAt: time.Unix(id*100, 0),
+		ChangedAt:   changed,
+	}
+	f.entries = append(f.entries, fakeMinifluxEntry{feedId, e})
+}
+
+func (f *fakeMiniflux) entry(id int64) *minifluxEntry {
+	for i := range f.entries {
+		if f.entries[i].Id == id {
+			return &f.entries[i].minifluxEntry
+		}
+	}
+	return nil
+}
+
+func (f *fakeMiniflux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.Header.Get("X-Auth-Token") != "secret" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/v1/feeds":
+		json.NewEncoder(w).Encode(f.feeds)
+
+	case r.Method == "GET" && len(parts) == 4 && parts[1] == "feeds" && parts[3] == "entries":
+		feedId, _ := strconv.ParseInt(parts[2], 10, 64)
+		changedAfter, _ := strconv.ParseInt(r.FormValue("changed_after"), 10, 64)
+		offset, _ := strconv.Atoi(r.FormValue("offset"))
+		limit, _ := strconv.Atoi(r.FormValue("limit"))
+
+		matches := make([]minifluxEntry, 0)
+		for _, e := range f.entries {
+			if e.feedId == feedId && e.ChangedAt.Unix() > changedAfter {
+				matches = append(matches, e.minifluxEntry)
+			}
+		}
+
+		page := matches[offset:]
+		if len(page) > limit {
+			page = page[:limit]
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"total": len(matches), "entries": page})
+
+	case r.Method == "GET" && r.URL.Path == "/v1/entries" && r.FormValue("starred") == "true":
+		starred := make([]minifluxEntry, 0)
+		for _, e := range f.entries {
+			if e.Starred {
+				starred = append(starred, e.minifluxEntry)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"total": len(starred), "entries": starred})
+
+	case r.Method == "PUT" && r.URL.Path == "/v1/entries":
+		var req struct {
+			EntryIds []int64 `json:"entry_ids"`
+			Status   string  `json:"status"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, id := range req.EntryIds {
+			e := f.entry(id)
+			e.Status = req.Status
+			e.ChangedAt = time.Now()
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(parts) >= 3 && parts[1] == "entries":
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		e := f.entry(id)
+		if e == nil {
+			http.Error(w, "Not found", http.StatusNotFound)
+		} else if r.Method == "PUT" && len(parts) == 4 && parts[3] == "bookmark" {
+			e.Starred = !e.Starred
+			e.ChangedAt = time.Now()
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+func newMinifluxStandIn(t *testing.T, fake *fakeMiniflux) (Client, func()) {
+	server := httptest.NewServer(fake)
+	account := Account{Name: "home", Kind: KindMiniflux, Url: server.URL + "/", Token: "secret"}
+	client, err := NewClient(account, server.Client())
+	if err != nil {
+		t.Fatalf("Could not create client: %v", err)
+	}
+	return client, server.Close
+}
+
+func TestMinifluxSubscriptions(t *testing.T) {
+	fake := &fakeMiniflux{}
+	fake.addFeed(1, "http://foo.com/feed", "News")
+	client, closeServer := newMinifluxStandIn(t, fake)
+	defer closeServer()
+
+	subscriptions, err := client.Subscriptions()
+	if err != nil {
+		t.Fatalf("Could not retrieve subscriptions: %v", err)
+	}
+
+	expected := []Subscription{{Id: "1", Title: "Feed 1", FeedUrl: "http://foo.com/feed", Category: "News"}}
+	if !reflect.DeepEqual(subscriptions, expected) {
+		t.Errorf("Expected %v, but got %v", expected, subscriptions)
+	}
+}
+
+func TestMinifluxEntries(t *testing.T) {
+	fake := &fakeMiniflux{}
+	fake.addFeed(1, "http://foo.com/feed", "")
+	for i := 1; i <= minifluxPageSize+1; i++ {
+		fake.addEntry(1, int64(i), "unread", time.Unix(1000, 0))
+	}
+	fake.addEntry(1, 500, "read", time.Unix(2000, 0))
+	fake.addEntry(2, 600, "unread", time.Unix(2000, 0))
+	client, closeServer := newMinifluxStandIn(t, fake)
+	defer closeServer()
+
+	// Every entry in the feed, over two pages
+	entries, err := client.Entries("1", time.Time{})
+	if err != nil {
+		t.Fatalf("Could not retrieve entries: %v", err)
+	}
+	if len(entries) != minifluxPageSize+2 {
+		t.Errorf("Expected %v entries, but got %v", minifluxPageSize+2, len(entries))
+	}
+
+	// Only the entry that changed
+	entries, err = client.Entries("1", time.Unix(1500, 0))
+	if err != nil {
+		t.Fatalf("Could not retrieve entries: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected one changed entry, but got %v", entries)
+	}
+
+	e := entries[0]
+	if e.Id != "500" || !e.Read || e.Starred || !e.Changed.Equal(time.Unix(2000, 0)) || e.Item.Title != "Entry 500" {
+		t.Errorf("Unexpected entry %v", e)
+	}
+}
+
+func TestMinifluxSetState(t *testing.T) {
+	fake := &fakeMiniflux{}
+	fake.addEntry(1, 1, "unread", time.Unix(1000, 0))
+	fake.addEntry(1, 2, "unread", time.Unix(1000, 0))
+	client, closeServer := newMinifluxStandIn(t, fake)
+	defer closeServer()
+
+	if err := client.SetRead([]string{"1", "2"}, true); err != nil {
+		t.Fatalf("Could not mark entries read: %v", err)
+	}
+
+	// Starring a starred entry must not toggle it back
+	for i := 0; i < 2; i++ {
+		if err := client.SetStarred([]string{"2"}, true); err != nil {
+			t.Fatalf("Could not star entry: %v", err)
+		}
+	}
+
+	if e := fake.entry(1); e.Status != "read" || e.Starred {
+		t.Errorf("Expected entry 1 read and not starred, but got %v", e)
+	}
+	if e := fake.entry(2); e.Status != "read" || !e.Starred {
+		t.Errorf("Expected entry 2 read and starred, but got %v", e)
+	}
+
+	if err := client.SetRead([]string{"not a number"}, true); err == nil {
+		t.Errorf("Expected error for invalid entry ID")
+	}
+}
@@ -0,0 +1,113 @@
+package remote
+
+import (
+	"fmt"
+	"github.com/wedaly/local-news/internal/feed"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Kind identifies the API spoken by a remote aggregator
+type Kind string
+
+const (
+	// KindMiniflux is Miniflux's REST API
+	KindMiniflux Kind = "miniflux"
+
+	// KindGReader is the Google Reader API, implemented by FreshRSS,
+	// Inoreader, and other aggregators (including localnews itself).
+	KindGReader Kind = "greader"
+)
+
+// Account configures a connection to a remote aggregator.
+// The aggregator manages the subscriptions, and localnews syncs
+// their items and the read and starred state of each item.
+type Account struct {
+	// Name of the account, which identifies its feeds in the database
+	Name string
+
+	Kind Kind
+
+	// Base URL of the server, e.g. "https://miniflux.example.com"
+	Url string
+
+	Username string
+	Password string
+
+	// API key used instead of the username and password (Miniflux only)
+	Token string
+}
+
+// Subscription is a feed the user subscribes to on the server
+type Subscription struct {
+	// The server's ID for the subscription
+	Id string
+
+	Title   string
+	FeedUrl string
+	SiteUrl string
+
+	// Category (Miniflux) or label (Google Reader), which becomes the feed's folder
+	Category string
+}
+
+// Entry is an item in a feed on the server
+type Entry struct {
+	// The server's ID for the entry
+	Id string
+
+	// The entry's content.  The GUID is ignored.
+	Item feed.FeedItem
+
+	Read    bool
+	Starred bool
+
+	// When the entry's read or starred state last changed on the server,
+	// or zero if the server doesn't report it.
+	Changed time.Time
+
+	// If set, the entry was retrieved only for its ID and state,
+	// and `Item` is empty.
+	StateOnly bool
+}
+
+// Client retrieves subscriptions and entries from a remote aggregator,
+// and changes the state of entries on the server.
+type Client interface {
+	// Subscriptions retrieves every subscription
+	Subscriptions() ([]Subscription, error)
+
+	// Entries retrieves a subscription's entries that were published or
+	// changed after a time (all of them if the time is zero).  Clients for
+	// servers that don't report when an entry changed also return the
+	// state of the subscription's recent entries as state-only entries.
+	Entries(subscriptionId string, since time.Time) ([]Entry, error)
+
+	// SetRead marks entries as read or unread
+	SetRead(entryIds []string, read bool) error
+
+	// SetStarred stars or unstars entries
+	SetStarred(entryIds []string, starred bool) error
+}
+
+// NewClient creates a client for an account's server
+func NewClient(account Account, httpClient *http.Client) (Client, error) {
+	baseUrl := strings.TrimSuffix(account.Url, "/")
+	switch account.Kind {
+	case KindMiniflux:
+		return newMinifluxClient(account, baseUrl, httpClient), nil
+	case KindGReader:
+		return newGReaderClient(account, baseUrl, httpClient), nil
+	default:
+		return nil, fmt.Errorf("Unknown kind of remote account '%v'", account.Kind)
+	}
+}
+
+// checkResponse returns an error if the server responded unsuccessfully
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Server responded with status %v for %v", resp.Status, resp.Request.URL.Path)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package remote
+
+import (
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/store"
+	"strings"
+	"time"
+)
+
+// Syncer keeps the feeds in the database in sync with the subscriptions
+// in a remote account.  Feeds are loaded from the server instead of
+// from their URLs, and read and starred states are synced both ways.
+// When an item's state changed both locally and on the server,
+// the most recent change wins.
+type Syncer struct {
+	account   string
+	client    Client
+	feedStore *store.FeedStore
+}
+
+// NewSyncer creates a syncer for an account
+func NewSyncer(account string, client Client, feedStore *store.FeedStore) *Syncer {
+	return &Syncer{account, client, feedStore}
+}
+
+// SyncSubscriptions adds a feed for each subscription that isn't in the
+// database yet, moves feeds to the folders of their subscriptions, and
+// deletes the feeds of subscriptions that were removed from the server.
+// A feed that was already in the database is linked to its subscription,
+// and its new items are loaded from the server from then on.  If that
+// subscription is removed, the feed is kept and only unlinked.
+// This returns the IDs of the feeds that weren't linked before,
+// which must be loaded (see `SyncFeed`) to retrieve their items.
+func (s *Syncer) SyncSubscriptions() ([]store.FeedId, error) {
+	subscriptions, err := s.client.Subscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	remoteFeeds, err := s.feedStore.RetrieveRemoteFeeds(s.account)
+	if err != nil {
+		return nil, err
+	}
+
+	feedIds := make(map[string]store.FeedId, len(remoteFeeds))
+	for _, remoteFeed := range remoteFeeds {
+		feedIds[remoteFeed.RemoteId] = remoteFeed.FeedId
+	}
+
+	linkedIds := make([]store.FeedId, 0)
+	subscribed := make(map[store.FeedId]bool, len(subscriptions))
+	for _, subscription := range subscriptions {
+		feedId, ok := feedIds[subscription.Id]
+		created := false
+		if !ok {
+			var found bool
+			if found, feedId, err = s.feedStore.RetrieveFeedIdWithUrl(subscription.FeedUrl); err != nil {
+				return nil, err
+			}
+
+			if !found {
+				if feedId, err = s.feedStore.GetOrCreateFeedWithUrl(subscription.FeedUrl); err != nil {
+					return nil, err
+				}
+				created = true
+			}
+			linkedIds = append(linkedIds, feedId)
+		}
+		subscribed[feedId] = true
+
+		err := s.feedStore.SetRemoteFeed(store.RemoteFeedRecord{
+			FeedId:   feedId,
+			Account:  s.account,
+			RemoteId: subscription.Id,
+			Title:    subscription.Title,
+			SiteUrl:  subscription.SiteUrl,
+			Created:  created,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.moveToFolder(feedId, subscription.Category); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, remoteFeed := range remoteFeeds {
+		if subscribed[remoteFeed.FeedId] {
+			continue
+		}
+
+		// Feeds the user had before linking them are kept
+		if remoteFeed.Created {
+			err = s.feedStore.DeleteFeed(remoteFeed.FeedId)
+		} else {
+			err = s.feedStore.DeleteRemoteFeed(remoteFeed.FeedId)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return linkedIds, nil
+}
+
+// moveToFolder changes a feed's folder, keeping its other settings
+func (s *Syncer) moveToFolder(feedId store.FeedId, folder string) error {
+	record, err := s.feedStore.RetrieveFeed(feedId)
+	if err != nil || record.Folder == folder {
+		return err
+	}
+
+	return s.feedStore.UpdateFeedSettings(feedId, store.FeedSettings{
+		Url:             record.Url,
+		CustomName:      record.CustomName,
+		Folder:          folder,
+		RefreshInterval: record.RefreshInterval,
+		Muted:           record.Muted,
+		ArchiveArticles: record.ArchiveArticles,
+	})
+}
+
+// SyncFeed loads the entries of a feed's subscription that were added or
+// changed since the last sync, and syncs the read and starred states.
+// This returns the items inserted into the database.
+func (s *Syncer) SyncFeed(remoteFeed store.RemoteFeedRecord) ([]store.FeedItemRecord, error) {
+	// Changes made on the server while syncing are retrieved next time.
+	// Sync times are stored in seconds, so the next sync overlaps this one
+	// by a second rather than miss changes made in the same second.
+	started := time.Now()
+	since := remoteFeed.Synced
+	if !since.IsZero() {
+		since = since.Add(-time.Second)
+	}
+
+	entries, err := s.client.Entries(remoteFeed.RemoteId, since)
+	if err != nil {
+		return nil, err
+	}
+
+	f := feed.Feed{
+		Name:    remoteFeed.Title,
+		SiteUrl: remoteFeed.SiteUrl,
+		Items:   make([]feed.FeedItem, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		if !entry.StateOnly {
+			item := entry.Item
+			item.Guid = s.itemGuid(entry.Id)
+			f.Items = append(f.Items, item)
+		}
+	}
+
+	newItems, err := s.feedStore.SyncFeed(remoteFeed.FeedId, f)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.syncStates(remoteFeed, entries); err != nil {
+		return nil, err
+	}
+
+	if err := s.feedStore.SetRemoteFeedSynced(remoteFeed.FeedId, started); err != nil {
+		return nil, err
+	}
+
+	// Return the new items with the states from the server
+	for i, item := range newItems {
+		if newItems[i], err = s.feedStore.RetrieveFeedItem(item.Id); err != nil {
+			return nil, err
+		}
+	}
+	return newItems, nil
+}
+
+// syncStates resolves differences between the state of each item and its
+// entry on the server.  Local changes since the last sync are sent to the
+// server unless the entry changed on the server more recently.
+func (s *Syncer) syncStates(remoteFeed store.RemoteFeedRecord, entries []Entry) error {
+	states, err := s.feedStore.RetrieveItemStates(remoteFeed.FeedId)
+	if err != nil {
+		return err
+	}
+
+	localStates := make(map[string]store.ItemStateRecord, len(states))
+	for _, state := range states {
+		localStates[state.Guid] = state
+	}
+
+	// Items in the feed before it was linked to the server aren't synced
+	isPending := func(state store.ItemStateRecord) bool {
+		return strings.HasPrefix(state.Guid, s.itemGuid("")) &&
+			!state.Changed.IsZero() && !state.Changed.Before(remoteFeed.Synced)
+	}
+
+	pending := make([]store.ItemStateRecord, 0)
+	checked := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		guid := s.itemGuid(entry.Id)
+		local, ok := localStates[guid]
+		if !ok {
+			continue
+		}
+		checked[guid] = true
+
+		if local.Read == entry.Read && local.Starred == entry.Starred {
+			continue
+		}
+
+		if isPending(local) && (entry.Changed.IsZero() || !entry.Changed.After(local.Changed)) {
+			pending = append(pending, local)
+		} else if err := s.feedStore.SetSyncedItemState(local.Id, entry.Read, entry.Starred); err != nil {
+			return err
+		}
+	}
+
+	// Entries that didn't change on the server since the last sync
+	for _, state := range states {
+		if !checked[state.Guid] && isPending(state) {
+			pending = append(pending, state)
+		}
+	}
+
+	return s.pushStates(pending)
+}
+
+// pushStates sends the state of items to the server
+func (s *Syncer) pushStates(states []store.ItemStateRecord) error {
+	var read, unread, starred, unstarred []string
+	for _, state := range states {
+		entryId := strings.TrimPrefix(state.Guid, s.itemGuid(""))
+		if state.Read {
+			read = append(read, entryId)
+		} else {
+			unread = append(unread, entryId)
+		}
+
+		if state.Starred {
+			starred = append(starred, entryId)
+		} else {
+			unstarred = append(unstarred, entryId)
+		}
+	}
+
+	if err := s.client.SetRead(read, true); err != nil {
+		return err
+	}
+	if err := s.client.SetRead(unread, false); err != nil {
+		return err
+	}
+	if err := s.client.SetStarred(starred, true); err != nil {
+		return err
+	}
+	return s.client.SetStarred(unstarred, false)
+}
+
+// itemGuid returns the GUID of the item for an entry.  The GUID includes
+// the account, so items from the server are never confused with items
+// loaded from the feed's URL before it was linked to the server.
+func (s *Syncer) itemGuid(entryId string) string {
+	return "remote:" + s.account + ":" + entryId
+}
@@ -0,0 +1,212 @@
+package remote
+
+import (
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/store"
+	"testing"
+	"time"
+)
+
+func execWithStore(t *testing.T, f func(*store.FeedStore)) {
+	feedStore := store.NewFeedStore("file::memory:")
+	if err := feedStore.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer feedStore.Close()
+	f(feedStore)
+}
+
+func syncFeed(t *testing.T, syncer *Syncer, feedStore *store.FeedStore, feedId store.FeedId) []store.FeedItemRecord {
+	ok, remoteFeed, err := feedStore.RetrieveRemoteFeed(feedId)
+	if err != nil || !ok {
+		t.Fatalf("Could not retrieve remote feed: %v, %v", ok, err)
+	}
+
+	newItems, err := syncer.SyncFeed(remoteFeed)
+	if err != nil {
+		t.Fatalf("Could not sync feed: %v", err)
+	}
+	return newItems
+}
+
+func retrieveItem(t *testing.T, feedStore *store.FeedStore, feedId store.FeedId, guid string) store.FeedItemRecord {
+	items, err := feedStore.RetrieveFeedItems(feedId)
+	if err != nil {
+		t.Fatalf("Could not retrieve items: %v", err)
+	}
+
+	for _, item := range items {
+		if item.Guid == guid {
+			return item
+		}
+	}
+	t.Fatalf("No item with GUID %v", guid)
+	return store.FeedItemRecord{}
+}
+
+func TestSyncSubscriptions(t *testing.T) {
+	execWithStore(t, func(feedStore *store.FeedStore) {
+		fake := &fakeMiniflux{}
+		fake.addFeed(1, "http://foo.com/feed", "News")
+		fake.addFeed(2, "http://bar.com/feed", "")
+		client, closeServer := newMinifluxStandIn(t, fake)
+		defer closeServer()
+
+		// A feed that's already in the database is linked, not added
+		fooId, err := feedStore.GetOrCreateFeedWithUrl("http://foo.com/feed")
+		if err != nil {
+			t.Fatalf("Could not create feed: %v", err)
+		}
+
+		syncer := NewSyncer("home", client, feedStore)
+		linkedIds, err := syncer.SyncSubscriptions()
+		if err != nil {
+			t.Fatalf("Could not sync subscriptions: %v", err)
+		}
+
+		if len(linkedIds) != 2 || linkedIds[0] != fooId {
+			t.Errorf("Expected both feeds to be linked, but got %v", linkedIds)
+		}
+
+		if feeds, err := feedStore.RetrieveFeeds(); err != nil || len(feeds) != 2 {
+			t.Errorf("Expected one feed to be added, but got %v, %v", feeds, err)
+		}
+
+		if record, err := feedStore.RetrieveFeed(fooId); err != nil || record.Folder != "News" {
+			t.Errorf("Expected feed in folder News, but got %v, %v", record, err)
+		}
+
+		// Feeds unsubscribed on the server are deleted
+		fake.feeds = fake.feeds[:1]
+		if linkedIds, err := syncer.SyncSubscriptions(); err != nil || len(linkedIds) != 0 {
+			t.Fatalf("Expected no new feeds, but got %v, %v", linkedIds, err)
+		}
+
+		feeds, err := feedStore.RetrieveFeeds()
+		if err != nil || len(feeds) != 1 || feeds[0].Id != fooId {
+			t.Errorf("Expected only the first feed, but got %v, %v", feeds, err)
+		}
+	})
+}
+
+func TestSyncSubscriptionsKeepsExistingFeed(t *testing.T) {
+	execWithStore(t, func(feedStore *store.FeedStore) {
+		fake := &fakeMiniflux{}
+		fake.addFeed(1, "http://foo.com/feed", "")
+		client, closeServer := newMinifluxStandIn(t, fake)
+		defer closeServer()
+
+		// The user's own feed, with an item loaded from its URL
+		fooId, err := feedStore.GetOrCreateFeedWithUrl("http://foo.com/feed")
+		if err != nil {
+			t.Fatalf("Could not create feed: %v", err)
+		}
+		localFeed := feed.Feed{Items: []feed.FeedItem{{Title: "Local", Guid: "local"}}}
+		if _, err := feedStore.SyncFeed(fooId, localFeed); err != nil {
+			t.Fatalf("Could not sync local feed: %v", err)
+		}
+
+		syncer := NewSyncer("home", client, feedStore)
+		if _, err := syncer.SyncSubscriptions(); err != nil {
+			t.Fatalf("Could not sync subscriptions: %v", err)
+		}
+
+		if found, remoteFeed, err := feedStore.RetrieveRemoteFeed(fooId); err != nil || !found || remoteFeed.Created {
+			t.Fatalf("Expected the existing feed to be linked, but got %v, %v", remoteFeed, err)
+		}
+
+		// Unsubscribing on the server unlinks the feed, but keeps it
+		fake.feeds = nil
+		if _, err := syncer.SyncSubscriptions(); err != nil {
+			t.Fatalf("Could not sync subscriptions: %v", err)
+		}
+
+		if found, _, err := feedStore.RetrieveRemoteFeed(fooId); err != nil || found {
+			t.Errorf("Expected the feed to be unlinked, but got %v, %v", found, err)
+		}
+
+		feeds, err := feedStore.RetrieveFeeds()
+		if err != nil || len(feeds) != 1 || feeds[0].Id != fooId {
+			t.Fatalf("Expected the existing feed to be kept, but got %v, %v", feeds, err)
+		}
+
+		retrieveItem(t, feedStore, fooId, "local")
+	})
+}
+
+func TestSyncFeedStates(t *testing.T) {
+	execWithStore(t, func(feedStore *store.FeedStore) {
+		fake := &fakeMiniflux{}
+		fake.addFeed(1, "http://foo.com/feed", "")
+		fake.addEntry(1, 10, "unread", time.Now().Add(-time.Hour))
+		fake.addEntry(1, 11, "read", time.Now().Add(-time.Hour))
+		client, closeServer := newMinifluxStandIn(t, fake)
+		defer closeServer()
+
+		// An item loaded from the feed's URL before it was linked to the server
+		feedId, err := feedStore.GetOrCreateFeedWithUrl("http://foo.com/feed")
+		if err != nil {
+			t.Fatalf("Could not create feed: %v", err)
+		}
+		localFeed := feed.Feed{Items: []feed.FeedItem{{Title: "Local", Guid: "local"}}}
+		if _, err := feedStore.SyncFeed(feedId, localFeed); err != nil {
+			t.Fatalf("Could not sync local feed: %v", err)
+		}
+
+		syncer := NewSyncer("home", client, feedStore)
+		if _, err := syncer.SyncSubscriptions(); err != nil {
+			t.Fatalf("Could not sync subscriptions: %v", err)
+		}
+
+		newItems := syncFeed(t, syncer, feedStore, feedId)
+		if len(newItems) != 2 || newItems[0].Title != "Entry 10" || newItems[0].Read || !newItems[1].Read {
+			t.Fatalf("Expected new items with states from the server, but got %v", newItems)
+		}
+
+		if record, err := feedStore.RetrieveFeed(feedId); err != nil || record.Name != "Feed 1" {
+			t.Errorf("Expected the subscription's title, but got %v, %v", record, err)
+		}
+
+		first := retrieveItem(t, feedStore, feedId, "remote:home:10")
+		second := retrieveItem(t, feedStore, feedId, "remote:home:11")
+		local := retrieveItem(t, feedStore, feedId, "local")
+
+		// Local changes are sent to the server...
+		if err := feedStore.SetItemRead(first.Id, true); err != nil {
+			t.Fatalf("Could not mark item read: %v", err)
+		}
+		if err := feedStore.SetItemStarred(first.Id, true); err != nil {
+			t.Fatalf("Could not star item: %v", err)
+		}
+		if err := feedStore.SetItemStarred(local.Id, true); err != nil {
+			t.Fatalf("Could not star item: %v", err)
+		}
+
+		// ...unless the entry changed on the server more recently
+		if err := feedStore.SetItemStarred(second.Id, true); err != nil {
+			t.Fatalf("Could not star item: %v", err)
+		}
+		fake.entry(11).Status = "unread"
+		fake.entry(11).ChangedAt = time.Now().Add(time.Hour)
+
+		// A new entry that was already read on the server
+		fake.addEntry(1, 12, "read", time.Now())
+
+		newItems = syncFeed(t, syncer, feedStore, feedId)
+		if len(newItems) != 1 || newItems[0].Title != "Entry 12" || !newItems[0].Read {
+			t.Errorf("Expected new read item, but got %v", newItems)
+		}
+
+		if e := fake.entry(10); e.Status != "read" || !e.Starred {
+			t.Errorf("Expected local changes on the server, but got %v", e)
+		}
+
+		if second = retrieveItem(t, feedStore, feedId, "remote:home:11"); second.Read || second.Starred {
+			t.Errorf("Expected the state from the server, but got %v", second)
+		}
+
+		if e := fake.entry(11); e.Starred {
+			t.Errorf("Expected older local change not to be sent, but got %v", e)
+		}
+	})
+}
@@ -0,0 +1,330 @@
+package settings
+
+import (
+	"encoding/xml"
+	"github.com/wedaly/local-news/internal/download"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/hook"
+	"github.com/wedaly/local-news/internal/notify"
+	"github.com/wedaly/local-news/internal/readlater"
+	"github.com/wedaly/local-news/internal/remote"
+	"github.com/wedaly/local-news/internal/task"
+	"io"
+	"os"
+	"os/user"
+	"path"
+	"strings"
+	"time"
+)
+
+// Settings are user preferences that apply to the whole program,
+// regardless of locale.  See `configs/etc/settings.xml` for an example.
+type Settings struct {
+	Loader        LoaderSettings       `xml:"loader"`
+	Refresh       RefreshSettings      `xml:"refresh"`
+	Downloads     DownloadSettings     `xml:"downloads"`
+	Notifications NotificationSettings `xml:"notifications"`
+	Hooks         []HookSettings       `xml:"hooks>hook"`
+	Remotes       []RemoteSettings     `xml:"remotes>remote"`
+	ReadLater     ReadLaterSettings    `xml:"readLater"`
+	Archive       ArchiveSettings      `xml:"archive"`
+}
+
+// LoaderSettings control how feeds are retrieved over HTTP.
+// Individual feeds can override these.
+type LoaderSettings struct {
+	Timeout               Duration `xml:"timeout"`
+	ResponseHeaderTimeout Duration `xml:"responseHeaderTimeout"`
+	UserAgent             string   `xml:"userAgent"`
+	ProxyUrl              string   `xml:"proxy"`
+	CACertFile            string   `xml:"caCertFile"`
+	InsecureSkipVerify    bool     `xml:"insecureSkipVerify"`
+}
+
+// RefreshSettings bound how often feeds are refreshed automatically,
+// based on their hints and how often they post.  Individual feeds
+// can override these with their own refresh interval.
+type RefreshSettings struct {
+	MinInterval Duration `xml:"minInterval"`
+	MaxInterval Duration `xml:"maxInterval"`
+}
+
+// DownloadSettings control how enclosures (e.g. podcast episodes)
+// are downloaded and played.
+type DownloadSettings struct {
+	// Directory for downloaded files.  A leading "~/" is replaced
+	// with the user's home directory.
+	Directory string `xml:"directory"`
+
+	// Maximum size of a downloaded file in megabytes, or zero for no limit
+	MaxSizeMB int64 `xml:"maxSizeMB"`
+
+	// Number of files downloaded at the same time
+	Concurrent int `xml:"concurrent"`
+
+	// Command to play an enclosure.  The file's path or URL
+	// is passed as the last argument.
+	Player string `xml:"player"`
+}
+
+// NotificationSettings control how the user is notified when
+// refreshing feeds brings in new items.  Notifications are disabled
+// unless a command or the bell is enabled.
+type NotificationSettings struct {
+	// Command to notify the user.  The notification's title and body
+	// are appended as arguments.
+	Command string `xml:"command"`
+
+	// Whether to ring the terminal bell
+	Bell bool `xml:"bell"`
+
+	// Whether to notify only about items highlighted by a filter rule
+	HighlightedOnly bool `xml:"highlightedOnly"`
+}
+
+// HookSettings configure a command that receives the new items
+// from each successful sync as JSON on its standard input.
+type HookSettings struct {
+	Command string `xml:"command"`
+
+	// Maximum time the command can run.  Defaults to 30 seconds.
+	Timeout Duration `xml:"timeout"`
+}
+
+// RemoteSettings configure an account on a self-hosted aggregator
+// (Miniflux or a Google Reader API server such as FreshRSS).
+// The account's subscriptions are synced into the database.
+type RemoteSettings struct {
+	// Name identifying the account's feeds in the database.
+	// Renaming an account re-creates its feeds.
+	Name string `xml:"name,attr"`
+
+	// Either "miniflux" or "greader"
+	Type string `xml:"type"`
+
+	Url      string `xml:"url"`
+	Username string `xml:"username"`
+	Password string `xml:"password"`
+
+	// Miniflux API key, used instead of the username and password
+	Token string `xml:"token"`
+}
+
+// ReadLaterSettings configure a Wallabag-compatible service that
+// articles can be saved to.  The service is disabled without a URL.
+type ReadLaterSettings struct {
+	Url string `xml:"url"`
+
+	// OAuth2 client created on the server
+	ClientId     string `xml:"clientId"`
+	ClientSecret string `xml:"clientSecret"`
+
+	Username string `xml:"username"`
+	Password string `xml:"password"`
+
+	// How often articles that couldn't be sent are retried
+	RetryInterval Duration `xml:"retryInterval"`
+}
+
+// ArchiveSettings control which linked articles are archived for offline
+// reading, in addition to those in feeds with archiving enabled.
+type ArchiveSettings struct {
+	// Whether to archive the articles linked from starred items
+	StarredItems bool `xml:"starredItems"`
+
+	// Maximum size of a page to download in megabytes, or zero for no limit
+	MaxPageSizeMB int64 `xml:"maxPageSizeMB"`
+
+	// Maximum total size of the archive in megabytes, or zero for no limit
+	MaxTotalSizeMB int64 `xml:"maxTotalSizeMB"`
+}
+
+// Duration is a time.Duration written in XML as a Go duration string (e.g. "30s")
+type Duration time.Duration
+
+// UnmarshalText parses a duration string.  See `time.ParseDuration` for the format.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalText formats a duration string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// DefaultSettings returns the settings used when no settings file is found.
+// Any setting missing from a settings file also uses its default value.
+func DefaultSettings() Settings {
+	loaderConfig := feed.DefaultLoaderConfig()
+	refreshPolicy := task.DefaultRefreshPolicy()
+	archivePolicy := task.DefaultArchivePolicy()
+	return Settings{
+		Loader: LoaderSettings{
+			Timeout:               Duration(loaderConfig.Timeout),
+			ResponseHeaderTimeout: Duration(loaderConfig.ResponseHeaderTimeout),
+			UserAgent:             loaderConfig.UserAgent,
+		},
+		Refresh: RefreshSettings{
+			MinInterval: Duration(refreshPolicy.MinInterval),
+			MaxInterval: Duration(refreshPolicy.MaxInterval),
+		},
+		Downloads: DownloadSettings{
+			Directory:  "~/Downloads/localnews",
+			MaxSizeMB:  500,
+			Concurrent: 2,
+			Player:     "xdg-open",
+		},
+		Archive: ArchiveSettings{
+			StarredItems:   archivePolicy.StarredItems,
+			MaxPageSizeMB:  archivePolicy.MaxPageBytes >> 20,
+			MaxTotalSizeMB: archivePolicy.MaxTotalBytes >> 20,
+		},
+	}
+}
+
+// LoaderConfig converts the loader settings to a feed loader config
+func (s Settings) LoaderConfig() feed.LoaderConfig {
+	insecureSkipVerify := feed.ToggleDefault
+	if s.Loader.InsecureSkipVerify {
+		insecureSkipVerify = feed.ToggleOn
+	}
+
+	return feed.LoaderConfig{
+		Timeout:               time.Duration(s.Loader.Timeout),
+		ResponseHeaderTimeout: time.Duration(s.Loader.ResponseHeaderTimeout),
+		UserAgent:             s.Loader.UserAgent,
+		ProxyUrl:              s.Loader.ProxyUrl,
+		CACertFile:            s.Loader.CACertFile,
+		InsecureSkipVerify:    insecureSkipVerify,
+	}
+}
+
+// RefreshPolicy converts the refresh settings to a scheduler policy
+func (s Settings) RefreshPolicy() task.RefreshPolicy {
+	return task.RefreshPolicy{
+		MinInterval: time.Duration(s.Refresh.MinInterval),
+		MaxInterval: time.Duration(s.Refresh.MaxInterval),
+	}
+}
+
+// ArchivePolicy converts the archive settings to a task manager policy
+func (s Settings) ArchivePolicy() task.ArchivePolicy {
+	return task.ArchivePolicy{
+		StarredItems:  s.Archive.StarredItems,
+		MaxPageBytes:  s.Archive.MaxPageSizeMB << 20,
+		MaxTotalBytes: s.Archive.MaxTotalSizeMB << 20,
+	}
+}
+
+// NotifyConfig converts the notification settings to a notifier config
+func (s Settings) NotifyConfig() notify.Config {
+	return notify.Config{
+		Command:         s.Notifications.Command,
+		Bell:            s.Notifications.Bell,
+		HighlightedOnly: s.Notifications.HighlightedOnly,
+	}
+}
+
+// HookList converts the hook settings to hooks for a hook runner.
+// Hooks without a command are ignored.
+func (s Settings) HookList() []hook.Hook {
+	hooks := make([]hook.Hook, 0, len(s.Hooks))
+	for _, h := range s.Hooks {
+		if command := strings.TrimSpace(h.Command); len(command) > 0 {
+			hooks = append(hooks, hook.Hook{Command: command, Timeout: time.Duration(h.Timeout)})
+		}
+	}
+	return hooks
+}
+
+// RemoteList converts the remote settings to accounts for syncing.
+// Accounts without a name or URL are ignored.
+func (s Settings) RemoteList() []remote.Account {
+	accounts := make([]remote.Account, 0, len(s.Remotes))
+	for _, r := range s.Remotes {
+		name, url := strings.TrimSpace(r.Name), strings.TrimSpace(r.Url)
+		if len(name) > 0 && len(url) > 0 {
+			accounts = append(accounts, remote.Account{
+				Name:     name,
+				Kind:     remote.Kind(strings.TrimSpace(r.Type)),
+				Url:      url,
+				Username: r.Username,
+				Password: r.Password,
+				Token:    r.Token,
+			})
+		}
+	}
+	return accounts
+}
+
+// ReadLaterConfig converts the read-later settings to an outbox config
+func (s Settings) ReadLaterConfig() readlater.Config {
+	return readlater.Config{
+		Url:           strings.TrimSpace(s.ReadLater.Url),
+		ClientId:      s.ReadLater.ClientId,
+		ClientSecret:  s.ReadLater.ClientSecret,
+		Username:      s.ReadLater.Username,
+		Password:      s.ReadLater.Password,
+		RetryInterval: time.Duration(s.ReadLater.RetryInterval),
+	}
+}
+
+// DownloadConfig converts the download settings to a download manager config
+func (s Settings) DownloadConfig() download.Config {
+	return download.Config{
+		Directory:  expandHome(s.Downloads.Directory),
+		MaxBytes:   s.Downloads.MaxSizeMB * 1024 * 1024,
+		NumWorkers: s.Downloads.Concurrent,
+		UserAgent:  s.Loader.UserAgent,
+	}
+}
+
+// expandHome replaces a leading "~/" in a path with the user's home directory
+func expandHome(p string) string {
+	if !strings.HasPrefix(p, "~/") {
+		return p
+	}
+
+	usr, err := user.Current()
+	if err != nil {
+		return p
+	}
+	return path.Join(usr.HomeDir, p[2:])
+}
+
+// ParseSettingsXml loads settings from XML.
+// Settings missing from the XML use their default values.
+func ParseSettingsXml(r io.Reader) (Settings, error) {
+	settings := DefaultSettings()
+	decoder := xml.NewDecoder(r)
+	if err := decoder.Decode(&settings); err != nil {
+		return DefaultSettings(), err
+	}
+	return settings, nil
+}
+
+// LoadSettings locates and loads the settings file.
+// Search paths are directories to search in order.
+// When an XML file is found at {SEARCHPATH}/settings.xml,
+// it is parsed and returned.
+// If no settings file can be found, then the default settings are returned.
+// An error is returned only if a settings file exists but is invalid.
+func LoadSettings(searchPaths []string) (Settings, error) {
+	for _, dir := range searchPaths {
+		path := path.Join(dir, "settings.xml")
+		if fileInfo, err := os.Stat(path); err == nil && !fileInfo.IsDir() {
+			f, err := os.Open(path)
+			if err != nil {
+				return DefaultSettings(), err
+			}
+			defer f.Close()
+			return ParseSettingsXml(f)
+		}
+	}
+	return DefaultSettings(), nil
+}
@@ -0,0 +1,282 @@
+package settings
+
+import (
+	"github.com/wedaly/local-news/internal/readlater"
+	"github.com/wedaly/local-news/internal/remote"
+	"github.com/wedaly/local-news/internal/task"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSettingsXml(t *testing.T) {
+	settingsXml := `
+		<?xml version="1.0" encoding="utf-8"?>
+		<localnews>
+			<loader>
+				<timeout>10s</timeout>
+				<proxy>socks5://localhost:1080</proxy>
+				<insecureSkipVerify>true</insecureSkipVerify>
+			</loader>
+		</localnews>`
+
+	settings, err := ParseSettingsXml(strings.NewReader(strings.TrimSpace(settingsXml)))
+	if err != nil {
+		t.Fatalf("Could not parse settings: %v", err)
+	}
+
+	config := settings.LoaderConfig()
+	if config.Timeout != 10*time.Second {
+		t.Errorf("Incorrect timeout %v", config.Timeout)
+	}
+
+	if config.ProxyUrl != "socks5://localhost:1080" {
+		t.Errorf("Incorrect proxy %v", config.ProxyUrl)
+	}
+
+	if !config.InsecureSkipVerify.IsOn() {
+		t.Errorf("Expected insecure TLS to be enabled")
+	}
+
+	// Settings missing from the XML use the defaults
+	defaultConfig := DefaultSettings().LoaderConfig()
+	if config.UserAgent != defaultConfig.UserAgent {
+		t.Errorf("Expected default User-Agent, but got %v", config.UserAgent)
+	}
+
+	if config.ResponseHeaderTimeout != defaultConfig.ResponseHeaderTimeout {
+		t.Errorf("Expected default header timeout, but got %v", config.ResponseHeaderTimeout)
+	}
+}
+
+func TestParseSettingsXmlInvalidDuration(t *testing.T) {
+	settingsXml := `<localnews><loader><timeout>soon</timeout></loader></localnews>`
+	if _, err := ParseSettingsXml(strings.NewReader(settingsXml)); err == nil {
+		t.Errorf("Expected error for invalid duration")
+	}
+}
+
+func TestParseSettingsXmlDownloads(t *testing.T) {
+	settingsXml := `
+		<localnews>
+			<loader><userAgent>custom</userAgent></loader>
+			<downloads>
+				<directory>/tmp/podcasts</directory>
+				<maxSizeMB>2</maxSizeMB>
+				<player>mpv --no-video</player>
+			</downloads>
+		</localnews>`
+
+	settings, err := ParseSettingsXml(strings.NewReader(settingsXml))
+	if err != nil {
+		t.Fatalf("Could not parse settings: %v", err)
+	}
+
+	config := settings.DownloadConfig()
+	if config.Directory != "/tmp/podcasts" {
+		t.Errorf("Incorrect directory %v", config.Directory)
+	}
+
+	if config.MaxBytes != 2*1024*1024 {
+		t.Errorf("Incorrect max bytes %v", config.MaxBytes)
+	}
+
+	if config.UserAgent != "custom" {
+		t.Errorf("Expected loader User-Agent, but got %v", config.UserAgent)
+	}
+
+	if config.NumWorkers != DefaultSettings().Downloads.Concurrent {
+		t.Errorf("Expected default number of workers, but got %v", config.NumWorkers)
+	}
+
+	if settings.Downloads.Player != "mpv --no-video" {
+		t.Errorf("Incorrect player %v", settings.Downloads.Player)
+	}
+}
+
+func TestDefaultDownloadDirectoryIsInHome(t *testing.T) {
+	config := DefaultSettings().DownloadConfig()
+	if strings.HasPrefix(config.Directory, "~") {
+		t.Errorf("Expected home directory to be expanded, got %v", config.Directory)
+	}
+}
+
+func TestParseSettingsXmlRefresh(t *testing.T) {
+	settingsXml := `<localnews><refresh><maxInterval>6h</maxInterval></refresh></localnews>`
+
+	settings, err := ParseSettingsXml(strings.NewReader(settingsXml))
+	if err != nil {
+		t.Fatalf("Could not parse settings: %v", err)
+	}
+
+	policy := settings.RefreshPolicy()
+	if policy.MaxInterval != 6*time.Hour {
+		t.Errorf("Incorrect max interval %v", policy.MaxInterval)
+	}
+
+	if policy.MinInterval != 15*time.Minute {
+		t.Errorf("Expected default min interval, but got %v", policy.MinInterval)
+	}
+}
+
+func TestParseSettingsXmlNotifications(t *testing.T) {
+	if DefaultSettings().NotifyConfig().Enabled() {
+		t.Errorf("Expected notifications to be disabled by default")
+	}
+
+	settingsXml := `
+		<localnews>
+			<notifications>
+				<command>notify-send -a localnews</command>
+				<highlightedOnly>true</highlightedOnly>
+			</notifications>
+		</localnews>`
+
+	settings, err := ParseSettingsXml(strings.NewReader(settingsXml))
+	if err != nil {
+		t.Fatalf("Could not parse settings: %v", err)
+	}
+
+	config := settings.NotifyConfig()
+	if config.Command != "notify-send -a localnews" {
+		t.Errorf("Incorrect command %v", config.Command)
+	}
+
+	if !config.HighlightedOnly || config.Bell {
+		t.Errorf("Incorrect notification options %+v", config)
+	}
+}
+
+func TestParseSettingsXmlHooks(t *testing.T) {
+	settingsXml := `
+		<localnews>
+			<hooks>
+				<hook><command>~/bin/chat</command><timeout>5s</timeout></hook>
+				<hook><command> </command></hook>
+				<hook><command>tee -a /tmp/items.log</command></hook>
+			</hooks>
+		</localnews>`
+
+	settings, err := ParseSettingsXml(strings.NewReader(settingsXml))
+	if err != nil {
+		t.Fatalf("Could not parse settings: %v", err)
+	}
+
+	hooks := settings.HookList()
+	if len(hooks) != 2 {
+		t.Fatalf("Expected 2 hooks, but got %v", len(hooks))
+	}
+
+	if hooks[0].Command != "~/bin/chat" || hooks[0].Timeout != 5*time.Second {
+		t.Errorf("Incorrect hook %+v", hooks[0])
+	}
+
+	if hooks[1].Command != "tee -a /tmp/items.log" || hooks[1].Timeout != 0 {
+		t.Errorf("Incorrect hook %+v", hooks[1])
+	}
+}
+
+func TestParseSettingsXmlRemotes(t *testing.T) {
+	settingsXml := `
+		<localnews>
+			<remotes>
+				<remote name="home">
+					<type>miniflux</type>
+					<url>https://miniflux.example.com</url>
+					<token>secret</token>
+				</remote>
+				<remote name="nourl"><type>greader</type></remote>
+				<remote name="work">
+					<type>greader</type>
+					<url>https://rss.example.com/api/greader.php</url>
+					<username>alice</username>
+					<password>pass</password>
+				</remote>
+			</remotes>
+		</localnews>`
+
+	settings, err := ParseSettingsXml(strings.NewReader(settingsXml))
+	if err != nil {
+		t.Fatalf("Could not parse settings: %v", err)
+	}
+
+	accounts := settings.RemoteList()
+	expected := []remote.Account{
+		{Name: "home", Kind: remote.KindMiniflux, Url: "https://miniflux.example.com", Token: "secret"},
+		{Name: "work", Kind: remote.KindGReader, Url: "https://rss.example.com/api/greader.php", Username: "alice", Password: "pass"},
+	}
+	if !reflect.DeepEqual(accounts, expected) {
+		t.Errorf("Expected %+v, but got %+v", expected, accounts)
+	}
+}
+
+func TestParseSettingsXmlReadLater(t *testing.T) {
+	settings, err := ParseSettingsXml(strings.NewReader("<localnews></localnews>"))
+	if err != nil {
+		t.Fatalf("Could not parse settings: %v", err)
+	}
+
+	if settings.ReadLaterConfig().Enabled() {
+		t.Errorf("Expected read-later service to be disabled by default")
+	}
+
+	settingsXml := `
+		<localnews>
+			<readLater>
+				<url> https://wallabag.example.com </url>
+				<clientId>1_abc</clientId>
+				<clientSecret>secret</clientSecret>
+				<username>alice</username>
+				<password>pass</password>
+				<retryInterval>10m</retryInterval>
+			</readLater>
+		</localnews>`
+
+	settings, err = ParseSettingsXml(strings.NewReader(settingsXml))
+	if err != nil {
+		t.Fatalf("Could not parse settings: %v", err)
+	}
+
+	expected := readlater.Config{
+		Url:           "https://wallabag.example.com",
+		ClientId:      "1_abc",
+		ClientSecret:  "secret",
+		Username:      "alice",
+		Password:      "pass",
+		RetryInterval: 10 * time.Minute,
+	}
+	if config := settings.ReadLaterConfig(); config != expected || !config.Enabled() {
+		t.Errorf("Expected %+v, but got %+v", expected, config)
+	}
+}
+
+func TestParseSettingsXmlArchive(t *testing.T) {
+	settings, err := ParseSettingsXml(strings.NewReader("<localnews></localnews>"))
+	if err != nil {
+		t.Fatalf("Could not parse settings: %v", err)
+	}
+
+	if policy := settings.ArchivePolicy(); policy != task.DefaultArchivePolicy() {
+		t.Errorf("Expected default archive policy, but got %+v", policy)
+	}
+
+	settingsXml := `
+		<localnews>
+			<archive>
+				<starredItems>true</starredItems>
+				<maxPageSizeMB>1</maxPageSizeMB>
+				<maxTotalSizeMB>0</maxTotalSizeMB>
+			</archive>
+		</localnews>`
+
+	settings, err = ParseSettingsXml(strings.NewReader(settingsXml))
+	if err != nil {
+		t.Fatalf("Could not parse settings: %v", err)
+	}
+
+	expected := task.ArchivePolicy{StarredItems: true, MaxPageBytes: 1 << 20}
+	if policy := settings.ArchivePolicy(); policy != expected {
+		t.Errorf("Expected %+v, but got %+v", expected, policy)
+	}
+}
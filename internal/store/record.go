@@ -1,6 +1,10 @@
 package store
 
-import "time"
+import (
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/filter"
+	"time"
+)
 
 // FeedId is a unique identifier for each feed stored in the database
 type FeedId int64
@@ -8,6 +12,18 @@ type FeedId int64
 // FeedItemId is a unique identifier for each feed item stored in the database
 type FeedItemId int64
 
+// EnclosureId is a unique identifier for each enclosure stored in the database
+type EnclosureId int64
+
+// FilterRuleId is a unique identifier for each filter rule stored in the database
+type FilterRuleId int64
+
+// SavedSearchId is a unique identifier for each saved search stored in the database
+type SavedSearchId int64
+
+// OutboxEntryId is a unique identifier for each entry in the read-later outbox
+type OutboxEntryId int64
+
 // FeedRecord is the data associated with a feed in the database
 type FeedRecord struct {
 	Id FeedId
@@ -15,14 +31,80 @@ type FeedRecord struct {
 	// Url for the feed, must be unique
 	Url string
 
-	// Name of the feed
+	// Name of the feed, retrieved from the feed source
 	Name string
+
+	// Name chosen by the user, which takes precedence over
+	// the retrieved name.  Empty if the user hasn't set one.
+	CustomName string
+
+	// Folder used to organize the feed.  Empty if none.
+	Folder string
+
+	// How often the feed should be refreshed automatically.
+	// Zero means the feed uses the default refresh interval.
+	RefreshInterval time.Duration
+
+	// Whether new items in the feed are excluded from notifications
+	Muted bool
+
+	// Whether the articles linked from new items are archived for offline reading
+	ArchiveArticles bool
+
+	// URL of the website the feed belongs to (retrieved)
+	SiteUrl string
+
+	// Description of the feed (retrieved)
+	Description string
+
+	// Language code of the feed, e.g. "en-us" (retrieved)
+	Language string
+
+	// URL of the feed's logo or icon (retrieved)
+	ImageUrl string
+
+	// The feed's suggestions for how often to refresh it (retrieved)
+	RefreshHints feed.RefreshHints
+}
+
+// DisplayName returns the name that should be shown to the user.
+func (r FeedRecord) DisplayName() string {
+	if len(r.CustomName) > 0 {
+		return r.CustomName
+	}
+	return r.Name
+}
+
+// FeedSettings are the properties of a feed that the user can edit
+type FeedSettings struct {
+	// Url for the feed, must be unique
+	Url string
+
+	// Name that overrides the name retrieved from the feed source.
+	// Set to the empty string to use the retrieved name.
+	CustomName string
+
+	// Folder used to organize the feed.  Empty if none.
+	Folder string
+
+	// How often the feed should be refreshed automatically.
+	// Zero means the feed uses the default refresh interval.
+	RefreshInterval time.Duration
+
+	// Whether new items in the feed are excluded from notifications
+	Muted bool
+
+	// Whether the articles linked from new items are archived for offline reading
+	ArchiveArticles bool
 }
 
 // FeedItemRecord is the data associated with a feed item in the database
 type FeedItemRecord struct {
 	Id FeedItemId
 
+	// The feed containing the item
+	FeedId FeedId
+
 	// Title of the item (retrieved)
 	Title string
 
@@ -35,6 +117,125 @@ type FeedItemRecord struct {
 	// Globally unique identifier for the item, retrieved
 	// from the feed source.
 	Guid string
+
+	// Date the item was last modified, or zero if unknown
+	Updated time.Time
+
+	// Content of the item as HTML (may be empty)
+	ContentHtml string
+
+	// Content of the item as plain text (may be empty)
+	ContentText string
+
+	// Names of the item's authors, separated by commas (may be empty)
+	Author string
+
+	// Whether the item has been read
+	Read bool
+
+	// Whether the item was hidden by a filter rule
+	Hidden bool
+
+	// Whether the item was highlighted by a filter rule
+	Highlighted bool
+
+	// Whether the user starred the item to keep it
+	Starred bool
+}
+
+// FilterRuleRecord is a user-defined rule for hiding, marking,
+// or highlighting feed items
+type FilterRuleRecord struct {
+	Id FilterRuleId
+
+	// The feed the rule applies to, or zero if it applies to every feed
+	FeedId FeedId
+
+	Rule filter.Rule
+}
+
+// EnclosureRecord is a file attached to a feed item (e.g. a podcast episode)
+type EnclosureRecord struct {
+	Id EnclosureId
+
+	// The item the file is attached to
+	ItemId FeedItemId
+
+	// Url of the file
+	Url string
+
+	// MIME type of the file, if provided by the feed
+	MimeType string
+
+	// Title of the file, if provided by the feed
+	Title string
+
+	// Size of the file in bytes, or zero if unknown
+	Length int64
+
+	// Duration of audio or video, or zero if unknown
+	Duration time.Duration
+}
+
+// SavedSearchRecord is a query saved by the user, which is
+// displayed like a feed containing the matching items
+type SavedSearchRecord struct {
+	Id SavedSearchId
+
+	// Name chosen by the user
+	Name string
+
+	// Query in the syntax accepted by `query.Parse`
+	Query string
+}
+
+// ItemOrder is the order of items retrieved with an `ItemFilter`
+type ItemOrder int
+
+const (
+	// Most recently published first
+	NewestFirst ItemOrder = iota
+
+	// Least recently published first
+	OldestFirst
+
+	// In the order the items were inserted
+	IdAscending
+
+	// In the reverse of the order the items were inserted
+	IdDescending
+)
+
+// ItemFilter selects items for sync clients, such as mobile apps.
+// Fields with zero values match every item.  Hidden items never match.
+type ItemFilter struct {
+	// Only items in this feed
+	FeedId FeedId
+
+	// Only items in feeds in this folder
+	Folder string
+
+	// Only unread, read, or starred items
+	Unread  bool
+	Read    bool
+	Starred bool
+
+	// Only items with these IDs
+	Ids []FeedItemId
+
+	// Only items with IDs greater than `AfterId` or less than `BeforeId`
+	AfterId  FeedItemId
+	BeforeId FeedItemId
+
+	// Only items published at or after `Since`, or before `Until`
+	Since time.Time
+	Until time.Time
+
+	Order ItemOrder
+
+	// Maximum number of items, or zero for no limit, after skipping `Offset` items
+	Limit  int
+	Offset int
 }
 
 // FeedSyncStatus represents the most recent attempt to synchronize
@@ -51,3 +252,119 @@ type FeedSyncStatus struct {
 	// for debugging purposes.
 	Error error
 }
+
+// SyncLogKind identifies the type of event recorded in a feed's sync history
+type SyncLogKind int
+
+const (
+	// The feed was successfully synchronized.
+	// The detail is the number of items retrieved.
+	SyncLogSuccess SyncLogKind = iota
+
+	// An error occurred while synchronizing the feed.
+	// The detail is the error message.
+	SyncLogError
+
+	// The feed's URL was changed because of a permanent redirect.
+	// The subject is the old URL and the detail is the new URL.
+	SyncLogUrlMoved
+
+	// A hook failed while processing the feed's new items.
+	// The subject is the hook's command and the detail is the error message.
+	SyncLogHookFailed
+)
+
+// FeedSyncLogEntry is an event in a feed's sync history
+type FeedSyncLogEntry struct {
+	// The datetime of the event
+	Date time.Time
+
+	// The type of event, which determines the meaning
+	// of the subject and detail fields.
+	Kind SyncLogKind
+
+	// What the event applies to (e.g. a URL), if anything
+	Subject string
+
+	// Additional information about the event, if any
+	Detail string
+}
+
+// RemoteFeedRecord links a feed to its subscription on a remote
+// aggregator (such as Miniflux), which the feed's items are loaded from.
+type RemoteFeedRecord struct {
+	FeedId FeedId
+
+	// Name of the remote account in the settings
+	Account string
+
+	// ID of the subscription on the server
+	RemoteId string
+
+	// Title and website of the feed, as reported by the server
+	Title   string
+	SiteUrl string
+
+	// When the feed's items were last synced with the server, or zero if never
+	Synced time.Time
+
+	// Whether the feed was created for the subscription, rather than linked
+	// to a feed already in the database.  Only created feeds are deleted
+	// when the subscription is removed from the server.
+	Created bool
+}
+
+// ItemStateRecord is the read and starred state of an item,
+// which is synced with a remote aggregator.
+type ItemStateRecord struct {
+	Id   FeedItemId
+	Guid string
+
+	Read    bool
+	Starred bool
+
+	// When the read or starred state was last changed in this app
+	// (by the user or a filter rule), or zero if never
+	Changed time.Time
+}
+
+// OutboxEntryRecord is an article waiting to be sent to a read-later service
+type OutboxEntryRecord struct {
+	Id OutboxEntryId
+
+	Url   string
+	Title string
+
+	// When the user saved the article
+	Queued time.Time
+
+	// Number of failed attempts to send the article
+	Attempts int
+
+	// Why the most recent attempt failed, if any
+	LastError string
+}
+
+// ArchivedArticleRecord is the article linked from a feed item, saved
+// for reading offline.  The content is stored compressed.
+type ArchivedArticleRecord struct {
+	ItemId FeedItemId
+
+	// URL of the article after any redirects
+	Url string
+
+	// Title of the article, which may differ from the item's title
+	Title string
+
+	// The article's main content as HTML, without navigation, ads, etc.
+	ContentHtml string
+
+	// When the article was archived, or when the last attempt failed
+	Archived time.Time
+
+	// Why the last attempt to archive the article failed, if it did
+	Err string
+
+	// Whether the content was removed to keep the archive under its size limit
+	Pruned bool
+}
@@ -1,16 +1,25 @@
 package store
 
 import (
+	"bytes"
+	"compress/gzip"
 	"database/sql"
 	"errors"
 	"fmt"
 	sqlite3 "github.com/mattn/go-sqlite3"
 	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/filter"
+	"github.com/wedaly/local-news/internal/query"
+	"io"
+	"io/ioutil"
 	"log"
+	neturl "net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
-const numStatements int = 11
+const numStatements int = 100
 
 const (
 	selectEveryFeedStmt = iota
@@ -24,8 +33,104 @@ const (
 	deleteItemsInFeedStmt
 	upsertFeedSyncStatusStmt
 	selectFeedSyncStatusStmt
+	updateFeedSettingsStmt
+	updateFeedUrlStmt
+	selectFeedUrlStmt
+	mergeItemsIntoFeedStmt
+	mergeEnclosuresOfDuplicateItemsStmt
+	mergeCategoriesOfDuplicateItemsStmt
+	mergeFilterMatchesOfDuplicateItemsStmt
+	mergeArchiveOfDuplicateItemsStmt
+	mergeDeliveriesOfDuplicateItemsStmt
+	mergeStateOfDuplicateItemsStmt
+	deleteDuplicateItemsStmt
+	mergeCredentialsIntoFeedStmt
+	mergeHeadersIntoFeedStmt
+	mergeLoaderConfigIntoFeedStmt
+	mergeScrapeConfigIntoFeedStmt
+	mergeRemoteFeedIntoFeedStmt
+	mergeSyncLogIntoFeedStmt
+	insertFeedSyncLogStmt
+	pruneFeedSyncLogStmt
+	selectFeedSyncLogStmt
+	selectFeedCredentialsStmt
+	upsertFeedCredentialsStmt
+	deleteFeedCredentialsStmt
+	selectFeedHeadersStmt
+	insertFeedHeaderStmt
+	deleteFeedHeadersStmt
+	selectFeedLoaderConfigStmt
+	upsertFeedLoaderConfigStmt
+	deleteFeedLoaderConfigStmt
+	selectFeedScrapeConfigStmt
+	upsertFeedScrapeConfigStmt
+	deleteFeedScrapeConfigStmt
+	selectFeedItemIdStmt
+	selectEnclosuresForItemStmt
+	insertEnclosureStmt
+	deleteEnclosuresForItemStmt
+	deleteEnclosuresInFeedStmt
+	selectCategoriesForItemStmt
+	selectCategoriesForFeedStmt
+	selectFeedItemsInCategoryStmt
+	insertCategoryStmt
+	deleteCategoriesForItemStmt
+	deleteCategoriesInFeedStmt
+	updateItemReadStmt
+	applyFilterActionStmt
+	selectFilterRulesStmt
+	selectFilterRulesForFeedStmt
+	selectFilterRuleStmt
+	insertFilterRuleStmt
+	updateFilterRuleStmt
+	deleteFilterRuleStmt
+	deleteFilterRulesForFeedStmt
+	mergeFilterRulesIntoFeedStmt
+	insertFilterMatchStmt
+	selectFilterMatchesStmt
+	deleteFilterMatchesForRuleStmt
+	deleteFilterMatchesForFeedRulesStmt
+	deleteFilterMatchesInFeedStmt
+	selectUnreadCountsStmt
+	selectSavedSearchesStmt
+	selectSavedSearchStmt
+	insertSavedSearchStmt
+	updateSavedSearchStmt
+	deleteSavedSearchStmt
+	selectRecentItemDatesStmt
+	selectFeedItemStmt
+	updateItemStarredStmt
+	upsertRemoteFeedStmt
+	selectRemoteFeedStmt
+	selectRemoteFeedsStmt
+	updateRemoteFeedSyncedStmt
+	deleteRemoteFeedStmt
+	selectItemStatesStmt
+	updateItemStateStmt
+	selectUndeliveredItemsStmt
+	insertItemDeliveryStmt
+	insertOutboxEntryStmt
+	selectOutboxEntriesStmt
+	deleteOutboxEntryStmt
+	updateOutboxEntryErrorStmt
+	upsertArchivedArticleStmt
+	selectArchivedArticleStmt
+	selectItemsToArchiveStmt
+	selectArchiveSizesStmt
+	pruneArchivedArticleStmt
+	deleteSyncLogForFeedStmt
+	deleteSyncStatusForFeedStmt
+	deleteDeliveriesInFeedStmt
+	deleteArchiveInFeedStmt
 )
 
+// maxSyncLogEntries is the number of sync history entries retained per feed
+const maxSyncLogEntries int = 100
+
+// ErrDuplicateFeedUrl is returned when changing a feed's URL
+// to the URL of another feed.
+var ErrDuplicateFeedUrl = errors.New("Another feed already has this URL")
+
 // FeedStore provides thread-safe CRUD operations for feeds and feed items
 type FeedStore struct {
 	dbPath     string
@@ -47,18 +152,18 @@ func (s *FeedStore) Initialize() error {
 		panic("Store already initialized")
 	}
 
-	db, err := sql.Open("sqlite3", s.dbPath)
+	db, err := sql.Open("sqlite3", dsnWithForeignKeys(s.dbPath))
 	if err != nil {
 		return err
 	}
 
 	s.db = db
 
-	if err := s.enableForeignKeyConstraints(); err != nil {
+	if err := s.installSchema(); err != nil {
 		return err
 	}
 
-	if err := s.installSchema(); err != nil {
+	if err := s.migrateSchema(); err != nil {
 		return err
 	}
 
@@ -116,19 +221,76 @@ func (s *FeedStore) GetOrCreateFeedWithUrl(url string) (FeedId, error) {
 	return id, nil
 }
 
+// RetrieveFeedIdWithUrl finds the feed with the specified URL.
+// The first return value is false if there is no such feed.
+func (s *FeedStore) RetrieveFeedIdWithUrl(url string) (bool, FeedId, error) {
+	stmt := s.statements[selectFeedIdByUrlStmt]
+	var id FeedId
+	if err := stmt.QueryRow(url).Scan(&id); err == sql.ErrNoRows {
+		return false, 0, nil
+	} else if err != nil {
+		return false, 0, err
+	}
+	return true, id, nil
+}
+
 // SyncFeed atomically updates a feed record and its items.
 // The feed name (but not ID) is overwritten with the new name.
+// The custom name set by the user, if any, is left unchanged.
 // The feed items are upserted, using the record GUID as the record's identity.
 // Existing items NOT included in the new feed are retained (not deleted)
-func (s *FeedStore) SyncFeed(id FeedId, feed feed.Feed) error {
-	return s.wrapInTx(func(tx *sql.Tx) error {
+// Filter rules for the feed are applied to each item the first time
+// the item matches the rule.
+// This returns the items that were inserted (rather than updated),
+// after filter rules were applied, including hidden items.
+func (s *FeedStore) SyncFeed(id FeedId, feed feed.Feed) ([]FeedItemRecord, error) {
+	var newItems []FeedItemRecord
+	err := s.wrapInTx(func(tx *sql.Tx) error {
 		err := s.updateFeedRecord(tx, id, feed)
 		if err != nil {
 			return err
 		}
 
+		rules, err := s.compileFilterRules(tx, id)
+		if err != nil {
+			return err
+		}
+
+		newItemIds := make([]FeedItemId, 0)
 		for _, item := range feed.Items {
-			err := s.upsertFeedItemRecord(tx, id, item)
+			// Check whether the item exists before upserting it,
+			// so we can report which items are new.
+			var itemId int64
+			stmt := tx.Stmt(s.statements[selectFeedItemIdStmt])
+			err := stmt.QueryRow(id, item.Guid).Scan(&itemId)
+			isNew := err == sql.ErrNoRows
+			if err != nil && !isNew {
+				return err
+			}
+
+			err = s.upsertFeedItemRecord(tx, id, item)
+			if err != nil {
+				return err
+			}
+
+			if isNew {
+				if err := stmt.QueryRow(id, item.Guid).Scan(&itemId); err != nil {
+					return err
+				}
+				newItemIds = append(newItemIds, FeedItemId(itemId))
+			}
+
+			err = s.replaceEnclosures(tx, FeedItemId(itemId), item)
+			if err != nil {
+				return err
+			}
+
+			err = s.replaceCategories(tx, FeedItemId(itemId), item)
+			if err != nil {
+				return err
+			}
+
+			err = s.applyFilterRules(tx, FeedItemId(itemId), item, rules)
 			if err != nil {
 				return err
 			}
@@ -139,10 +301,262 @@ func (s *FeedStore) SyncFeed(id FeedId, feed feed.Feed) error {
 			return err
 		}
 
+		detail := fmt.Sprintf("%d", len(feed.Items))
+		err = s.insertFeedSyncLogEntry(tx, id, SyncLogSuccess, "", detail)
+		if err != nil {
+			return err
+		}
+
+		newItems, err = s.retrieveFeedItemsById(tx, newItemIds)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return newItems, nil
+}
+
+// MoveFeed changes the URL of a feed in response to a permanent redirect,
+// recording the change in the feed's sync history.
+// If the new URL is on another host or uses another scheme, the feed's credentials
+// and headers are deleted.
+// If another feed already has the new URL, the items, sync history,
+// and settings of the moved feed are merged into the other feed,
+// and the moved feed is deleted.  This returns the ID of the feed with the new URL.
+func (s *FeedStore) MoveFeed(id FeedId, newUrl string) (FeedId, error) {
+	var newId FeedId
+	err := s.wrapInTx(func(tx *sql.Tx) error {
+		var oldUrl string
+		stmt := tx.Stmt(s.statements[selectFeedUrlStmt])
+		if err := stmt.QueryRow(id).Scan(&oldUrl); err != nil {
+			return err
+		}
+
+		// Credentials and headers (which often contain API keys)
+		// are only sent to the host the user configured them for,
+		// and never over a less secure scheme
+		if !isSameOrigin(oldUrl, newUrl) {
+			if err := s.deleteFeedSecrets(tx, id); err != nil {
+				return err
+			}
+		}
+
+		stmt = tx.Stmt(s.statements[selectFeedIdByUrlStmt])
+		err := stmt.QueryRow(newUrl).Scan(&newId)
+		if err == sql.ErrNoRows {
+			newId = id
+			stmt = tx.Stmt(s.statements[updateFeedUrlStmt])
+			if _, err := stmt.Exec(newUrl, id); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		} else if newId != id {
+			if err := s.mergeFeed(tx, id, newId); err != nil {
+				return err
+			}
+		}
+
+		return s.insertFeedSyncLogEntry(tx, newId, SyncLogUrlMoved, feed.RedactUrl(oldUrl), feed.RedactUrl(newUrl))
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return newId, nil
+}
+
+// UpdateFeedSettings changes the user-editable properties of a feed.
+// Items in the feed are retained, even if the URL changes.
+// If another feed already has the new URL, this returns ErrDuplicateFeedUrl.
+func (s *FeedStore) UpdateFeedSettings(id FeedId, settings FeedSettings) error {
+	return s.wrapInTx(func(tx *sql.Tx) error {
+		var existingId FeedId
+		stmt := tx.Stmt(s.statements[selectFeedIdByUrlStmt])
+		err := stmt.QueryRow(settings.Url).Scan(&existingId)
+		if err == nil && existingId != id {
+			return ErrDuplicateFeedUrl
+		} else if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		stmt = tx.Stmt(s.statements[updateFeedSettingsStmt])
+		_, err = stmt.Exec(
+			settings.Url,
+			settings.CustomName,
+			settings.Folder,
+			int64(settings.RefreshInterval/time.Second),
+			settings.Muted,
+			settings.ArchiveArticles,
+			id)
+		return err
+	})
+}
+
+// RetrieveFeedCredentials retrieves the credentials used to load a feed.
+// If the feed has no credentials, the scheme is `feed.AuthNone`.
+func (s *FeedStore) RetrieveFeedCredentials(id FeedId) (feed.Credentials, error) {
+	var scheme int
+	var creds feed.Credentials
+
+	stmt := s.statements[selectFeedCredentialsStmt]
+	err := stmt.QueryRow(id).Scan(&scheme, &creds.Username, &creds.Secret, &creds.SecretCommand)
+	if err == sql.ErrNoRows {
+		return feed.Credentials{}, nil
+	} else if err != nil {
+		return feed.Credentials{}, err
+	}
+
+	creds.Scheme = feed.AuthScheme(scheme)
+	return creds, nil
+}
+
+// SetFeedCredentials replaces the credentials used to load a feed.
+// Setting the scheme to `feed.AuthNone` removes the credentials.
+func (s *FeedStore) SetFeedCredentials(id FeedId, creds feed.Credentials) error {
+	if creds.Scheme == feed.AuthNone {
+		stmt := s.statements[deleteFeedCredentialsStmt]
+		_, err := stmt.Exec(id)
+		return err
+	}
+
+	stmt := s.statements[upsertFeedCredentialsStmt]
+	_, err := stmt.Exec(id, int(creds.Scheme), creds.Username, creds.Secret, creds.SecretCommand)
+	return err
+}
+
+// RetrieveFeedHeaders retrieves the custom HTTP headers sent when loading a feed
+func (s *FeedStore) RetrieveFeedHeaders(id FeedId) (map[string]string, error) {
+	stmt := s.statements[selectFeedHeadersStmt]
+	rows, err := stmt.Query(id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	headers := make(map[string]string, 0)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		headers[name] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return headers, nil
+}
+
+// SetFeedHeaders replaces the custom HTTP headers sent when loading a feed
+func (s *FeedStore) SetFeedHeaders(id FeedId, headers map[string]string) error {
+	return s.wrapInTx(func(tx *sql.Tx) error {
+		stmt := tx.Stmt(s.statements[deleteFeedHeadersStmt])
+		if _, err := stmt.Exec(id); err != nil {
+			return err
+		}
+
+		stmt = tx.Stmt(s.statements[insertFeedHeaderStmt])
+		for name, value := range headers {
+			if _, err := stmt.Exec(id, name, value); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 }
 
+// RetrieveFeedLoaderConfig retrieves the settings that override
+// the global loader config for a feed.  Fields that aren't
+// overridden have zero values.
+func (s *FeedStore) RetrieveFeedLoaderConfig(id FeedId) (feed.LoaderConfig, error) {
+	var timeout, responseHeaderTimeout int64
+	var config feed.LoaderConfig
+
+	stmt := s.statements[selectFeedLoaderConfigStmt]
+	err := stmt.QueryRow(id).Scan(
+		&timeout,
+		&responseHeaderTimeout,
+		&config.UserAgent,
+		&config.ProxyUrl,
+		&config.CACertFile,
+		&config.InsecureSkipVerify)
+	if err == sql.ErrNoRows {
+		return feed.LoaderConfig{}, nil
+	} else if err != nil {
+		return feed.LoaderConfig{}, err
+	}
+
+	config.Timeout = time.Duration(timeout) * time.Millisecond
+	config.ResponseHeaderTimeout = time.Duration(responseHeaderTimeout) * time.Millisecond
+	return config, nil
+}
+
+// SetFeedLoaderConfig replaces the settings that override
+// the global loader config for a feed.
+func (s *FeedStore) SetFeedLoaderConfig(id FeedId, config feed.LoaderConfig) error {
+	if config == (feed.LoaderConfig{}) {
+		stmt := s.statements[deleteFeedLoaderConfigStmt]
+		_, err := stmt.Exec(id)
+		return err
+	}
+
+	stmt := s.statements[upsertFeedLoaderConfigStmt]
+	_, err := stmt.Exec(
+		id,
+		int64(config.Timeout/time.Millisecond),
+		int64(config.ResponseHeaderTimeout/time.Millisecond),
+		config.UserAgent,
+		config.ProxyUrl,
+		config.CACertFile,
+		config.InsecureSkipVerify)
+	return err
+}
+
+// RetrieveFeedScrapeConfig retrieves the selectors used to scrape
+// a feed's items from an HTML page.  If the feed isn't scraped,
+// this returns an empty config.
+func (s *FeedStore) RetrieveFeedScrapeConfig(id FeedId) (feed.ScrapeConfig, error) {
+	var config feed.ScrapeConfig
+	stmt := s.statements[selectFeedScrapeConfigStmt]
+	err := stmt.QueryRow(id).Scan(
+		&config.ItemSelector,
+		&config.TitleSelector,
+		&config.LinkSelector,
+		&config.DateSelector)
+	if err == sql.ErrNoRows {
+		return feed.ScrapeConfig{}, nil
+	} else if err != nil {
+		return feed.ScrapeConfig{}, err
+	}
+
+	return config, nil
+}
+
+// SetFeedScrapeConfig replaces the selectors used to scrape a feed's items.
+// If the config isn't set, the feed is parsed as a regular feed.
+func (s *FeedStore) SetFeedScrapeConfig(id FeedId, config feed.ScrapeConfig) error {
+	if !config.IsSet() {
+		stmt := s.statements[deleteFeedScrapeConfigStmt]
+		_, err := stmt.Exec(id)
+		return err
+	}
+
+	stmt := s.statements[upsertFeedScrapeConfigStmt]
+	_, err := stmt.Exec(
+		id,
+		config.ItemSelector,
+		config.TitleSelector,
+		config.LinkSelector,
+		config.DateSelector)
+	return err
+}
+
 // DeleteFeed transactionally deletes the specified feed and all its items
 func (s *FeedStore) DeleteFeed(feedId FeedId) error {
 	return s.wrapInTx(func(tx *sql.Tx) error {
@@ -150,6 +564,14 @@ func (s *FeedStore) DeleteFeed(feedId FeedId) error {
 			return err
 		}
 
+		if err := s.deleteFilterRulesForFeed(tx, feedId); err != nil {
+			return err
+		}
+
+		if err := s.deleteFeedSettings(tx, feedId); err != nil {
+			return err
+		}
+
 		if err := s.deleteFeedRecord(tx, feedId); err != nil {
 			return err
 		}
@@ -170,17 +592,35 @@ func (s *FeedStore) RetrieveFeeds() ([]FeedRecord, error) {
 	records := make([]FeedRecord, 0)
 	for rows.Next() {
 		var id int64
-		var url string
-		var name string
-
-		if err := rows.Scan(&id, &url, &name); err != nil {
+		var url, name, customName, folder string
+		var refreshInterval int64
+		var muted, archiveArticles bool
+		var siteUrl, description, language, imageUrl string
+		var ttl, updateInterval int64
+		var skipHours, skipDays string
+
+		err := rows.Scan(
+			&id, &url, &name, &customName, &folder, &refreshInterval, &muted, &archiveArticles,
+			&siteUrl, &description, &language, &imageUrl,
+			&ttl, &updateInterval, &skipHours, &skipDays)
+		if err != nil {
 			return nil, err
 		}
 
 		records = append(records, FeedRecord{
-			Id:   FeedId(id),
-			Url:  url,
-			Name: name,
+			Id:              FeedId(id),
+			Url:             url,
+			Name:            name,
+			CustomName:      customName,
+			Folder:          folder,
+			RefreshInterval: time.Duration(refreshInterval) * time.Second,
+			Muted:           muted,
+			ArchiveArticles: archiveArticles,
+			SiteUrl:         siteUrl,
+			Description:     description,
+			Language:        language,
+			ImageUrl:        imageUrl,
+			RefreshHints:    refreshHints(ttl, updateInterval, skipHours, skipDays),
 		})
 	}
 
@@ -193,18 +633,36 @@ func (s *FeedStore) RetrieveFeeds() ([]FeedRecord, error) {
 
 // RetrieveFeed retrieves a single feed record by its id.
 func (s *FeedStore) RetrieveFeed(id FeedId) (FeedRecord, error) {
-	var url, name string
+	var url, name, customName, folder string
+	var refreshInterval int64
+	var muted, archiveArticles bool
+	var siteUrl, description, language, imageUrl string
+	var ttl, updateInterval int64
+	var skipHours, skipDays string
 
 	stmt := s.statements[selectFeedStmt]
-	err := stmt.QueryRow(id).Scan(&url, &name)
+	err := stmt.QueryRow(id).Scan(
+		&url, &name, &customName, &folder, &refreshInterval, &muted, &archiveArticles,
+		&siteUrl, &description, &language, &imageUrl,
+		&ttl, &updateInterval, &skipHours, &skipDays)
 	if err != nil {
 		return FeedRecord{}, err
 	}
 
 	record := FeedRecord{
-		Id:   id,
-		Url:  url,
-		Name: name,
+		Id:              id,
+		Url:             url,
+		Name:            name,
+		CustomName:      customName,
+		Folder:          folder,
+		RefreshInterval: time.Duration(refreshInterval) * time.Second,
+		Muted:           muted,
+		ArchiveArticles: archiveArticles,
+		SiteUrl:         siteUrl,
+		Description:     description,
+		Language:        language,
+		ImageUrl:        imageUrl,
+		RefreshHints:    refreshHints(ttl, updateInterval, skipHours, skipDays),
 	}
 	return record, nil
 }
@@ -217,26 +675,135 @@ func (s *FeedStore) RetrieveFeedItems(feedId FeedId) ([]FeedItemRecord, error) {
 		return nil, err
 	}
 	defer rows.Close()
+	return scanFeedItems(rows)
+}
+
+// RetrieveFeedItemsInCategory retrieves a record for every item
+// in a feed that has the specified category (tag)
+func (s *FeedStore) RetrieveFeedItemsInCategory(feedId FeedId, category string) ([]FeedItemRecord, error) {
+	stmt := s.statements[selectFeedItemsInCategoryStmt]
+	rows, err := stmt.Query(feedId, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFeedItems(rows)
+}
+
+// RetrieveFeedCategories retrieves every category used by items in a feed,
+// sorted by name
+func (s *FeedStore) RetrieveFeedCategories(feedId FeedId) ([]string, error) {
+	return s.queryStrings(s.statements[selectCategoriesForFeedStmt], feedId)
+}
+
+// RetrieveItemCategories retrieves the categories of a feed item, sorted by name
+func (s *FeedStore) RetrieveItemCategories(itemId FeedItemId) ([]string, error) {
+	return s.queryStrings(s.statements[selectCategoriesForItemStmt], itemId)
+}
+
+func (s *FeedStore) queryStrings(stmt *sql.Stmt, args ...interface{}) ([]string, error) {
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make([]string, 0)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
+	return values, nil
+}
+
+func scanFeedItems(rows *sql.Rows) ([]FeedItemRecord, error) {
 	records := make([]FeedItemRecord, 0)
 	for rows.Next() {
-		var id int64
+		var id, feedId int64
 		var guid string
 		var url string
 		var title string
 		var date int64
+		var dateModified sql.NullInt64
+		var contentHtml string
+		var contentText string
+		var author string
+		var read, hidden, highlighted, starred bool
+
+		err := rows.Scan(
+			&id, &feedId, &guid, &url, &title, &date, &dateModified,
+			&contentHtml, &contentText, &author,
+			&read, &hidden, &highlighted, &starred)
+		if err != nil {
+			return nil, err
+		}
+
+		record := FeedItemRecord{
+			Id:          FeedItemId(id),
+			FeedId:      FeedId(feedId),
+			Title:       title,
+			Date:        time.Unix(date, 0),
+			Url:         url,
+			Guid:        guid,
+			ContentHtml: contentHtml,
+			ContentText: contentText,
+			Author:      author,
+			Read:        read,
+			Hidden:      hidden,
+			Highlighted: highlighted,
+			Starred:     starred,
+		}
+
+		if dateModified.Valid {
+			record.Updated = time.Unix(dateModified.Int64, 0)
+		}
+
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// RetrieveItemEnclosures retrieves the files attached to a feed item
+func (s *FeedStore) RetrieveItemEnclosures(itemId FeedItemId) ([]EnclosureRecord, error) {
+	stmt := s.statements[selectEnclosuresForItemStmt]
+	rows, err := stmt.Query(itemId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-		if err := rows.Scan(&id, &guid, &url, &title, &date); err != nil {
+	records := make([]EnclosureRecord, 0)
+	for rows.Next() {
+		var id int64
+		var durationMs int64
+		record := EnclosureRecord{ItemId: itemId}
+		err := rows.Scan(
+			&id,
+			&record.Url,
+			&record.MimeType,
+			&record.Title,
+			&record.Length,
+			&durationMs)
+		if err != nil {
 			return nil, err
 		}
 
-		records = append(records, FeedItemRecord{
-			Id:    FeedItemId(id),
-			Title: title,
-			Date:  time.Unix(date, 0),
-			Url:   url,
-			Guid:  guid,
-		})
+		record.Id = EnclosureId(id)
+		record.Duration = time.Duration(durationMs) * time.Millisecond
+		records = append(records, record)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -246,194 +813,2383 @@ func (s *FeedStore) RetrieveFeedItems(feedId FeedId) ([]FeedItemRecord, error) {
 	return records, nil
 }
 
-// SetFeedSyncStatusError sets the most recent sync attempt to "error" status
-func (s *FeedStore) SetFeedSyncStatusError(id FeedId, syncErr error) error {
-	stmt := s.statements[upsertFeedSyncStatusStmt]
-	syncErrStr := fmt.Sprintf("%v", syncErr)
-	_, err := stmt.Exec(id, false, syncErrStr)
+// SetItemRead marks a feed item as read or unread
+func (s *FeedStore) SetItemRead(itemId FeedItemId, read bool) error {
+	stmt := s.statements[updateItemReadStmt]
+	_, err := stmt.Exec(read, itemId, read)
 	return err
 }
 
-// RetrieveFeedSyncStatus retrieves the status of
-// the most recently completed sync attempt
-// If no attempt has yet completed because the feed was just added,
-// then the first return value will be false.
-func (s *FeedStore) RetrieveFeedSyncStatus(id FeedId) (bool, FeedSyncStatus, error) {
-	var date int64
-	var success bool
-	var syncErrVal sql.NullString
+// SetItemStarred stars or unstars a feed item
+func (s *FeedStore) SetItemStarred(itemId FeedItemId, starred bool) error {
+	stmt := s.statements[updateItemStarredStmt]
+	_, err := stmt.Exec(starred, itemId, starred)
+	return err
+}
 
-	stmt := s.statements[selectFeedSyncStatusStmt]
-	err := stmt.QueryRow(id).Scan(&date, &success, &syncErrVal)
-	if err == sql.ErrNoRows {
-		return false, FeedSyncStatus{}, nil
-	} else if err != nil {
-		return false, FeedSyncStatus{}, err
+// RetrieveItemStates retrieves the read and starred state of every item
+// in a feed, including hidden items, in the order the items were inserted.
+func (s *FeedStore) RetrieveItemStates(feedId FeedId) ([]ItemStateRecord, error) {
+	stmt := s.statements[selectItemStatesStmt]
+	rows, err := stmt.Query(feedId)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	var syncErr error
-	if syncErrVal.Valid {
-		syncErr = errors.New(syncErrVal.String)
+	records := make([]ItemStateRecord, 0)
+	for rows.Next() {
+		var record ItemStateRecord
+		var changed int64
+		if err := rows.Scan(&record.Id, &record.Guid, &record.Read, &record.Starred, &changed); err != nil {
+			return nil, err
+		}
+
+		if changed > 0 {
+			record.Changed = time.Unix(changed, 0)
+		}
+		records = append(records, record)
 	}
 
-	status := FeedSyncStatus{
-		Date:    time.Unix(date, 0),
-		Success: success,
-		Error:   syncErr,
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	return true, status, nil
+
+	return records, nil
 }
 
-func (s *FeedStore) enableForeignKeyConstraints() error {
-	sql := "PRAGMA foreign_keys = ON;"
-	_, err := s.db.Exec(sql)
+// SetSyncedItemState sets the read and starred state of an item to its
+// state on a remote aggregator.  Unlike `SetItemRead` and `SetItemStarred`,
+// this isn't recorded as a local change.
+func (s *FeedStore) SetSyncedItemState(itemId FeedItemId, read bool, starred bool) error {
+	stmt := s.statements[updateItemStateStmt]
+	_, err := stmt.Exec(read, starred, itemId)
 	return err
 }
 
-func (s *FeedStore) installSchema() error {
-	sql := `
-	CREATE TABLE IF NOT EXISTS feed (
-		id INTEGER NOT NULL PRIMARY KEY,
-		url VARCHAR UNIQUE NOT NULL,
-		name VARCHAR NOT NULL
-	);
+// RetrieveUndeliveredItems retrieves the items that haven't been delivered
+// to a destination (such as a mailbox) yet, oldest first.  Hidden items
+// are never delivered.
+func (s *FeedStore) RetrieveUndeliveredItems(destination string) ([]FeedItemRecord, error) {
+	stmt := s.statements[selectUndeliveredItemsStmt]
+	rows, err := stmt.Query(destination)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFeedItems(rows)
+}
 
-	CREATE TABLE IF NOT EXISTS feed_item (
-		id INTEGER NOT NULL PRIMARY KEY,
-		feed_id INTEGER NOT NULL,
-		guid VARCHAR NOT NULL,
-		url VARCHAR NOT NULL,
-		title VARCHAR NOT NULL,
-		date INTEGER NOT NULL,
+// MarkItemsDelivered records that items were delivered to a destination,
+// so `RetrieveUndeliveredItems` won't retrieve them again.
+func (s *FeedStore) MarkItemsDelivered(destination string, itemIds []FeedItemId) error {
+	return s.wrapInTx(func(tx *sql.Tx) error {
+		stmt := tx.Stmt(s.statements[insertItemDeliveryStmt])
+		for _, itemId := range itemIds {
+			if _, err := stmt.Exec(destination, itemId); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddOutboxEntry queues an article to be sent to a read-later service.
+// Entries stay in the outbox until they're sent, even across restarts.
+func (s *FeedStore) AddOutboxEntry(url string, title string) (OutboxEntryId, error) {
+	stmt := s.statements[insertOutboxEntryStmt]
+	result, err := stmt.Exec(url, title)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return OutboxEntryId(id), err
+}
+
+// RetrieveOutboxEntries retrieves every entry in the outbox, in the order queued
+func (s *FeedStore) RetrieveOutboxEntries() ([]OutboxEntryRecord, error) {
+	stmt := s.statements[selectOutboxEntriesStmt]
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]OutboxEntryRecord, 0)
+	for rows.Next() {
+		var record OutboxEntryRecord
+		var queued int64
+		err := rows.Scan(&record.Id, &record.Url, &record.Title, &queued, &record.Attempts, &record.LastError)
+		if err != nil {
+			return nil, err
+		}
+		record.Queued = time.Unix(queued, 0)
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// DeleteOutboxEntry removes an entry from the outbox once it's sent
+func (s *FeedStore) DeleteOutboxEntry(id OutboxEntryId) error {
+	stmt := s.statements[deleteOutboxEntryStmt]
+	_, err := stmt.Exec(id)
+	return err
+}
+
+// SetOutboxEntryError records a failed attempt to send an entry
+func (s *FeedStore) SetOutboxEntryError(id OutboxEntryId, sendErr error) error {
+	stmt := s.statements[updateOutboxEntryErrorStmt]
+	_, err := stmt.Exec(sendErr.Error(), id)
+	return err
+}
+
+// SaveArchivedArticle stores the article linked from an item,
+// replacing any earlier attempt to archive it.
+func (s *FeedStore) SaveArchivedArticle(article ArchivedArticleRecord) error {
+	content, err := compress(article.ContentHtml)
+	if err != nil {
+		return err
+	}
+
+	stmt := s.statements[upsertArchivedArticleStmt]
+	_, err = stmt.Exec(article.ItemId, article.Url, article.Title, content, len(content), "")
+	return err
+}
+
+// SetArchiveError records a failed attempt to archive the article linked
+// from an item.  The attempt is retried once it's older than the cutoff
+// passed to `RetrieveItemsToArchive`.
+func (s *FeedStore) SetArchiveError(itemId FeedItemId, url string, archiveErr error) error {
+	stmt := s.statements[upsertArchivedArticleStmt]
+	_, err := stmt.Exec(itemId, url, "", []byte{}, 0, archiveErr.Error())
+	return err
+}
+
+// RetrieveArchivedArticle retrieves the article archived for an item.
+// If no attempt has been made to archive it, the first return value is false.
+func (s *FeedStore) RetrieveArchivedArticle(itemId FeedItemId) (bool, ArchivedArticleRecord, error) {
+	var content []byte
+	var archived int64
+	article := ArchivedArticleRecord{ItemId: itemId}
+	stmt := s.statements[selectArchivedArticleStmt]
+	err := stmt.QueryRow(itemId).Scan(
+		&article.Url, &article.Title, &content, &archived, &article.Err, &article.Pruned)
+	if err == sql.ErrNoRows {
+		return false, ArchivedArticleRecord{}, nil
+	} else if err != nil {
+		return false, ArchivedArticleRecord{}, err
+	}
+
+	article.Archived = time.Unix(archived, 0)
+	if article.ContentHtml, err = decompress(content); err != nil {
+		return false, ArchivedArticleRecord{}, err
+	}
+	return true, article, nil
+}
+
+// RetrieveItemsToArchive retrieves up to `limit` items whose linked
+// articles should be archived, most recent first: items in feeds that
+// archive articles and, optionally, starred items.  Items that failed
+// to archive are included once the failure is older than the cutoff.
+func (s *FeedStore) RetrieveItemsToArchive(includeStarred bool, retryCutoff time.Time, limit int) ([]FeedItemRecord, error) {
+	stmt := s.statements[selectItemsToArchiveStmt]
+	rows, err := stmt.Query(includeStarred, retryCutoff.Unix(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFeedItems(rows)
+}
+
+// PruneArchive removes the content of archived articles until the
+// compressed size of the archive is at most `maxBytes`.  Articles for
+// unstarred items are removed before starred items, oldest first.
+// Pruned articles aren't archived again.  This returns the number
+// of articles pruned.
+func (s *FeedStore) PruneArchive(maxBytes int64) (int, error) {
+	numPruned := 0
+	err := s.wrapInTx(func(tx *sql.Tx) error {
+		numPruned = 0
+		rows, err := tx.Stmt(s.statements[selectArchiveSizesStmt]).Query()
+		if err != nil {
+			return err
+		}
+
+		var itemIds []FeedItemId
+		var sizes []int64
+		var totalBytes int64
+		for rows.Next() {
+			var itemId FeedItemId
+			var size int64
+			if err := rows.Scan(&itemId, &size); err != nil {
+				rows.Close()
+				return err
+			}
+			itemIds = append(itemIds, itemId)
+			sizes = append(sizes, size)
+			totalBytes += size
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		stmt := tx.Stmt(s.statements[pruneArchivedArticleStmt])
+		for i := 0; i < len(itemIds) && totalBytes > maxBytes; i++ {
+			if _, err := stmt.Exec(itemIds[i]); err != nil {
+				return err
+			}
+			totalBytes -= sizes[i]
+			numPruned++
+		}
+		return nil
+	})
+	return numPruned, err
+}
+
+// compress gzips text for storage
+func compress(s string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(w, s); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress reverses `compress`.  Empty data decompresses to an empty string.
+func decompress(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	text, err := ioutil.ReadAll(r)
+	return string(text), err
+}
+
+// RetrieveFeedItem retrieves a single feed item by its ID
+func (s *FeedStore) RetrieveFeedItem(itemId FeedItemId) (FeedItemRecord, error) {
+	stmt := s.statements[selectFeedItemStmt]
+	rows, err := stmt.Query(itemId)
+	if err != nil {
+		return FeedItemRecord{}, err
+	}
+	defer rows.Close()
+
+	records, err := scanFeedItems(rows)
+	if err != nil {
+		return FeedItemRecord{}, err
+	} else if len(records) == 0 {
+		return FeedItemRecord{}, sql.ErrNoRows
+	}
+	return records[0], nil
+}
+
+// RetrieveFilterRules retrieves every filter rule, in the order created
+func (s *FeedStore) RetrieveFilterRules() ([]FilterRuleRecord, error) {
+	stmt := s.statements[selectFilterRulesStmt]
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFilterRules(rows)
+}
+
+// RetrieveFilterRule retrieves a single filter rule by its id
+func (s *FeedStore) RetrieveFilterRule(id FilterRuleId) (FilterRuleRecord, error) {
+	stmt := s.statements[selectFilterRuleStmt]
+	rows, err := stmt.Query(id)
+	if err != nil {
+		return FilterRuleRecord{}, err
+	}
+	defer rows.Close()
+
+	records, err := scanFilterRules(rows)
+	if err != nil {
+		return FilterRuleRecord{}, err
+	} else if len(records) == 0 {
+		return FilterRuleRecord{}, sql.ErrNoRows
+	}
+	return records[0], nil
+}
+
+// CreateFilterRule adds a filter rule, which is applied to items
+// the next time their feeds are synced.
+// If the rule is invalid (for example, a malformed regular expression),
+// this returns an error without creating the rule.
+func (s *FeedStore) CreateFilterRule(record FilterRuleRecord) (FilterRuleId, error) {
+	if _, err := filter.Compile(record.Rule); err != nil {
+		return 0, err
+	}
+
+	stmt := s.statements[insertFilterRuleStmt]
+	result, err := stmt.Exec(
+		nullFeedId(record.FeedId),
+		int(record.Rule.Field),
+		record.Rule.Pattern,
+		record.Rule.Regex,
+		int(record.Rule.Action))
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return FilterRuleId(id), nil
+}
+
+// UpdateFilterRule replaces a filter rule.  The rule's previous matches
+// are forgotten, so it is reapplied the next time feeds are synced.
+// Actions already applied to items (e.g. hiding them) are NOT undone.
+func (s *FeedStore) UpdateFilterRule(record FilterRuleRecord) error {
+	if _, err := filter.Compile(record.Rule); err != nil {
+		return err
+	}
+
+	return s.wrapInTx(func(tx *sql.Tx) error {
+		stmt := tx.Stmt(s.statements[deleteFilterMatchesForRuleStmt])
+		if _, err := stmt.Exec(record.Id); err != nil {
+			return err
+		}
+
+		stmt = tx.Stmt(s.statements[updateFilterRuleStmt])
+		_, err := stmt.Exec(
+			nullFeedId(record.FeedId),
+			int(record.Rule.Field),
+			record.Rule.Pattern,
+			record.Rule.Regex,
+			int(record.Rule.Action),
+			record.Id)
+		return err
+	})
+}
+
+// DeleteFilterRule deletes a filter rule and the record of its matches.
+// Actions already applied to items are NOT undone.
+func (s *FeedStore) DeleteFilterRule(id FilterRuleId) error {
+	return s.wrapInTx(func(tx *sql.Tx) error {
+		stmt := tx.Stmt(s.statements[deleteFilterMatchesForRuleStmt])
+		if _, err := stmt.Exec(id); err != nil {
+			return err
+		}
+
+		stmt = tx.Stmt(s.statements[deleteFilterRuleStmt])
+		_, err := stmt.Exec(id)
+		return err
+	})
+}
+
+// RetrieveFilterRuleMatches retrieves the items a filter rule has matched,
+// most recently matched first.  This includes hidden items.
+func (s *FeedStore) RetrieveFilterRuleMatches(id FilterRuleId) ([]FeedItemRecord, error) {
+	stmt := s.statements[selectFilterMatchesStmt]
+	rows, err := stmt.Query(id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFeedItems(rows)
+}
+
+func scanFilterRules(rows *sql.Rows) ([]FilterRuleRecord, error) {
+	records := make([]FilterRuleRecord, 0)
+	for rows.Next() {
+		var id int64
+		var feedId sql.NullInt64
+		var field, action int
+		var pattern string
+		var regex bool
+
+		if err := rows.Scan(&id, &feedId, &field, &pattern, &regex, &action); err != nil {
+			return nil, err
+		}
+
+		records = append(records, FilterRuleRecord{
+			Id:     FilterRuleId(id),
+			FeedId: FeedId(feedId.Int64),
+			Rule: filter.Rule{
+				Field:   filter.Field(field),
+				Pattern: pattern,
+				Regex:   regex,
+				Action:  filter.Action(action),
+			},
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// nullFeedId stores rules that apply to every feed with a NULL feed ID
+func nullFeedId(id FeedId) sql.NullInt64 {
+	if id == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(id), Valid: true}
+}
+
+// RetrievePostingInterval retrieves the average time between a feed's
+// most recent items, or zero if the feed has fewer than two items.
+func (s *FeedStore) RetrievePostingInterval(feedId FeedId) (time.Duration, error) {
+	const maxItems int = 20
+
+	stmt := s.statements[selectRecentItemDatesStmt]
+	rows, err := stmt.Query(feedId, maxItems)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var newest, oldest int64
+	count := 0
+	for rows.Next() {
+		var date int64
+		if err := rows.Scan(&date); err != nil {
+			return 0, err
+		}
+
+		if count == 0 {
+			newest = date
+		}
+		oldest = date
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if count < 2 {
+		return 0, nil
+	}
+
+	return time.Duration(newest-oldest) * time.Second / time.Duration(count-1), nil
+}
+
+// RetrieveUnreadCounts retrieves the number of unread items in each feed.
+// Hidden items aren't counted, and feeds without unread items are omitted.
+func (s *FeedStore) RetrieveUnreadCounts() (map[FeedId]int, error) {
+	stmt := s.statements[selectUnreadCountsStmt]
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[FeedId]int, 0)
+	for rows.Next() {
+		var feedId int64
+		var count int
+		if err := rows.Scan(&feedId, &count); err != nil {
+			return nil, err
+		}
+		counts[FeedId(feedId)] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// RetrieveSavedSearches retrieves every saved search, sorted by name
+func (s *FeedStore) RetrieveSavedSearches() ([]SavedSearchRecord, error) {
+	stmt := s.statements[selectSavedSearchesStmt]
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]SavedSearchRecord, 0)
+	for rows.Next() {
+		var id int64
+		var record SavedSearchRecord
+		if err := rows.Scan(&id, &record.Name, &record.Query); err != nil {
+			return nil, err
+		}
+		record.Id = SavedSearchId(id)
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// RetrieveSavedSearch retrieves a single saved search by its id
+func (s *FeedStore) RetrieveSavedSearch(id SavedSearchId) (SavedSearchRecord, error) {
+	var recordId int64
+	var record SavedSearchRecord
+	stmt := s.statements[selectSavedSearchStmt]
+	if err := stmt.QueryRow(id).Scan(&recordId, &record.Name, &record.Query); err != nil {
+		return SavedSearchRecord{}, err
+	}
+	record.Id = SavedSearchId(recordId)
+	return record, nil
+}
+
+// CreateSavedSearch saves a query.  If the query is invalid,
+// this returns the parse error without saving it.
+func (s *FeedStore) CreateSavedSearch(record SavedSearchRecord) (SavedSearchId, error) {
+	if _, err := query.Parse(record.Query); err != nil {
+		return 0, err
+	}
+
+	stmt := s.statements[insertSavedSearchStmt]
+	result, err := stmt.Exec(record.Name, record.Query)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return SavedSearchId(id), nil
+}
+
+// UpdateSavedSearch changes the name and query of a saved search.
+// If the query is invalid, this returns the parse error without saving it.
+func (s *FeedStore) UpdateSavedSearch(record SavedSearchRecord) error {
+	if _, err := query.Parse(record.Query); err != nil {
+		return err
+	}
+
+	stmt := s.statements[updateSavedSearchStmt]
+	_, err := stmt.Exec(record.Name, record.Query, record.Id)
+	return err
+}
+
+// DeleteSavedSearch deletes a saved search.  The items it matched are NOT deleted.
+func (s *FeedStore) DeleteSavedSearch(id SavedSearchId) error {
+	stmt := s.statements[deleteSavedSearchStmt]
+	_, err := stmt.Exec(id)
+	return err
+}
+
+// RetrieveSavedSearchItems retrieves every item matching a saved search,
+// across all feeds.  Hidden items never match.
+func (s *FeedStore) RetrieveSavedSearchItems(id SavedSearchId) ([]FeedItemRecord, error) {
+	q, err := s.savedSearchQuery(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.RetrieveQueryItems(q)
+}
+
+// RetrieveQueryItems retrieves the items in every feed matching a query,
+// most recent first.  Hidden items never match.  A query without terms
+// matches every item.
+func (s *FeedStore) RetrieveQueryItems(q query.Query) ([]FeedItemRecord, error) {
+	where, args := compileQuery(q)
+	sql := `
+		SELECT i.id, i.feed_id, i.guid, i.url, i.title, i.date, i.date_modified,
+			i.content_html, i.content_text, i.author,
+			i.read, i.hidden, i.highlighted, i.starred
+		FROM feed_item i
+		JOIN feed f ON f.id = i.feed_id
+		WHERE ` + where + `
+		ORDER BY i.date DESC, i.title ASC`
+
+	// The SQL depends on the query, so it can't be a prepared statement
+	rows, err := s.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFeedItems(rows)
+}
+
+// CountUnreadSavedSearchItems counts the unread items matching a saved search
+func (s *FeedStore) CountUnreadSavedSearchItems(id SavedSearchId) (int, error) {
+	q, err := s.savedSearchQuery(id)
+	if err != nil {
+		return 0, err
+	}
+
+	where, args := compileQuery(q)
+	sql := `
+		SELECT COUNT(*)
+		FROM feed_item i
+		JOIN feed f ON f.id = i.feed_id
+		WHERE ` + where + ` AND i.read = 0`
+
+	var count int
+	if err := s.db.QueryRow(sql, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// RetrieveFilteredItems retrieves the items matching a filter
+func (s *FeedStore) RetrieveFilteredItems(filter ItemFilter) ([]FeedItemRecord, error) {
+	where, args := compileItemFilter(filter)
+	sql := `
+		SELECT i.id, i.feed_id, i.guid, i.url, i.title, i.date, i.date_modified,
+			i.content_html, i.content_text, i.author,
+			i.read, i.hidden, i.highlighted, i.starred
+		FROM feed_item i
+		JOIN feed f ON f.id = i.feed_id
+		WHERE ` + where + itemFilterOrderAndLimit(filter)
+
+	// The SQL depends on the filter, so it can't be a prepared statement
+	rows, err := s.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFeedItems(rows)
+}
+
+// RetrieveFilteredItemIds retrieves the IDs of the items matching a filter,
+// without loading their content.
+func (s *FeedStore) RetrieveFilteredItemIds(filter ItemFilter) ([]FeedItemId, error) {
+	where, args := compileItemFilter(filter)
+	sql := `
+		SELECT i.id
+		FROM feed_item i
+		JOIN feed f ON f.id = i.feed_id
+		WHERE ` + where + itemFilterOrderAndLimit(filter)
+
+	rows, err := s.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]FeedItemId, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, FeedItemId(id))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// CountFilteredItems counts the items matching a filter,
+// ignoring the filter's limit and offset.
+func (s *FeedStore) CountFilteredItems(filter ItemFilter) (int, error) {
+	where, args := compileItemFilter(filter)
+	sql := `
+		SELECT COUNT(*)
+		FROM feed_item i
+		JOIN feed f ON f.id = i.feed_id
+		WHERE ` + where
+
+	var count int
+	if err := s.db.QueryRow(sql, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// MarkFilteredItemsRead marks every item matching a filter as read,
+// ignoring the filter's order, limit, and offset.
+func (s *FeedStore) MarkFilteredItemsRead(filter ItemFilter) error {
+	where, args := compileItemFilter(filter)
+	sql := `
+		UPDATE feed_item SET read = 1, state_changed = strftime('%s', 'now')
+		WHERE read = 0 AND id IN (
+			SELECT i.id
+			FROM feed_item i
+			JOIN feed f ON f.id = i.feed_id
+			WHERE ` + where + `)`
+
+	_, err := s.db.Exec(sql, args...)
+	return err
+}
+
+// compileItemFilter converts a filter to a SQL condition over feed items
+// (aliased "i") joined with their feeds (aliased "f").
+// Values are always passed as arguments, never inserted into the SQL.
+func compileItemFilter(filter ItemFilter) (string, []interface{}) {
+	conditions := []string{"i.hidden = 0"}
+	args := make([]interface{}, 0)
+	addCondition := func(condition string, conditionArgs ...interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+
+	if filter.FeedId > 0 {
+		addCondition("i.feed_id = ?", filter.FeedId)
+	}
+
+	if len(filter.Folder) > 0 {
+		addCondition("lower(f.folder) = lower(?)", filter.Folder)
+	}
+
+	if filter.Unread {
+		addCondition("i.read = 0")
+	}
+
+	if filter.Read {
+		addCondition("i.read = 1")
+	}
+
+	if filter.Starred {
+		addCondition("i.starred = 1")
+	}
+
+	if filter.Ids != nil {
+		// An empty list matches nothing
+		placeholders := make([]string, len(filter.Ids))
+		idArgs := make([]interface{}, len(filter.Ids))
+		for i, id := range filter.Ids {
+			placeholders[i] = "?"
+			idArgs[i] = id
+		}
+		addCondition("i.id IN ("+strings.Join(placeholders, ", ")+")", idArgs...)
+	}
+
+	if filter.AfterId > 0 {
+		addCondition("i.id > ?", filter.AfterId)
+	}
+
+	if filter.BeforeId > 0 {
+		addCondition("i.id < ?", filter.BeforeId)
+	}
+
+	if !filter.Since.IsZero() {
+		addCondition("i.date >= ?", filter.Since.Unix())
+	}
+
+	if !filter.Until.IsZero() {
+		addCondition("i.date < ?", filter.Until.Unix())
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// itemFilterOrderAndLimit returns the ORDER BY and LIMIT clauses for a filter.
+// These contain only constants and integers, so they're safe to insert into the SQL.
+func itemFilterOrderAndLimit(filter ItemFilter) string {
+	var sql string
+	switch filter.Order {
+	case OldestFirst:
+		sql = " ORDER BY i.date ASC, i.id ASC"
+	case IdAscending:
+		sql = " ORDER BY i.id ASC"
+	case IdDescending:
+		sql = " ORDER BY i.id DESC"
+	default:
+		sql = " ORDER BY i.date DESC, i.id DESC"
+	}
+
+	if filter.Limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d OFFSET %d", filter.Limit, filter.Offset)
+	} else if filter.Offset > 0 {
+		sql += fmt.Sprintf(" LIMIT -1 OFFSET %d", filter.Offset)
+	}
+	return sql
+}
+
+func (s *FeedStore) savedSearchQuery(id SavedSearchId) (query.Query, error) {
+	record, err := s.RetrieveSavedSearch(id)
+	if err != nil {
+		return query.Query{}, err
+	}
+	return query.Parse(record.Query)
+}
+
+// compileQuery converts a query to a SQL condition over feed items
+// (aliased "i") joined with their feeds (aliased "f").
+// Values are always passed as arguments, never inserted into the SQL.
+func compileQuery(q query.Query) (string, []interface{}) {
+	conditions := []string{"i.hidden = 0"}
+	args := make([]interface{}, 0)
+
+	for _, term := range q.Terms {
+		// contains matches the term's value anywhere in a column, ignoring case
+		value := term.Value
+		contains := func(column string) string {
+			args = append(args, value)
+			return "instr(lower(" + column + "), lower(?)) > 0"
+		}
+
+		var condition string
+		switch term.Field {
+		case query.FieldText:
+			condition = "(" + contains("i.title") +
+				" OR " + contains("i.content_text") +
+				" OR " + contains("i.content_html") + ")"
+		case query.FieldTitle:
+			condition = contains("i.title")
+		case query.FieldContent:
+			condition = "(" + contains("i.content_text") + " OR " + contains("i.content_html") + ")"
+		case query.FieldAuthor:
+			condition = contains("i.author")
+		case query.FieldCategory:
+			condition = `EXISTS (
+				SELECT 1 FROM feed_item_category c
+				WHERE c.item_id = i.id AND ` + contains("c.name") + `)`
+		case query.FieldUrl:
+			condition = contains("i.url")
+		case query.FieldFeed:
+			condition = "(" + contains("f.name") + " OR " + contains("f.custom_name") + ")"
+		case query.FieldFolder:
+			condition = "lower(f.folder) = lower(?)"
+			args = append(args, value)
+		case query.FieldIs:
+			switch value {
+			case query.StateRead:
+				condition = "i.read = 1"
+			case query.StateUnread:
+				condition = "i.read = 0"
+			case query.StateHighlighted:
+				condition = "i.highlighted = 1"
+			case query.StateStarred:
+				condition = "i.starred = 1"
+			}
+		}
+
+		if len(condition) == 0 {
+			continue
+		} else if term.Negate {
+			condition = "NOT " + condition
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if q.MaxAge > 0 {
+		conditions = append(conditions, "i.date >= strftime('%s', 'now') - ?")
+		args = append(args, int64(q.MaxAge/time.Second))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// SetFeedSyncStatusError sets the most recent sync attempt to "error" status
+func (s *FeedStore) SetFeedSyncStatusError(id FeedId, syncErr error) error {
+	syncErrStr := fmt.Sprintf("%v", syncErr)
+	return s.wrapInTx(func(tx *sql.Tx) error {
+		stmt := tx.Stmt(s.statements[upsertFeedSyncStatusStmt])
+		if _, err := stmt.Exec(id, false, syncErrStr); err != nil {
+			return err
+		}
+
+		return s.insertFeedSyncLogEntry(tx, id, SyncLogError, "", syncErrStr)
+	})
+}
+
+// AddHookFailureToSyncLog records in a feed's sync history that
+// a hook failed while processing the feed's new items.
+// This doesn't change the status of the feed's last sync.
+func (s *FeedStore) AddHookFailureToSyncLog(id FeedId, hookCommand string, hookErr error) error {
+	return s.wrapInTx(func(tx *sql.Tx) error {
+		return s.insertFeedSyncLogEntry(tx, id, SyncLogHookFailed, hookCommand, hookErr.Error())
+	})
+}
+
+// RetrieveFeedSyncLog retrieves the sync history for a feed, most recent first.
+func (s *FeedStore) RetrieveFeedSyncLog(id FeedId) ([]FeedSyncLogEntry, error) {
+	stmt := s.statements[selectFeedSyncLogStmt]
+	rows, err := stmt.Query(id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]FeedSyncLogEntry, 0)
+	for rows.Next() {
+		var date int64
+		var kind int
+		var subject, detail string
+
+		if err := rows.Scan(&date, &kind, &subject, &detail); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, FeedSyncLogEntry{
+			Date:    time.Unix(date, 0),
+			Kind:    SyncLogKind(kind),
+			Subject: subject,
+			Detail:  detail,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// RetrieveFeedSyncStatus retrieves the status of
+// the most recently completed sync attempt
+// If no attempt has yet completed because the feed was just added,
+// then the first return value will be false.
+func (s *FeedStore) RetrieveFeedSyncStatus(id FeedId) (bool, FeedSyncStatus, error) {
+	var date int64
+	var success bool
+	var syncErrVal sql.NullString
+
+	stmt := s.statements[selectFeedSyncStatusStmt]
+	err := stmt.QueryRow(id).Scan(&date, &success, &syncErrVal)
+	if err == sql.ErrNoRows {
+		return false, FeedSyncStatus{}, nil
+	} else if err != nil {
+		return false, FeedSyncStatus{}, err
+	}
+
+	var syncErr error
+	if syncErrVal.Valid {
+		syncErr = errors.New(syncErrVal.String)
+	}
+
+	status := FeedSyncStatus{
+		Date:    time.Unix(date, 0),
+		Success: success,
+		Error:   syncErr,
+	}
+	return true, status, nil
+}
+
+// SetRemoteFeed links a feed to its subscription on a remote aggregator,
+// or updates the subscription's details.  Feeds linked to a remote
+// account are loaded from the aggregator instead of from their URLs.
+func (s *FeedStore) SetRemoteFeed(record RemoteFeedRecord) error {
+	stmt := s.statements[upsertRemoteFeedStmt]
+	_, err := stmt.Exec(record.FeedId, record.Account, record.RemoteId, record.Title, record.SiteUrl, record.Created)
+	return err
+}
+
+// RetrieveRemoteFeed retrieves the remote subscription linked to a feed.
+// The first return value is false if the feed isn't linked to one.
+func (s *FeedStore) RetrieveRemoteFeed(id FeedId) (bool, RemoteFeedRecord, error) {
+	stmt := s.statements[selectRemoteFeedStmt]
+	rows, err := stmt.Query(id)
+	if err != nil {
+		return false, RemoteFeedRecord{}, err
+	}
+	defer rows.Close()
+
+	records, err := scanRemoteFeeds(rows)
+	if err != nil || len(records) == 0 {
+		return false, RemoteFeedRecord{}, err
+	}
+	return true, records[0], nil
+}
+
+// RetrieveRemoteFeeds retrieves the feeds linked to subscriptions in a remote account
+func (s *FeedStore) RetrieveRemoteFeeds(account string) ([]RemoteFeedRecord, error) {
+	stmt := s.statements[selectRemoteFeedsStmt]
+	rows, err := stmt.Query(account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRemoteFeeds(rows)
+}
+
+// SetRemoteFeedSynced records when a feed's items were synced with a remote aggregator
+func (s *FeedStore) SetRemoteFeedSynced(id FeedId, synced time.Time) error {
+	stmt := s.statements[updateRemoteFeedSyncedStmt]
+	_, err := stmt.Exec(synced.Unix(), id)
+	return err
+}
+
+// DeleteRemoteFeed unlinks a feed from its subscription on a remote aggregator,
+// keeping the feed and its items.  The feed is loaded from its URL from then on.
+func (s *FeedStore) DeleteRemoteFeed(id FeedId) error {
+	stmt := s.statements[deleteRemoteFeedStmt]
+	_, err := stmt.Exec(id)
+	return err
+}
+
+func scanRemoteFeeds(rows *sql.Rows) ([]RemoteFeedRecord, error) {
+	records := make([]RemoteFeedRecord, 0)
+	for rows.Next() {
+		var record RemoteFeedRecord
+		var synced int64
+		err := rows.Scan(&record.FeedId, &record.Account, &record.RemoteId, &record.Title, &record.SiteUrl, &synced, &record.Created)
+		if err != nil {
+			return nil, err
+		}
+
+		if synced > 0 {
+			record.Synced = time.Unix(synced, 0)
+		}
+		records = append(records, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// dsnWithForeignKeys adds the driver option that enables foreign key constraints.
+// A PRAGMA would apply only to the pooled connection that ran it, so the option
+// is set in the DSN to enable the constraints on every connection the pool opens.
+func dsnWithForeignKeys(dbPath string) string {
+	if strings.ContainsRune(dbPath, '?') {
+		return dbPath + "&_foreign_keys=on"
+	}
+	return dbPath + "?_foreign_keys=on"
+}
+
+func (s *FeedStore) installSchema() error {
+	sql := `
+	CREATE TABLE IF NOT EXISTS feed (
+		id INTEGER NOT NULL PRIMARY KEY,
+		url VARCHAR UNIQUE NOT NULL,
+		name VARCHAR NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS feed_item (
+		id INTEGER NOT NULL PRIMARY KEY,
+		feed_id INTEGER NOT NULL,
+		guid VARCHAR NOT NULL,
+		url VARCHAR NOT NULL,
+		title VARCHAR NOT NULL,
+		date INTEGER NOT NULL,
 		FOREIGN KEY (feed_id) REFERENCES feed(id)
 	);
 
-	CREATE UNIQUE INDEX IF NOT EXISTS feed_item_feed_guid_idx
-		ON feed_item(feed_id, guid);
+	CREATE UNIQUE INDEX IF NOT EXISTS feed_item_feed_guid_idx
+		ON feed_item(feed_id, guid);
+
+	CREATE INDEX IF NOT EXISTS feed_item_date_idx
+		ON feed_item(date);
+
+	CREATE TABLE IF NOT EXISTS feed_sync_status (
+		feed_id INTEGER NOT NULL PRIMARY KEY,
+		date INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+		success INTEGER NOT NULL,
+		error TEXT,
+		FOREIGN KEY (feed_id)
+			REFERENCES feed(id)
+			ON DELETE CASCADE
+	);
+	`
+	_, err := s.db.Exec(sql)
+	return err
+}
+
+// schemaMigrations alter tables created by earlier versions of the schema.
+// They are applied in order, and the number of migrations applied so far
+// is tracked in the database's user_version.  Never edit or reorder
+// existing migrations; always append new ones to the end.
+var schemaMigrations = []string{
+	`ALTER TABLE feed ADD COLUMN custom_name VARCHAR NOT NULL DEFAULT '';
+	ALTER TABLE feed ADD COLUMN folder VARCHAR NOT NULL DEFAULT '';
+	ALTER TABLE feed ADD COLUMN refresh_interval INTEGER NOT NULL DEFAULT 0;`,
+
+	`CREATE TABLE feed_sync_log (
+		id INTEGER NOT NULL PRIMARY KEY,
+		feed_id INTEGER NOT NULL,
+		date INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+		kind INTEGER NOT NULL,
+		subject TEXT NOT NULL,
+		detail TEXT NOT NULL,
+		FOREIGN KEY (feed_id)
+			REFERENCES feed(id)
+			ON DELETE CASCADE
+	);
+	CREATE INDEX feed_sync_log_feed_idx ON feed_sync_log(feed_id, id);`,
+
+	`CREATE TABLE feed_credentials (
+		feed_id INTEGER NOT NULL PRIMARY KEY,
+		scheme INTEGER NOT NULL,
+		username TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		secret_command TEXT NOT NULL,
+		FOREIGN KEY (feed_id)
+			REFERENCES feed(id)
+			ON DELETE CASCADE
+	);
+	CREATE TABLE feed_header (
+		feed_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		value TEXT NOT NULL,
+		PRIMARY KEY (feed_id, name),
+		FOREIGN KEY (feed_id)
+			REFERENCES feed(id)
+			ON DELETE CASCADE
+	);`,
+
+	`CREATE TABLE feed_loader_config (
+		feed_id INTEGER NOT NULL PRIMARY KEY,
+		timeout_ms INTEGER NOT NULL,
+		response_header_timeout_ms INTEGER NOT NULL,
+		user_agent TEXT NOT NULL,
+		proxy_url TEXT NOT NULL,
+		ca_cert_file TEXT NOT NULL,
+		insecure_skip_verify INTEGER NOT NULL,
+		FOREIGN KEY (feed_id)
+			REFERENCES feed(id)
+			ON DELETE CASCADE
+	);`,
+
+	`ALTER TABLE feed_item ADD COLUMN date_modified INTEGER;
+	ALTER TABLE feed_item ADD COLUMN content_html TEXT NOT NULL DEFAULT '';
+	ALTER TABLE feed_item ADD COLUMN content_text TEXT NOT NULL DEFAULT '';`,
+
+	`CREATE TABLE feed_scrape (
+		feed_id INTEGER NOT NULL PRIMARY KEY,
+		item_selector TEXT NOT NULL,
+		title_selector TEXT NOT NULL,
+		link_selector TEXT NOT NULL,
+		date_selector TEXT NOT NULL,
+		FOREIGN KEY (feed_id)
+			REFERENCES feed(id)
+			ON DELETE CASCADE
+	);`,
+
+	`CREATE TABLE feed_item_enclosure (
+		id INTEGER NOT NULL PRIMARY KEY,
+		item_id INTEGER NOT NULL,
+		url TEXT NOT NULL,
+		mime_type TEXT NOT NULL,
+		title TEXT NOT NULL,
+		length INTEGER NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		FOREIGN KEY (item_id)
+			REFERENCES feed_item(id)
+			ON DELETE CASCADE
+	);
+	CREATE INDEX feed_item_enclosure_item_idx ON feed_item_enclosure(item_id);`,
+
+	`ALTER TABLE feed ADD COLUMN site_url TEXT NOT NULL DEFAULT '';
+	ALTER TABLE feed ADD COLUMN description TEXT NOT NULL DEFAULT '';
+	ALTER TABLE feed ADD COLUMN language TEXT NOT NULL DEFAULT '';
+	ALTER TABLE feed ADD COLUMN image_url TEXT NOT NULL DEFAULT '';
+	ALTER TABLE feed_item ADD COLUMN author TEXT NOT NULL DEFAULT '';
+	CREATE TABLE feed_item_category (
+		item_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		PRIMARY KEY (item_id, name),
+		FOREIGN KEY (item_id)
+			REFERENCES feed_item(id)
+			ON DELETE CASCADE
+	);
+	CREATE INDEX feed_item_category_name_idx ON feed_item_category(name);`,
+
+	`ALTER TABLE feed_item ADD COLUMN read INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE feed_item ADD COLUMN hidden INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE feed_item ADD COLUMN highlighted INTEGER NOT NULL DEFAULT 0;
+	CREATE TABLE filter_rule (
+		id INTEGER NOT NULL PRIMARY KEY,
+		feed_id INTEGER,
+		field INTEGER NOT NULL,
+		pattern TEXT NOT NULL,
+		is_regex INTEGER NOT NULL,
+		action INTEGER NOT NULL,
+		FOREIGN KEY (feed_id)
+			REFERENCES feed(id)
+			ON DELETE CASCADE
+	);
+	CREATE TABLE filter_match (
+		rule_id INTEGER NOT NULL,
+		item_id INTEGER NOT NULL,
+		date INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+		PRIMARY KEY (rule_id, item_id),
+		FOREIGN KEY (rule_id)
+			REFERENCES filter_rule(id)
+			ON DELETE CASCADE,
+		FOREIGN KEY (item_id)
+			REFERENCES feed_item(id)
+			ON DELETE CASCADE
+	);
+	CREATE INDEX filter_match_item_idx ON filter_match(item_id);`,
+
+	`CREATE TABLE saved_search (
+		id INTEGER NOT NULL PRIMARY KEY,
+		name TEXT NOT NULL,
+		query TEXT NOT NULL
+	);
+	CREATE INDEX feed_item_read_idx ON feed_item(feed_id, read);`,
+
+	`ALTER TABLE feed ADD COLUMN ttl INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE feed ADD COLUMN update_interval INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE feed ADD COLUMN skip_hours TEXT NOT NULL DEFAULT '';
+	ALTER TABLE feed ADD COLUMN skip_days TEXT NOT NULL DEFAULT '';`,
+
+	`ALTER TABLE feed ADD COLUMN muted INTEGER NOT NULL DEFAULT 0;`,
+
+	`ALTER TABLE feed_item ADD COLUMN starred INTEGER NOT NULL DEFAULT 0;`,
+
+	`ALTER TABLE feed_item ADD COLUMN state_changed INTEGER NOT NULL DEFAULT 0;
+	CREATE TABLE remote_feed (
+		feed_id INTEGER NOT NULL PRIMARY KEY,
+		account TEXT NOT NULL,
+		remote_id TEXT NOT NULL,
+		title TEXT NOT NULL,
+		site_url TEXT NOT NULL,
+		synced INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (feed_id)
+			REFERENCES feed(id)
+			ON DELETE CASCADE
+	);
+	CREATE UNIQUE INDEX remote_feed_account_idx ON remote_feed(account, remote_id);`,
+
+	`CREATE TABLE item_delivery (
+		destination TEXT NOT NULL,
+		item_id INTEGER NOT NULL,
+		delivered INTEGER NOT NULL,
+		PRIMARY KEY (destination, item_id),
+		FOREIGN KEY (item_id)
+			REFERENCES feed_item(id)
+			ON DELETE CASCADE
+	);`,
+
+	`CREATE TABLE read_later_outbox (
+		id INTEGER NOT NULL PRIMARY KEY,
+		url TEXT NOT NULL,
+		title TEXT NOT NULL,
+		queued INTEGER NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT ''
+	);`,
+
+	`ALTER TABLE feed ADD COLUMN archive_articles INTEGER NOT NULL DEFAULT 0;
+	CREATE TABLE item_archive (
+		item_id INTEGER NOT NULL PRIMARY KEY,
+		url TEXT NOT NULL,
+		title TEXT NOT NULL,
+		content BLOB NOT NULL,
+		size INTEGER NOT NULL,
+		archived INTEGER NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		pruned INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (item_id)
+			REFERENCES feed_item(id)
+			ON DELETE CASCADE
+	);`,
+
+	// Links made before this migration are treated as linking feeds
+	// the user already had, so they're never deleted by a sync.
+	`ALTER TABLE remote_feed ADD COLUMN created INTEGER NOT NULL DEFAULT 0;`,
+}
+
+func (s *FeedStore) migrateSchema() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	for version < len(schemaMigrations) {
+		migration := schemaMigrations[version]
+		version++
+		err := s.wrapInTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migration); err != nil {
+				return err
+			}
+
+			// PRAGMA statements don't support placeholders
+			_, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *FeedStore) prepareStatements() error {
+	s.statements = make([]*sql.Stmt, numStatements)
+
+	selectEveryFeedSql := `
+		SELECT id, url, name, custom_name, folder, refresh_interval, muted, archive_articles,
+			site_url, description, language, image_url,
+			ttl, update_interval, skip_hours, skip_days
+		FROM feed
+		ORDER BY name ASC`
+	if stmt, err := s.db.Prepare(selectEveryFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[selectEveryFeedStmt] = stmt
+	}
+
+	selectFeedSql := `
+		SELECT url, name, custom_name, folder, refresh_interval, muted, archive_articles,
+			site_url, description, language, image_url,
+			ttl, update_interval, skip_hours, skip_days
+		FROM feed WHERE id = ?`
+	if stmt, err := s.db.Prepare(selectFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedStmt] = stmt
+	}
+
+	selectFeedIdByUrlSql := "SELECT id FROM feed WHERE url = ?"
+	if stmt, err := s.db.Prepare(selectFeedIdByUrlSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedIdByUrlStmt] = stmt
+	}
+
+	insertFeedSql := `
+		INSERT INTO feed (url, name) VALUES (?, ?)
+		ON CONFLICT(url) DO NOTHING`
+	if stmt, err := s.db.Prepare(insertFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[insertFeedStmt] = stmt
+	}
+
+	updateFeedSql := `
+		UPDATE feed
+		SET name = ?, site_url = ?, description = ?, language = ?, image_url = ?,
+			ttl = ?, update_interval = ?, skip_hours = ?, skip_days = ?
+		WHERE id = ?`
+	if stmt, err := s.db.Prepare(updateFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[updateFeedStmt] = stmt
+	}
+
+	deleteFeedSql := "DELETE FROM feed WHERE id = ?"
+	if stmt, err := s.db.Prepare(deleteFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteFeedStmt] = stmt
+	}
+
+	selectFeedItemsForFeedSql := `
+		SELECT id, feed_id, guid, url, title, date, date_modified, content_html, content_text, author,
+			read, hidden, highlighted, starred
+		FROM feed_item
+		WHERE feed_id = ? AND hidden = 0
+		ORDER BY date DESC, title ASC`
+	if stmt, err := s.db.Prepare(selectFeedItemsForFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedItemsForFeedStmt] = stmt
+	}
+
+	upsertFeedItemSql := `
+		INSERT INTO feed_item (feed_id, guid, url, title, date, date_modified, content_html, content_text, author)
+		VALUES (?1, ?2, ?3, ?4, COALESCE(?5, strftime('%s', 'now')), ?6, ?7, ?8, ?9)
+		ON CONFLICT(feed_id, guid)
+		DO UPDATE SET
+			url=excluded.url,
+			title=excluded.title,
+			date=COALESCE(?5, feed_item.date),
+			date_modified=excluded.date_modified,
+			content_html=excluded.content_html,
+			content_text=excluded.content_text,
+			author=excluded.author
+	`
+	if stmt, err := s.db.Prepare(upsertFeedItemSql); err != nil {
+		return err
+	} else {
+		s.statements[upsertFeedItemStmt] = stmt
+	}
+
+	deleteItemsInFeedSql := "DELETE FROM feed_item WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(deleteItemsInFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteItemsInFeedStmt] = stmt
+	}
+
+	upsertFeedSyncStatusSql := `
+		INSERT INTO feed_sync_status (feed_id, success, error)
+		VALUES (?, ?, ?)
+		ON CONFLICT(feed_id)
+		DO UPDATE SET
+			date = strftime('%s', 'now'),
+			success = excluded.success,
+			error = excluded.error
+	`
+	if stmt, err := s.db.Prepare(upsertFeedSyncStatusSql); err != nil {
+		return err
+	} else {
+		s.statements[upsertFeedSyncStatusStmt] = stmt
+	}
+
+	selectFeedSyncStatusSql := `
+		SELECT date, success, error
+		FROM feed_sync_status
+		WHERE feed_id = ?
+	`
+	if stmt, err := s.db.Prepare(selectFeedSyncStatusSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedSyncStatusStmt] = stmt
+	}
+
+	updateFeedSettingsSql := `
+		UPDATE feed
+		SET url = ?, custom_name = ?, folder = ?, refresh_interval = ?, muted = ?, archive_articles = ?
+		WHERE id = ?`
+	if stmt, err := s.db.Prepare(updateFeedSettingsSql); err != nil {
+		return err
+	} else {
+		s.statements[updateFeedSettingsStmt] = stmt
+	}
+
+	updateFeedUrlSql := "UPDATE feed SET url = ? WHERE id = ?"
+	if stmt, err := s.db.Prepare(updateFeedUrlSql); err != nil {
+		return err
+	} else {
+		s.statements[updateFeedUrlStmt] = stmt
+	}
+
+	selectFeedUrlSql := "SELECT url FROM feed WHERE id = ?"
+	if stmt, err := s.db.Prepare(selectFeedUrlSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedUrlStmt] = stmt
+	}
+
+	// Items are moved in place, so rows keyed by item ID stay with them.
+	// Items with the same GUID as an item in the other feed are deleted first.
+	mergeItemsIntoFeedSql := "UPDATE feed_item SET feed_id = ? WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(mergeItemsIntoFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeItemsIntoFeedStmt] = stmt
+	}
+
+	// Each of these copies the rows of items in one feed (the first parameter)
+	// to the items with the same GUID in another feed (the second parameter).
+	// Enclosures are copied only if the other item has none, since
+	// they're always replaced together.
+	mergeEnclosuresOfDuplicateItemsSql := `
+		INSERT INTO feed_item_enclosure (item_id, url, mime_type, title, length, duration_ms)
+		SELECT dup.id, e.url, e.mime_type, e.title, e.length, e.duration_ms
+		FROM feed_item_enclosure e
+		JOIN feed_item src ON src.id = e.item_id
+		JOIN feed_item dup ON dup.guid = src.guid
+		WHERE src.feed_id = ? AND dup.feed_id = ?
+		AND NOT EXISTS (SELECT 1 FROM feed_item_enclosure WHERE item_id = dup.id)
+		ORDER BY e.id
+	`
+	if stmt, err := s.db.Prepare(mergeEnclosuresOfDuplicateItemsSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeEnclosuresOfDuplicateItemsStmt] = stmt
+	}
+
+	mergeCategoriesOfDuplicateItemsSql := `
+		INSERT OR IGNORE INTO feed_item_category (item_id, name)
+		SELECT dup.id, c.name
+		FROM feed_item_category c
+		JOIN feed_item src ON src.id = c.item_id
+		JOIN feed_item dup ON dup.guid = src.guid
+		WHERE src.feed_id = ? AND dup.feed_id = ?
+	`
+	if stmt, err := s.db.Prepare(mergeCategoriesOfDuplicateItemsSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeCategoriesOfDuplicateItemsStmt] = stmt
+	}
+
+	mergeFilterMatchesOfDuplicateItemsSql := `
+		INSERT OR IGNORE INTO filter_match (rule_id, item_id, date)
+		SELECT m.rule_id, dup.id, m.date
+		FROM filter_match m
+		JOIN feed_item src ON src.id = m.item_id
+		JOIN feed_item dup ON dup.guid = src.guid
+		WHERE src.feed_id = ? AND dup.feed_id = ?
+	`
+	if stmt, err := s.db.Prepare(mergeFilterMatchesOfDuplicateItemsSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeFilterMatchesOfDuplicateItemsStmt] = stmt
+	}
+
+	mergeArchiveOfDuplicateItemsSql := `
+		INSERT OR IGNORE INTO item_archive (item_id, url, title, content, size, archived, error, pruned)
+		SELECT dup.id, a.url, a.title, a.content, a.size, a.archived, a.error, a.pruned
+		FROM item_archive a
+		JOIN feed_item src ON src.id = a.item_id
+		JOIN feed_item dup ON dup.guid = src.guid
+		WHERE src.feed_id = ? AND dup.feed_id = ?
+	`
+	if stmt, err := s.db.Prepare(mergeArchiveOfDuplicateItemsSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeArchiveOfDuplicateItemsStmt] = stmt
+	}
+
+	mergeDeliveriesOfDuplicateItemsSql := `
+		INSERT OR IGNORE INTO item_delivery (destination, item_id, delivered)
+		SELECT d.destination, dup.id, d.delivered
+		FROM item_delivery d
+		JOIN feed_item src ON src.id = d.item_id
+		JOIN feed_item dup ON dup.guid = src.guid
+		WHERE src.feed_id = ? AND dup.feed_id = ?
+	`
+	if stmt, err := s.db.Prepare(mergeDeliveriesOfDuplicateItemsSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeDeliveriesOfDuplicateItemsStmt] = stmt
+	}
+
+	// Items are read, starred, or highlighted if either copy is,
+	// and the latest state change is kept for syncing with a remote aggregator.
+	mergeStateOfDuplicateItemsSql := `
+		UPDATE feed_item
+		SET
+			read = read OR (
+				SELECT src.read FROM feed_item src
+				WHERE src.feed_id = ?1 AND src.guid = feed_item.guid),
+			starred = starred OR (
+				SELECT src.starred FROM feed_item src
+				WHERE src.feed_id = ?1 AND src.guid = feed_item.guid),
+			highlighted = highlighted OR (
+				SELECT src.highlighted FROM feed_item src
+				WHERE src.feed_id = ?1 AND src.guid = feed_item.guid),
+			state_changed = MAX(state_changed, (
+				SELECT src.state_changed FROM feed_item src
+				WHERE src.feed_id = ?1 AND src.guid = feed_item.guid))
+		WHERE feed_id = ?2 AND guid IN (SELECT guid FROM feed_item WHERE feed_id = ?1)
+	`
+	if stmt, err := s.db.Prepare(mergeStateOfDuplicateItemsSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeStateOfDuplicateItemsStmt] = stmt
+	}
+
+	deleteDuplicateItemsSql := `
+		DELETE FROM feed_item
+		WHERE feed_id = ? AND guid IN (SELECT guid FROM feed_item WHERE feed_id = ?)
+	`
+	if stmt, err := s.db.Prepare(deleteDuplicateItemsSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteDuplicateItemsStmt] = stmt
+	}
+
+	// Settings of the merged feed are kept unless the other feed has its own
+	mergeCredentialsIntoFeedSql := "UPDATE OR IGNORE feed_credentials SET feed_id = ? WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(mergeCredentialsIntoFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeCredentialsIntoFeedStmt] = stmt
+	}
+
+	mergeHeadersIntoFeedSql := "UPDATE OR IGNORE feed_header SET feed_id = ? WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(mergeHeadersIntoFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeHeadersIntoFeedStmt] = stmt
+	}
+
+	mergeLoaderConfigIntoFeedSql := "UPDATE OR IGNORE feed_loader_config SET feed_id = ? WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(mergeLoaderConfigIntoFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeLoaderConfigIntoFeedStmt] = stmt
+	}
+
+	mergeScrapeConfigIntoFeedSql := "UPDATE OR IGNORE feed_scrape SET feed_id = ? WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(mergeScrapeConfigIntoFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeScrapeConfigIntoFeedStmt] = stmt
+	}
+
+	mergeRemoteFeedIntoFeedSql := "UPDATE OR IGNORE remote_feed SET feed_id = ? WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(mergeRemoteFeedIntoFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeRemoteFeedIntoFeedStmt] = stmt
+	}
+
+	mergeSyncLogIntoFeedSql := "UPDATE feed_sync_log SET feed_id = ? WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(mergeSyncLogIntoFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeSyncLogIntoFeedStmt] = stmt
+	}
+
+	insertFeedSyncLogSql := `
+		INSERT INTO feed_sync_log (feed_id, kind, subject, detail)
+		VALUES (?, ?, ?, ?)
+	`
+	if stmt, err := s.db.Prepare(insertFeedSyncLogSql); err != nil {
+		return err
+	} else {
+		s.statements[insertFeedSyncLogStmt] = stmt
+	}
+
+	pruneFeedSyncLogSql := `
+		DELETE FROM feed_sync_log
+		WHERE feed_id = ? AND id NOT IN (
+			SELECT id FROM feed_sync_log
+			WHERE feed_id = ?
+			ORDER BY id DESC
+			LIMIT ?
+		)
+	`
+	if stmt, err := s.db.Prepare(pruneFeedSyncLogSql); err != nil {
+		return err
+	} else {
+		s.statements[pruneFeedSyncLogStmt] = stmt
+	}
+
+	selectFeedSyncLogSql := `
+		SELECT date, kind, subject, detail
+		FROM feed_sync_log
+		WHERE feed_id = ?
+		ORDER BY id DESC
+	`
+	if stmt, err := s.db.Prepare(selectFeedSyncLogSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedSyncLogStmt] = stmt
+	}
+
+	selectFeedCredentialsSql := `
+		SELECT scheme, username, secret, secret_command
+		FROM feed_credentials
+		WHERE feed_id = ?
+	`
+	if stmt, err := s.db.Prepare(selectFeedCredentialsSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedCredentialsStmt] = stmt
+	}
+
+	upsertFeedCredentialsSql := `
+		INSERT INTO feed_credentials (feed_id, scheme, username, secret, secret_command)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(feed_id)
+		DO UPDATE SET
+			scheme = excluded.scheme,
+			username = excluded.username,
+			secret = excluded.secret,
+			secret_command = excluded.secret_command
+	`
+	if stmt, err := s.db.Prepare(upsertFeedCredentialsSql); err != nil {
+		return err
+	} else {
+		s.statements[upsertFeedCredentialsStmt] = stmt
+	}
+
+	deleteFeedCredentialsSql := "DELETE FROM feed_credentials WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(deleteFeedCredentialsSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteFeedCredentialsStmt] = stmt
+	}
+
+	selectFeedHeadersSql := `
+		SELECT name, value
+		FROM feed_header
+		WHERE feed_id = ?
+		ORDER BY name ASC
+	`
+	if stmt, err := s.db.Prepare(selectFeedHeadersSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedHeadersStmt] = stmt
+	}
+
+	insertFeedHeaderSql := "INSERT INTO feed_header (feed_id, name, value) VALUES (?, ?, ?)"
+	if stmt, err := s.db.Prepare(insertFeedHeaderSql); err != nil {
+		return err
+	} else {
+		s.statements[insertFeedHeaderStmt] = stmt
+	}
+
+	deleteFeedHeadersSql := "DELETE FROM feed_header WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(deleteFeedHeadersSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteFeedHeadersStmt] = stmt
+	}
+
+	selectFeedLoaderConfigSql := `
+		SELECT timeout_ms, response_header_timeout_ms, user_agent,
+			proxy_url, ca_cert_file, insecure_skip_verify
+		FROM feed_loader_config
+		WHERE feed_id = ?
+	`
+	if stmt, err := s.db.Prepare(selectFeedLoaderConfigSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedLoaderConfigStmt] = stmt
+	}
+
+	upsertFeedLoaderConfigSql := `
+		INSERT INTO feed_loader_config (
+			feed_id, timeout_ms, response_header_timeout_ms, user_agent,
+			proxy_url, ca_cert_file, insecure_skip_verify)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(feed_id)
+		DO UPDATE SET
+			timeout_ms = excluded.timeout_ms,
+			response_header_timeout_ms = excluded.response_header_timeout_ms,
+			user_agent = excluded.user_agent,
+			proxy_url = excluded.proxy_url,
+			ca_cert_file = excluded.ca_cert_file,
+			insecure_skip_verify = excluded.insecure_skip_verify
+	`
+	if stmt, err := s.db.Prepare(upsertFeedLoaderConfigSql); err != nil {
+		return err
+	} else {
+		s.statements[upsertFeedLoaderConfigStmt] = stmt
+	}
+
+	deleteFeedLoaderConfigSql := "DELETE FROM feed_loader_config WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(deleteFeedLoaderConfigSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteFeedLoaderConfigStmt] = stmt
+	}
+
+	selectFeedScrapeConfigSql := `
+		SELECT item_selector, title_selector, link_selector, date_selector
+		FROM feed_scrape
+		WHERE feed_id = ?
+	`
+	if stmt, err := s.db.Prepare(selectFeedScrapeConfigSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedScrapeConfigStmt] = stmt
+	}
+
+	upsertFeedScrapeConfigSql := `
+		INSERT INTO feed_scrape (
+			feed_id, item_selector, title_selector, link_selector, date_selector)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(feed_id)
+		DO UPDATE SET
+			item_selector = excluded.item_selector,
+			title_selector = excluded.title_selector,
+			link_selector = excluded.link_selector,
+			date_selector = excluded.date_selector
+	`
+	if stmt, err := s.db.Prepare(upsertFeedScrapeConfigSql); err != nil {
+		return err
+	} else {
+		s.statements[upsertFeedScrapeConfigStmt] = stmt
+	}
+
+	deleteFeedScrapeConfigSql := "DELETE FROM feed_scrape WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(deleteFeedScrapeConfigSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteFeedScrapeConfigStmt] = stmt
+	}
+
+	selectFeedItemSql := `
+		SELECT id, feed_id, guid, url, title, date, date_modified, content_html, content_text, author,
+			read, hidden, highlighted, starred
+		FROM feed_item
+		WHERE id = ?`
+	if stmt, err := s.db.Prepare(selectFeedItemSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedItemStmt] = stmt
+	}
+
+	selectFeedItemIdSql := "SELECT id FROM feed_item WHERE feed_id = ? AND guid = ?"
+	if stmt, err := s.db.Prepare(selectFeedItemIdSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedItemIdStmt] = stmt
+	}
+
+	selectEnclosuresForItemSql := `
+		SELECT id, url, mime_type, title, length, duration_ms
+		FROM feed_item_enclosure
+		WHERE item_id = ?
+		ORDER BY id ASC
+	`
+	if stmt, err := s.db.Prepare(selectEnclosuresForItemSql); err != nil {
+		return err
+	} else {
+		s.statements[selectEnclosuresForItemStmt] = stmt
+	}
+
+	insertEnclosureSql := `
+		INSERT INTO feed_item_enclosure (
+			item_id, url, mime_type, title, length, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	if stmt, err := s.db.Prepare(insertEnclosureSql); err != nil {
+		return err
+	} else {
+		s.statements[insertEnclosureStmt] = stmt
+	}
+
+	deleteEnclosuresForItemSql := "DELETE FROM feed_item_enclosure WHERE item_id = ?"
+	if stmt, err := s.db.Prepare(deleteEnclosuresForItemSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteEnclosuresForItemStmt] = stmt
+	}
+
+	deleteEnclosuresInFeedSql := `
+		DELETE FROM feed_item_enclosure
+		WHERE item_id IN (SELECT id FROM feed_item WHERE feed_id = ?)
+	`
+	if stmt, err := s.db.Prepare(deleteEnclosuresInFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteEnclosuresInFeedStmt] = stmt
+	}
+
+	selectCategoriesForItemSql := `
+		SELECT name FROM feed_item_category
+		WHERE item_id = ?
+		ORDER BY name ASC
+	`
+	if stmt, err := s.db.Prepare(selectCategoriesForItemSql); err != nil {
+		return err
+	} else {
+		s.statements[selectCategoriesForItemStmt] = stmt
+	}
+
+	selectCategoriesForFeedSql := `
+		SELECT DISTINCT c.name
+		FROM feed_item_category c
+		JOIN feed_item i ON i.id = c.item_id
+		WHERE i.feed_id = ?
+		ORDER BY c.name ASC
+	`
+	if stmt, err := s.db.Prepare(selectCategoriesForFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[selectCategoriesForFeedStmt] = stmt
+	}
+
+	selectFeedItemsInCategorySql := `
+		SELECT i.id, i.feed_id, i.guid, i.url, i.title, i.date, i.date_modified,
+			i.content_html, i.content_text, i.author,
+			i.read, i.hidden, i.highlighted, i.starred
+		FROM feed_item i
+		JOIN feed_item_category c ON c.item_id = i.id
+		WHERE i.feed_id = ? AND c.name = ? AND i.hidden = 0
+		ORDER BY i.date DESC, i.title ASC
+	`
+	if stmt, err := s.db.Prepare(selectFeedItemsInCategorySql); err != nil {
+		return err
+	} else {
+		s.statements[selectFeedItemsInCategoryStmt] = stmt
+	}
+
+	insertCategorySql := `
+		INSERT INTO feed_item_category (item_id, name)
+		VALUES (?, ?)
+		ON CONFLICT(item_id, name) DO NOTHING
+	`
+	if stmt, err := s.db.Prepare(insertCategorySql); err != nil {
+		return err
+	} else {
+		s.statements[insertCategoryStmt] = stmt
+	}
+
+	deleteCategoriesForItemSql := "DELETE FROM feed_item_category WHERE item_id = ?"
+	if stmt, err := s.db.Prepare(deleteCategoriesForItemSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteCategoriesForItemStmt] = stmt
+	}
+
+	deleteCategoriesInFeedSql := `
+		DELETE FROM feed_item_category
+		WHERE item_id IN (SELECT id FROM feed_item WHERE feed_id = ?)
+	`
+	if stmt, err := s.db.Prepare(deleteCategoriesInFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteCategoriesInFeedStmt] = stmt
+	}
+
+	// The time of each local change is recorded, so changes can be
+	// synced with a remote aggregator (see `RetrieveItemStates`).
+	updateItemReadSql := `
+		UPDATE feed_item
+		SET state_changed = strftime('%s', 'now'), read = ?
+		WHERE id = ? AND read != ?`
+	if stmt, err := s.db.Prepare(updateItemReadSql); err != nil {
+		return err
+	} else {
+		s.statements[updateItemReadStmt] = stmt
+	}
+
+	updateItemStarredSql := `
+		UPDATE feed_item
+		SET state_changed = strftime('%s', 'now'), starred = ?
+		WHERE id = ? AND starred != ?`
+	if stmt, err := s.db.Prepare(updateItemStarredSql); err != nil {
+		return err
+	} else {
+		s.statements[updateItemStarredStmt] = stmt
+	}
+
+	// Filter actions only ever set flags, so an item matched by several
+	// rules keeps the effect of each.
+	applyFilterActionSql := `
+		UPDATE feed_item
+		SET hidden = (hidden OR ?1), read = (read OR ?2), highlighted = (highlighted OR ?3),
+			state_changed = CASE WHEN ?2 AND NOT read THEN strftime('%s', 'now') ELSE state_changed END
+		WHERE id = ?4
+	`
+	if stmt, err := s.db.Prepare(applyFilterActionSql); err != nil {
+		return err
+	} else {
+		s.statements[applyFilterActionStmt] = stmt
+	}
+
+	selectFilterRulesSql := `
+		SELECT id, feed_id, field, pattern, is_regex, action
+		FROM filter_rule
+		ORDER BY id ASC
+	`
+	if stmt, err := s.db.Prepare(selectFilterRulesSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFilterRulesStmt] = stmt
+	}
+
+	selectFilterRulesForFeedSql := `
+		SELECT id, feed_id, field, pattern, is_regex, action
+		FROM filter_rule
+		WHERE feed_id IS NULL OR feed_id = ?
+		ORDER BY id ASC
+	`
+	if stmt, err := s.db.Prepare(selectFilterRulesForFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFilterRulesForFeedStmt] = stmt
+	}
+
+	selectFilterRuleSql := `
+		SELECT id, feed_id, field, pattern, is_regex, action
+		FROM filter_rule
+		WHERE id = ?
+	`
+	if stmt, err := s.db.Prepare(selectFilterRuleSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFilterRuleStmt] = stmt
+	}
+
+	insertFilterRuleSql := `
+		INSERT INTO filter_rule (feed_id, field, pattern, is_regex, action)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	if stmt, err := s.db.Prepare(insertFilterRuleSql); err != nil {
+		return err
+	} else {
+		s.statements[insertFilterRuleStmt] = stmt
+	}
+
+	updateFilterRuleSql := `
+		UPDATE filter_rule
+		SET feed_id = ?, field = ?, pattern = ?, is_regex = ?, action = ?
+		WHERE id = ?
+	`
+	if stmt, err := s.db.Prepare(updateFilterRuleSql); err != nil {
+		return err
+	} else {
+		s.statements[updateFilterRuleStmt] = stmt
+	}
+
+	deleteFilterRuleSql := "DELETE FROM filter_rule WHERE id = ?"
+	if stmt, err := s.db.Prepare(deleteFilterRuleSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteFilterRuleStmt] = stmt
+	}
+
+	deleteFilterRulesForFeedSql := "DELETE FROM filter_rule WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(deleteFilterRulesForFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteFilterRulesForFeedStmt] = stmt
+	}
+
+	mergeFilterRulesIntoFeedSql := "UPDATE filter_rule SET feed_id = ? WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(mergeFilterRulesIntoFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[mergeFilterRulesIntoFeedStmt] = stmt
+	}
+
+	insertFilterMatchSql := `
+		INSERT INTO filter_match (rule_id, item_id)
+		VALUES (?, ?)
+		ON CONFLICT(rule_id, item_id) DO NOTHING
+	`
+	if stmt, err := s.db.Prepare(insertFilterMatchSql); err != nil {
+		return err
+	} else {
+		s.statements[insertFilterMatchStmt] = stmt
+	}
+
+	selectFilterMatchesSql := `
+		SELECT i.id, i.feed_id, i.guid, i.url, i.title, i.date, i.date_modified,
+			i.content_html, i.content_text, i.author,
+			i.read, i.hidden, i.highlighted, i.starred
+		FROM feed_item i
+		JOIN filter_match m ON m.item_id = i.id
+		WHERE m.rule_id = ?
+		ORDER BY m.date DESC, i.date DESC
+	`
+	if stmt, err := s.db.Prepare(selectFilterMatchesSql); err != nil {
+		return err
+	} else {
+		s.statements[selectFilterMatchesStmt] = stmt
+	}
+
+	deleteFilterMatchesForRuleSql := "DELETE FROM filter_match WHERE rule_id = ?"
+	if stmt, err := s.db.Prepare(deleteFilterMatchesForRuleSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteFilterMatchesForRuleStmt] = stmt
+	}
+
+	deleteFilterMatchesForFeedRulesSql := `
+		DELETE FROM filter_match
+		WHERE rule_id IN (SELECT id FROM filter_rule WHERE feed_id = ?)
+	`
+	if stmt, err := s.db.Prepare(deleteFilterMatchesForFeedRulesSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteFilterMatchesForFeedRulesStmt] = stmt
+	}
+
+	deleteFilterMatchesInFeedSql := `
+		DELETE FROM filter_match
+		WHERE item_id IN (SELECT id FROM feed_item WHERE feed_id = ?)
+	`
+	if stmt, err := s.db.Prepare(deleteFilterMatchesInFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteFilterMatchesInFeedStmt] = stmt
+	}
+
+	deleteDeliveriesInFeedSql := `
+		DELETE FROM item_delivery
+		WHERE item_id IN (SELECT id FROM feed_item WHERE feed_id = ?)
+	`
+	if stmt, err := s.db.Prepare(deleteDeliveriesInFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteDeliveriesInFeedStmt] = stmt
+	}
+
+	deleteArchiveInFeedSql := `
+		DELETE FROM item_archive
+		WHERE item_id IN (SELECT id FROM feed_item WHERE feed_id = ?)
+	`
+	if stmt, err := s.db.Prepare(deleteArchiveInFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteArchiveInFeedStmt] = stmt
+	}
+
+	selectUnreadCountsSql := `
+		SELECT feed_id, COUNT(*)
+		FROM feed_item
+		WHERE read = 0 AND hidden = 0
+		GROUP BY feed_id
+	`
+	if stmt, err := s.db.Prepare(selectUnreadCountsSql); err != nil {
+		return err
+	} else {
+		s.statements[selectUnreadCountsStmt] = stmt
+	}
+
+	selectSavedSearchesSql := "SELECT id, name, query FROM saved_search ORDER BY name ASC"
+	if stmt, err := s.db.Prepare(selectSavedSearchesSql); err != nil {
+		return err
+	} else {
+		s.statements[selectSavedSearchesStmt] = stmt
+	}
+
+	selectSavedSearchSql := "SELECT id, name, query FROM saved_search WHERE id = ?"
+	if stmt, err := s.db.Prepare(selectSavedSearchSql); err != nil {
+		return err
+	} else {
+		s.statements[selectSavedSearchStmt] = stmt
+	}
+
+	insertSavedSearchSql := "INSERT INTO saved_search (name, query) VALUES (?, ?)"
+	if stmt, err := s.db.Prepare(insertSavedSearchSql); err != nil {
+		return err
+	} else {
+		s.statements[insertSavedSearchStmt] = stmt
+	}
+
+	updateSavedSearchSql := "UPDATE saved_search SET name = ?, query = ? WHERE id = ?"
+	if stmt, err := s.db.Prepare(updateSavedSearchSql); err != nil {
+		return err
+	} else {
+		s.statements[updateSavedSearchStmt] = stmt
+	}
+
+	deleteSavedSearchSql := "DELETE FROM saved_search WHERE id = ?"
+	if stmt, err := s.db.Prepare(deleteSavedSearchSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteSavedSearchStmt] = stmt
+	}
+
+	selectRecentItemDatesSql := `
+		SELECT date FROM feed_item
+		WHERE feed_id = ?
+		ORDER BY date DESC
+		LIMIT ?`
+	if stmt, err := s.db.Prepare(selectRecentItemDatesSql); err != nil {
+		return err
+	} else {
+		s.statements[selectRecentItemDatesStmt] = stmt
+	}
+
+	// Whether the feed was created for the subscription never changes
+	upsertRemoteFeedSql := `
+		INSERT INTO remote_feed (feed_id, account, remote_id, title, site_url, created)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(feed_id)
+		DO UPDATE SET
+			account = excluded.account,
+			remote_id = excluded.remote_id,
+			title = excluded.title,
+			site_url = excluded.site_url
+	`
+	if stmt, err := s.db.Prepare(upsertRemoteFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[upsertRemoteFeedStmt] = stmt
+	}
+
+	selectRemoteFeedSql := `
+		SELECT feed_id, account, remote_id, title, site_url, synced, created
+		FROM remote_feed
+		WHERE feed_id = ?
+	`
+	if stmt, err := s.db.Prepare(selectRemoteFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[selectRemoteFeedStmt] = stmt
+	}
+
+	selectRemoteFeedsSql := `
+		SELECT feed_id, account, remote_id, title, site_url, synced, created
+		FROM remote_feed
+		WHERE account = ?
+		ORDER BY feed_id
+	`
+	if stmt, err := s.db.Prepare(selectRemoteFeedsSql); err != nil {
+		return err
+	} else {
+		s.statements[selectRemoteFeedsStmt] = stmt
+	}
+
+	updateRemoteFeedSyncedSql := "UPDATE remote_feed SET synced = ? WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(updateRemoteFeedSyncedSql); err != nil {
+		return err
+	} else {
+		s.statements[updateRemoteFeedSyncedStmt] = stmt
+	}
+
+	deleteRemoteFeedSql := "DELETE FROM remote_feed WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(deleteRemoteFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteRemoteFeedStmt] = stmt
+	}
 
-	CREATE INDEX IF NOT EXISTS feed_item_date_idx
-		ON feed_item(date);
+	deleteSyncLogForFeedSql := "DELETE FROM feed_sync_log WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(deleteSyncLogForFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteSyncLogForFeedStmt] = stmt
+	}
 
-	CREATE TABLE IF NOT EXISTS feed_sync_status (
-		feed_id INTEGER NOT NULL PRIMARY KEY,
-		date INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
-		success INTEGER NOT NULL,
-		error TEXT,
-		FOREIGN KEY (feed_id)
-			REFERENCES feed(id)
-			ON DELETE CASCADE
-	);
+	deleteSyncStatusForFeedSql := "DELETE FROM feed_sync_status WHERE feed_id = ?"
+	if stmt, err := s.db.Prepare(deleteSyncStatusForFeedSql); err != nil {
+		return err
+	} else {
+		s.statements[deleteSyncStatusForFeedStmt] = stmt
+	}
+
+	selectItemStatesSql := `
+		SELECT id, guid, read, starred, state_changed
+		FROM feed_item
+		WHERE feed_id = ?
+		ORDER BY id
 	`
-	_, err := s.db.Exec(sql)
-	return err
-}
+	if stmt, err := s.db.Prepare(selectItemStatesSql); err != nil {
+		return err
+	} else {
+		s.statements[selectItemStatesStmt] = stmt
+	}
 
-func (s *FeedStore) prepareStatements() error {
-	s.statements = make([]*sql.Stmt, numStatements)
+	// States received from a remote aggregator aren't local changes
+	updateItemStateSql := "UPDATE feed_item SET read = ?, starred = ? WHERE id = ?"
+	if stmt, err := s.db.Prepare(updateItemStateSql); err != nil {
+		return err
+	} else {
+		s.statements[updateItemStateStmt] = stmt
+	}
 
-	selectEveryFeedSql := `
-		SELECT id, url, name FROM feed
-		ORDER BY name ASC`
-	if stmt, err := s.db.Prepare(selectEveryFeedSql); err != nil {
+	selectUndeliveredItemsSql := `
+		SELECT i.id, i.feed_id, i.guid, i.url, i.title, i.date, i.date_modified,
+			i.content_html, i.content_text, i.author,
+			i.read, i.hidden, i.highlighted, i.starred
+		FROM feed_item i
+		WHERE i.hidden = 0 AND NOT EXISTS (
+			SELECT 1 FROM item_delivery d
+			WHERE d.destination = ? AND d.item_id = i.id
+		)
+		ORDER BY i.date ASC, i.id ASC
+	`
+	if stmt, err := s.db.Prepare(selectUndeliveredItemsSql); err != nil {
 		return err
 	} else {
-		s.statements[selectEveryFeedStmt] = stmt
+		s.statements[selectUndeliveredItemsStmt] = stmt
 	}
 
-	selectFeedSql := "SELECT url, name FROM feed WHERE id = ?"
-	if stmt, err := s.db.Prepare(selectFeedSql); err != nil {
+	insertItemDeliverySql := `
+		INSERT OR IGNORE INTO item_delivery (destination, item_id, delivered)
+		VALUES (?, ?, strftime('%s', 'now'))
+	`
+	if stmt, err := s.db.Prepare(insertItemDeliverySql); err != nil {
 		return err
 	} else {
-		s.statements[selectFeedStmt] = stmt
+		s.statements[insertItemDeliveryStmt] = stmt
 	}
 
-	selectFeedIdByUrlSql := "SELECT id FROM feed WHERE url = ?"
-	if stmt, err := s.db.Prepare(selectFeedIdByUrlSql); err != nil {
+	insertOutboxEntrySql := `
+		INSERT INTO read_later_outbox (url, title, queued)
+		VALUES (?, ?, strftime('%s', 'now'))
+	`
+	if stmt, err := s.db.Prepare(insertOutboxEntrySql); err != nil {
 		return err
 	} else {
-		s.statements[selectFeedIdByUrlStmt] = stmt
+		s.statements[insertOutboxEntryStmt] = stmt
 	}
 
-	insertFeedSql := `
-		INSERT INTO feed (url, name) VALUES (?, ?)
-		ON CONFLICT(url) DO NOTHING`
-	if stmt, err := s.db.Prepare(insertFeedSql); err != nil {
+	selectOutboxEntriesSql := `
+		SELECT id, url, title, queued, attempts, last_error
+		FROM read_later_outbox
+		ORDER BY id
+	`
+	if stmt, err := s.db.Prepare(selectOutboxEntriesSql); err != nil {
 		return err
 	} else {
-		s.statements[insertFeedStmt] = stmt
+		s.statements[selectOutboxEntriesStmt] = stmt
 	}
 
-	updateFeedSql := "UPDATE feed SET name = ? WHERE id = ?"
-	if stmt, err := s.db.Prepare(updateFeedSql); err != nil {
+	deleteOutboxEntrySql := "DELETE FROM read_later_outbox WHERE id = ?"
+	if stmt, err := s.db.Prepare(deleteOutboxEntrySql); err != nil {
 		return err
 	} else {
-		s.statements[updateFeedStmt] = stmt
+		s.statements[deleteOutboxEntryStmt] = stmt
 	}
 
-	deleteFeedSql := "DELETE FROM feed WHERE id = ?"
-	if stmt, err := s.db.Prepare(deleteFeedSql); err != nil {
+	updateOutboxEntryErrorSql := `
+		UPDATE read_later_outbox
+		SET attempts = attempts + 1, last_error = ?
+		WHERE id = ?
+	`
+	if stmt, err := s.db.Prepare(updateOutboxEntryErrorSql); err != nil {
 		return err
 	} else {
-		s.statements[deleteFeedStmt] = stmt
+		s.statements[updateOutboxEntryErrorStmt] = stmt
 	}
 
-	selectFeedItemsForFeedSql := `
-		SELECT id, guid, url, title, date
-		FROM feed_item
-		WHERE feed_id = ?
-		ORDER BY date DESC, title ASC`
-	if stmt, err := s.db.Prepare(selectFeedItemsForFeedSql); err != nil {
+	upsertArchivedArticleSql := `
+		INSERT OR REPLACE INTO item_archive (item_id, url, title, content, size, archived, error, pruned)
+		VALUES (?, ?, ?, ?, ?, strftime('%s', 'now'), ?, 0)
+	`
+	if stmt, err := s.db.Prepare(upsertArchivedArticleSql); err != nil {
 		return err
 	} else {
-		s.statements[selectFeedItemsForFeedStmt] = stmt
+		s.statements[upsertArchivedArticleStmt] = stmt
 	}
 
-	upsertFeedItemSql := `
-		INSERT INTO feed_item (feed_id, guid, url, title, date)
-		VALUES (?, ?, ?, ?, ?)
-		ON CONFLICT(feed_id, guid)
-		DO UPDATE SET
-			url=excluded.url,
-			title=excluded.title,
-			date=excluded.date
+	selectArchivedArticleSql := `
+		SELECT url, title, content, archived, error, pruned
+		FROM item_archive
+		WHERE item_id = ?
 	`
-	if stmt, err := s.db.Prepare(upsertFeedItemSql); err != nil {
+	if stmt, err := s.db.Prepare(selectArchivedArticleSql); err != nil {
 		return err
 	} else {
-		s.statements[upsertFeedItemStmt] = stmt
+		s.statements[selectArchivedArticleStmt] = stmt
 	}
 
-	deleteItemsInFeedSql := "DELETE FROM feed_item WHERE feed_id = ?"
-	if stmt, err := s.db.Prepare(deleteItemsInFeedSql); err != nil {
+	// Failed attempts are retried once they're older than the cutoff (?2)
+	selectItemsToArchiveSql := `
+		SELECT i.id, i.feed_id, i.guid, i.url, i.title, i.date, i.date_modified,
+			i.content_html, i.content_text, i.author,
+			i.read, i.hidden, i.highlighted, i.starred
+		FROM feed_item i
+		JOIN feed f ON f.id = i.feed_id
+		LEFT JOIN item_archive a ON a.item_id = i.id
+		WHERE i.hidden = 0
+			AND (i.url LIKE 'http://%' OR i.url LIKE 'https://%')
+			AND (f.archive_articles = 1 OR (?1 AND i.starred = 1))
+			AND (a.item_id IS NULL OR (a.error != '' AND a.archived < ?2))
+		ORDER BY i.date DESC, i.id DESC
+		LIMIT ?3
+	`
+	if stmt, err := s.db.Prepare(selectItemsToArchiveSql); err != nil {
 		return err
 	} else {
-		s.statements[deleteItemsInFeedStmt] = stmt
+		s.statements[selectItemsToArchiveStmt] = stmt
 	}
 
-	upsertFeedSyncStatusSql := `
-		INSERT INTO feed_sync_status (feed_id, success, error)
-		VALUES (?, ?, ?)
-		ON CONFLICT(feed_id)
-		DO UPDATE SET
-			date = strftime('%s', 'now'),
-			success = excluded.success,
-			error = excluded.error
+	// Articles for unstarred and older items are pruned first
+	selectArchiveSizesSql := `
+		SELECT a.item_id, a.size
+		FROM item_archive a
+		JOIN feed_item i ON i.id = a.item_id
+		WHERE a.size > 0
+		ORDER BY i.starred ASC, i.date ASC, i.id ASC
 	`
-	if stmt, err := s.db.Prepare(upsertFeedSyncStatusSql); err != nil {
+	if stmt, err := s.db.Prepare(selectArchiveSizesSql); err != nil {
 		return err
 	} else {
-		s.statements[upsertFeedSyncStatusStmt] = stmt
+		s.statements[selectArchiveSizesStmt] = stmt
 	}
 
-	selectFeedSyncStatusSql := `
-		SELECT date, success, error
-		FROM feed_sync_status
-		WHERE feed_id = ?
+	pruneArchivedArticleSql := `
+		UPDATE item_archive
+		SET content = X'', size = 0, pruned = 1
+		WHERE item_id = ?
 	`
-	if stmt, err := s.db.Prepare(selectFeedSyncStatusSql); err != nil {
+	if stmt, err := s.db.Prepare(pruneArchivedArticleSql); err != nil {
 		return err
 	} else {
-		s.statements[selectFeedSyncStatusStmt] = stmt
+		s.statements[pruneArchivedArticleStmt] = stmt
 	}
 
 	return nil
@@ -479,10 +3235,39 @@ func shouldRetryOnError(err error) bool {
 
 func (s *FeedStore) updateFeedRecord(tx *sql.Tx, id FeedId, feed feed.Feed) error {
 	stmt := tx.Stmt(s.statements[updateFeedStmt])
-	_, err := stmt.Exec(feed.Name, id)
+	_, err := stmt.Exec(
+		feed.Name,
+		feed.SiteUrl,
+		feed.Description,
+		feed.Language,
+		feed.ImageUrl,
+		int64(feed.RefreshHints.TTL/time.Second),
+		int64(feed.RefreshHints.UpdateInterval/time.Second),
+		formatSkipHours(feed.RefreshHints.SkipHours),
+		formatSkipDays(feed.RefreshHints.SkipDays),
+		id)
 	return err
 }
 
+func (s *FeedStore) retrieveFeedItemsById(tx *sql.Tx, ids []FeedItemId) ([]FeedItemRecord, error) {
+	records := make([]FeedItemRecord, 0, len(ids))
+	stmt := tx.Stmt(s.statements[selectFeedItemStmt])
+	for _, id := range ids {
+		rows, err := stmt.Query(id)
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := scanFeedItems(rows)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, items...)
+	}
+	return records, nil
+}
+
 func (s *FeedStore) deleteFeedRecord(tx *sql.Tx, id FeedId) error {
 	stmt := tx.Stmt(s.statements[deleteFeedStmt])
 	_, err := stmt.Exec(id)
@@ -490,19 +3275,362 @@ func (s *FeedStore) deleteFeedRecord(tx *sql.Tx, id FeedId) error {
 }
 
 func (s *FeedStore) upsertFeedItemRecord(tx *sql.Tx, feedId FeedId, item feed.FeedItem) error {
+	// The modification date is NULL if the feed didn't provide one
+	var dateModified sql.NullInt64
+	if !item.Updated.IsZero() {
+		dateModified = sql.NullInt64{Int64: item.Updated.Unix(), Valid: true}
+	}
+
+	// If the publication date is unknown, use the date the item
+	// was first seen, so it doesn't change on every sync.
+	var date sql.NullInt64
+	if !item.Date.IsZero() {
+		date = sql.NullInt64{Int64: item.Date.Unix(), Valid: true}
+	}
+
 	stmt := tx.Stmt(s.statements[upsertFeedItemStmt])
-	_, err := stmt.Exec(feedId, item.Guid, item.Url, item.Title, item.Date.Unix())
+	_, err := stmt.Exec(
+		feedId,
+		item.Guid,
+		item.Url,
+		item.Title,
+		date,
+		dateModified,
+		item.ContentHtml,
+		item.ContentText,
+		formatAuthors(item.Authors))
+	return err
+}
+
+// replaceCategories replaces the categories of an item.
+// Duplicate and empty categories are ignored.
+func (s *FeedStore) replaceCategories(tx *sql.Tx, itemId FeedItemId, item feed.FeedItem) error {
+	stmt := tx.Stmt(s.statements[deleteCategoriesForItemStmt])
+	if _, err := stmt.Exec(itemId); err != nil {
+		return err
+	}
+
+	stmt = tx.Stmt(s.statements[insertCategoryStmt])
+	for _, category := range item.Categories {
+		category = strings.TrimSpace(category)
+		if len(category) == 0 {
+			continue
+		}
+
+		if _, err := stmt.Exec(itemId, category); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compiledFilterRule is a filter rule prepared for matching during a sync
+type compiledFilterRule struct {
+	id      FilterRuleId
+	matcher *filter.Matcher
+}
+
+// compileFilterRules prepares the rules that apply to a feed.
+// Rules are validated when saved, so any that fail to compile
+// (e.g. because of a change in the regex syntax) are skipped.
+func (s *FeedStore) compileFilterRules(tx *sql.Tx, feedId FeedId) ([]compiledFilterRule, error) {
+	stmt := tx.Stmt(s.statements[selectFilterRulesForFeedStmt])
+	rows, err := stmt.Query(feedId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records, err := scanFilterRules(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]compiledFilterRule, 0, len(records))
+	for _, record := range records {
+		if matcher, err := filter.Compile(record.Rule); err == nil {
+			rules = append(rules, compiledFilterRule{record.Id, matcher})
+		}
+	}
+	return rules, nil
+}
+
+// applyFilterRules records which rules match an item, and applies
+// the action of each rule the first time it matches the item.
+// This way, an item the user marked unread again stays unread.
+func (s *FeedStore) applyFilterRules(tx *sql.Tx, itemId FeedItemId, item feed.FeedItem, rules []compiledFilterRule) error {
+	for _, rule := range rules {
+		if !rule.matcher.Match(item) {
+			continue
+		}
+
+		stmt := tx.Stmt(s.statements[insertFilterMatchStmt])
+		result, err := stmt.Exec(rule.id, itemId)
+		if err != nil {
+			return err
+		}
+
+		if numRows, err := result.RowsAffected(); err != nil {
+			return err
+		} else if numRows == 0 {
+			// The rule already matched this item in an earlier sync
+			continue
+		}
+
+		action := rule.matcher.Rule.Action
+		stmt = tx.Stmt(s.statements[applyFilterActionStmt])
+		_, err = stmt.Exec(
+			action == filter.ActionHide,
+			action == filter.ActionMarkRead,
+			action == filter.ActionHighlight,
+			itemId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteFeedSecrets deletes the credentials and headers of a feed
+func (s *FeedStore) deleteFeedSecrets(tx *sql.Tx, feedId FeedId) error {
+	stmt := tx.Stmt(s.statements[deleteFeedCredentialsStmt])
+	if _, err := stmt.Exec(feedId); err != nil {
+		return err
+	}
+
+	stmt = tx.Stmt(s.statements[deleteFeedHeadersStmt])
+	_, err := stmt.Exec(feedId)
+	return err
+}
+
+// isSameOrigin returns whether both URLs have the same scheme, host, and port.
+// URLs that can't be parsed never have the same origin.
+func isSameOrigin(a string, b string) bool {
+	urlA, err := neturl.Parse(a)
+	if err != nil {
+		return false
+	}
+
+	urlB, err := neturl.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(urlA.Scheme, urlB.Scheme) &&
+		strings.EqualFold(urlA.Host, urlB.Host)
+}
+
+// deleteFeedSettings deletes every row that references a feed, other than
+// its items and filter rules.  The schema cascades these deletes too, but
+// they're deleted explicitly so that a feed later created with the same
+// rowid can never inherit another feed's credentials.
+func (s *FeedStore) deleteFeedSettings(tx *sql.Tx, feedId FeedId) error {
+	stmtIndexes := []int{
+		deleteFeedCredentialsStmt,
+		deleteFeedHeadersStmt,
+		deleteFeedLoaderConfigStmt,
+		deleteFeedScrapeConfigStmt,
+		deleteRemoteFeedStmt,
+		deleteSyncLogForFeedStmt,
+		deleteSyncStatusForFeedStmt,
+	}
+
+	for _, i := range stmtIndexes {
+		stmt := tx.Stmt(s.statements[i])
+		if _, err := stmt.Exec(feedId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *FeedStore) deleteFilterRulesForFeed(tx *sql.Tx, feedId FeedId) error {
+	stmt := tx.Stmt(s.statements[deleteFilterMatchesForFeedRulesStmt])
+	if _, err := stmt.Exec(feedId); err != nil {
+		return err
+	}
+
+	stmt = tx.Stmt(s.statements[deleteFilterRulesForFeedStmt])
+	_, err := stmt.Exec(feedId)
+	return err
+}
+
+// formatAuthors formats the names of an item's authors for display
+func formatAuthors(authors []feed.Author) string {
+	names := make([]string, 0, len(authors))
+	for _, author := range authors {
+		if len(author.Name) > 0 {
+			names = append(names, author.Name)
+		} else if len(author.Email) > 0 {
+			names = append(names, author.Email)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// mergeFeed moves the items, history, and settings of one feed into another,
+// then deletes the first feed.  Items with the same GUID as an item in the
+// other feed are replaced by that item, which inherits their enclosures,
+// categories, filter matches, archived article, deliveries, and read,
+// starred, and highlighted state.
+func (s *FeedStore) mergeFeed(tx *sql.Tx, fromId FeedId, intoId FeedId) error {
+	duplicateItemStmts := []int{
+		mergeEnclosuresOfDuplicateItemsStmt,
+		mergeCategoriesOfDuplicateItemsStmt,
+		mergeFilterMatchesOfDuplicateItemsStmt,
+		mergeArchiveOfDuplicateItemsStmt,
+		mergeDeliveriesOfDuplicateItemsStmt,
+		mergeStateOfDuplicateItemsStmt,
+	}
+	for _, stmtIdx := range duplicateItemStmts {
+		stmt := tx.Stmt(s.statements[stmtIdx])
+		if _, err := stmt.Exec(fromId, intoId); err != nil {
+			return err
+		}
+	}
+
+	stmt := tx.Stmt(s.statements[deleteDuplicateItemsStmt])
+	if _, err := stmt.Exec(fromId, intoId); err != nil {
+		return err
+	}
+
+	mergeStmts := []int{
+		mergeItemsIntoFeedStmt,
+		mergeSyncLogIntoFeedStmt,
+		mergeFilterRulesIntoFeedStmt,
+		mergeCredentialsIntoFeedStmt,
+		mergeHeadersIntoFeedStmt,
+		mergeLoaderConfigIntoFeedStmt,
+		mergeScrapeConfigIntoFeedStmt,
+		mergeRemoteFeedIntoFeedStmt,
+	}
+	for _, stmtIdx := range mergeStmts {
+		stmt := tx.Stmt(s.statements[stmtIdx])
+		if _, err := stmt.Exec(intoId, fromId); err != nil {
+			return err
+		}
+	}
+
+	return s.deleteFeedRecord(tx, fromId)
+}
+
+func (s *FeedStore) insertFeedSyncLogEntry(tx *sql.Tx, id FeedId, kind SyncLogKind, subject string, detail string) error {
+	stmt := tx.Stmt(s.statements[insertFeedSyncLogStmt])
+	if _, err := stmt.Exec(id, int(kind), subject, detail); err != nil {
+		return err
+	}
+
+	stmt = tx.Stmt(s.statements[pruneFeedSyncLogStmt])
+	_, err := stmt.Exec(id, id, maxSyncLogEntries)
 	return err
 }
 
 func (s *FeedStore) deleteItemsInFeed(tx *sql.Tx, id FeedId) error {
-	stmt := tx.Stmt(s.statements[deleteItemsInFeedStmt])
+	stmt := tx.Stmt(s.statements[deleteEnclosuresInFeedStmt])
+	if _, err := stmt.Exec(id); err != nil {
+		return err
+	}
+
+	stmt = tx.Stmt(s.statements[deleteDeliveriesInFeedStmt])
+	if _, err := stmt.Exec(id); err != nil {
+		return err
+	}
+
+	stmt = tx.Stmt(s.statements[deleteArchiveInFeedStmt])
+	if _, err := stmt.Exec(id); err != nil {
+		return err
+	}
+
+	stmt = tx.Stmt(s.statements[deleteFilterMatchesInFeedStmt])
+	if _, err := stmt.Exec(id); err != nil {
+		return err
+	}
+
+	stmt = tx.Stmt(s.statements[deleteCategoriesInFeedStmt])
+	if _, err := stmt.Exec(id); err != nil {
+		return err
+	}
+
+	stmt = tx.Stmt(s.statements[deleteItemsInFeedStmt])
 	_, err := stmt.Exec(id)
 	return err
 }
 
+// replaceEnclosures replaces the enclosures of an item
+func (s *FeedStore) replaceEnclosures(tx *sql.Tx, itemId FeedItemId, item feed.FeedItem) error {
+	stmt := tx.Stmt(s.statements[deleteEnclosuresForItemStmt])
+	if _, err := stmt.Exec(itemId); err != nil {
+		return err
+	}
+
+	stmt = tx.Stmt(s.statements[insertEnclosureStmt])
+	for _, enclosure := range item.Enclosures {
+		_, err := stmt.Exec(
+			itemId,
+			enclosure.Url,
+			enclosure.MimeType,
+			enclosure.Title,
+			enclosure.Length,
+			int64(enclosure.Duration/time.Millisecond))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s *FeedStore) setFeedSyncStatusSuccess(tx *sql.Tx, id FeedId) error {
 	stmt := tx.Stmt(s.statements[upsertFeedSyncStatusStmt])
 	_, err := stmt.Exec(id, true, nil)
 	return err
 }
+
+func refreshHints(ttl, updateInterval int64, skipHours, skipDays string) feed.RefreshHints {
+	hints := feed.RefreshHints{
+		TTL:            time.Duration(ttl) * time.Second,
+		UpdateInterval: time.Duration(updateInterval) * time.Second,
+		SkipHours:      splitInts(skipHours),
+	}
+
+	for _, day := range splitInts(skipDays) {
+		hints.SkipDays = append(hints.SkipDays, time.Weekday(day))
+	}
+
+	return hints
+}
+
+// formatSkipHours stores hours as a comma-separated list, e.g. "0,1,23"
+func formatSkipHours(hours []int) string {
+	return joinInts(hours)
+}
+
+// formatSkipDays stores days as a comma-separated list, where Sunday is 0
+func formatSkipDays(days []time.Weekday) string {
+	values := make([]int, len(days))
+	for i, day := range days {
+		values[i] = int(day)
+	}
+	return joinInts(values)
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = strconv.Itoa(value)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitInts(s string) []int {
+	var values []int
+	for _, part := range strings.Split(s, ",") {
+		if value, err := strconv.Atoi(part); err == nil {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,151 @@
+package task
+
+import (
+	"bytes"
+	"errors"
+	"github.com/wedaly/local-news/internal/archive"
+	"github.com/wedaly/local-news/internal/store"
+	"time"
+)
+
+// How long to wait before retrying an article that couldn't be archived
+const archiveRetryInterval = 24 * time.Hour
+
+// Maximum number of articles archived each time tasks are scheduled
+const archiveBatchSize = 50
+
+// ErrAlreadyArchiving means that the item's article is being archived by another task
+var ErrAlreadyArchiving = errors.New("The article is already being archived")
+
+// ArchivePolicy controls which articles are archived for offline reading,
+// in addition to those linked from feeds with archiving enabled.
+type ArchivePolicy struct {
+	// Whether to archive the articles linked from starred items
+	StarredItems bool
+
+	// Maximum size of a page to download, or zero for no limit
+	MaxPageBytes int64
+
+	// Maximum total size of the (compressed) archive, or zero for no limit.
+	// When the archive is too large, the content of the articles linked
+	// from unstarred items is removed first, oldest first.
+	MaxTotalBytes int64
+}
+
+// DefaultArchivePolicy returns the policy used unless the settings override it
+func DefaultArchivePolicy() ArchivePolicy {
+	return ArchivePolicy{
+		MaxPageBytes:  5 << 20,
+		MaxTotalBytes: 200 << 20,
+	}
+}
+
+// SetArchivePolicy sets which articles are archived and the size limits.
+// This is NOT thread-safe, so it must be called before scheduling any tasks.
+func (m *TaskManager) SetArchivePolicy(policy ArchivePolicy) {
+	m.archivePolicy = policy
+}
+
+// ScheduleArchiveTasks enqueues tasks to archive the articles linked from
+// items that should be archived, but haven't been yet.  Articles that
+// couldn't be archived are retried after a day.  Subscribers are NOT notified.
+func (m *TaskManager) ScheduleArchiveTasks() error {
+	retryCutoff := time.Now().Add(-archiveRetryInterval)
+	items, err := m.feedStore.RetrieveItemsToArchive(m.archivePolicy.StarredItems, retryCutoff, archiveBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		m.scheduleArchiveTask(item, func(store.ArchivedArticleRecord, error) {})
+	}
+
+	return nil
+}
+
+// ArchiveItem archives the article linked from an item in the background,
+// replacing any previously archived copy.  The callback is invoked from
+// another goroutine with the archived article or an error.
+func (m *TaskManager) ArchiveItem(item store.FeedItemRecord, callback func(store.ArchivedArticleRecord, error)) {
+	if !m.scheduleArchiveTask(item, callback) {
+		go callback(store.ArchivedArticleRecord{}, ErrAlreadyArchiving)
+	}
+}
+
+// WaitForArchiveTasks blocks until every scheduled archive task has completed
+func (m *TaskManager) WaitForArchiveTasks() {
+	m.archiveWaitGroup.Wait()
+}
+
+// scheduleArchiveTask returns false if the item is already being archived
+func (m *TaskManager) scheduleArchiveTask(item store.FeedItemRecord, callback func(store.ArchivedArticleRecord, error)) bool {
+	m.archiveMutex.Lock()
+	defer m.archiveMutex.Unlock()
+	{
+		if m.archiving[item.Id] {
+			return false
+		}
+		m.archiving[item.Id] = true
+	}
+
+	m.archiveWaitGroup.Add(1)
+	go func() {
+		defer m.archiveWaitGroup.Done()
+		defer func() {
+			m.archiveMutex.Lock()
+			delete(m.archiving, item.Id)
+			m.archiveMutex.Unlock()
+		}()
+
+		callback(m.archiveArticle(item))
+	}()
+
+	return true
+}
+
+func (m *TaskManager) archiveArticle(item store.FeedItemRecord) (store.ArchivedArticleRecord, error) {
+	// Articles are retrieved with the same proxy and TLS settings as their feed
+	config, err := m.feedStore.RetrieveFeedLoaderConfig(item.FeedId)
+	if err != nil {
+		return store.ArchivedArticleRecord{}, err
+	}
+
+	// Block until loader is available, but release it before
+	// extracting the article, which doesn't need the network
+	loader := <-m.loaderChan
+	page, err := loader.LoadPage(item.Url, config, m.archivePolicy.MaxPageBytes)
+	m.loaderChan <- loader
+
+	var extracted archive.Article
+	if err == nil {
+		extracted, err = archive.Extract(bytes.NewReader(page.Html), page.Url)
+	}
+
+	// The item's feed may have been deleted while the article was retrieved,
+	// so store errors are returned instead of crashing the app.
+	if err != nil {
+		if storeErr := m.feedStore.SetArchiveError(item.Id, item.Url, err); storeErr != nil {
+			return store.ArchivedArticleRecord{}, storeErr
+		}
+		return store.ArchivedArticleRecord{}, err
+	}
+
+	article := store.ArchivedArticleRecord{
+		ItemId:      item.Id,
+		Url:         page.Url,
+		Title:       extracted.Title,
+		ContentHtml: extracted.ContentHtml,
+		Archived:    time.Now(),
+	}
+	if err := m.feedStore.SaveArchivedArticle(article); err != nil {
+		return store.ArchivedArticleRecord{}, err
+	}
+
+	if m.archivePolicy.MaxTotalBytes > 0 {
+		if _, err := m.feedStore.PruneArchive(m.archivePolicy.MaxTotalBytes); err != nil {
+			return article, err
+		}
+	}
+
+	return article, nil
+}
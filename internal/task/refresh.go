@@ -0,0 +1,106 @@
+package task
+
+import (
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/store"
+	"time"
+)
+
+// RefreshPolicy decides how often each feed is refreshed, based on
+// the feed's hints and how often it posts new items.
+type RefreshPolicy struct {
+	// Bounds for intervals derived from hints and posting frequency.
+	// A refresh interval the user set for a feed isn't limited.
+	MinInterval time.Duration
+	MaxInterval time.Duration
+}
+
+// DefaultRefreshPolicy returns the policy used when the settings don't specify one
+func DefaultRefreshPolicy() RefreshPolicy {
+	return RefreshPolicy{
+		MinInterval: 15 * time.Minute,
+		MaxInterval: 24 * time.Hour,
+	}
+}
+
+// Interval returns how long to wait between refreshes of a feed.
+// The posting interval is the average time between the feed's
+// recent items, or zero if unknown.
+func (p RefreshPolicy) Interval(feedRecord store.FeedRecord, postingInterval time.Duration) time.Duration {
+	// The user's choice overrides everything else
+	if feedRecord.RefreshInterval > 0 {
+		return feedRecord.RefreshInterval
+	}
+
+	// Check about twice as often as the feed posts,
+	// so new items show up reasonably soon.
+	interval := DefaultRefreshInterval
+	if postingInterval > 0 {
+		interval = postingInterval / 2
+	}
+
+	// Don't refresh more often than the feed asks
+	hints := feedRecord.RefreshHints
+	if interval < hints.TTL {
+		interval = hints.TTL
+	}
+
+	if interval < hints.UpdateInterval {
+		interval = hints.UpdateInterval
+	}
+
+	if p.MinInterval > 0 && interval < p.MinInterval {
+		interval = p.MinInterval
+	}
+
+	if p.MaxInterval > 0 && interval > p.MaxInterval {
+		interval = p.MaxInterval
+	}
+
+	return interval
+}
+
+// NextRefresh returns when a feed last refreshed at `lastRefresh` is due again.
+// Unless the user set the feed's refresh interval, this is postponed past
+// any hours or days the feed asks to skip.
+func (p RefreshPolicy) NextRefresh(feedRecord store.FeedRecord, lastRefresh time.Time, postingInterval time.Duration) time.Time {
+	next := lastRefresh.Add(p.Interval(feedRecord, postingInterval))
+	if feedRecord.RefreshInterval > 0 {
+		return next
+	}
+	return skipHoursAndDays(next, feedRecord.RefreshHints)
+}
+
+// skipHoursAndDays returns the start of the first hour at or after `t`
+// that the feed doesn't ask to skip.
+func skipHoursAndDays(t time.Time, hints feed.RefreshHints) time.Time {
+	// A feed that skips every hour would never be refreshed,
+	// so ignore the hints if there's no allowed hour within a week.
+	next := t
+	for i := 0; i < 7*24; i++ {
+		utc := next.UTC()
+		if !containsHour(hints.SkipHours, utc.Hour()) && !containsDay(hints.SkipDays, utc.Weekday()) {
+			return next
+		}
+		next = utc.Truncate(time.Hour).Add(time.Hour)
+	}
+	return t
+}
+
+func containsHour(hours []int, hour int) bool {
+	for _, h := range hours {
+		if h == hour {
+			return true
+		}
+	}
+	return false
+}
+
+func containsDay(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package task
+
+import (
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/store"
+	"testing"
+	"time"
+)
+
+func TestRefreshInterval(t *testing.T) {
+	policy := RefreshPolicy{MinInterval: 15 * time.Minute, MaxInterval: 12 * time.Hour}
+
+	testCases := []struct {
+		name            string
+		feedRecord      store.FeedRecord
+		postingInterval time.Duration
+		expected        time.Duration
+	}{
+		{
+			name:     "no hints or items",
+			expected: DefaultRefreshInterval,
+		},
+		{
+			name:            "busy feed",
+			postingInterval: 10 * time.Minute,
+			expected:        15 * time.Minute,
+		},
+		{
+			name:            "daily feed",
+			postingInterval: 24 * time.Hour,
+			expected:        12 * time.Hour,
+		},
+		{
+			name:            "weekly feed",
+			postingInterval: 7 * 24 * time.Hour,
+			expected:        12 * time.Hour,
+		},
+		{
+			name: "ttl",
+			feedRecord: store.FeedRecord{
+				RefreshHints: feed.RefreshHints{TTL: 3 * time.Hour},
+			},
+			postingInterval: time.Hour,
+			expected:        3 * time.Hour,
+		},
+		{
+			name: "syndication update period",
+			feedRecord: store.FeedRecord{
+				RefreshHints: feed.RefreshHints{TTL: time.Hour, UpdateInterval: 6 * time.Hour},
+			},
+			expected: 6 * time.Hour,
+		},
+		{
+			name: "user override ignores hints and bounds",
+			feedRecord: store.FeedRecord{
+				RefreshInterval: 5 * time.Minute,
+				RefreshHints:    feed.RefreshHints{TTL: 3 * time.Hour},
+			},
+			postingInterval: 24 * time.Hour,
+			expected:        5 * time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			interval := policy.Interval(tc.feedRecord, tc.postingInterval)
+			if interval != tc.expected {
+				t.Errorf("Expected interval %v, but got %v", tc.expected, interval)
+			}
+		})
+	}
+}
+
+func TestNextRefreshSkipsHoursAndDays(t *testing.T) {
+	policy := DefaultRefreshPolicy()
+
+	// Saturday at 22:30 UTC
+	lastRefresh := time.Date(2020, time.April, 4, 22, 30, 0, 0, time.UTC)
+
+	feedRecord := store.FeedRecord{
+		RefreshHints: feed.RefreshHints{
+			SkipHours: []int{23, 0},
+			SkipDays:  []time.Weekday{time.Sunday},
+		},
+	}
+
+	// Skip the rest of Saturday night and all of Sunday
+	next := policy.NextRefresh(feedRecord, lastRefresh, 0)
+	expected := time.Date(2020, time.April, 6, 1, 0, 0, 0, time.UTC)
+	if !next.Equal(expected) {
+		t.Errorf("Expected next refresh at %v, but got %v", expected, next)
+	}
+
+	// A feed that skips every day is refreshed anyway
+	feedRecord.RefreshHints.SkipDays = []time.Weekday{
+		time.Sunday, time.Monday, time.Tuesday, time.Wednesday,
+		time.Thursday, time.Friday, time.Saturday,
+	}
+	next = policy.NextRefresh(feedRecord, lastRefresh, 0)
+	expected = lastRefresh.Add(DefaultRefreshInterval)
+	if !next.Equal(expected) {
+		t.Errorf("Expected next refresh at %v, but got %v", expected, next)
+	}
+
+	// The user's interval ignores the hints
+	feedRecord.RefreshInterval = 30 * time.Minute
+	next = policy.NextRefresh(feedRecord, lastRefresh, 0)
+	expected = lastRefresh.Add(30 * time.Minute)
+	if !next.Equal(expected) {
+		t.Errorf("Expected next refresh at %v, but got %v", expected, next)
+	}
+}
@@ -0,0 +1,135 @@
+package task
+
+import (
+	"github.com/wedaly/local-news/internal/store"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshInterval is used for feeds without a refresh interval,
+// hints, or enough items to estimate how often they post.
+const DefaultRefreshInterval time.Duration = time.Hour
+
+// Scheduler periodically schedules tasks to refresh feeds
+// once their refresh interval has elapsed since they were last synced.
+// The interval of each feed is decided by the refresh policy.
+type Scheduler struct {
+	feedStore     *store.FeedStore
+	taskManager   *TaskManager
+	policy        RefreshPolicy
+	mutex         sync.Mutex
+	lastScheduled map[store.FeedId]time.Time
+	done          chan struct{}
+}
+
+func NewScheduler(feedStore *store.FeedStore, taskManager *TaskManager, policy RefreshPolicy) *Scheduler {
+	return &Scheduler{
+		feedStore:     feedStore,
+		taskManager:   taskManager,
+		policy:        policy,
+		lastScheduled: make(map[store.FeedId]time.Time, 0),
+	}
+}
+
+// Start checks for feeds due for a refresh every `checkInterval`
+// until Stop is called.
+func (s *Scheduler) Start(checkInterval time.Duration) {
+	if s.done != nil {
+		panic("Scheduler already started")
+	}
+
+	s.done = make(chan struct{})
+	ticker := time.NewTicker(checkInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				// Errors are recorded in each feed's sync status,
+				// and the next tick will try again.
+				s.ScheduleDueFeeds(now)
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts the periodic checks started by Start
+func (s *Scheduler) Stop() {
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+}
+
+// ScheduleDueFeeds schedules a task to load each feed whose next refresh
+// is due as of `now`.  It returns the number of tasks scheduled.
+func (s *Scheduler) ScheduleDueFeeds(now time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	feedRecords, err := s.feedStore.RetrieveFeeds()
+	if err != nil {
+		return 0, err
+	}
+
+	numScheduled := 0
+	for _, feedRecord := range feedRecords {
+		nextRefresh, err := s.nextRefresh(feedRecord)
+		if err != nil {
+			return numScheduled, err
+		}
+
+		if !now.Before(nextRefresh) {
+			s.taskManager.ScheduleLoadFeedTask(feedRecord.Id)
+			s.lastScheduled[feedRecord.Id] = now
+			numScheduled++
+		}
+	}
+
+	return numScheduled, nil
+}
+
+// NextRefresh returns when the scheduler will next refresh a feed.
+// This is in the past if the feed is already due.
+func (s *Scheduler) NextRefresh(feedId store.FeedId) (time.Time, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	feedRecord, err := s.feedStore.RetrieveFeed(feedId)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return s.nextRefresh(feedRecord)
+}
+
+// nextRefresh returns when a feed is due, counting from the later of its
+// last sync and the last time it was scheduled.
+// The caller must hold the mutex.
+func (s *Scheduler) nextRefresh(feedRecord store.FeedRecord) (time.Time, error) {
+	hasSynced, syncStatus, err := s.feedStore.RetrieveFeedSyncStatus(feedRecord.Id)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Avoid scheduling a feed again while an earlier task
+	// is still waiting for a loader.
+	lastRefresh, ok := s.lastScheduled[feedRecord.Id]
+	if hasSynced && syncStatus.Date.After(lastRefresh) {
+		lastRefresh, ok = syncStatus.Date, true
+	}
+
+	// Feeds that have never been loaded are due immediately
+	if !ok {
+		return time.Time{}, nil
+	}
+
+	postingInterval, err := s.feedStore.RetrievePostingInterval(feedRecord.Id)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return s.policy.NextRefresh(feedRecord, lastRefresh, postingInterval), nil
+}
@@ -0,0 +1,71 @@
+package task
+
+import (
+	"fmt"
+	"github.com/wedaly/local-news/internal/feed"
+	"github.com/wedaly/local-news/internal/store"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestScheduleDueFeeds(t *testing.T) {
+	dbPath := path.Join(os.TempDir(), "test-scheduler.db")
+	defer func() { os.Remove(dbPath) }()
+	feedStore := store.NewFeedStore(dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer feedStore.Close()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `<rss><channel><title>Feed</title></channel></rss>`)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	subscriber := &StubSubscriber{
+		resultChan: make(chan TaskResult, 100),
+	}
+	tm := NewTaskManager(feedStore, feed.DefaultLoaderConfig())
+	tm.Subscribe(subscriber)
+	scheduler := NewScheduler(feedStore, tm, DefaultRefreshPolicy())
+
+	// One feed was synced just now, the other has never been synced
+	syncedId, err := feedStore.GetOrCreateFeedWithUrl(server.URL + "/synced")
+	if err != nil {
+		t.Fatalf("Could not insert feed record: %v", err)
+	}
+	if _, err := feedStore.SyncFeed(syncedId, feed.Feed{Name: "Synced"}); err != nil {
+		t.Fatalf("Could not sync feed: %v", err)
+	}
+	if _, err := feedStore.GetOrCreateFeedWithUrl(server.URL + "/new"); err != nil {
+		t.Fatalf("Could not insert feed record: %v", err)
+	}
+
+	now := time.Now()
+	assertScheduled := func(now time.Time, expected int) {
+		numScheduled, err := scheduler.ScheduleDueFeeds(now)
+		if err != nil {
+			t.Fatalf("Could not schedule feeds: %v", err)
+		}
+		if numScheduled != expected {
+			t.Errorf("Expected %v feeds scheduled, but got %v", expected, numScheduled)
+		}
+		for i := 0; i < numScheduled; i++ {
+			<-subscriber.resultChan
+		}
+	}
+
+	// Only the feed that has never been synced is due
+	assertScheduled(now, 1)
+
+	// Neither feed is due again until the interval elapses
+	assertScheduled(now.Add(time.Minute), 0)
+
+	// Both feeds are due after the default interval
+	assertScheduled(now.Add(2*DefaultRefreshInterval), 2)
+}
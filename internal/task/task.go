@@ -1,6 +1,7 @@
 package task
 
 import (
+	"fmt"
 	"github.com/wedaly/local-news/internal/feed"
 	"github.com/wedaly/local-news/internal/store"
 	"sync"
@@ -8,8 +9,17 @@ import (
 
 // TaskResult describes the outcome of a task to load a feed
 type TaskResult struct {
+	// The feed that was loaded, which is set even if the task failed
 	FeedId store.FeedId
 	Err    error
+
+	// If the feed's URL moved to the URL of another feed,
+	// the two feeds are merged and `FeedId` is the ID of the other feed.
+	// This is the ID of the feed that was merged (and deleted), or zero.
+	MergedFeedId store.FeedId
+
+	// Items inserted by the task (not those updated), if it succeeded
+	NewItems []store.FeedItemRecord
 }
 
 // TaskSubscriber receives notifications about tasks
@@ -23,6 +33,14 @@ type TaskSubscriber interface {
 	HandleTaskCompleted(TaskResult)
 }
 
+// RemoteSource loads the feeds linked to subscriptions on a remote
+// aggregator (such as Miniflux) instead of loading them from their URLs.
+type RemoteSource interface {
+	// SyncFeed loads a feed from the server and writes it to the database,
+	// returning the items inserted.  This must be thread-safe.
+	SyncFeed(store.RemoteFeedRecord) ([]store.FeedItemRecord, error)
+}
+
 // TaskManager schedules async, concurrent tasks to load feeds
 // It notifies all subscribers when tasks are scheduled and completed.
 type TaskManager struct {
@@ -30,22 +48,38 @@ type TaskManager struct {
 	subscribersMutex sync.Mutex
 	subscribers      []TaskSubscriber
 	loaderChan       chan *feed.FeedLoader
+	remotes          map[string]RemoteSource
+	archivePolicy    ArchivePolicy
+	archiveMutex     sync.Mutex
+	archiving        map[store.FeedItemId]bool
+	archiveWaitGroup sync.WaitGroup
 }
 
-func NewTaskManager(feedStore *store.FeedStore) *TaskManager {
+// NewTaskManager creates a task manager whose feed loaders use
+// the specified config, unless overridden by a feed's own settings.
+func NewTaskManager(feedStore *store.FeedStore, loaderConfig feed.LoaderConfig) *TaskManager {
 	const numFeedLoaders int = 10
 	loaderChan := make(chan *feed.FeedLoader, numFeedLoaders)
 	for i := 0; i < numFeedLoaders; i++ {
-		loaderChan <- feed.NewFeedLoader()
+		loaderChan <- feed.NewFeedLoader(loaderConfig)
 	}
 
 	return &TaskManager{
-		feedStore:   feedStore,
-		subscribers: make([]TaskSubscriber, 0),
-		loaderChan:  loaderChan,
+		feedStore:     feedStore,
+		subscribers:   make([]TaskSubscriber, 0),
+		loaderChan:    loaderChan,
+		remotes:       make(map[string]RemoteSource, 0),
+		archivePolicy: DefaultArchivePolicy(),
+		archiving:     make(map[store.FeedItemId]bool, 0),
 	}
 }
 
+// RegisterRemote sets the source of the feeds linked to a remote account.
+// This is NOT thread-safe, so it must be called before scheduling any tasks.
+func (m *TaskManager) RegisterRemote(account string, source RemoteSource) {
+	m.remotes[account] = source
+}
+
 func (m *TaskManager) Subscribe(s TaskSubscriber) {
 	m.subscribersMutex.Lock()
 	defer m.subscribersMutex.Unlock()
@@ -68,32 +102,141 @@ func (m *TaskManager) ScheduleLoadFeedTask(feedId store.FeedId) {
 		// This implicitly validates that the feed has not been deleted
 		feedRecord, err := m.feedStore.RetrieveFeed(feedId)
 		if err != nil {
-			m.notifyTaskCompleted(TaskResult{Err: err})
+			m.notifyTaskCompleted(TaskResult{FeedId: feedId, Err: err})
+			return
+		}
+
+		// Feeds linked to a remote account are loaded from its server
+		isRemote, remoteFeed, err := m.feedStore.RetrieveRemoteFeed(feedId)
+		if err != nil {
+			m.notifyTaskCompleted(TaskResult{FeedId: feedId, Err: err})
+			return
+		} else if isRemote {
+			result := m.loadRemoteFeed(remoteFeed)
+			m.scheduleArchiveTasksForResult(result)
+			m.notifyTaskCompleted(result)
+			return
+		}
+
+		// Retrieve credentials and headers for private feeds
+		req, err := m.buildLoadRequest(feedRecord)
+		if err != nil {
+			m.notifyTaskCompleted(TaskResult{FeedId: feedId, Err: err})
 			return
 		}
 
 		// Retrieve and parse the feed from a URL
-		feed, err := loader.LoadFeedFromUrl(feedRecord.Url)
+		feed, err := loader.LoadFeed(req)
 		if err != nil {
 			if err := m.feedStore.SetFeedSyncStatusError(feedId, err); err != nil {
 				panic(err)
 			}
-			m.notifyTaskCompleted(TaskResult{Err: err})
+			m.notifyTaskCompleted(TaskResult{FeedId: feedId, Err: err})
 			return
 		}
 
+		// Update the feed's URL if the source permanently redirected
+		result := TaskResult{FeedId: feedId}
+		if len(feed.MovedTo) > 0 {
+			movedId, err := m.feedStore.MoveFeed(feedId, feed.MovedTo)
+			if err != nil {
+				m.notifyTaskCompleted(TaskResult{FeedId: feedId, Err: err})
+				return
+			}
+
+			if movedId != feedId {
+				result = TaskResult{FeedId: movedId, MergedFeedId: feedId}
+			}
+		}
+
 		// Update the database
-		err = m.feedStore.SyncFeed(feedId, feed)
+		result.NewItems, err = m.feedStore.SyncFeed(result.FeedId, feed)
 		if err != nil {
-			m.notifyTaskCompleted(TaskResult{Err: err})
+			m.notifyTaskCompleted(TaskResult{FeedId: feedId, Err: err})
 			return
 		}
 
+		// Archive the articles linked from the new items, if enabled
+		m.scheduleArchiveTasksForResult(result)
+
 		// Notify subscribers that the task completed successfully
-		m.notifyTaskCompleted(TaskResult{FeedId: feedId})
+		m.notifyTaskCompleted(result)
 	}()
 }
 
+// PreviewFeed loads a feed in the background without saving it,
+// so the user can check the feed's settings (such as scrape selectors)
+// before adding it.  The callback is invoked from another goroutine
+// with the loaded feed or an error.  Subscribers are NOT notified.
+func (m *TaskManager) PreviewFeed(req feed.LoadRequest, callback func(feed.Feed, error)) {
+	go func() {
+		// Block until loader is available
+		loader := <-m.loaderChan
+		defer func() { m.loaderChan <- loader }()
+
+		callback(loader.LoadFeed(req))
+	}()
+}
+
+func (m *TaskManager) loadRemoteFeed(remoteFeed store.RemoteFeedRecord) TaskResult {
+	feedId := remoteFeed.FeedId
+	var newItems []store.FeedItemRecord
+	var err error
+	if source, ok := m.remotes[remoteFeed.Account]; ok {
+		newItems, err = source.SyncFeed(remoteFeed)
+	} else {
+		err = fmt.Errorf("No remote account named '%v' in the settings", remoteFeed.Account)
+	}
+
+	if err != nil {
+		if err := m.feedStore.SetFeedSyncStatusError(feedId, err); err != nil {
+			panic(err)
+		}
+		return TaskResult{FeedId: feedId, Err: err}
+	}
+
+	return TaskResult{FeedId: feedId, NewItems: newItems}
+}
+
+func (m *TaskManager) scheduleArchiveTasksForResult(result TaskResult) {
+	if result.Err == nil && len(result.NewItems) > 0 {
+		if err := m.ScheduleArchiveTasks(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (m *TaskManager) buildLoadRequest(feedRecord store.FeedRecord) (feed.LoadRequest, error) {
+	creds, err := m.feedStore.RetrieveFeedCredentials(feedRecord.Id)
+	if err != nil {
+		return feed.LoadRequest{}, err
+	}
+
+	headers, err := m.feedStore.RetrieveFeedHeaders(feedRecord.Id)
+	if err != nil {
+		return feed.LoadRequest{}, err
+	}
+
+	config, err := m.feedStore.RetrieveFeedLoaderConfig(feedRecord.Id)
+	if err != nil {
+		return feed.LoadRequest{}, err
+	}
+
+	scrapeConfig, err := m.feedStore.RetrieveFeedScrapeConfig(feedRecord.Id)
+	if err != nil {
+		return feed.LoadRequest{}, err
+	}
+
+	req := feed.LoadRequest{
+		Url:         feedRecord.Url,
+		Credentials: creds,
+		Headers:     headers,
+		Config:      config,
+		Scrape:      scrapeConfig,
+	}
+	return req, nil
+}
+
 func (m *TaskManager) notifyTaskScheduled() {
 	m.subscribersMutex.Lock()
 	defer m.subscribersMutex.Unlock()
@@ -2,13 +2,16 @@ package task
 
 import (
 	"fmt"
+	"github.com/wedaly/local-news/internal/feed"
 	"github.com/wedaly/local-news/internal/store"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 type StubSubscriber struct {
@@ -66,7 +69,7 @@ func TestScheduleLoadFeedTasks(t *testing.T) {
 	}
 
 	// Set up the task manager
-	tm := NewTaskManager(store)
+	tm := NewTaskManager(store, feed.DefaultLoaderConfig())
 	tm.Subscribe(subscriber)
 
 	// Insert a new feed
@@ -99,3 +102,360 @@ func TestScheduleLoadFeedTasks(t *testing.T) {
 			numTasks, subscriber.numScheduled)
 	}
 }
+
+func TestLoadFeedTaskError(t *testing.T) {
+	dbPath := path.Join(os.TempDir(), "test-task-error.db")
+	defer func() { os.Remove(dbPath) }()
+	store := store.NewFeedStore(dbPath)
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer store.Close()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	subscriber := &StubSubscriber{
+		resultChan: make(chan TaskResult, 1),
+	}
+	tm := NewTaskManager(store, feed.DefaultLoaderConfig())
+	tm.Subscribe(subscriber)
+
+	feedId, err := store.GetOrCreateFeedWithUrl(server.URL)
+	if err != nil {
+		t.Fatalf("Could not insert feed record: %v", err)
+	}
+
+	// The result identifies the feed, so subscribers can tell which one failed
+	tm.ScheduleLoadFeedTask(feedId)
+	r := <-subscriber.resultChan
+	if r.Err == nil {
+		t.Errorf("Expected error loading feed")
+	} else if r.FeedId != feedId {
+		t.Errorf("Expected feed id %v in task result, but got %v", feedId, r.FeedId)
+	}
+}
+
+func TestLoadFeedTaskMovesFeed(t *testing.T) {
+	dbPath := path.Join(os.TempDir(), "test-task-moved.db")
+	defer func() { os.Remove(dbPath) }()
+	store := store.NewFeedStore(dbPath)
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer store.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `<rss><channel><title>Feed</title></channel></rss>`)
+	})
+	mux.Handle("/old", http.RedirectHandler("/feed", http.StatusMovedPermanently))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	subscriber := &StubSubscriber{
+		resultChan: make(chan TaskResult, 1),
+	}
+	tm := NewTaskManager(store, feed.DefaultLoaderConfig())
+	tm.Subscribe(subscriber)
+
+	feedId, err := store.GetOrCreateFeedWithUrl(server.URL + "/old")
+	if err != nil {
+		t.Fatalf("Could not insert feed record: %v", err)
+	}
+
+	tm.ScheduleLoadFeedTask(feedId)
+	r := <-subscriber.resultChan
+	if r.Err != nil {
+		t.Fatalf("Unexpected error processing task: %v", r.Err)
+	}
+
+	if r.FeedId != feedId || r.MergedFeedId != 0 {
+		t.Errorf("Unexpected feed IDs in task result: %v", r)
+	}
+
+	feedRecord, err := store.RetrieveFeed(feedId)
+	if err != nil {
+		t.Fatalf("Could not retrieve feed: %v", err)
+	}
+
+	if feedRecord.Url != server.URL+"/feed" {
+		t.Errorf("Feed URL was not updated, got %v", feedRecord.Url)
+	}
+}
+
+func TestLoadFeedTaskScrapesPage(t *testing.T) {
+	dbPath := path.Join(os.TempDir(), "test-task-scrape.db")
+	defer func() { os.Remove(dbPath) }()
+	store := store.NewFeedStore(dbPath)
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer store.Close()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `
+			<html>
+				<head><title>News</title></head>
+				<body><h2><a href="/story">Story</a></h2></body>
+			</html>`)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	subscriber := &StubSubscriber{
+		resultChan: make(chan TaskResult, 1),
+	}
+	tm := NewTaskManager(store, feed.DefaultLoaderConfig())
+	tm.Subscribe(subscriber)
+
+	feedId, err := store.GetOrCreateFeedWithUrl(server.URL)
+	if err != nil {
+		t.Fatalf("Could not insert feed record: %v", err)
+	}
+
+	scrapeConfig := feed.ScrapeConfig{ItemSelector: "h2"}
+	if err := store.SetFeedScrapeConfig(feedId, scrapeConfig); err != nil {
+		t.Fatalf("Could not set scrape config: %v", err)
+	}
+
+	tm.ScheduleLoadFeedTask(feedId)
+	r := <-subscriber.resultChan
+	if r.Err != nil {
+		t.Fatalf("Unexpected error processing task: %v", r.Err)
+	}
+
+	items, err := store.RetrieveFeedItems(feedId)
+	if err != nil {
+		t.Fatalf("Could not retrieve items: %v", err)
+	}
+
+	expectedUrl := server.URL + "/story"
+	if len(items) != 1 || items[0].Guid != expectedUrl || items[0].Title != "Story" {
+		t.Errorf("Incorrect scraped items: %v", items)
+	}
+}
+
+// stubRemoteSource records the remote feeds it loads
+type stubRemoteSource chan store.RemoteFeedRecord
+
+func (s stubRemoteSource) SyncFeed(r store.RemoteFeedRecord) ([]store.FeedItemRecord, error) {
+	s <- r
+	return nil, nil
+}
+
+func TestLoadRemoteFeedTask(t *testing.T) {
+	dbPath := path.Join(os.TempDir(), "test-task-remote.db")
+	defer func() { os.Remove(dbPath) }()
+	feedStore := store.NewFeedStore(dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer feedStore.Close()
+
+	subscriber := &StubSubscriber{
+		resultChan: make(chan TaskResult, 1),
+	}
+	source := make(stubRemoteSource, 1)
+	tm := NewTaskManager(feedStore, feed.DefaultLoaderConfig())
+	tm.RegisterRemote("home", source)
+	tm.Subscribe(subscriber)
+
+	// The URL can't be loaded, so the feed must be loaded from the remote source
+	feedId, err := feedStore.GetOrCreateFeedWithUrl("http://127.0.0.1:1/feed")
+	if err != nil {
+		t.Fatalf("Could not insert feed record: %v", err)
+	}
+
+	remoteFeed := store.RemoteFeedRecord{FeedId: feedId, Account: "home", RemoteId: "42"}
+	if err := feedStore.SetRemoteFeed(remoteFeed); err != nil {
+		t.Fatalf("Could not link remote feed: %v", err)
+	}
+
+	tm.ScheduleLoadFeedTask(feedId)
+	if r := <-subscriber.resultChan; r.Err != nil || r.FeedId != feedId {
+		t.Errorf("Unexpected result %v", r)
+	}
+
+	if r := <-source; r != remoteFeed {
+		t.Errorf("Expected remote feed %v, but got %v", remoteFeed, r)
+	}
+
+	// Feeds linked to accounts that aren't in the settings fail
+	remoteFeed.Account = "work"
+	if err := feedStore.SetRemoteFeed(remoteFeed); err != nil {
+		t.Fatalf("Could not link remote feed: %v", err)
+	}
+
+	tm.ScheduleLoadFeedTask(feedId)
+	if r := <-subscriber.resultChan; r.Err == nil {
+		t.Errorf("Expected error for unknown account")
+	}
+}
+
+func TestLoadFeedTaskArchivesArticles(t *testing.T) {
+	dbPath := path.Join(os.TempDir(), "test-task-archive.db")
+	defer func() { os.Remove(dbPath) }()
+	feedStore := store.NewFeedStore(dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer feedStore.Close()
+
+	mux := http.NewServeMux()
+	var serverUrl string
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `
+			<rss>
+				<channel>
+					<title>Blog</title>
+					<item>
+						<title>Article</title>
+						<link>%[1]v/article</link>
+						<guid>1</guid>
+						<pubDate>Sat, 06 Apr 2019 02:00:22 +0000</pubDate>
+					</item>
+					<item>
+						<title>Missing</title>
+						<link>%[1]v/missing</link>
+						<guid>2</guid>
+						<pubDate>Sun, 07 Apr 2019 02:00:22 +0000</pubDate>
+					</item>
+				</channel>
+			</rss>`, serverUrl)
+	})
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Full article</title></head><body><div class="post">
+			<p>The article's first paragraph, which is long, has commas, and is about something.</p>
+			<p>The article's second paragraph, which is also long, has commas, and concludes it.</p>
+			</div></body></html>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverUrl = server.URL
+
+	subscriber := &StubSubscriber{
+		resultChan: make(chan TaskResult, 1),
+	}
+	tm := NewTaskManager(feedStore, feed.DefaultLoaderConfig())
+	tm.Subscribe(subscriber)
+
+	feedId, err := feedStore.GetOrCreateFeedWithUrl(server.URL + "/feed")
+	if err != nil {
+		t.Fatalf("Could not insert feed record: %v", err)
+	}
+
+	settings := store.FeedSettings{Url: server.URL + "/feed", ArchiveArticles: true}
+	if err := feedStore.UpdateFeedSettings(feedId, settings); err != nil {
+		t.Fatalf("Could not update feed settings: %v", err)
+	}
+
+	tm.ScheduleLoadFeedTask(feedId)
+	r := <-subscriber.resultChan
+	if r.Err != nil || len(r.NewItems) != 2 {
+		t.Fatalf("Unexpected result %v", r)
+	}
+
+	// Archive tasks run in the background after the feed is loaded
+	var archived, failed store.ArchivedArticleRecord
+	for i := 0; i < 100 && (archived.Archived.IsZero() || failed.Archived.IsZero()); i++ {
+		time.Sleep(50 * time.Millisecond)
+		_, archived, _ = feedStore.RetrieveArchivedArticle(r.NewItems[0].Id)
+		_, failed, _ = feedStore.RetrieveArchivedArticle(r.NewItems[1].Id)
+	}
+
+	if archived.Title != "Full article" || !strings.Contains(archived.ContentHtml, "second paragraph") || archived.Err != "" {
+		t.Errorf("Unexpected archived article %v", archived)
+	}
+
+	if failed.Err == "" || failed.ContentHtml != "" {
+		t.Errorf("Expected error archiving missing article, but got %v", failed)
+	}
+
+	// Archiving an item manually replaces the failed attempt
+	missing := r.NewItems[1]
+	missing.Url = server.URL + "/article"
+	done := make(chan error, 1)
+	tm.ArchiveItem(missing, func(article store.ArchivedArticleRecord, err error) {
+		if err == nil && article.ItemId != missing.Id {
+			err = fmt.Errorf("Unexpected article %v", article)
+		}
+		done <- err
+	})
+
+	if err := <-done; err != nil {
+		t.Errorf("Could not archive item: %v", err)
+	} else if _, article, _ := feedStore.RetrieveArchivedArticle(missing.Id); article.Err != "" {
+		t.Errorf("Expected archived article to replace error, but got %v", article)
+	}
+}
+
+func TestArchiveItemWithFeedLoaderConfig(t *testing.T) {
+	dbPath := path.Join(os.TempDir(), "test-task-archive-config.db")
+	defer func() { os.Remove(dbPath) }()
+	feedStore := store.NewFeedStore(dbPath)
+	if err := feedStore.Initialize(); err != nil {
+		t.Fatalf("Could not initialize store: %v", err)
+	}
+	defer feedStore.Close()
+
+	userAgents := make(chan string, 2)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		userAgents <- r.UserAgent()
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><title>Full article</title></head><body><div class="post">
+			<p>The article's first paragraph, which is long, has commas, and is about something.</p>
+			<p>The article's second paragraph, which is also long, has commas, and concludes it.</p>
+			</div></body></html>`)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	feedId, err := feedStore.GetOrCreateFeedWithUrl(server.URL + "/feed")
+	if err != nil {
+		t.Fatalf("Could not insert feed record: %v", err)
+	}
+
+	f := feed.Feed{
+		Name:  "Blog",
+		Items: []feed.FeedItem{feed.FeedItem{Title: "Article", Url: server.URL + "/article", Guid: "1"}},
+	}
+	items, err := feedStore.SyncFeed(feedId, f)
+	if err != nil {
+		t.Fatalf("Could not sync feed: %v", err)
+	}
+
+	config := feed.LoaderConfig{UserAgent: "feed-agent"}
+	if err := feedStore.SetFeedLoaderConfig(feedId, config); err != nil {
+		t.Fatalf("Could not set loader config: %v", err)
+	}
+
+	tm := NewTaskManager(feedStore, feed.DefaultLoaderConfig())
+	archive := func() error {
+		done := make(chan error, 1)
+		tm.ArchiveItem(items[0], func(article store.ArchivedArticleRecord, err error) {
+			done <- err
+		})
+		return <-done
+	}
+
+	// The article is retrieved with the feed's settings
+	if err := archive(); err != nil {
+		t.Fatalf("Could not archive item: %v", err)
+	}
+	if userAgent := <-userAgents; userAgent != "feed-agent" {
+		t.Errorf("Expected feed's User-Agent, but got %q", userAgent)
+	}
+
+	// Archiving an item from a deleted feed returns an error
+	if err := feedStore.DeleteFeed(feedId); err != nil {
+		t.Fatalf("Could not delete feed: %v", err)
+	}
+	if err := archive(); err == nil {
+		t.Errorf("Expected error archiving item of deleted feed")
+	}
+}